@@ -0,0 +1,261 @@
+// Package car implements reading and writing of CARv1 (content addressed
+// archive) files.
+//
+// A CAR file is a varint length-prefixed dag-cbor header, listing the roots
+// of the archive, followed by a sequence of varint length-prefixed sections
+// each holding a CID and the raw bytes of the block it addresses.
+package car
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+
+	cid "gx/ipfs/QmR8BauakNcBa3RbE4nbQu76PDiJgoQgz8AJdhJuiU4TAw/go-cid"
+	ipld "gx/ipfs/QmRL22E4paat7ky7vx9MLpR97JHHbFPrg3ytFQw6qp1y1s/go-ipld-format"
+	cbor "gx/ipfs/QmRZxJ7oybgnnwriuRub9JXp5YdFM9wiGSyRq38QC7swpS/go-ipld-cbor"
+	blocks "gx/ipfs/QmWoXtvgC8inqFkAATB7cp2Dax7XBi9VDvSg9RCCZufmRk/go-block-format"
+)
+
+// Version is the only CAR format version supported by this package.
+const Version = 1
+
+// maxSectionSize bounds the size of a single header or block section so a
+// corrupted length prefix can't make us allocate unbounded amounts of memory.
+const maxSectionSize = 32 << 20
+
+var (
+	// ErrNoRoots is returned when a CAR header doesn't list any roots.
+	ErrNoRoots = errors.New("car header has no roots")
+
+	// ErrSectionTooLarge is returned when a section length prefix exceeds
+	// the maximum allowed size.
+	ErrSectionTooLarge = errors.New("car section too large")
+)
+
+func init() {
+	cbor.RegisterCborType(Header{})
+}
+
+// Header is the header of a CAR file.
+type Header struct {
+	Roots   []cid.Cid `refmt:"roots"`
+	Version uint64    `refmt:"version"`
+}
+
+// Putter is the subset of a blockstore needed to load a CAR file.
+type Putter interface {
+	Put(blocks.Block) error
+}
+
+// WriteCar writes a CAR file containing every block reachable from the given
+// roots to w. Blocks are written in depth-first order and each block is
+// written only once.
+func WriteCar(ctx context.Context, ng ipld.NodeGetter, roots []cid.Cid, w io.Writer) error {
+	if len(roots) == 0 {
+		return ErrNoRoots
+	}
+
+	hb, err := cbor.DumpObject(&Header{Roots: roots, Version: Version})
+	if err != nil {
+		return err
+	}
+
+	bw := bufio.NewWriter(w)
+	if err := writeSection(bw, hb); err != nil {
+		return err
+	}
+
+	seen := cid.NewSet()
+	for _, r := range roots {
+		if err := writeDag(ctx, ng, r, seen, bw); err != nil {
+			return err
+		}
+	}
+
+	return bw.Flush()
+}
+
+func writeDag(ctx context.Context, ng ipld.NodeGetter, c cid.Cid, seen *cid.Set, w io.Writer) error {
+	if !seen.Visit(c) {
+		return nil
+	}
+
+	nd, err := ng.Get(ctx, c)
+	if err != nil {
+		return err
+	}
+
+	if err := writeSection(w, c.Bytes(), nd.RawData()); err != nil {
+		return err
+	}
+
+	for _, l := range nd.Links() {
+		if err := writeDag(ctx, ng, l.Cid, seen, w); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func writeSection(w io.Writer, data ...[]byte) error {
+	var size uint64
+	for _, d := range data {
+		size += uint64(len(d))
+	}
+
+	buf := make([]byte, binary.MaxVarintLen64)
+	n := binary.PutUvarint(buf, size)
+	if _, err := w.Write(buf[:n]); err != nil {
+		return err
+	}
+
+	for _, d := range data {
+		if _, err := w.Write(d); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Reader reads blocks from a CAR file.
+type Reader struct {
+	br     *bufio.Reader
+	Header *Header
+}
+
+// NewReader reads and validates the header of the CAR file in r and returns
+// a Reader positioned at the first block.
+func NewReader(r io.Reader) (*Reader, error) {
+	br := bufio.NewReader(r)
+
+	hb, err := readSection(br)
+	if err != nil {
+		if err == io.EOF {
+			return nil, io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+
+	var h Header
+	if err := cbor.DecodeInto(hb, &h); err != nil {
+		return nil, fmt.Errorf("invalid car header: %s", err)
+	}
+
+	if h.Version != Version {
+		return nil, fmt.Errorf("unsupported car version: %d", h.Version)
+	}
+
+	if len(h.Roots) == 0 {
+		return nil, ErrNoRoots
+	}
+
+	return &Reader{br: br, Header: &h}, nil
+}
+
+// Next returns the next block in the CAR file, verifying that its data
+// matches its CID. It returns io.EOF once all blocks have been read.
+func (r *Reader) Next() (blocks.Block, error) {
+	data, err := readSection(r.br)
+	if err != nil {
+		return nil, err
+	}
+
+	n, c, err := readCid(data)
+	if err != nil {
+		return nil, err
+	}
+	data = data[n:]
+
+	chk, err := c.Prefix().Sum(data)
+	if err != nil {
+		return nil, err
+	}
+
+	if !chk.Equals(c) {
+		return nil, fmt.Errorf("car block data doesn't match cid %s", c)
+	}
+
+	return blocks.NewBlockWithCid(data, c)
+}
+
+// LoadCar stores every block of the CAR file in r using p and returns the
+// header of the file.
+func LoadCar(p Putter, r io.Reader) (*Header, error) {
+	cr, err := NewReader(r)
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		blk, err := cr.Next()
+		switch err {
+		case nil:
+		case io.EOF:
+			return cr.Header, nil
+		default:
+			return nil, err
+		}
+
+		if err := p.Put(blk); err != nil {
+			return nil, err
+		}
+	}
+}
+
+func readSection(br *bufio.Reader) ([]byte, error) {
+	size, err := binary.ReadUvarint(br)
+	if err != nil {
+		return nil, err
+	}
+
+	if size > maxSectionSize {
+		return nil, ErrSectionTooLarge
+	}
+
+	buf := make([]byte, size)
+	if _, err := io.ReadFull(br, buf); err != nil {
+		if err == io.EOF {
+			return nil, io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+	return buf, nil
+}
+
+// readCid reads a CID from the start of buf and returns the number of bytes
+// it occupied.
+func readCid(buf []byte) (int, cid.Cid, error) {
+	// CIDv0 is a bare sha2-256 multihash
+	if len(buf) >= 34 && buf[0] == 0x12 && buf[1] == 0x20 {
+		c, err := cid.Cast(buf[:34])
+		return 34, c, err
+	}
+
+	n := 0
+	// version, codec, multihash code, multihash length
+	var fields [4]uint64
+	for i := range fields {
+		v, l := binary.Uvarint(buf[n:])
+		if l <= 0 {
+			return 0, cid.Cid{}, errors.New("invalid cid in car section")
+		}
+		fields[i] = v
+		n += l
+	}
+
+	if fields[0] != 1 {
+		return 0, cid.Cid{}, fmt.Errorf("unsupported cid version: %d", fields[0])
+	}
+
+	if uint64(len(buf)-n) < fields[3] {
+		return 0, cid.Cid{}, errors.New("invalid cid in car section")
+	}
+	n += int(fields[3])
+
+	c, err := cid.Cast(buf[:n])
+	return n, c, err
+}
@@ -0,0 +1,117 @@
+package car
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"testing"
+
+	dag "gx/ipfs/QmUtsx89yiCY6F8mbpP6ecXckiSzCBH7EvkKZuZEHBcr1m/go-merkledag"
+	mdtest "gx/ipfs/QmUtsx89yiCY6F8mbpP6ecXckiSzCBH7EvkKZuZEHBcr1m/go-merkledag/test"
+
+	cid "gx/ipfs/QmR8BauakNcBa3RbE4nbQu76PDiJgoQgz8AJdhJuiU4TAw/go-cid"
+	ipld "gx/ipfs/QmRL22E4paat7ky7vx9MLpR97JHHbFPrg3ytFQw6qp1y1s/go-ipld-format"
+	blockstore "gx/ipfs/QmS2aqUZLJp8kF1ihE5rvDGE5LvmKDPnx32w9Z1BW9xLV5/go-ipfs-blockstore"
+	ds "gx/ipfs/Qmf4xQhNomPNhrtZc67qSnfJSjxjXs9LWvknJtSXwimPrM/go-datastore"
+	dssync "gx/ipfs/Qmf4xQhNomPNhrtZc67qSnfJSjxjXs9LWvknJtSXwimPrM/go-datastore/sync"
+)
+
+func TestRoundtrip(t *testing.T) {
+	ctx := context.Background()
+	dserv := mdtest.Mock()
+
+	shared := dag.NewRawNode([]byte("shared"))
+	a := dag.NodeWithData([]byte("a"))
+	b := dag.NodeWithData([]byte("b"))
+	if err := a.AddNodeLink("shared", shared); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.AddNodeLink("shared", shared); err != nil {
+		t.Fatal(err)
+	}
+	root := dag.NodeWithData([]byte("root"))
+	if err := root.AddNodeLink("a", a); err != nil {
+		t.Fatal(err)
+	}
+	if err := root.AddNodeLink("b", b); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := dserv.AddMany(ctx, []ipld.Node{shared, a, b, root}); err != nil {
+		t.Fatal(err)
+	}
+
+	buf := new(bytes.Buffer)
+	if err := WriteCar(ctx, dserv, []cid.Cid{root.Cid()}, buf); err != nil {
+		t.Fatal(err)
+	}
+
+	bs := blockstore.NewBlockstore(dssync.MutexWrap(ds.NewMapDatastore()))
+	h, err := LoadCar(bs, bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(h.Roots) != 1 || !h.Roots[0].Equals(root.Cid()) {
+		t.Fatalf("unexpected roots: %v", h.Roots)
+	}
+
+	for _, nd := range []ipld.Node{shared, a, b, root} {
+		has, err := bs.Has(nd.Cid())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !has {
+			t.Errorf("block %s missing after load", nd.Cid())
+		}
+	}
+
+	cr, err := NewReader(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	count := 0
+	for {
+		_, err := cr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		count++
+	}
+	if count != 4 {
+		t.Errorf("expected 4 blocks in car, got %d", count)
+	}
+}
+
+func TestCorruptBlock(t *testing.T) {
+	ctx := context.Background()
+	dserv := mdtest.Mock()
+
+	nd := dag.NodeWithData([]byte("hello car"))
+	if err := dserv.Add(ctx, nd); err != nil {
+		t.Fatal(err)
+	}
+
+	buf := new(bytes.Buffer)
+	if err := WriteCar(ctx, dserv, []cid.Cid{nd.Cid()}, buf); err != nil {
+		t.Fatal(err)
+	}
+
+	data := buf.Bytes()
+	data[len(data)-1] ^= 0xff
+
+	bs := blockstore.NewBlockstore(dssync.MutexWrap(ds.NewMapDatastore()))
+	if _, err := LoadCar(bs, bytes.NewReader(data)); err == nil {
+		t.Fatal("expected loading a corrupted car to fail")
+	}
+}
+
+func TestNoRoots(t *testing.T) {
+	err := WriteCar(context.Background(), mdtest.Mock(), nil, new(bytes.Buffer))
+	if err != ErrNoRoots {
+		t.Fatalf("expected ErrNoRoots, got %v", err)
+	}
+}
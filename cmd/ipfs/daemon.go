@@ -21,6 +21,8 @@ import (
 	corehttp "github.com/ipfs/go-ipfs/core/corehttp"
 	corerepo "github.com/ipfs/go-ipfs/core/corerepo"
 	nodeMount "github.com/ipfs/go-ipfs/fuse/node"
+	plugin "github.com/ipfs/go-ipfs/plugin"
+	loader "github.com/ipfs/go-ipfs/plugin/loader"
 	fsrepo "github.com/ipfs/go-ipfs/repo/fsrepo"
 	migrate "github.com/ipfs/go-ipfs/repo/fsrepo/migrations"
 
@@ -45,9 +47,7 @@ const (
 	offlineKwd                = "offline" // global option
 	routingOptionKwd          = "routing"
 	routingOptionSupernodeKwd = "supernode"
-	routingOptionDHTClientKwd = "dhtclient"
 	routingOptionDHTKwd       = "dht"
-	routingOptionNoneKwd      = "none"
 	routingOptionDefaultKwd   = "default"
 	unencryptTransportKwd     = "disable-transport-encryption"
 	unrestrictedApiAccessKwd  = "unrestricted-api"
@@ -261,7 +261,15 @@ func daemonFunc(req *cmds.Request, re cmds.ResponseEmitter, env cmds.Environment
 			return fmt.Errorf("fs-repo requires migration")
 		}
 
-		err = migrate.RunMigration(fsrepo.RepoVersion)
+		// the external migrations bring the repo up to the version the
+		// built-in ones start from
+		err = fsrepo.Migrate(cctx.ConfigRoot)
+		if err == fsrepo.ErrNeedMigration {
+			err = migrate.RunMigration(fsrepo.BuiltinMigrationsFrom)
+			if err == nil {
+				err = fsrepo.Migrate(cctx.ConfigRoot)
+			}
+		}
 		if err != nil {
 			fmt.Println("The migrations of fs-repo failed:")
 			fmt.Printf("  %s\n", err)
@@ -314,17 +322,13 @@ func daemonFunc(req *cmds.Request, re cmds.ResponseEmitter, env cmds.Environment
 			routingOption = routingOptionDHTKwd
 		}
 	}
-	switch routingOption {
-	case routingOptionSupernodeKwd:
+	if routingOption == routingOptionSupernodeKwd {
 		return errors.New("supernode routing was never fully implemented and has been removed")
-	case routingOptionDHTClientKwd:
-		ncfg.Routing = core.DHTClientOption
-	case routingOptionDHTKwd:
-		ncfg.Routing = core.DHTOption
-	case routingOptionNoneKwd:
-		ncfg.Routing = core.NilRouterOption
-	default:
-		return fmt.Errorf("unrecognized routing option: %s", routingOption)
+	}
+	// the built-in options, and the ones added by routing plugins
+	ncfg.Routing, err = core.RoutingOptionByName(routingOption)
+	if err != nil {
+		return err
 	}
 
 	node, err := core.NewNode(req.Context, ncfg)
@@ -333,6 +337,7 @@ func daemonFunc(req *cmds.Request, re cmds.ResponseEmitter, env cmds.Environment
 		return err
 	}
 	node.SetLocal(false)
+	node.IsDaemon = true
 
 	if node.PNetFingerprint != nil {
 		fmt.Println("Swarm is limited to private network of peers with the swarm key")
@@ -472,6 +477,9 @@ func serveHTTPApi(req *cmds.Request, cctx *oldcmds.Context) (<-chan error, error
 
 	var opts = []corehttp.ServeOption{
 		corehttp.MetricsCollectionOption("api"),
+	}
+	opts = append(opts, pluginServeOptions(cctx.Plugins, plugin.HTTPAPI)...)
+	opts = append(opts,
 		corehttp.CheckVersionOption(),
 		corehttp.CommandsOption(*cctx),
 		corehttp.WebUIOption,
@@ -482,7 +490,7 @@ func serveHTTPApi(req *cmds.Request, cctx *oldcmds.Context) (<-chan error, error
 		corehttp.MutexFractionOption("/debug/pprof-mutex/"),
 		corehttp.MetricsScrapingOption("/debug/metrics/prometheus"),
 		corehttp.LogOption(),
-	}
+	)
 
 	if len(cfg.Gateway.RootRedirect) > 0 {
 		opts = append(opts, corehttp.RedirectOption("", cfg.Gateway.RootRedirect))
@@ -585,14 +593,28 @@ func serveHTTPGateway(req *cmds.Request, cctx *oldcmds.Context) (<-chan error, e
 	cmdctx := *cctx
 	cmdctx.Gateway = true
 
+	node, err := cctx.ConstructNode()
+	if err != nil {
+		return nil, fmt.Errorf("serveHTTPGateway: ConstructNode() failed: %s", err)
+	}
+
+	publicGateways, err := corehttp.PublicGateways(node.Repo)
+	if err != nil {
+		return nil, fmt.Errorf("serveHTTPGateway: %s", err)
+	}
+
 	var opts = []corehttp.ServeOption{
 		corehttp.MetricsCollectionOption("gateway"),
+	}
+	opts = append(opts, pluginServeOptions(cctx.Plugins, plugin.HTTPGateway)...)
+	opts = append(opts,
+		corehttp.SubdomainGatewayOption(publicGateways),
 		corehttp.IPNSHostnameOption(),
 		corehttp.GatewayOption(writable, "/ipfs", "/ipns"),
 		corehttp.VersionOption(),
 		corehttp.CheckVersionOption(),
 		corehttp.CommandsROOption(cmdctx),
-	}
+	)
 
 	if cfg.Experimental.P2pHttpProxy {
 		opts = append(opts, corehttp.ProxyOption())
@@ -602,11 +624,6 @@ func serveHTTPGateway(req *cmds.Request, cctx *oldcmds.Context) (<-chan error, e
 		opts = append(opts, corehttp.RedirectOption("", cfg.Gateway.RootRedirect))
 	}
 
-	node, err := cctx.ConstructNode()
-	if err != nil {
-		return nil, fmt.Errorf("serveHTTPGateway: ConstructNode() failed: %s", err)
-	}
-
 	errc := make(chan error)
 	var wg sync.WaitGroup
 	for _, lis := range listeners {
@@ -625,6 +642,15 @@ func serveHTTPGateway(req *cmds.Request, cctx *oldcmds.Context) (<-chan error, e
 	return errc, nil
 }
 
+// pluginServeOptions returns the options the plugins apply to the given server
+func pluginServeOptions(plugins *loader.PluginLoader, server plugin.HTTPServer) []corehttp.ServeOption {
+	var opts []corehttp.ServeOption
+	for _, opt := range plugins.ServeOptions(server) {
+		opts = append(opts, corehttp.ServeOption(opt))
+	}
+	return opts
+}
+
 //collects options and opens the fuse mountpoint
 func mountFuse(req *cmds.Request, cctx *oldcmds.Context) error {
 	cfg, err := cctx.GetConfig()
@@ -93,4 +93,10 @@ var cmdDetailsMap = map[string]cmdDetails{
 	"repo/fsck":   {cannotRunOnDaemon: true},
 	"config/edit": {cannotRunOnDaemon: true, doesNotUseRepo: true},
 	"cid":         {doesNotUseRepo: true},
+
+	"name/inspect": {doesNotUseRepo: true},
+
+	"key/export":            {cannotRunOnDaemon: true},
+	"key/import":            {cannotRunOnDaemon: true},
+	"key/rotate-passphrase": {cannotRunOnDaemon: true},
 }
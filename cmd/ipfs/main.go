@@ -7,7 +7,6 @@ import (
 	"fmt"
 	"math/rand"
 	"os"
-	"path/filepath"
 	"runtime/pprof"
 	"strings"
 	"time"
@@ -20,6 +19,7 @@ import (
 	loader "github.com/ipfs/go-ipfs/plugin/loader"
 	repo "github.com/ipfs/go-ipfs/repo"
 	fsrepo "github.com/ipfs/go-ipfs/repo/fsrepo"
+	passphrase "github.com/ipfs/go-ipfs/thirdparty/passphrase"
 
 	ma "gx/ipfs/QmNTCey11oxhb1AxDnQBRHtdhap6Ctud872NjAYPYYXPuc/go-multiaddr"
 	u "gx/ipfs/QmNohiVssaPw3KVLZik59DBVGTSm2dGvYT9eoXt5DQ36Yz/go-ipfs-util"
@@ -48,9 +48,10 @@ const (
 	heapProfile        = "ipfs.memprof"
 )
 
-func loadPlugins(repoPath string) (*loader.PluginLoader, error) {
-	pluginpath := filepath.Join(repoPath, "plugins")
-
+// loadPlugins loads the plugins of the repo at the given path. When fallback
+// is set and they fail to load, it loads the preloaded plugins only, with
+// their default config.
+func loadPlugins(repoPath string, fallback bool) (*loader.PluginLoader, error) {
 	// check if repo is accessible before loading plugins
 	var plugins *loader.PluginLoader
 	ok, err := checkPermissions(repoPath)
@@ -58,11 +59,15 @@ func loadPlugins(repoPath string) (*loader.PluginLoader, error) {
 		return nil, err
 	}
 	if !ok {
-		pluginpath = ""
+		repoPath = ""
+	}
+	plugins, err = loader.NewPluginLoader(repoPath)
+	if err != nil && fallback {
+		log.Errorf("error loading plugins, falling back to the preloaded plugins with their default config: %s", err)
+		plugins, err = loader.NewPluginLoader("")
 	}
-	plugins, err = loader.NewPluginLoader(pluginpath)
 	if err != nil {
-		log.Error("error loading plugins: ", err)
+		return nil, fmt.Errorf("error loading plugins: %s", err)
 	}
 
 	if err := plugins.Initialize(); err != nil {
@@ -132,6 +137,12 @@ func mainRet() int {
 	// so we need to make sure it's stable
 	os.Args[0] = "ipfs"
 
+	// ask for the passphrase of an encrypted keystore when the repo is
+	// opened and it's not set in the environment
+	fsrepo.KeystorePassphrase = func() ([]byte, error) {
+		return passphrase.Read("Enter the keystore passphrase: ")
+	}
+
 	buildEnv := func(ctx context.Context, req *cmds.Request) (cmds.Environment, error) {
 		checkDebug(req)
 		repoPath, err := getRepoPath(req)
@@ -140,7 +151,9 @@ func mainRet() int {
 		}
 		log.Debugf("config path is %s", repoPath)
 
-		plugins, err := loadPlugins(repoPath)
+		// the config commands can run without the plugins of the repo, so
+		// that a config the plugins fail to load with can be fixed
+		plugins, err := loadPlugins(repoPath, len(req.Path) > 0 && req.Path[0] == "config")
 		if err != nil {
 			return nil, err
 		}
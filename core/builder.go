@@ -12,6 +12,8 @@ import (
 	filestore "github.com/ipfs/go-ipfs/filestore"
 	namesys "github.com/ipfs/go-ipfs/namesys"
 	pin "github.com/ipfs/go-ipfs/pin"
+	gc "github.com/ipfs/go-ipfs/pin/gc"
+	remote "github.com/ipfs/go-ipfs/pin/remote"
 	repo "github.com/ipfs/go-ipfs/repo"
 	cidv0v1 "github.com/ipfs/go-ipfs/thirdparty/cidv0v1"
 	"github.com/ipfs/go-ipfs/thirdparty/verifbs"
@@ -235,6 +237,9 @@ func setupNode(ctx context.Context, n *IpfsNode, cfg *BuildCfg) error {
 		n.Blockstore = &verifbs.VerifBSGC{GCBlockstore: n.Blockstore}
 	}
 
+	// lets incremental GC runs release the GC lock between sweep batches
+	n.Blockstore = gc.NewWriteBarrier(n.Blockstore)
+
 	rcfg, err := n.Repo.Config()
 	if err != nil {
 		return err
@@ -254,6 +259,11 @@ func setupNode(ctx context.Context, n *IpfsNode, cfg *BuildCfg) error {
 		You will not be able to connect to any nodes configured to use encrypted connections`)
 	}
 
+	n.DNSResolver, err = n.newDNSResolver()
+	if err != nil {
+		return err
+	}
+
 	if cfg.Online {
 		do := setupDiscoveryOption(rcfg.Discovery)
 		if err := n.startOnlineServices(ctx, cfg.Routing, hostOption, do, cfg.getOpt("pubsub"), cfg.getOpt("ipnsps"), cfg.getOpt("mplex")); err != nil {
@@ -262,7 +272,17 @@ func setupNode(ctx context.Context, n *IpfsNode, cfg *BuildCfg) error {
 	} else {
 		n.Exchange = offline.Exchange(n.Blockstore)
 		n.Routing = offroute.NewOfflineRouter(n.Repo.Datastore(), n.RecordValidator)
-		n.Namesys = namesys.NewNameSystem(n.Routing, n.Repo.Datastore(), 0)
+		n.Namesys = namesys.NewCustomNameSystem(n.Routing, n.Repo.Datastore(), n.DNSResolver, nil)
+
+		// offline nodes don't cache resolved names, but can still manage
+		// the persisted cache
+		cache, persisted, err := n.newNamesysCache()
+		if err != nil {
+			return err
+		}
+		if persisted {
+			n.NamesysCache = cache
+		}
 	}
 
 	n.Blocks = bserv.New(n.Blockstore, n.Exchange)
@@ -277,6 +297,7 @@ func setupNode(ctx context.Context, n *IpfsNode, cfg *BuildCfg) error {
 		// this is kinda sketchy and could cause data loss
 		n.Pinning = pin.NewPinner(n.Repo.Datastore(), n.DAG, internalDag)
 	}
+	n.RemotePins = remote.NewTracker(n.Repo.Datastore(), remote.RepoClients(n.Repo))
 	n.Resolver = resolver.NewBasicResolver(n.DAG)
 
 	if cfg.Online {
@@ -3,9 +3,11 @@ package commands
 import (
 	"fmt"
 	"io"
+	"time"
 
 	cmdenv "github.com/ipfs/go-ipfs/core/commands/cmdenv"
 	e "github.com/ipfs/go-ipfs/core/commands/e"
+	reprovide "github.com/ipfs/go-ipfs/exchange/reprovide"
 
 	peer "gx/ipfs/QmPJxxDsX2UbchSHobbYuvz7qnyJTFKvaKMzE2rZWJ4x5B/go-libp2p-peer"
 	humanize "gx/ipfs/QmPSBJL4momYnE7DcUyk2DVhD6rH488ZmHBGLbxNdhU44K/go-humanize"
@@ -193,13 +195,21 @@ prints the ledger associated with a given peer.
 	},
 }
 
+const reprovideStatOptionName = "stat"
+
 var reprovideCmd = &cmds.Command{
 	Helptext: cmdkit.HelpText{
 		Tagline: "Trigger reprovider.",
 		ShortDescription: `
 Trigger reprovider to announce our data to network.
+
+With --stat, the statistics of the reprovider are shown instead, without
+triggering a run.
 `,
 	},
+	Options: []cmdkit.Option{
+		cmdkit.BoolOption(reprovideStatOptionName, "Show reprovider statistics instead of triggering a run."),
+	},
 	Run: func(req *cmds.Request, res cmds.ResponseEmitter, env cmds.Environment) error {
 		nd, err := cmdenv.GetNode(env)
 		if err != nil {
@@ -210,6 +220,11 @@ Trigger reprovider to announce our data to network.
 			return ErrNotOnline
 		}
 
+		if stat, _ := req.Options[reprovideStatOptionName].(bool); stat {
+			s := nd.Reprovider.Stat()
+			return cmds.EmitOnce(res, &s)
+		}
+
 		err = nd.Reprovider.Trigger(req.Context)
 		if err != nil {
 			return err
@@ -217,4 +232,22 @@ Trigger reprovider to announce our data to network.
 
 		return nil
 	},
+	Encoders: cmds.EncoderMap{
+		cmds.Text: cmds.MakeTypedEncoder(func(req *cmds.Request, w io.Writer, s *reprovide.Stats) error {
+			fmt.Fprintln(w, "reprovider status")
+			fmt.Fprintf(w, "\trunning: %t\n", s.Running)
+			if s.LastRun.IsZero() {
+				fmt.Fprintln(w, "\tlast run: never")
+			} else {
+				fmt.Fprintf(w, "\tlast run: %s\n", s.LastRun.Format(time.RFC3339))
+				fmt.Fprintf(w, "\tlast run duration: %s\n", s.LastRunDuration)
+			}
+			fmt.Fprintf(w, "\tkeys provided in last run: %d\n", s.KeysProvided)
+			fmt.Fprintf(w, "\tfailures in last run: %d\n", s.KeysFailed)
+			fmt.Fprintf(w, "\ttotal keys provided: %d\n", s.TotalProvided)
+			fmt.Fprintf(w, "\ttotal failures: %d\n", s.TotalFailed)
+			return nil
+		}),
+	},
+	Type: reprovide.Stats{},
 }
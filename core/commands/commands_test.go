@@ -23,6 +23,7 @@ func TestROCommands(t *testing.T) {
 		"/cat",
 		"/commands",
 		"/dag",
+		"/dag/export",
 		"/dag/get",
 		"/dag/resolve",
 		"/dns",
@@ -94,7 +95,9 @@ func TestCommands(t *testing.T) {
 		"/config/profile",
 		"/config/profile/apply",
 		"/dag",
+		"/dag/export",
 		"/dag/get",
+		"/dag/import",
 		"/dag/put",
 		"/dag/resolve",
 		"/dht",
@@ -130,10 +133,13 @@ func TestCommands(t *testing.T) {
 		"/get",
 		"/id",
 		"/key",
+		"/key/export",
 		"/key/gen",
+		"/key/import",
 		"/key/list",
 		"/key/rename",
 		"/key/rm",
+		"/key/rotate-passphrase",
 		"/log",
 		"/log/level",
 		"/log/ls",
@@ -141,11 +147,20 @@ func TestCommands(t *testing.T) {
 		"/ls",
 		"/mount",
 		"/name",
+		"/name/cache",
+		"/name/cache/clear",
+		"/name/cache/ls",
+		"/name/cache/rm",
+		"/name/create",
+		"/name/inspect",
 		"/name/publish",
+		"/name/put",
 		"/name/pubsub",
 		"/name/pubsub/state",
 		"/name/pubsub/subs",
 		"/name/pubsub/cancel",
+		"/name/republish",
+		"/name/republish/status",
 		"/name/resolve",
 		"/object",
 		"/object/data",
@@ -172,9 +187,19 @@ func TestCommands(t *testing.T) {
 		"/pin/add",
 		"/ping",
 		"/pin/ls",
+		"/pin/remote",
+		"/pin/remote/add",
+		"/pin/remote/ls",
+		"/pin/remote/rm",
+		"/pin/remote/service",
+		"/pin/remote/service/add",
+		"/pin/remote/service/ls",
+		"/pin/remote/service/rm",
 		"/pin/rm",
 		"/pin/update",
 		"/pin/verify",
+		"/plugin",
+		"/plugin/ls",
 		"/pubsub",
 		"/pubsub/ls",
 		"/pubsub/peers",
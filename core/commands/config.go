@@ -11,6 +11,7 @@ import (
 	"strings"
 
 	"github.com/ipfs/go-ipfs/core/commands/cmdenv"
+	"github.com/ipfs/go-ipfs/pin/remote"
 	"github.com/ipfs/go-ipfs/repo"
 	"github.com/ipfs/go-ipfs/repo/fsrepo"
 
@@ -114,6 +115,9 @@ Set the value of the 'Datastore.Path' key:
 			}
 		} else {
 			output, err = getConfig(r, key)
+			if err == nil {
+				output.Value, err = scrubConfigKey(key, output.Value)
+			}
 		}
 
 		if err != nil {
@@ -145,7 +149,7 @@ var configShowCmd = &cmds.Command{
 	Helptext: cmdkit.HelpText{
 		Tagline: "Output config file contents.",
 		ShortDescription: `
-NOTE: For security reasons, this command will omit your private key. If you would like to make a full backup of your config (private key included), you must copy the config file from your repo.
+NOTE: For security reasons, this command will omit your private key and the access keys of the remote pinning services. If you would like to make a full backup of your config (keys included), you must copy the config file from your repo.
 `,
 	},
 	Type: map[string]interface{}{},
@@ -175,6 +179,7 @@ NOTE: For security reasons, this command will omit your private key. If you woul
 		if err != nil {
 			return err
 		}
+		scrubRemoteServiceKeys(cfg)
 
 		return cmds.EmitOnce(res, &cfg)
 	},
@@ -233,6 +238,55 @@ func scrubValue(m map[string]interface{}, key []string) error {
 	return nil
 }
 
+// scrubRemoteServiceKeys removes the access keys of the remote pinning
+// services from the config map
+func scrubRemoteServiceKeys(m map[string]interface{}) {
+	find := func(m map[string]interface{}, k string) map[string]interface{} {
+		for mkey, val := range m {
+			if strings.EqualFold(mkey, k) {
+				mval, _ := val.(map[string]interface{})
+				return mval
+			}
+		}
+		return nil
+	}
+
+	services := m
+	for _, part := range strings.Split(remote.ConfigKey, ".") {
+		services = find(services, part)
+	}
+	for _, s := range services {
+		smap, _ := s.(map[string]interface{})
+		api := find(smap, "API")
+		for k := range api {
+			if strings.EqualFold(k, "Key") {
+				delete(api, k)
+			}
+		}
+	}
+}
+
+// scrubConfigKey removes the secrets from the value of a config key
+func scrubConfigKey(key string, value interface{}) (interface{}, error) {
+	// nest the value back under its key, to scrub it as the whole config
+	parts := strings.Split(key, ".")
+	root := map[string]interface{}{}
+	cur := root
+	for _, part := range parts[:len(parts)-1] {
+		next := map[string]interface{}{}
+		cur[part] = next
+		cur = next
+	}
+	cur[parts[len(parts)-1]] = value
+
+	scrubRemoteServiceKeys(root)
+
+	if _, ok := cur[parts[len(parts)-1]]; !ok {
+		return nil, errors.New("cannot show the access key of a remote pinning service")
+	}
+	return value, nil
+}
+
 var configEditCmd = &cmds.Command{
 	Helptext: cmdkit.HelpText{
 		Tagline: "Open the config file for editing in $EDITOR.",
@@ -8,6 +8,7 @@ import (
 
 	"github.com/ipfs/go-ipfs/core/commands/cmdenv"
 	"github.com/ipfs/go-ipfs/core/coreapi/interface"
+	"github.com/ipfs/go-ipfs/core/coreapi/interface/options"
 	"github.com/ipfs/go-ipfs/core/coredag"
 
 	path "gx/ipfs/QmQ3YSqfxunT5QBg6KBVskKyRE26q6hjSMyhpxchpm7jEN/go-path"
@@ -34,6 +35,8 @@ to deprecate and replace the existing 'ipfs object' command moving forward.
 		"put":     DagPutCmd,
 		"get":     DagGetCmd,
 		"resolve": DagResolveCmd,
+		"export":  DagExportCmd,
+		"import":  DagImportCmd,
 	},
 }
 
@@ -252,3 +255,104 @@ var DagResolveCmd = &cmds.Command{
 	},
 	Type: ResolveOutput{},
 }
+
+// DagExportCmd streams the DAG under a root as a CAR archive
+var DagExportCmd = &cmds.Command{
+	Helptext: cmdkit.HelpText{
+		Tagline: "Streams the selected DAG as a .car stream on stdout.",
+		ShortDescription: `
+'ipfs dag export' fetches a dag and streams every block reachable from the
+root out in the CARv1 (content addressed archive) format.
+
+The blocks are written in depth-first order and each block is written only
+once. Use 'ipfs dag import' to load the archive on another node.
+`,
+	},
+	Arguments: []cmdkit.Argument{
+		cmdkit.StringArg("root", true, false, "CID or path of the root of the DAG to export.").EnableStdin(),
+	},
+	Run: func(req *cmds.Request, res cmds.ResponseEmitter, env cmds.Environment) error {
+		api, err := cmdenv.GetApi(env, req)
+		if err != nil {
+			return err
+		}
+
+		p, err := iface.ParsePath(req.Arguments[0])
+		if err != nil {
+			return err
+		}
+
+		rp, err := api.ResolvePath(req.Context, p)
+		if err != nil {
+			return err
+		}
+
+		r, w := io.Pipe()
+		go func() {
+			w.CloseWithError(api.Dag().Export(req.Context, rp.Cid(), w))
+		}()
+
+		return res.Emit(r)
+	},
+}
+
+// DagImportCmd loads the blocks of CAR archives into the blockstore
+var DagImportCmd = &cmds.Command{
+	Helptext: cmdkit.HelpText{
+		Tagline: "Import the contents of .car files.",
+		ShortDescription: `
+'ipfs dag import' imports all blocks present in the supplied CARv1 (content
+addressed archive) files and prints the roots listed in their headers.
+
+Every block is verified against its CID before it is stored. With --pin-roots
+the roots of each archive are pinned recursively, which fails unless the
+archive (or the local blockstore) contains the complete DAG.
+`,
+	},
+	Arguments: []cmdkit.Argument{
+		cmdkit.FileArg("path", true, true, "The path of a .car file.").EnableStdin(),
+	},
+	Options: []cmdkit.Option{
+		cmdkit.BoolOption("pin-roots", "Pin the roots listed in the .car headers after importing."),
+	},
+	Run: func(req *cmds.Request, res cmds.ResponseEmitter, env cmds.Environment) error {
+		api, err := cmdenv.GetApi(env, req)
+		if err != nil {
+			return err
+		}
+
+		pinRoots, _ := req.Options["pin-roots"].(bool)
+
+		it := req.Files.Entries()
+		for it.Next() {
+			file := files.FileFromEntry(it)
+			if file == nil {
+				return fmt.Errorf("expected a regular file")
+			}
+
+			roots, err := api.Dag().Import(req.Context, file, options.Dag.PinRoots(pinRoots))
+			file.Close()
+			if err != nil {
+				return err
+			}
+
+			for _, c := range roots {
+				if err := res.Emit(&OutputObject{Cid: c}); err != nil {
+					return err
+				}
+			}
+		}
+		return it.Err()
+	},
+	Type: OutputObject{},
+	Encoders: cmds.EncoderMap{
+		cmds.Text: cmds.MakeTypedEncoder(func(req *cmds.Request, w io.Writer, out *OutputObject) error {
+			enc, err := cmdenv.GetLowLevelCidEncoder(req)
+			if err != nil {
+				return err
+			}
+			fmt.Fprintln(w, enc.Encode(out.Cid))
+			return nil
+		}),
+	},
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"io"
 
+	cmdenv "github.com/ipfs/go-ipfs/core/commands/cmdenv"
 	ncmd "github.com/ipfs/go-ipfs/core/commands/name"
 	nsopts "github.com/ipfs/go-ipfs/core/coreapi/interface/options/namesys"
 	namesys "github.com/ipfs/go-ipfs/namesys"
@@ -63,7 +64,15 @@ The resolver can recursively resolve:
 	Run: func(req *cmds.Request, res cmds.ResponseEmitter, env cmds.Environment) error {
 		recursive, _ := req.Options[dnsRecursiveOptionName].(bool)
 		name := req.Arguments[0]
-		resolver := namesys.NewDNSResolver()
+		n, err := cmdenv.GetNode(env)
+		if err != nil {
+			return err
+		}
+
+		resolver := n.DNSResolver
+		if resolver == nil {
+			resolver = namesys.NewDNSResolver()
+		}
 
 		var ropts []nsopts.ResolveOpt
 		if !recursive {
@@ -1,36 +1,23 @@
 package commands
 
 import (
-	"context"
 	"errors"
 	"fmt"
 	"io"
-	"os"
-	gopath "path"
 	"sort"
 	"strings"
 
-	"github.com/ipfs/go-ipfs/core"
 	"github.com/ipfs/go-ipfs/core/commands/cmdenv"
 	"github.com/ipfs/go-ipfs/core/coreapi/interface"
+	"github.com/ipfs/go-ipfs/core/coreapi/interface/options"
 
 	"gx/ipfs/QmPSBJL4momYnE7DcUyk2DVhD6rH488ZmHBGLbxNdhU44K/go-humanize"
 	"gx/ipfs/QmR77mMvvh8mJBBWQmBfQBu8oD38NUN4KE9SL2gDgAQNc6/go-ipfs-cmds"
-	cid "gx/ipfs/QmR8BauakNcBa3RbE4nbQu76PDiJgoQgz8AJdhJuiU4TAw/go-cid"
-	ipld "gx/ipfs/QmRL22E4paat7ky7vx9MLpR97JHHbFPrg3ytFQw6qp1y1s/go-ipld-format"
-	dag "gx/ipfs/QmUtsx89yiCY6F8mbpP6ecXckiSzCBH7EvkKZuZEHBcr1m/go-merkledag"
 	"gx/ipfs/QmVBXaQqupXCFtS62xtr9EsKGkbK9LviqCKSzwcqzwvX9U/go-mfs"
-	"gx/ipfs/QmYZwey1thDTynSrvd6qQkX24UpTka6TFhQ2v569UpoqxD/go-ipfs-exchange-offline"
-	ft "gx/ipfs/QmZArMcsVDsXdcLbUx4844CuqKXBpbxdeiryM4cnmGTNRq/go-unixfs"
-	bservice "gx/ipfs/QmbgbNxC1PMyS2gbx7nf2jKNG7bZAfYJJebdK4ptBBWCz1/go-blockservice"
-	logging "gx/ipfs/QmcuXC5cxs79ro2cUuHs4HQ2bkDLJUYokwL8aivcX6HW3C/go-log"
-	cidenc "gx/ipfs/QmdPQx9fvN5ExVwMhRmh7YpCQJzJrFhd1AjVBwJmRMFJeX/go-cidutil/cidenc"
 	"gx/ipfs/Qmde5VP1qUkyQXKCfmEUA7bP64V2HAptbJ7phuPp7jXWwg/go-ipfs-cmdkit"
 	mh "gx/ipfs/QmerPMzPk1mJVowm8KgmoknWa4yCYvvugMPsgWmDNUvDLW/go-multihash"
 )
 
-var flog = logging.Logger("cmds/files")
-
 // FilesCmd is the 'ipfs files' command
 var FilesCmd = &cmds.Command{
 	Helptext: cmdkit.HelpText{
@@ -120,21 +107,11 @@ var filesStatCmd = &cmds.Command{
 			return cmdkit.Errorf(cmdkit.ErrClient, err.Error())
 		}
 
-		node, err := cmdenv.GetNode(env)
-		if err != nil {
-			return err
-		}
-
 		api, err := cmdenv.GetApi(env, req)
 		if err != nil {
 			return err
 		}
 
-		path, err := checkPath(req.Arguments[0])
-		if err != nil {
-			return err
-		}
-
 		withLocal, _ := req.Options[filesWithLocalOptionName].(bool)
 
 		enc, err := cmdenv.GetCidEncoder(req)
@@ -142,38 +119,26 @@ var filesStatCmd = &cmds.Command{
 			return err
 		}
 
-		var dagserv ipld.DAGService
-		if withLocal {
-			// an offline DAGService will not fetch from the network
-			dagserv = dag.NewDAGService(bservice.New(
-				node.Blockstore,
-				offline.Exchange(node.Blockstore),
-			))
-		} else {
-			dagserv = node.DAG
-		}
-
-		nd, err := getNodeFromPath(req.Context, node, api, path)
+		st, err := api.Files().Stat(req.Context, req.Arguments[0], options.Files.WithLocal(withLocal))
 		if err != nil {
 			return err
 		}
 
-		o, err := statNode(nd, enc)
-		if err != nil {
-			return err
-		}
-
-		if !withLocal {
-			return cmds.EmitOnce(res, o)
+		ndtype := "file"
+		if st.Type == iface.TDirectory {
+			ndtype = "directory"
 		}
 
-		local, sizeLocal, err := walkBlock(req.Context, dagserv, nd)
-
-		o.WithLocality = true
-		o.Local = local
-		o.SizeLocal = sizeLocal
-
-		return cmds.EmitOnce(res, o)
+		return cmds.EmitOnce(res, &statOutput{
+			Hash:           enc.Encode(st.Cid),
+			Size:           st.Size,
+			CumulativeSize: st.CumulativeSize,
+			Blocks:         st.Blocks,
+			Type:           ndtype,
+			WithLocality:   st.WithLocality,
+			Local:          st.Local,
+			SizeLocal:      st.SizeLocal,
+		})
 	},
 	Encoders: cmds.EncoderMap{
 		cmds.Text: cmds.MakeTypedEncoder(func(req *cmds.Request, w io.Writer, out *statOutput) error {
@@ -223,83 +188,6 @@ func statGetFormatOptions(req *cmds.Request) (string, error) {
 	}
 }
 
-func statNode(nd ipld.Node, enc cidenc.Encoder) (*statOutput, error) {
-	c := nd.Cid()
-
-	cumulsize, err := nd.Size()
-	if err != nil {
-		return nil, err
-	}
-
-	switch n := nd.(type) {
-	case *dag.ProtoNode:
-		d, err := ft.FSNodeFromBytes(n.Data())
-		if err != nil {
-			return nil, err
-		}
-
-		var ndtype string
-		switch d.Type() {
-		case ft.TDirectory, ft.THAMTShard:
-			ndtype = "directory"
-		case ft.TFile, ft.TMetadata, ft.TRaw:
-			ndtype = "file"
-		default:
-			return nil, fmt.Errorf("unrecognized node type: %s", d.Type())
-		}
-
-		return &statOutput{
-			Hash:           enc.Encode(c),
-			Blocks:         len(nd.Links()),
-			Size:           d.FileSize(),
-			CumulativeSize: cumulsize,
-			Type:           ndtype,
-		}, nil
-	case *dag.RawNode:
-		return &statOutput{
-			Hash:           enc.Encode(c),
-			Blocks:         0,
-			Size:           cumulsize,
-			CumulativeSize: cumulsize,
-			Type:           "file",
-		}, nil
-	default:
-		return nil, fmt.Errorf("not unixfs node (proto or raw)")
-	}
-}
-
-func walkBlock(ctx context.Context, dagserv ipld.DAGService, nd ipld.Node) (bool, uint64, error) {
-	// Start with the block data size
-	sizeLocal := uint64(len(nd.RawData()))
-
-	local := true
-
-	for _, link := range nd.Links() {
-		child, err := dagserv.Get(ctx, link.Cid)
-
-		if err == ipld.ErrNotFound {
-			local = false
-			continue
-		}
-
-		if err != nil {
-			return local, sizeLocal, err
-		}
-
-		childLocal, childLocalSize, err := walkBlock(ctx, dagserv, child)
-
-		if err != nil {
-			return local, sizeLocal, err
-		}
-
-		// Recursively add the child size
-		local = local && childLocal
-		sizeLocal += childLocalSize
-	}
-
-	return local, sizeLocal, nil
-}
-
 var filesCpCmd = &cmds.Command{
 	Helptext: cmdkit.HelpText{
 		Tagline: "Copy files into mfs.",
@@ -309,11 +197,6 @@ var filesCpCmd = &cmds.Command{
 		cmdkit.StringArg("dest", true, false, "Destination to copy object to."),
 	},
 	Run: func(req *cmds.Request, res cmds.ResponseEmitter, env cmds.Environment) error {
-		nd, err := cmdenv.GetNode(env)
-		if err != nil {
-			return err
-		}
-
 		api, err := cmdenv.GetApi(env, req)
 		if err != nil {
 			return err
@@ -321,61 +204,10 @@ var filesCpCmd = &cmds.Command{
 
 		flush, _ := req.Options[filesFlushOptionName].(bool)
 
-		src, err := checkPath(req.Arguments[0])
-		if err != nil {
-			return err
-		}
-		src = strings.TrimRight(src, "/")
-
-		dst, err := checkPath(req.Arguments[1])
-		if err != nil {
-			return err
-		}
-
-		if dst[len(dst)-1] == '/' {
-			dst += gopath.Base(src)
-		}
-
-		node, err := getNodeFromPath(req.Context, nd, api, src)
-		if err != nil {
-			return fmt.Errorf("cp: cannot get node from path %s: %s", src, err)
-		}
-
-		err = mfs.PutNode(nd.FilesRoot, dst, node)
-		if err != nil {
-			return fmt.Errorf("cp: cannot put node in path %s: %s", dst, err)
-		}
-
-		if flush {
-			err := mfs.FlushPath(nd.FilesRoot, dst)
-			if err != nil {
-				return fmt.Errorf("cp: cannot flush the created file %s: %s", dst, err)
-			}
-		}
-
-		return nil
+		return api.Files().Cp(req.Context, req.Arguments[0], req.Arguments[1], options.Files.Flush(flush))
 	},
 }
 
-func getNodeFromPath(ctx context.Context, node *core.IpfsNode, api iface.CoreAPI, p string) (ipld.Node, error) {
-	switch {
-	case strings.HasPrefix(p, "/ipfs/"):
-		np, err := iface.ParsePath(p)
-		if err != nil {
-			return nil, err
-		}
-
-		return api.ResolveNode(ctx, np)
-	default:
-		fsn, err := mfs.Lookup(node.FilesRoot, p)
-		if err != nil {
-			return nil, err
-		}
-
-		return fsn.GetNode()
-	}
-}
-
 type filesLsOutput struct {
 	Entries []mfs.NodeListing
 }
@@ -422,71 +254,37 @@ Examples:
 			arg = req.Arguments[0]
 		}
 
-		path, err := checkPath(arg)
+		api, err := cmdenv.GetApi(env, req)
 		if err != nil {
 			return err
 		}
 
-		nd, err := cmdenv.GetNode(env)
-		if err != nil {
-			return err
-		}
+		long, _ := req.Options[longOptionName].(bool)
 
-		fsn, err := mfs.Lookup(nd.FilesRoot, path)
+		enc, err := cmdenv.GetCidEncoder(req)
 		if err != nil {
 			return err
 		}
 
-		long, _ := req.Options[longOptionName].(bool)
-
-		enc, err := cmdenv.GetCidEncoder(req)
+		entries, err := api.Files().Ls(req.Context, arg, options.Files.Long(long))
 		if err != nil {
 			return err
 		}
 
-		switch fsn := fsn.(type) {
-		case *mfs.Directory:
-			if !long {
-				var output []mfs.NodeListing
-				names, err := fsn.ListNames(req.Context)
-				if err != nil {
-					return err
-				}
-
-				for _, name := range names {
-					output = append(output, mfs.NodeListing{
-						Name: name,
-					})
-				}
-				return cmds.EmitOnce(res, &filesLsOutput{output})
-			}
-			listing, err := fsn.List(req.Context)
-			if err != nil {
-				return err
-			}
-			return cmds.EmitOnce(res, &filesLsOutput{listing})
-		case *mfs.File:
-			_, name := gopath.Split(path)
-			out := &filesLsOutput{[]mfs.NodeListing{{Name: name}}}
+		var output []mfs.NodeListing
+		for _, e := range entries {
+			l := mfs.NodeListing{Name: e.Name}
 			if long {
-				out.Entries[0].Type = int(fsn.Type())
-
-				size, err := fsn.Size()
-				if err != nil {
-					return err
-				}
-				out.Entries[0].Size = size
-
-				nd, err := fsn.GetNode()
-				if err != nil {
-					return err
+				l.Type = int(mfs.TFile)
+				if e.Type == iface.TDirectory {
+					l.Type = int(mfs.TDir)
 				}
-				out.Entries[0].Hash = enc.Encode(nd.Cid())
+				l.Size = e.Size
+				l.Hash = enc.Encode(e.Cid)
 			}
-			return cmds.EmitOnce(res, out)
-		default:
-			return errors.New("unrecognized type")
+			output = append(output, l)
 		}
+		return cmds.EmitOnce(res, &filesLsOutput{output})
 	},
 	Encoders: cmds.EncoderMap{
 		cmds.Text: cmds.MakeTypedEncoder(func(req *cmds.Request, w io.Writer, out *filesLsOutput) error {
@@ -542,77 +340,29 @@ Examples:
 		cmdkit.Int64Option(filesCountOptionName, "n", "Maximum number of bytes to read."),
 	},
 	Run: func(req *cmds.Request, res cmds.ResponseEmitter, env cmds.Environment) error {
-		nd, err := cmdenv.GetNode(env)
-		if err != nil {
-			return err
-		}
-
-		path, err := checkPath(req.Arguments[0])
-		if err != nil {
-			return err
-		}
-
-		fsn, err := mfs.Lookup(nd.FilesRoot, path)
-		if err != nil {
-			return err
-		}
-
-		fi, ok := fsn.(*mfs.File)
-		if !ok {
-			return fmt.Errorf("%s was not a file", path)
-		}
-
-		rfd, err := fi.Open(mfs.Flags{Read: true})
+		api, err := cmdenv.GetApi(env, req)
 		if err != nil {
 			return err
 		}
 
-		defer rfd.Close()
-
-		offset, _ := req.Options[offsetOptionName].(int64)
-		if offset < 0 {
-			return fmt.Errorf("cannot specify negative offset")
-		}
-
-		filen, err := rfd.Size()
-		if err != nil {
-			return err
-		}
+		offset, _ := req.Options[filesOffsetOptionName].(int64)
+		opts := []options.FilesOption{options.Files.Offset(offset)}
 
-		if int64(offset) > filen {
-			return fmt.Errorf("offset was past end of file (%d > %d)", offset, filen)
+		count, found := req.Options[filesCountOptionName].(int64)
+		if found {
+			opts = append(opts, options.Files.Count(count))
 		}
 
-		_, err = rfd.Seek(int64(offset), io.SeekStart)
+		r, err := api.Files().Read(req.Context, req.Arguments[0], opts...)
 		if err != nil {
 			return err
 		}
+		defer r.Close()
 
-		var r io.Reader = &contextReaderWrapper{R: rfd, ctx: req.Context}
-		count, found := req.Options[filesCountOptionName].(int64)
-		if found {
-			if count < 0 {
-				return fmt.Errorf("cannot specify negative 'count'")
-			}
-			r = io.LimitReader(r, int64(count))
-		}
 		return res.Emit(r)
 	},
 }
 
-type contextReader interface {
-	CtxReadFull(context.Context, []byte) (int, error)
-}
-
-type contextReaderWrapper struct {
-	R   contextReader
-	ctx context.Context
-}
-
-func (crw *contextReaderWrapper) Read(b []byte) (int, error) {
-	return crw.R.CtxReadFull(crw.ctx, b)
-}
-
 var filesMvCmd = &cmds.Command{
 	Helptext: cmdkit.HelpText{
 		Tagline: "Move files.",
@@ -631,27 +381,14 @@ Example:
 		cmdkit.StringArg("dest", true, false, "Destination path for file to be moved to."),
 	},
 	Run: func(req *cmds.Request, res cmds.ResponseEmitter, env cmds.Environment) error {
-		nd, err := cmdenv.GetNode(env)
+		api, err := cmdenv.GetApi(env, req)
 		if err != nil {
 			return err
 		}
 
 		flush, _ := req.Options[filesFlushOptionName].(bool)
 
-		src, err := checkPath(req.Arguments[0])
-		if err != nil {
-			return err
-		}
-		dst, err := checkPath(req.Arguments[1])
-		if err != nil {
-			return err
-		}
-
-		err = mfs.Mv(nd.FilesRoot, src, dst)
-		if err == nil && flush {
-			err = mfs.FlushPath(nd.FilesRoot, "/")
-		}
-		return err
+		return api.Files().Mv(req.Context, req.Arguments[0], req.Arguments[1], options.Files.Flush(flush))
 	},
 }
 
@@ -711,8 +448,8 @@ stat' on the file or any of its ancestors.
 		cidVersionOption,
 		hashOption,
 	},
-	Run: func(req *cmds.Request, re cmds.ResponseEmitter, env cmds.Environment) (retErr error) {
-		path, err := checkPath(req.Arguments[0])
+	Run: func(req *cmds.Request, re cmds.ResponseEmitter, env cmds.Environment) error {
+		api, err := cmdenv.GetApi(env, req)
 		if err != nil {
 			return err
 		}
@@ -721,82 +458,35 @@ stat' on the file or any of its ancestors.
 		mkParents, _ := req.Options[filesParentsOptionName].(bool)
 		trunc, _ := req.Options[filesTruncateOptionName].(bool)
 		flush, _ := req.Options[filesFlushOptionName].(bool)
-		rawLeaves, rawLeavesDef := req.Options[filesRawLeavesOptionName].(bool)
-
-		prefix, err := getPrefixNew(req)
-		if err != nil {
-			return err
-		}
-
-		nd, err := cmdenv.GetNode(env)
-		if err != nil {
-			return err
-		}
-
 		offset, _ := req.Options[filesOffsetOptionName].(int64)
-		if offset < 0 {
-			return fmt.Errorf("cannot have negative write offset")
-		}
 
-		if mkParents {
-			err := ensureContainingDirectoryExists(nd.FilesRoot, path, prefix)
-			if err != nil {
-				return err
-			}
-		}
-
-		fi, err := getFileHandle(nd.FilesRoot, path, create, prefix)
+		opts, err := filesCidOptions(req)
 		if err != nil {
 			return err
 		}
-		if rawLeavesDef {
-			fi.RawLeaves = rawLeaves
-		}
 
-		wfd, err := fi.Open(mfs.Flags{Write: true, Sync: flush})
-		if err != nil {
-			return err
-		}
+		opts = append(opts,
+			options.Files.Create(create),
+			options.Files.Parents(mkParents),
+			options.Files.Truncate(trunc),
+			options.Files.Flush(flush),
+			options.Files.Offset(offset),
+		)
 
-		defer func() {
-			err := wfd.Close()
-			if err != nil {
-				if retErr == nil {
-					retErr = err
-				} else {
-					log.Error("files: error closing file mfs file descriptor", err)
-				}
-			}
-		}()
-
-		if trunc {
-			if err := wfd.Truncate(0); err != nil {
-				return err
-			}
-		}
-
-		count, countfound := req.Options[filesCountOptionName].(int64)
-		if countfound && count < 0 {
-			return fmt.Errorf("cannot have negative byte count")
+		if rawLeaves, ok := req.Options[filesRawLeavesOptionName].(bool); ok {
+			opts = append(opts, options.Files.RawLeaves(rawLeaves))
 		}
 
-		_, err = wfd.Seek(int64(offset), io.SeekStart)
-		if err != nil {
-			flog.Error("seekfail: ", err)
-			return err
+		if count, ok := req.Options[filesCountOptionName].(int64); ok {
+			opts = append(opts, options.Files.Count(count))
 		}
 
-		var r io.Reader
-		r, err = cmdenv.GetFileArg(req.Files.Entries())
+		r, err := cmdenv.GetFileArg(req.Files.Entries())
 		if err != nil {
 			return err
 		}
-		if countfound {
-			r = io.LimitReader(r, int64(count))
-		}
 
-		_, err = io.Copy(wfd, r)
-		return err
+		return api.Files().Write(req.Context, req.Arguments[0], r, opts...)
 	},
 }
 
@@ -827,32 +517,22 @@ Examples:
 		hashOption,
 	},
 	Run: func(req *cmds.Request, res cmds.ResponseEmitter, env cmds.Environment) error {
-		n, err := cmdenv.GetNode(env)
+		api, err := cmdenv.GetApi(env, req)
 		if err != nil {
 			return err
 		}
 
 		dashp, _ := req.Options[filesParentsOptionName].(bool)
-		dirtomake, err := checkPath(req.Arguments[0])
-		if err != nil {
-			return err
-		}
-
 		flush, _ := req.Options[filesFlushOptionName].(bool)
 
-		prefix, err := getPrefix(req)
+		opts, err := filesCidOptions(req)
 		if err != nil {
 			return err
 		}
-		root := n.FilesRoot
 
-		err = mfs.Mkdir(root, dirtomake, mfs.MkdirOpts{
-			Mkparents:  dashp,
-			Flush:      flush,
-			CidBuilder: prefix,
-		})
+		opts = append(opts, options.Files.Parents(dashp), options.Files.Flush(flush))
 
-		return err
+		return api.Files().Mkdir(req.Context, req.Arguments[0], opts...)
 	},
 }
 
@@ -868,7 +548,7 @@ are run with the '--flush=false'.
 		cmdkit.StringArg("path", false, false, "Path to flush. Default: '/'."),
 	},
 	Run: func(req *cmds.Request, res cmds.ResponseEmitter, env cmds.Environment) error {
-		nd, err := cmdenv.GetNode(env)
+		api, err := cmdenv.GetApi(env, req)
 		if err != nil {
 			return err
 		}
@@ -878,7 +558,7 @@ are run with the '--flush=false'.
 			path = req.Arguments[0]
 		}
 
-		return mfs.FlushPath(nd.FilesRoot, path)
+		return api.Files().Flush(req.Context, path)
 	},
 }
 
@@ -897,7 +577,7 @@ Change the cid version or hash function of the root node of a given path.
 		hashOption,
 	},
 	Run: func(req *cmds.Request, res cmds.ResponseEmitter, env cmds.Environment) error {
-		nd, err := cmdenv.GetNode(env)
+		api, err := cmdenv.GetApi(env, req)
 		if err != nil {
 			return err
 		}
@@ -909,39 +589,15 @@ Change the cid version or hash function of the root node of a given path.
 
 		flush, _ := req.Options[filesFlushOptionName].(bool)
 
-		prefix, err := getPrefix(req)
+		opts, err := filesCidOptions(req)
 		if err != nil {
 			return err
 		}
 
-		err = updatePath(nd.FilesRoot, path, prefix)
-		if err == nil && flush {
-			err = mfs.FlushPath(nd.FilesRoot, path)
-		}
-		return err
+		return api.Files().ChCid(req.Context, path, append(opts, options.Files.Flush(flush))...)
 	},
 }
 
-func updatePath(rt *mfs.Root, pth string, builder cid.Builder) error {
-	if builder == nil {
-		return nil
-	}
-
-	nd, err := mfs.Lookup(rt, pth)
-	if err != nil {
-		return err
-	}
-
-	switch n := nd.(type) {
-	case *mfs.Directory:
-		n.SetCidBuilder(builder)
-	default:
-		return fmt.Errorf("can only update directories")
-	}
-
-	return nil
-}
-
 var filesRmCmd = &cmds.Command{
 	Helptext: cmdkit.HelpText{
 		Tagline: "Remove a file.",
@@ -965,209 +621,34 @@ Remove files or directories.
 		cmdkit.BoolOption(forceOptionName, "Forcibly remove target at path; implies -r for directories"),
 	},
 	Run: func(req *cmds.Request, res cmds.ResponseEmitter, env cmds.Environment) error {
-		nd, err := cmdenv.GetNode(env)
-		if err != nil {
-			return err
-		}
-
-		path, err := checkPath(req.Arguments[0])
-		if err != nil {
-			return err
-		}
-
-		if path == "/" {
-			return fmt.Errorf("cannot delete root")
-		}
-
-		// 'rm a/b/c/' will fail unless we trim the slash at the end
-		if path[len(path)-1] == '/' {
-			path = path[:len(path)-1]
-		}
-
-		dir, name := gopath.Split(path)
-		parent, err := mfs.Lookup(nd.FilesRoot, dir)
-		if err != nil {
-			return fmt.Errorf("parent lookup: %s", err)
-		}
-
-		pdir, ok := parent.(*mfs.Directory)
-		if !ok {
-			return fmt.Errorf("no such file or directory: %s", path)
-		}
-
-		// if '--force' specified, it will remove anything else,
-		// including file, directory, corrupted node, etc
-		force, _ := req.Options[forceOptionName].(bool)
-		if force {
-			err := pdir.Unlink(name)
-			if err != nil {
-				return err
-			}
-
-			return pdir.Flush()
-		}
-
-		// get child node by name, when the node is corrupted and nonexistent,
-		// it will return specific error.
-		child, err := pdir.Child(name)
+		api, err := cmdenv.GetApi(env, req)
 		if err != nil {
 			return err
 		}
 
 		dashr, _ := req.Options[recursiveOptionName].(bool)
+		force, _ := req.Options[forceOptionName].(bool)
 
-		switch child.(type) {
-		case *mfs.Directory:
-			if !dashr {
-				return fmt.Errorf("%s is a directory, use -r to remove directories", path)
-			}
-		}
-
-		err = pdir.Unlink(name)
-		if err != nil {
-			return err
-		}
-
-		return pdir.Flush()
+		return api.Files().Rm(req.Context, req.Arguments[0], options.Files.Recursive(dashr), options.Files.Force(force))
 	},
 }
 
-func getPrefixNew(req *cmds.Request) (cid.Builder, error) {
-	cidVer, cidVerSet := req.Options[filesCidVersionOptionName].(int)
-	hashFunStr, hashFunSet := req.Options[filesHashOptionName].(string)
-
-	if !cidVerSet && !hashFunSet {
-		return nil, nil
-	}
-
-	if hashFunSet && cidVer == 0 {
-		cidVer = 1
-	}
-
-	prefix, err := dag.PrefixForCidVersion(cidVer)
-	if err != nil {
-		return nil, err
-	}
-
-	if hashFunSet {
-		hashFunCode, ok := mh.Names[strings.ToLower(hashFunStr)]
-		if !ok {
-			return nil, fmt.Errorf("unrecognized hash function: %s", strings.ToLower(hashFunStr))
-		}
-		prefix.MhType = hashFunCode
-		prefix.MhLength = -1
-	}
-
-	return &prefix, nil
-}
-
-func getPrefix(req *cmds.Request) (cid.Builder, error) {
-	cidVer, cidVerSet := req.Options[filesCidVersionOptionName].(int)
-	hashFunStr, hashFunSet := req.Options[filesHashOptionName].(string)
-
-	if !cidVerSet && !hashFunSet {
-		return nil, nil
-	}
-
-	if hashFunSet && cidVer == 0 {
-		cidVer = 1
-	}
+// filesCidOptions returns the FilesAPI options selected with the
+// --cid-version and --hash flags
+func filesCidOptions(req *cmds.Request) ([]options.FilesOption, error) {
+	var opts []options.FilesOption
 
-	prefix, err := dag.PrefixForCidVersion(cidVer)
-	if err != nil {
-		return nil, err
+	if cidVer, ok := req.Options[filesCidVersionOptionName].(int); ok {
+		opts = append(opts, options.Files.CidVersion(cidVer))
 	}
 
-	if hashFunSet {
+	if hashFunStr, ok := req.Options[filesHashOptionName].(string); ok {
 		hashFunCode, ok := mh.Names[strings.ToLower(hashFunStr)]
 		if !ok {
 			return nil, fmt.Errorf("unrecognized hash function: %s", strings.ToLower(hashFunStr))
 		}
-		prefix.MhType = hashFunCode
-		prefix.MhLength = -1
-	}
-
-	return &prefix, nil
-}
-
-func ensureContainingDirectoryExists(r *mfs.Root, path string, builder cid.Builder) error {
-	dirtomake := gopath.Dir(path)
-
-	if dirtomake == "/" {
-		return nil
-	}
-
-	return mfs.Mkdir(r, dirtomake, mfs.MkdirOpts{
-		Mkparents:  true,
-		CidBuilder: builder,
-	})
-}
-
-func getFileHandle(r *mfs.Root, path string, create bool, builder cid.Builder) (*mfs.File, error) {
-	target, err := mfs.Lookup(r, path)
-	switch err {
-	case nil:
-		fi, ok := target.(*mfs.File)
-		if !ok {
-			return nil, fmt.Errorf("%s was not a file", path)
-		}
-		return fi, nil
-
-	case os.ErrNotExist:
-		if !create {
-			return nil, err
-		}
-
-		// if create is specified and the file doesnt exist, we create the file
-		dirname, fname := gopath.Split(path)
-		pdiri, err := mfs.Lookup(r, dirname)
-		if err != nil {
-			flog.Error("lookupfail ", dirname)
-			return nil, err
-		}
-		pdir, ok := pdiri.(*mfs.Directory)
-		if !ok {
-			return nil, fmt.Errorf("%s was not a directory", dirname)
-		}
-		if builder == nil {
-			builder = pdir.GetCidBuilder()
-		}
-
-		nd := dag.NodeWithData(ft.FilePBData(nil, 0))
-		nd.SetCidBuilder(builder)
-		err = pdir.AddChild(fname, nd)
-		if err != nil {
-			return nil, err
-		}
-
-		fsn, err := pdir.Child(fname)
-		if err != nil {
-			return nil, err
-		}
-
-		fi, ok := fsn.(*mfs.File)
-		if !ok {
-			return nil, errors.New("expected *mfs.File, didnt get it. This is likely a race condition")
-		}
-		return fi, nil
-
-	default:
-		return nil, err
-	}
-}
-
-func checkPath(p string) (string, error) {
-	if len(p) == 0 {
-		return "", fmt.Errorf("paths must not be empty")
-	}
-
-	if p[0] != '/' {
-		return "", fmt.Errorf("paths must start with a leading slash")
+		opts = append(opts, options.Files.Hash(hashFunCode))
 	}
 
-	cleaned := gopath.Clean(p)
-	if p[len(p)-1] == '/' && p != "/" {
-		cleaned += "/"
-	}
-	return cleaned, nil
+	return opts, nil
 }
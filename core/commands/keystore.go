@@ -1,14 +1,20 @@
 package commands
 
 import (
+	"bytes"
+	"errors"
 	"fmt"
 	"io"
+	"os"
 	"text/tabwriter"
 
 	cmdenv "github.com/ipfs/go-ipfs/core/commands/cmdenv"
 	options "github.com/ipfs/go-ipfs/core/coreapi/interface/options"
+	fsrepo "github.com/ipfs/go-ipfs/repo/fsrepo"
+	passphrase "github.com/ipfs/go-ipfs/thirdparty/passphrase"
 
 	cmds "gx/ipfs/QmR77mMvvh8mJBBWQmBfQBu8oD38NUN4KE9SL2gDgAQNc6/go-ipfs-cmds"
+	files "gx/ipfs/QmaXvvAVAQ5ABqM5xtjYmV85xmN5MkWAZsX9H9Fwo4FVXp/go-ipfs-files"
 	cmdkit "gx/ipfs/Qmde5VP1qUkyQXKCfmEUA7bP64V2HAptbJ7phuPp7jXWwg/go-ipfs-cmdkit"
 )
 
@@ -27,13 +33,21 @@ publish'.
   > ipfs key list
   self
   mykey
+
+'ipfs key export' and 'ipfs key import' move keys between nodes.
+
+  > ipfs key export mykey > mykey.key
+  > ipfs key import mykey mykey.key
 		`,
 	},
 	Subcommands: map[string]*cmds.Command{
-		"gen":    keyGenCmd,
-		"list":   keyListCmd,
-		"rename": keyRenameCmd,
-		"rm":     keyRmCmd,
+		"gen":               keyGenCmd,
+		"export":            keyExportCmd,
+		"import":            keyImportCmd,
+		"list":              keyListCmd,
+		"rename":            keyRenameCmd,
+		"rm":                keyRmCmd,
+		"rotate-passphrase": keyRotatePassphraseCmd,
 	},
 }
 
@@ -113,6 +127,180 @@ var keyGenCmd = &cmds.Command{
 	Type: KeyOutput{},
 }
 
+const (
+	keyFormatOptionName = "format"
+)
+
+// EnvKeyPassphrase is the environment variable read by 'ipfs key export'
+// and 'ipfs key import' for the passphrase of the libp2p-protobuf-encrypted
+// format
+const EnvKeyPassphrase = "IPFS_KEY_PASSPHRASE"
+
+// errKeyOnDaemon is returned when a command handling private keys is sent to
+// the API of a daemon, so that API clients can't read or replace the keys
+var errKeyOnDaemon = errors.New("private keys can't be exported or imported through the API, stop the daemon and run the command locally")
+
+// keyEncodingOptions returns the encoding options of the request. The
+// passphrase of the encrypted format is read from $IPFS_KEY_PASSPHRASE, or
+// asked on the terminal, so that it doesn't show in the arguments.
+func keyEncodingOptions(req *cmds.Request, newPassphrase bool) ([]options.KeyEncodingOption, error) {
+	format, _ := req.Options[keyFormatOptionName].(string)
+	opts := []options.KeyEncodingOption{options.Key.Format(format)}
+	if format != options.KeyFormatEncrypted {
+		return opts, nil
+	}
+
+	pass := []byte(os.Getenv(EnvKeyPassphrase))
+	if len(pass) == 0 {
+		var err error
+		if newPassphrase {
+			pass, err = passphrase.ReadNew("Enter the passphrase to encrypt the key with: ")
+		} else {
+			pass, err = passphrase.Read("Enter the passphrase of the key: ")
+		}
+		if err != nil {
+			return nil, fmt.Errorf("reading the passphrase: %s, set %s", err, EnvKeyPassphrase)
+		}
+	}
+	if len(pass) == 0 {
+		return nil, fmt.Errorf("the passphrase must not be empty")
+	}
+
+	return append(opts, options.Key.Passphrase(string(pass))), nil
+}
+
+var keyExportCmd = &cmds.Command{
+	Helptext: cmdkit.HelpText{
+		Tagline: "Export a keypair",
+		ShortDescription: `
+Writes the private key stored under the given name to stdout. The key of
+the node itself can be exported as 'self'.
+
+Supported formats:
+  libp2p-protobuf-cleartext  the encoding used by the keystore (default)
+  pem-pkcs8-cleartext        PEM encoded PKCS#8, for RSA and Ed25519 keys
+  libp2p-protobuf-encrypted  encrypted with a key derived from a passphrase
+
+The passphrase of the encrypted format is read from $IPFS_KEY_PASSPHRASE, or
+asked on the terminal when it isn't set.
+
+  > ipfs key export mykey > mykey.key
+  > ipfs key export --format=pem-pkcs8-cleartext mykey > mykey.pem
+
+This command can only run when no ipfs daemons are running.
+`,
+	},
+	Arguments: []cmdkit.Argument{
+		cmdkit.StringArg("name", true, false, "name of key to export").EnableStdin(),
+	},
+	Options: []cmdkit.Option{
+		cmdkit.StringOption(keyFormatOptionName, "f", "format of the exported key.").WithDefault(options.KeyFormatLibp2pProtobuf),
+	},
+	Run: func(req *cmds.Request, res cmds.ResponseEmitter, env cmds.Environment) error {
+		n, err := cmdenv.GetNode(env)
+		if err != nil {
+			return err
+		}
+		if n.IsDaemon {
+			return errKeyOnDaemon
+		}
+
+		api, err := cmdenv.GetApi(env, req)
+		if err != nil {
+			return err
+		}
+
+		opts, err := keyEncodingOptions(req, true)
+		if err != nil {
+			return err
+		}
+
+		data, err := api.Key().Export(req.Context, req.Arguments[0], opts...)
+		if err != nil {
+			return err
+		}
+
+		return res.Emit(bytes.NewReader(data))
+	},
+}
+
+var keyImportCmd = &cmds.Command{
+	Helptext: cmdkit.HelpText{
+		Tagline: "Import a keypair",
+		ShortDescription: `
+Reads a private key from a file, as written by 'ipfs key export', and
+stores it in the keystore under the given name. See 'ipfs key export' for
+the supported formats.
+
+  > ipfs key import mykey mykey.key
+
+This command can only run when no ipfs daemons are running.
+`,
+	},
+	Arguments: []cmdkit.Argument{
+		cmdkit.StringArg("name", true, false, "name to store the key under"),
+		cmdkit.FileArg("key", true, false, "file containing the key").EnableStdin(),
+	},
+	Options: []cmdkit.Option{
+		cmdkit.StringOption(keyFormatOptionName, "f", "format of the imported key.").WithDefault(options.KeyFormatLibp2pProtobuf),
+	},
+	Run: func(req *cmds.Request, res cmds.ResponseEmitter, env cmds.Environment) error {
+		n, err := cmdenv.GetNode(env)
+		if err != nil {
+			return err
+		}
+		if n.IsDaemon {
+			return errKeyOnDaemon
+		}
+
+		api, err := cmdenv.GetApi(env, req)
+		if err != nil {
+			return err
+		}
+
+		name := req.Arguments[0]
+		if name == "self" {
+			return fmt.Errorf("cannot import key with name 'self'")
+		}
+
+		it := req.Files.Entries()
+		if !it.Next() {
+			if it.Err() != nil {
+				return it.Err()
+			}
+			return fmt.Errorf("no key file given")
+		}
+
+		file := files.FileFromEntry(it)
+		if file == nil {
+			return fmt.Errorf("expected a regular file")
+		}
+		defer file.Close()
+
+		opts, err := keyEncodingOptions(req, false)
+		if err != nil {
+			return err
+		}
+
+		key, err := api.Key().Import(req.Context, name, file, opts...)
+		if err != nil {
+			return err
+		}
+
+		return cmds.EmitOnce(res, &KeyOutput{
+			Name: name,
+			Id:   key.ID().Pretty(),
+		})
+	},
+	Encoders: cmds.EncoderMap{
+		cmds.Text: cmds.MakeTypedEncoder(func(req *cmds.Request, w io.Writer, ko *KeyOutput) error {
+			_, err := w.Write([]byte(ko.Id + "\n"))
+			return err
+		}),
+	},
+	Type: KeyOutput{},
+}
+
 var keyListCmd = &cmds.Command{
 	Helptext: cmdkit.HelpText{
 		Tagline: "List all local keypairs",
@@ -231,6 +419,60 @@ var keyRmCmd = &cmds.Command{
 	Type: KeyOutputList{},
 }
 
+// EnvKeystoreNewPassphrase is the environment variable read by
+// 'ipfs key rotate-passphrase' for the new passphrase
+const EnvKeystoreNewPassphrase = "IPFS_KEYSTORE_NEW_PASSPHRASE"
+
+var keyRotatePassphraseCmd = &cmds.Command{
+	Helptext: cmdkit.HelpText{
+		Tagline: "Encrypt the keystore or change its passphrase",
+		ShortDescription: `
+'ipfs key rotate-passphrase' changes the passphrase protecting the keys in
+the keystore. If the keystore is not encrypted yet, it is converted to the
+encrypted format.
+
+The current passphrase is read from $IPFS_KEYSTORE_PASSPHRASE, the new one
+from $IPFS_KEYSTORE_NEW_PASSPHRASE. Passphrases that aren't set are asked
+for on the terminal. Once the keystore is encrypted, the daemon needs the
+passphrase to start.
+
+The key of the node itself is stored in the config file and is not
+affected. This command can only run when no ipfs daemons are running.
+`,
+	},
+	Run: func(req *cmds.Request, res cmds.ResponseEmitter, env cmds.Environment) error {
+		cfgRoot, err := cmdenv.GetConfigRoot(env)
+		if err != nil {
+			return err
+		}
+
+		r, err := fsrepo.Open(cfgRoot)
+		if err != nil {
+			return err
+		}
+		defer r.Close()
+
+		fsr, ok := r.(*fsrepo.FSRepo)
+		if !ok {
+			return fmt.Errorf("repo doesn't support keystore encryption")
+		}
+
+		newPassphrase := []byte(os.Getenv(EnvKeystoreNewPassphrase))
+		if len(newPassphrase) == 0 {
+			newPassphrase, err = passphrase.ReadNew("Enter the new keystore passphrase: ")
+			if err != nil {
+				return fmt.Errorf("reading the new passphrase: %s, set %s", err, EnvKeystoreNewPassphrase)
+			}
+		}
+
+		if len(newPassphrase) == 0 {
+			return fmt.Errorf("the passphrase must not be empty")
+		}
+
+		return fsr.SetKeystorePassphrase(newPassphrase)
+	},
+}
+
 func keyOutputListEncoders() cmds.EncoderFunc {
 	return cmds.MakeTypedEncoder(func(req *cmds.Request, w io.Writer, list *KeyOutputList) error {
 		withID, _ := req.Options["l"].(bool)
@@ -0,0 +1,184 @@
+package name
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	cmdenv "github.com/ipfs/go-ipfs/core/commands/cmdenv"
+
+	cmds "gx/ipfs/QmR77mMvvh8mJBBWQmBfQBu8oD38NUN4KE9SL2gDgAQNc6/go-ipfs-cmds"
+	cmdkit "gx/ipfs/Qmde5VP1qUkyQXKCfmEUA7bP64V2HAptbJ7phuPp7jXWwg/go-ipfs-cmdkit"
+)
+
+var errNoResolveCache = errors.New("the resolve cache is only used in online mode, unless Namesys.PersistResolveCache is set")
+
+// CacheEntry is a resolved name held by the resolve cache
+type CacheEntry struct {
+	Name  string
+	Value string
+	EOL   time.Time
+}
+
+// CacheListOutput is the content of the resolve cache
+type CacheListOutput struct {
+	Entries []CacheEntry
+	Hits    uint64
+	Misses  uint64
+}
+
+// CacheRemoveOutput is the names evicted from the resolve cache, and the
+// ones which weren't cached
+type CacheRemoveOutput struct {
+	Removed []string
+	Missing []string
+}
+
+// CacheClearOutput is the number of entries evicted from the resolve cache
+type CacheClearOutput struct {
+	Removed int
+}
+
+var CacheCmd = &cmds.Command{
+	Helptext: cmdkit.HelpText{
+		Tagline: "Manage the cache of resolved names.",
+		ShortDescription: `
+IPNS names and DNSLinks resolved by the node are cached until the TTL of their
+record runs out, see Ipns.ResolveCacheSize for the size of the cache. When
+Namesys.PersistResolveCache is set, the cache is kept in the datastore and
+survives restarts.
+`,
+	},
+
+	Subcommands: map[string]*cmds.Command{
+		"ls":    cacheLsCmd,
+		"rm":    cacheRmCmd,
+		"clear": cacheClearCmd,
+	},
+}
+
+var cacheLsCmd = &cmds.Command{
+	Helptext: cmdkit.HelpText{
+		Tagline: "List the cached names.",
+		ShortDescription: `
+Lists the cached names with their value and the time their entry expires at,
+along with the hit and miss counters of the cache since the node started.
+`,
+	},
+
+	Run: func(req *cmds.Request, res cmds.ResponseEmitter, env cmds.Environment) error {
+		n, err := cmdenv.GetNode(env)
+		if err != nil {
+			return err
+		}
+		if n.NamesysCache == nil {
+			return errNoResolveCache
+		}
+
+		entries := n.NamesysCache.List()
+		stats := n.NamesysCache.Stats()
+
+		out := &CacheListOutput{
+			Entries: make([]CacheEntry, 0, len(entries)),
+			Hits:    stats.Hits,
+			Misses:  stats.Misses,
+		}
+		for _, e := range entries {
+			out.Entries = append(out.Entries, CacheEntry{
+				Name:  e.Name,
+				Value: e.Value.String(),
+				EOL:   e.EOL,
+			})
+		}
+		return cmds.EmitOnce(res, out)
+	},
+	Encoders: cmds.EncoderMap{
+		cmds.Text: cmds.MakeTypedEncoder(func(req *cmds.Request, w io.Writer, out *CacheListOutput) error {
+			tw := tabwriter.NewWriter(w, 1, 2, 1, ' ', 0)
+			fmt.Fprintln(tw, "NAME\tVALUE\tEXPIRES")
+			for _, e := range out.Entries {
+				fmt.Fprintf(tw, "%s\t%s\t%s\n", e.Name, e.Value, e.EOL.Format(time.RFC3339))
+			}
+			if err := tw.Flush(); err != nil {
+				return err
+			}
+			_, err := fmt.Fprintf(w, "%d hits, %d misses\n", out.Hits, out.Misses)
+			return err
+		}),
+	},
+	Type: CacheListOutput{},
+}
+
+var cacheRmCmd = &cmds.Command{
+	Helptext: cmdkit.HelpText{
+		Tagline: "Evict names from the cache.",
+		ShortDescription: `
+Evicts the given names from the cache, so that they are resolved again on
+their next use.
+`,
+	},
+
+	Arguments: []cmdkit.Argument{
+		cmdkit.StringArg("name", true, true, "The IPNS names or DNSLink domains to evict."),
+	},
+	Run: func(req *cmds.Request, res cmds.ResponseEmitter, env cmds.Environment) error {
+		n, err := cmdenv.GetNode(env)
+		if err != nil {
+			return err
+		}
+		if n.NamesysCache == nil {
+			return errNoResolveCache
+		}
+
+		out := &CacheRemoveOutput{Removed: []string{}, Missing: []string{}}
+		for _, name := range req.Arguments {
+			name = strings.TrimPrefix(name, "/ipns/")
+			if n.NamesysCache.Remove(name) {
+				out.Removed = append(out.Removed, name)
+			} else {
+				out.Missing = append(out.Missing, name)
+			}
+		}
+		return cmds.EmitOnce(res, out)
+	},
+	Encoders: cmds.EncoderMap{
+		cmds.Text: cmds.MakeTypedEncoder(func(req *cmds.Request, w io.Writer, out *CacheRemoveOutput) error {
+			for _, name := range out.Removed {
+				fmt.Fprintf(w, "removed %s\n", name)
+			}
+			for _, name := range out.Missing {
+				fmt.Fprintf(w, "%s was not cached\n", name)
+			}
+			return nil
+		}),
+	},
+	Type: CacheRemoveOutput{},
+}
+
+var cacheClearCmd = &cmds.Command{
+	Helptext: cmdkit.HelpText{
+		Tagline: "Evict every name from the cache.",
+	},
+
+	Run: func(req *cmds.Request, res cmds.ResponseEmitter, env cmds.Environment) error {
+		n, err := cmdenv.GetNode(env)
+		if err != nil {
+			return err
+		}
+		if n.NamesysCache == nil {
+			return errNoResolveCache
+		}
+
+		return cmds.EmitOnce(res, &CacheClearOutput{Removed: n.NamesysCache.Clear()})
+	},
+	Encoders: cmds.EncoderMap{
+		cmds.Text: cmds.MakeTypedEncoder(func(req *cmds.Request, w io.Writer, out *CacheClearOutput) error {
+			_, err := fmt.Fprintf(w, "removed %d entries\n", out.Removed)
+			return err
+		}),
+	},
+	Type: CacheClearOutput{},
+}
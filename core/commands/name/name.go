@@ -51,6 +51,12 @@ Resolve the value of another name:
   > ipfs name resolve QmaCpDMGvV2BGHeYERUEnRQAwe3N8SzbUtfsmvsqQLuvuJ
   /ipfs/QmSiTko9JZyabH56y2fussEt1A5oDqsFXB3CkvAqraFryz
 
+Create a record offline, and publish it from another node:
+
+  > ipfs name create --key=mykey /ipfs/QmatmE9msSfkKxoffpHwNLNKgwZG8eT9Bud6YoPab52vpy > record.ipns
+  > ipfs name put QmSrPmbaUKA3ZodhzPWZnpFgcPMFWF4QsxXbkWfEptTBJd record.ipns
+  Published to QmSrPmbaUKA3ZodhzPWZnpFgcPMFWF4QsxXbkWfEptTBJd: /ipfs/QmatmE9msSfkKxoffpHwNLNKgwZG8eT9Bud6YoPab52vpy
+
 Resolve the value of a dnslink:
 
   > ipfs name resolve ipfs.io
@@ -60,8 +66,13 @@ Resolve the value of a dnslink:
 	},
 
 	Subcommands: map[string]*cmds.Command{
-		"publish": PublishCmd,
-		"resolve": IpnsCmd,
-		"pubsub":  IpnsPubsubCmd,
+		"publish":   PublishCmd,
+		"resolve":   IpnsCmd,
+		"pubsub":    IpnsPubsubCmd,
+		"republish": RepublishCmd,
+		"create":    CreateCmd,
+		"inspect":   InspectCmd,
+		"put":       PutCmd,
+		"cache":     CacheCmd,
 	},
 }
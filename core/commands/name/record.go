@@ -0,0 +1,310 @@
+package name
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+	"time"
+
+	cmdenv "github.com/ipfs/go-ipfs/core/commands/cmdenv"
+	iface "github.com/ipfs/go-ipfs/core/coreapi/interface"
+	options "github.com/ipfs/go-ipfs/core/coreapi/interface/options"
+	namesys "github.com/ipfs/go-ipfs/namesys"
+
+	ci "gx/ipfs/QmNiJiXwWE3kRhZrC5ej3kSjWHm337pYfhjLGSCDNKJP2s/go-libp2p-crypto"
+	peer "gx/ipfs/QmPJxxDsX2UbchSHobbYuvz7qnyJTFKvaKMzE2rZWJ4x5B/go-libp2p-peer"
+	cmds "gx/ipfs/QmR77mMvvh8mJBBWQmBfQBu8oD38NUN4KE9SL2gDgAQNc6/go-ipfs-cmds"
+	ipns "gx/ipfs/QmVpC4PPSaoqZzWYEnQURnsQagimcWEzNKZouZyd7sNJdZ/go-ipns"
+	pb "gx/ipfs/QmVpC4PPSaoqZzWYEnQURnsQagimcWEzNKZouZyd7sNJdZ/go-ipns/pb"
+	cmdkit "gx/ipfs/Qmde5VP1qUkyQXKCfmEUA7bP64V2HAptbJ7phuPp7jXWwg/go-ipfs-cmdkit"
+	proto "gx/ipfs/QmdxUuburamoF6zF9qjeQC4WYcWGbWuRmdLacMEsW8ioD8/gogo-protobuf/proto"
+)
+
+const (
+	sequenceOptionName = "sequence"
+	verifyOptionName   = "verify"
+)
+
+// maxRecordSize is the size limit of IPNS records in the routing system
+const maxRecordSize = 10 << 10
+
+// IpnsInspectEntry is the decoded content of an IPNS record
+type IpnsInspectEntry struct {
+	Value        string
+	Sequence     uint64
+	ValidityType string
+	Validity     time.Time
+	TTL          time.Duration
+
+	// PublicKey is the peer ID of the public key embedded in the record,
+	// empty when the key is inlined in the peer ID
+	PublicKey string `json:",omitempty"`
+
+	Verification *IpnsInspectVerification `json:",omitempty"`
+}
+
+// IpnsInspectVerification is the result of verifying a record against a name
+type IpnsInspectVerification struct {
+	Name  string
+	Valid bool
+	Error string `json:",omitempty"`
+}
+
+var CreateCmd = &cmds.Command{
+	Helptext: cmdkit.HelpText{
+		Tagline: "Create a signed IPNS record without publishing it.",
+		ShortDescription: `
+Creates an IPNS record pointing to <ipfs-path>, signed with the given key, and
+writes it to stdout. Nothing is stored or published, so the record can be
+created on an offline machine and published elsewhere with 'ipfs name put'.
+
+By default, the sequence number follows on from the last record published by
+this node with the key.
+
+Examples:
+
+  > ipfs name create --key=mykey /ipfs/QmatmE9msSfkKxoffpHwNLNKgwZG8eT9Bud6YoPab52vpy > record.ipns
+`,
+	},
+
+	Arguments: []cmdkit.Argument{
+		cmdkit.StringArg(ipfsPathOptionName, true, false, "ipfs path the record points to.").EnableStdin(),
+	},
+	Options: []cmdkit.Option{
+		cmdkit.StringOption(keyOptionName, "k", "Name of the key to be used or a valid PeerID, as listed by 'ipfs key list -l'.").WithDefault("self"),
+		cmdkit.StringOption(lifeTimeOptionName, "t",
+			`Time duration that the record will be valid for. <<default>>
+    This accepts durations such as "300s", "1.5h" or "2h45m". Valid time units are
+    "ns", "us" (or "µs"), "ms", "s", "m", "h".`).WithDefault("24h"),
+		cmdkit.StringOption(ttlOptionName, "Time duration this record should be cached for (caution: experimental)."),
+		cmdkit.Uint64Option(sequenceOptionName, "Sequence number of the record."),
+	},
+	Run: func(req *cmds.Request, res cmds.ResponseEmitter, env cmds.Environment) error {
+		api, err := cmdenv.GetApi(env, req)
+		if err != nil {
+			return err
+		}
+
+		kname, _ := req.Options[keyOptionName].(string)
+
+		validTimeOpt, _ := req.Options[lifeTimeOptionName].(string)
+		validTime, err := time.ParseDuration(validTimeOpt)
+		if err != nil {
+			return fmt.Errorf("error parsing lifetime option: %s", err)
+		}
+
+		opts := []options.NameCreateOption{
+			options.Name.CreateKey(kname),
+			options.Name.CreateValidTime(validTime),
+		}
+
+		if ttl, found := req.Options[ttlOptionName].(string); found {
+			d, err := time.ParseDuration(ttl)
+			if err != nil {
+				return err
+			}
+
+			opts = append(opts, options.Name.CreateTTL(d))
+		}
+
+		if seq, found := req.Options[sequenceOptionName].(uint64); found {
+			opts = append(opts, options.Name.Sequence(seq))
+		}
+
+		p, err := iface.ParsePath(req.Arguments[0])
+		if err != nil {
+			return err
+		}
+
+		rec, err := api.Name().CreateRecord(req.Context, p, opts...)
+		if err != nil {
+			return err
+		}
+
+		return res.Emit(bytes.NewReader(rec))
+	},
+}
+
+var InspectCmd = &cmds.Command{
+	Helptext: cmdkit.HelpText{
+		Tagline: "Inspect an IPNS record.",
+		ShortDescription: `
+Decodes the IPNS record read from <record> and prints its value, sequence
+number, validity, TTL and embedded public key. With --verify, the record is
+also checked to be signed by the key of the given name and not expired.
+
+Examples:
+
+  > ipfs name inspect record.ipns
+  > ipfs name inspect --verify=QmSrPmbaUKA3ZodhzPWZnpFgcPMFWF4QsxXbkWfEptTBJd < record.ipns
+`,
+	},
+
+	Arguments: []cmdkit.Argument{
+		cmdkit.FileArg("record", true, false, "The IPNS record to inspect.").EnableStdin(),
+	},
+	Options: []cmdkit.Option{
+		cmdkit.StringOption(verifyOptionName, "Verify the record against the given IPNS name."),
+	},
+	Run: func(req *cmds.Request, res cmds.ResponseEmitter, env cmds.Environment) error {
+		entry, err := readRecord(req)
+		if err != nil {
+			return err
+		}
+
+		out := &IpnsInspectEntry{
+			Value:        string(entry.GetValue()),
+			Sequence:     entry.GetSequence(),
+			ValidityType: entry.GetValidityType().String(),
+			TTL:          time.Duration(entry.GetTtl()),
+		}
+
+		if entry.GetValidityType() == pb.IpnsEntry_EOL {
+			out.Validity, err = ipns.GetEOL(entry)
+			if err != nil {
+				return err
+			}
+		}
+
+		if len(entry.PubKey) > 0 {
+			pk, err := ci.UnmarshalPublicKey(entry.PubKey)
+			if err != nil {
+				return fmt.Errorf("invalid public key in record: %s", err)
+			}
+			pid, err := peer.IDFromPublicKey(pk)
+			if err != nil {
+				return err
+			}
+			out.PublicKey = pid.Pretty()
+		}
+
+		if name, found := req.Options[verifyOptionName].(string); found {
+			out.Verification = &IpnsInspectVerification{Name: name}
+
+			pid, err := peer.IDB58Decode(strings.TrimPrefix(name, "/ipns/"))
+			if err == nil {
+				_, err = namesys.VerifyRecord(pid, entry)
+			}
+			if err != nil {
+				out.Verification.Error = err.Error()
+			} else {
+				out.Verification.Valid = true
+			}
+		}
+
+		return cmds.EmitOnce(res, out)
+	},
+	Encoders: cmds.EncoderMap{
+		cmds.Text: cmds.MakeTypedEncoder(func(req *cmds.Request, w io.Writer, out *IpnsInspectEntry) error {
+			fmt.Fprintf(w, "Value: %s\n", out.Value)
+			fmt.Fprintf(w, "Sequence: %d\n", out.Sequence)
+			fmt.Fprintf(w, "Validity Type: %s\n", out.ValidityType)
+			if !out.Validity.IsZero() {
+				fmt.Fprintf(w, "Validity: %s\n", out.Validity.Format(time.RFC3339Nano))
+			}
+			if out.TTL != 0 {
+				fmt.Fprintf(w, "TTL: %s\n", out.TTL)
+			}
+			if out.PublicKey != "" {
+				fmt.Fprintf(w, "Public Key: %s\n", out.PublicKey)
+			}
+
+			if v := out.Verification; v != nil {
+				if v.Valid {
+					fmt.Fprintf(w, "Signature: valid for %s\n", v.Name)
+				} else {
+					fmt.Fprintf(w, "Signature: invalid for %s: %s\n", v.Name, v.Error)
+				}
+			}
+			return nil
+		}),
+	},
+	Type: IpnsInspectEntry{},
+}
+
+var PutCmd = &cmds.Command{
+	Helptext: cmdkit.HelpText{
+		Tagline: "Publish an IPNS record signed elsewhere.",
+		ShortDescription: `
+Verifies that the IPNS record read from <record> is signed by the key of
+<name> and isn't expired, and puts it to the routing system. Use it to publish
+records created with 'ipfs name create' on another machine, without access to
+their private key.
+
+Examples:
+
+  > ipfs name put QmSrPmbaUKA3ZodhzPWZnpFgcPMFWF4QsxXbkWfEptTBJd record.ipns
+`,
+	},
+
+	Arguments: []cmdkit.Argument{
+		cmdkit.StringArg("name", true, false, "The IPNS name the record is for."),
+		cmdkit.FileArg("record", true, false, "The IPNS record to publish.").EnableStdin(),
+	},
+	Options: []cmdkit.Option{
+		cmdkit.BoolOption(allowOfflineOptionName, "When offline, save the IPNS record to the the local datastore without broadcasting to the network instead of simply failing."),
+	},
+	Run: func(req *cmds.Request, res cmds.ResponseEmitter, env cmds.Environment) error {
+		api, err := cmdenv.GetApi(env, req)
+		if err != nil {
+			return err
+		}
+
+		entry, err := readRecord(req)
+		if err != nil {
+			return err
+		}
+		rec, err := proto.Marshal(entry)
+		if err != nil {
+			return err
+		}
+
+		allowOffline, _ := req.Options[allowOfflineOptionName].(bool)
+		name := req.Arguments[0]
+
+		err = api.Name().PutRecord(req.Context, name, rec, options.Name.PutAllowOffline(allowOffline))
+		if err != nil {
+			if err == iface.ErrOffline {
+				err = errAllowOffline
+			}
+			return err
+		}
+
+		return cmds.EmitOnce(res, &IpnsEntry{
+			Name:  strings.TrimPrefix(name, "/ipns/"),
+			Value: string(entry.GetValue()),
+		})
+	},
+	Encoders: cmds.EncoderMap{
+		cmds.Text: cmds.MakeTypedEncoder(func(req *cmds.Request, w io.Writer, ie *IpnsEntry) error {
+			_, err := fmt.Fprintf(w, "Published to %s: %s\n", ie.Name, ie.Value)
+			return err
+		}),
+	},
+	Type: IpnsEntry{},
+}
+
+// readRecord reads and decodes the IPNS record of the file argument
+func readRecord(req *cmds.Request) (*pb.IpnsEntry, error) {
+	file, err := cmdenv.GetFileArg(req.Files.Entries())
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	data, err := ioutil.ReadAll(io.LimitReader(file, maxRecordSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > maxRecordSize {
+		return nil, fmt.Errorf("record is larger than the %d bytes limit", maxRecordSize)
+	}
+
+	entry := new(pb.IpnsEntry)
+	if err := proto.Unmarshal(data, entry); err != nil {
+		return nil, fmt.Errorf("invalid IPNS record: %s", err)
+	}
+	return entry, nil
+}
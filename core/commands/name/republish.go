@@ -0,0 +1,171 @@
+package name
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"text/tabwriter"
+	"time"
+
+	cmdenv "github.com/ipfs/go-ipfs/core/commands/cmdenv"
+	republisher "github.com/ipfs/go-ipfs/namesys/republisher"
+
+	cmds "gx/ipfs/QmR77mMvvh8mJBBWQmBfQBu8oD38NUN4KE9SL2gDgAQNc6/go-ipfs-cmds"
+	cmdkit "gx/ipfs/Qmde5VP1qUkyQXKCfmEUA7bP64V2HAptbJ7phuPp7jXWwg/go-ipfs-cmdkit"
+)
+
+var errNotRepublishing = errors.New("the IPNS republisher only runs in online mode, try running 'ipfs daemon' first")
+
+// RepublishResult is the result of republishing the record of a key
+type RepublishResult struct {
+	Key      string
+	NoRecord bool   `json:",omitempty"`
+	Error    string `json:",omitempty"`
+}
+
+// RepublishStatus is the republishing status of a key
+type RepublishStatus struct {
+	Key         string
+	Id          string
+	LastSuccess time.Time
+	NextRun     time.Time
+	LastError   string `json:",omitempty"`
+}
+
+// RepublishStatusOutput is the republishing status of all the keys
+type RepublishStatusOutput struct {
+	Keys []RepublishStatus
+}
+
+var RepublishCmd = &cmds.Command{
+	Helptext: cmdkit.HelpText{
+		Tagline: "Republish IPNS records right away.",
+		ShortDescription: `
+The daemon republishes the IPNS records of every key periodically, and shortly
+before they expire. 'ipfs name republish' republishes them right away, with
+their current value, and reschedules their next republish. Republishing a
+single key which no record was published with fails.
+
+The republishing settings can be changed per key in the Namesys.Keys config
+section, see 'ipfs name republish status' for the state of each key.
+`,
+	},
+
+	Options: []cmdkit.Option{
+		cmdkit.StringOption(keyOptionName, "k", "Name of the key to republish, all keys if unset."),
+	},
+	Run: func(req *cmds.Request, res cmds.ResponseEmitter, env cmds.Environment) error {
+		n, err := cmdenv.GetNode(env)
+		if err != nil {
+			return err
+		}
+		if n.IpnsRepub == nil {
+			return errNotRepublishing
+		}
+
+		if kname, ok := req.Options[keyOptionName].(string); ok {
+			if err := n.IpnsRepub.Republish(req.Context, kname); err != nil {
+				return err
+			}
+			return cmds.EmitOnce(res, &RepublishResult{Key: kname})
+		}
+
+		status, err := n.IpnsRepub.Status()
+		if err != nil {
+			return err
+		}
+		for _, st := range status {
+			out := &RepublishResult{Key: st.Name}
+			switch err := n.IpnsRepub.Republish(req.Context, st.Name); err {
+			case nil:
+			case republisher.ErrNoRecord:
+				out.NoRecord = true
+			default:
+				if req.Context.Err() != nil {
+					return req.Context.Err()
+				}
+				out.Error = err.Error()
+			}
+			if err := res.Emit(out); err != nil {
+				return err
+			}
+		}
+		return nil
+	},
+	Encoders: cmds.EncoderMap{
+		cmds.Text: cmds.MakeTypedEncoder(func(req *cmds.Request, w io.Writer, out *RepublishResult) error {
+			var err error
+			switch {
+			case out.Error != "":
+				_, err = fmt.Fprintf(w, "Failed to republish %s: %s\n", out.Key, out.Error)
+			case out.NoRecord:
+				_, err = fmt.Fprintf(w, "Nothing to republish for %s, no record was published with it\n", out.Key)
+			default:
+				_, err = fmt.Fprintf(w, "Republished %s\n", out.Key)
+			}
+			return err
+		}),
+	},
+	Type: RepublishResult{},
+
+	Subcommands: map[string]*cmds.Command{
+		"status": republishStatusCmd,
+	},
+}
+
+var republishStatusCmd = &cmds.Command{
+	Helptext: cmdkit.HelpText{
+		Tagline: "Show the IPNS republishing status of the keys.",
+		ShortDescription: `
+Shows, for every key, when its record was last republished, when it will be
+republished next and the error of the last republish if it failed. Keys
+without a last success weren't republished since the daemon started, or
+never had a record published.
+`,
+	},
+
+	Run: func(req *cmds.Request, res cmds.ResponseEmitter, env cmds.Environment) error {
+		n, err := cmdenv.GetNode(env)
+		if err != nil {
+			return err
+		}
+		if n.IpnsRepub == nil {
+			return errNotRepublishing
+		}
+
+		status, err := n.IpnsRepub.Status()
+		if err != nil {
+			return err
+		}
+
+		out := &RepublishStatusOutput{Keys: make([]RepublishStatus, 0, len(status))}
+		for _, st := range status {
+			out.Keys = append(out.Keys, RepublishStatus{
+				Key:         st.Name,
+				Id:          st.ID.Pretty(),
+				LastSuccess: st.LastSuccess,
+				NextRun:     st.NextRun,
+				LastError:   st.LastError,
+			})
+		}
+		return cmds.EmitOnce(res, out)
+	},
+	Encoders: cmds.EncoderMap{
+		cmds.Text: cmds.MakeTypedEncoder(func(req *cmds.Request, w io.Writer, out *RepublishStatusOutput) error {
+			formatTime := func(t time.Time) string {
+				if t.IsZero() {
+					return "-"
+				}
+				return t.Format(time.RFC3339)
+			}
+
+			tw := tabwriter.NewWriter(w, 1, 2, 1, ' ', 0)
+			fmt.Fprintln(tw, "KEY\tID\tLAST SUCCESS\tNEXT RUN\tLAST ERROR")
+			for _, k := range out.Keys {
+				fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", k.Key, k.Id, formatTime(k.LastSuccess), formatTime(k.NextRun), k.LastError)
+			}
+			return tw.Flush()
+		}),
+	},
+	Type: RepublishStatusOutput{},
+}
@@ -13,8 +13,11 @@ import (
 	core "github.com/ipfs/go-ipfs/core"
 	cmdenv "github.com/ipfs/go-ipfs/core/commands/cmdenv"
 	p2p "github.com/ipfs/go-ipfs/p2p"
+	repo "github.com/ipfs/go-ipfs/repo"
 
 	ma "gx/ipfs/QmNTCey11oxhb1AxDnQBRHtdhap6Ctud872NjAYPYYXPuc/go-multiaddr"
+	peer "gx/ipfs/QmPJxxDsX2UbchSHobbYuvz7qnyJTFKvaKMzE2rZWJ4x5B/go-libp2p-peer"
+	humanize "gx/ipfs/QmPSBJL4momYnE7DcUyk2DVhD6rH488ZmHBGLbxNdhU44K/go-humanize"
 	pstore "gx/ipfs/QmQFFp4ntkd4C14sP3FaH9WJyBuetuGUVo6dShNHvnoEvC/go-libp2p-peerstore"
 	madns "gx/ipfs/QmQc7jbDUsxUJZyFJzxVrnrWeECCct6fErEpMqtjyWvCX8/go-multiaddr-dns"
 	cmds "gx/ipfs/QmR77mMvvh8mJBBWQmBfQBu8oD38NUN4KE9SL2gDgAQNc6/go-ipfs-cmds"
@@ -31,6 +34,10 @@ type P2PListenerInfoOutput struct {
 	Protocol      string
 	ListenAddress string
 	TargetAddress string
+	AllowedPeers  []string `json:",omitempty"`
+	BytesIn       uint64
+	BytesOut      uint64
+	MaxRate       uint64 `json:",omitempty"`
 }
 
 // P2PStreamInfoOutput is output type of streams command
@@ -39,6 +46,10 @@ type P2PStreamInfoOutput struct {
 	Protocol      string
 	OriginAddress string
 	TargetAddress string
+	Peer          string
+	BytesIn       uint64
+	BytesOut      uint64
+	Duration      time.Duration
 }
 
 // P2PLsOutput is output type of ls command
@@ -54,6 +65,10 @@ type P2PStreamsOutput struct {
 const (
 	allowCustomProtocolOptionName = "allow-custom-protocol"
 	reportPeerIDOptionName        = "report-peer-id"
+	allowPeerOptionName           = "allow-peer"
+	allowGroupOptionName          = "allow-group"
+	p2pPersistOptionName          = "persist"
+	maxRateOptionName             = "max-rate"
 )
 
 var resolveTimeout = 10 * time.Second
@@ -87,6 +102,9 @@ Forward connections made to <listen-address> to <target-address>.
 <protocol> specifies the libp2p protocol name to use for libp2p
 connections and/or handlers. It must be prefixed with '` + P2PProtoPrefix + `'.
 
+With --persist, the forward is also added to the P2P.Forwards config section,
+and started again when the daemon restarts.
+
 Example:
   ipfs p2p forward ` + P2PProtoPrefix + `myproto /ip4/127.0.0.1/tcp/4567 /ipfs/QmPeer
     - Forward connections to 127.0.0.1:4567 to '` + P2PProtoPrefix + `myproto' service on /ipfs/QmPeer
@@ -100,6 +118,8 @@ Example:
 	},
 	Options: []cmdkit.Option{
 		cmdkit.BoolOption(allowCustomProtocolOptionName, "Don't require /x/ prefix"),
+		cmdkit.BoolOption(p2pPersistOptionName, "Start the forward again when the daemon restarts"),
+		cmdkit.StringOption(maxRateOptionName, "Cap the bandwidth of the streams in each direction, per second (e.g. 1MB)"),
 	},
 	Run: func(req *cmds.Request, res cmds.ResponseEmitter, env cmds.Environment) error {
 		n, err := p2pGetNode(env)
@@ -129,7 +149,20 @@ Example:
 			return errors.New("protocol name must be within '" + P2PProtoPrefix + "' namespace")
 		}
 
-		return forwardLocal(n.Context(), n.P2P, n.Peerstore, proto, listen, targets)
+		maxRate, err := maxRateOption(req)
+		if err != nil {
+			return err
+		}
+
+		listener, err := forwardLocal(n.Context(), n.P2P, n.Peerstore, proto, listen, targets, maxRate)
+		if err != nil {
+			return err
+		}
+
+		if persist, _ := req.Options[p2pPersistOptionName].(bool); persist {
+			return persistListener(n, listener, p2p.Forward{})
+		}
+		return nil
 	},
 }
 
@@ -172,10 +205,20 @@ Create libp2p service and forward connections made to <target-address>.
 
 <protocol> specifies the libp2p handler name. It must be prefixed with '` + P2PProtoPrefix + `'.
 
+By default any peer may open streams to the service. --allow-peer restricts it
+to the given peer IDs; it can be repeated, and each value may also be a
+comma-separated list. --allow-group restricts it to the peers of a group of
+the P2P.PeerGroups config section. Streams from other peers are reset.
+
 Example:
   ipfs p2p listen ` + P2PProtoPrefix + `myproto /ip4/127.0.0.1/tcp/1234
     - Forward connections to 'myproto' libp2p service to 127.0.0.1:1234
 
+  ipfs p2p listen --allow-peer=QmPeerA --allow-peer=QmPeerB ` + P2PProtoPrefix + `myproto /ip4/127.0.0.1/tcp/1234
+    - Same, only accepting streams from QmPeerA and QmPeerB
+
+With --persist, the service is also added to the P2P.Forwards config section,
+and started again when the daemon restarts.
 `,
 	},
 	Arguments: []cmdkit.Argument{
@@ -185,6 +228,10 @@ Example:
 	Options: []cmdkit.Option{
 		cmdkit.BoolOption(allowCustomProtocolOptionName, "Don't require /x/ prefix"),
 		cmdkit.BoolOption(reportPeerIDOptionName, "r", "Send remote base58 peerid to target when a new connection is established"),
+		cmdkit.StringsOption(allowPeerOptionName, "Only accept streams from this peer ID. Can be repeated, or given a comma-separated list"),
+		cmdkit.StringOption(allowGroupOptionName, "Only accept streams from the peers of this P2P.PeerGroups group"),
+		cmdkit.BoolOption(p2pPersistOptionName, "Start the service again when the daemon restarts"),
+		cmdkit.StringOption(maxRateOptionName, "Cap the bandwidth of the streams in each direction, per second (e.g. 1MB)"),
 	},
 	Run: func(req *cmds.Request, res cmds.ResponseEmitter, env cmds.Environment) error {
 		n, err := p2pGetNode(env)
@@ -214,11 +261,128 @@ Example:
 			return errors.New("protocol name must be within '" + P2PProtoPrefix + "' namespace")
 		}
 
-		_, err = n.P2P.ForwardRemote(n.Context(), proto, target, reportPeerID)
-		return err
+		allowed, err := allowedPeers(n.Repo, req)
+		if err != nil {
+			return err
+		}
+
+		maxRate, err := maxRateOption(req)
+		if err != nil {
+			return err
+		}
+
+		listener, err := n.P2P.ForwardRemote(n.Context(), proto, target, reportPeerID, allowed, maxRate)
+		if err != nil {
+			return err
+		}
+
+		if persist, _ := req.Options[p2pPersistOptionName].(bool); persist {
+			return persistListener(n, listener, p2p.Forward{ReportPeerID: reportPeerID})
+		}
+		return nil
 	},
 }
 
+// persistListener adds the listener to the P2P.Forwards config section,
+// replacing the previous entry of its protocol and addresses. The listener is
+// closed if the config can't be written.
+func persistListener(n *core.IpfsNode, listener p2p.Listener, f p2p.Forward) error {
+	info := listenerInfo(listener)
+	f.Protocol = info.Protocol
+	f.ListenAddress = info.ListenAddress
+	f.TargetAddress = info.TargetAddress
+	f.AllowedPeers = info.AllowedPeers
+	f.MaxRate = info.MaxRate
+
+	err := func() error {
+		forwards, err := p2p.Forwards(n.Repo)
+		if err != nil {
+			return err
+		}
+
+		kept := forwards[:0]
+		for _, old := range forwards {
+			if old.Protocol != f.Protocol || old.ListenAddress != f.ListenAddress || old.TargetAddress != f.TargetAddress {
+				kept = append(kept, old)
+			}
+		}
+		return p2p.SetForwards(n.Repo, append(kept, f))
+	}()
+	if err != nil {
+		match := func(l p2p.Listener) bool { return l == listener }
+		n.P2P.ListenersLocal.Close(match)
+		n.P2P.ListenersP2P.Close(match)
+		return fmt.Errorf("failed to persist the listener: %s", err)
+	}
+	return nil
+}
+
+// maxRateOption returns the bandwidth cap given by the --max-rate option, in
+// bytes per second, or 0 if it isn't set
+func maxRateOption(req *cmds.Request) (uint64, error) {
+	rate, ok := req.Options[maxRateOptionName].(string)
+	if !ok {
+		return 0, nil
+	}
+
+	maxRate, err := humanize.ParseBytes(rate)
+	if err != nil {
+		return 0, fmt.Errorf("invalid --%s: %s", maxRateOptionName, err)
+	}
+	if maxRate == 0 {
+		return 0, fmt.Errorf("--%s must be greater than 0", maxRateOptionName)
+	}
+	return maxRate, nil
+}
+
+// allowedPeers returns the peers given by the --allow-peer and --allow-group
+// options, or nil if neither is set
+func allowedPeers(r repo.Repo, req *cmds.Request) ([]peer.ID, error) {
+	var ids []string
+
+	if peersOpt, ok := req.Options[allowPeerOptionName].([]string); ok {
+		for _, opt := range peersOpt {
+			for _, id := range strings.Split(opt, ",") {
+				if id = strings.TrimSpace(id); id != "" {
+					ids = append(ids, id)
+				}
+			}
+		}
+		if len(ids) == 0 {
+			return nil, fmt.Errorf("--%s requires at least one peer ID", allowPeerOptionName)
+		}
+	}
+
+	if group, ok := req.Options[allowGroupOptionName].(string); ok {
+		groups, err := p2p.PeerGroups(r)
+		if err != nil {
+			return nil, err
+		}
+		members, ok := groups[group]
+		if !ok {
+			return nil, fmt.Errorf("peer group %q not found in %s", group, p2p.PeerGroupsConfigKey)
+		}
+		if len(members) == 0 {
+			return nil, fmt.Errorf("peer group %q is empty", group)
+		}
+		ids = append(ids, members...)
+	}
+
+	if ids == nil {
+		return nil, nil
+	}
+
+	peers := make([]peer.ID, 0, len(ids))
+	for _, id := range ids {
+		p, err := peer.IDB58Decode(id)
+		if err != nil {
+			return nil, fmt.Errorf("invalid peer ID %q: %s", id, err)
+		}
+		peers = append(peers, p)
+	}
+	return peers, nil
+}
+
 // checkPort checks whether target multiaddr contains tcp or udp protocol
 // and whether the port is equal to 0
 func checkPort(target ma.Multiaddr) error {
@@ -254,15 +418,14 @@ func checkPort(target ma.Multiaddr) error {
 }
 
 // forwardLocal forwards local connections to a libp2p service
-func forwardLocal(ctx context.Context, p *p2p.P2P, ps pstore.Peerstore, proto protocol.ID, bindAddr ma.Multiaddr, addrs []ipfsaddr.IPFSAddr) error {
+func forwardLocal(ctx context.Context, p *p2p.P2P, ps pstore.Peerstore, proto protocol.ID, bindAddr ma.Multiaddr, addrs []ipfsaddr.IPFSAddr, maxRate uint64) (p2p.Listener, error) {
 	for _, addr := range addrs {
 		ps.AddAddr(addr.ID(), addr.Multiaddr(), pstore.TempAddrTTL)
 	}
 	// TODO: return some info
 	// the length of the addrs must large than 0
 	// peerIDs in addr must be the same and choose addr[0] to connect
-	_, err := p.ForwardLocal(ctx, addrs[0].ID(), proto, bindAddr)
-	return err
+	return p.ForwardLocal(ctx, addrs[0].ID(), proto, bindAddr, maxRate)
 }
 
 const (
@@ -274,7 +437,7 @@ var p2pLsCmd = &cmds.Command{
 		Tagline: "List active p2p listeners.",
 	},
 	Options: []cmdkit.Option{
-		cmdkit.BoolOption(p2pHeadersOptionName, "v", "Print table headers (Protocol, Listen, Target)."),
+		cmdkit.BoolOption(p2pHeadersOptionName, "v", "Print table headers, the allowed peers and the traffic of the listeners."),
 	},
 	Run: func(req *cmds.Request, res cmds.ResponseEmitter, env cmds.Environment) error {
 		n, err := p2pGetNode(env)
@@ -286,21 +449,13 @@ var p2pLsCmd = &cmds.Command{
 
 		n.P2P.ListenersLocal.Lock()
 		for _, listener := range n.P2P.ListenersLocal.Listeners {
-			output.Listeners = append(output.Listeners, P2PListenerInfoOutput{
-				Protocol:      string(listener.Protocol()),
-				ListenAddress: listener.ListenAddress().String(),
-				TargetAddress: listener.TargetAddress().String(),
-			})
+			output.Listeners = append(output.Listeners, listenerInfo(listener))
 		}
 		n.P2P.ListenersLocal.Unlock()
 
 		n.P2P.ListenersP2P.Lock()
 		for _, listener := range n.P2P.ListenersP2P.Listeners {
-			output.Listeners = append(output.Listeners, P2PListenerInfoOutput{
-				Protocol:      string(listener.Protocol()),
-				ListenAddress: listener.ListenAddress().String(),
-				TargetAddress: listener.TargetAddress().String(),
-			})
+			output.Listeners = append(output.Listeners, listenerInfo(listener))
 		}
 		n.P2P.ListenersP2P.Unlock()
 
@@ -311,9 +466,22 @@ var p2pLsCmd = &cmds.Command{
 		cmds.Text: cmds.MakeTypedEncoder(func(req *cmds.Request, w io.Writer, out *P2PLsOutput) error {
 			headers, _ := req.Options[p2pHeadersOptionName].(bool)
 			tw := tabwriter.NewWriter(w, 1, 2, 1, ' ', 0)
+			if headers && len(out.Listeners) > 0 {
+				fmt.Fprintln(tw, "Protocol\tListen Address\tTarget Address\tAllowed Peers\tIn\tOut\tMax Rate")
+			}
 			for _, listener := range out.Listeners {
 				if headers {
-					fmt.Fprintln(tw, "Protocol\tListen Address\tTarget Address")
+					allowed := "all"
+					if len(listener.AllowedPeers) > 0 {
+						allowed = strings.Join(listener.AllowedPeers, ",")
+					}
+					maxRate := "none"
+					if listener.MaxRate > 0 {
+						maxRate = humanize.Bytes(listener.MaxRate) + "/s"
+					}
+					fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n", listener.Protocol, listener.ListenAddress, listener.TargetAddress, allowed,
+						humanize.Bytes(listener.BytesIn), humanize.Bytes(listener.BytesOut), maxRate)
+					continue
 				}
 
 				fmt.Fprintf(tw, "%s\t%s\t%s\n", listener.Protocol, listener.ListenAddress, listener.TargetAddress)
@@ -325,6 +493,21 @@ var p2pLsCmd = &cmds.Command{
 	},
 }
 
+func listenerInfo(listener p2p.Listener) P2PListenerInfoOutput {
+	info := P2PListenerInfoOutput{
+		Protocol:      string(listener.Protocol()),
+		ListenAddress: listener.ListenAddress().String(),
+		TargetAddress: listener.TargetAddress().String(),
+	}
+	for _, p := range listener.AllowedPeers() {
+		info.AllowedPeers = append(info.AllowedPeers, p.Pretty())
+	}
+	info.BytesIn = listener.Traffic().BytesIn()
+	info.BytesOut = listener.Traffic().BytesOut()
+	info.MaxRate = listener.MaxRate()
+	return info
+}
+
 const (
 	p2pAllOptionName           = "all"
 	p2pProtocolOptionName      = "protocol"
@@ -335,12 +518,18 @@ const (
 var p2pCloseCmd = &cmds.Command{
 	Helptext: cmdkit.HelpText{
 		Tagline: "Stop listening for new connections to forward.",
+		ShortDescription: `
+Closes the listeners matching the options. With --persist, the matching
+entries of the P2P.Forwards config section are removed too, so that they
+aren't started again when the daemon restarts.
+`,
 	},
 	Options: []cmdkit.Option{
 		cmdkit.BoolOption(p2pAllOptionName, "a", "Close all listeners."),
 		cmdkit.StringOption(p2pProtocolOptionName, "p", "Match protocol name"),
 		cmdkit.StringOption(p2pListenAddressOptionName, "l", "Match listen address"),
 		cmdkit.StringOption(p2pTargetAddressOptionName, "t", "Match target address"),
+		cmdkit.BoolOption(p2pPersistOptionName, "Also remove the matching persisted listeners"),
 	},
 	Run: func(req *cmds.Request, res cmds.ResponseEmitter, env cmds.Environment) error {
 		n, err := p2pGetNode(env)
@@ -373,21 +562,44 @@ var p2pCloseCmd = &cmds.Command{
 			return errors.New("can't combine --all with other matching options")
 		}
 
-		match := func(listener p2p.Listener) bool {
+		matchAddrs := func(lproto protocol.ID, laddr, taddr ma.Multiaddr) bool {
 			if closeAll {
 				return true
 			}
-			if p && proto != listener.Protocol() {
+			if p && proto != lproto {
 				return false
 			}
-			if l && !listen.Equal(listener.ListenAddress()) {
+			if l && !listen.Equal(laddr) {
 				return false
 			}
-			if t && !target.Equal(listener.TargetAddress()) {
+			if t && !target.Equal(taddr) {
 				return false
 			}
 			return true
 		}
+		match := func(listener p2p.Listener) bool {
+			return matchAddrs(listener.Protocol(), listener.ListenAddress(), listener.TargetAddress())
+		}
+
+		if persist, _ := req.Options[p2pPersistOptionName].(bool); persist {
+			forwards, err := p2p.Forwards(n.Repo)
+			if err != nil {
+				return err
+			}
+
+			kept := forwards[:0]
+			for _, f := range forwards {
+				laddr, lerr := ma.NewMultiaddr(f.ListenAddress)
+				taddr, terr := ma.NewMultiaddr(f.TargetAddress)
+				if lerr == nil && terr == nil && matchAddrs(protocol.ID(f.Protocol), laddr, taddr) {
+					continue
+				}
+				kept = append(kept, f)
+			}
+			if err := p2p.SetForwards(n.Repo, kept); err != nil {
+				return err
+			}
+		}
 
 		done := n.P2P.ListenersLocal.Close(match)
 		done += n.P2P.ListenersP2P.Close(match)
@@ -425,7 +637,7 @@ var p2pStreamLsCmd = &cmds.Command{
 		Tagline: "List active p2p streams.",
 	},
 	Options: []cmdkit.Option{
-		cmdkit.BoolOption(p2pHeadersOptionName, "v", "Print table headers (ID, Protocol, Local, Remote)."),
+		cmdkit.BoolOption(p2pHeadersOptionName, "v", "Print table headers, the peer and the traffic of the streams."),
 	},
 	Run: func(req *cmds.Request, res cmds.ResponseEmitter, env cmds.Environment) error {
 		n, err := p2pGetNode(env)
@@ -444,6 +656,11 @@ var p2pStreamLsCmd = &cmds.Command{
 
 				OriginAddress: s.OriginAddr.String(),
 				TargetAddress: s.TargetAddr.String(),
+
+				Peer:     s.Peer().Pretty(),
+				BytesIn:  s.BytesIn(),
+				BytesOut: s.BytesOut(),
+				Duration: time.Since(s.Started()),
 			})
 		}
 		n.P2P.Streams.Unlock()
@@ -455,9 +672,14 @@ var p2pStreamLsCmd = &cmds.Command{
 		cmds.Text: cmds.MakeTypedEncoder(func(req *cmds.Request, w io.Writer, out *P2PStreamsOutput) error {
 			headers, _ := req.Options[p2pHeadersOptionName].(bool)
 			tw := tabwriter.NewWriter(w, 1, 2, 1, ' ', 0)
+			if headers && len(out.Streams) > 0 {
+				fmt.Fprintln(tw, "ID\tProtocol\tOrigin\tTarget\tPeer\tIn\tOut\tDuration")
+			}
 			for _, stream := range out.Streams {
 				if headers {
-					fmt.Fprintln(tw, "ID\tProtocol\tOrigin\tTarget")
+					fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n", stream.HandlerID, stream.Protocol, stream.OriginAddress, stream.TargetAddress,
+						stream.Peer, humanize.Bytes(stream.BytesIn), humanize.Bytes(stream.BytesOut), stream.Duration.Round(time.Second))
+					continue
 				}
 
 				fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", stream.HandlerID, stream.Protocol, stream.OriginAddress, stream.TargetAddress)
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"strings"
 	"time"
 
 	core "github.com/ipfs/go-ipfs/core"
@@ -35,6 +36,7 @@ var PinCmd = &cmds.Command{
 		"ls":     listPinCmd,
 		"verify": verifyPinCmd,
 		"update": updatePinCmd,
+		"remote": remotePinCmd,
 	},
 }
 
@@ -50,12 +52,24 @@ type AddPinOutput struct {
 const (
 	pinRecursiveOptionName = "recursive"
 	pinProgressOptionName  = "progress"
+	pinNameOptionName      = "name"
+	pinMetaOptionName      = "meta"
 )
 
 var addPinCmd = &cmds.Command{
 	Helptext: cmdkit.HelpText{
 		Tagline:          "Pin objects to local storage.",
 		ShortDescription: "Stores an IPFS object(s) from a given path locally to disk.",
+		LongDescription: `
+Stores an IPFS object(s) from a given path locally to disk.
+
+Pins can be given a name with --name and metadata with --meta, a comma
+separated list of key=value pairs. Pinning an already pinned object with
+a name or metadata replaces them. Use 'ipfs pin ls --name' and
+'ipfs pin ls --meta' to find pins by their name or metadata.
+
+  > ipfs pin add --name release-1.2 --meta channel=stable,team=web <path>
+`,
 	},
 
 	Arguments: []cmdkit.Argument{
@@ -64,6 +78,8 @@ var addPinCmd = &cmds.Command{
 	Options: []cmdkit.Option{
 		cmdkit.BoolOption(pinRecursiveOptionName, "r", "Recursively pin the object linked to by the specified object(s).").WithDefault(true),
 		cmdkit.BoolOption(pinProgressOptionName, "Show progress"),
+		cmdkit.StringOption(pinNameOptionName, "n", "A name for the pin."),
+		cmdkit.StringOption(pinMetaOptionName, "Metadata for the pin, as comma separated key=value pairs."),
 	},
 	Type: AddPinOutput{},
 	Run: func(req *cmds.Request, res cmds.ResponseEmitter, env cmds.Environment) error {
@@ -76,6 +92,20 @@ var addPinCmd = &cmds.Command{
 		recursive, _ := req.Options[pinRecursiveOptionName].(bool)
 		showProgress, _ := req.Options[pinProgressOptionName].(bool)
 
+		opts := []options.PinAddOption{options.Pin.Recursive(recursive)}
+		if name, ok := req.Options[pinNameOptionName].(string); ok {
+			opts = append(opts, options.Pin.Name(name))
+		}
+		if m, ok := req.Options[pinMetaOptionName].(string); ok {
+			meta, err := parsePinMeta(m)
+			if err != nil {
+				return err
+			}
+			for k, v := range meta {
+				opts = append(opts, options.Pin.Meta(k, v))
+			}
+		}
+
 		if err := req.ParseBodyArgs(); err != nil {
 			return err
 		}
@@ -86,7 +116,7 @@ var addPinCmd = &cmds.Command{
 		}
 
 		if !showProgress {
-			added, err := pinAddMany(req.Context, api, enc, req.Arguments, recursive)
+			added, err := pinAddMany(req.Context, api, enc, req.Arguments, opts)
 			if err != nil {
 				return err
 			}
@@ -104,7 +134,7 @@ var addPinCmd = &cmds.Command{
 
 		ch := make(chan pinResult, 1)
 		go func() {
-			added, err := pinAddMany(ctx, api, enc, req.Arguments, recursive)
+			added, err := pinAddMany(ctx, api, enc, req.Arguments, opts)
 			ch <- pinResult{pins: added, err: err}
 		}()
 
@@ -180,7 +210,7 @@ var addPinCmd = &cmds.Command{
 	},
 }
 
-func pinAddMany(ctx context.Context, api coreiface.CoreAPI, enc cidenc.Encoder, paths []string, recursive bool) ([]string, error) {
+func pinAddMany(ctx context.Context, api coreiface.CoreAPI, enc cidenc.Encoder, paths []string, opts []options.PinAddOption) ([]string, error) {
 	added := make([]string, len(paths))
 	for i, b := range paths {
 		p, err := coreiface.ParsePath(b)
@@ -193,7 +223,7 @@ func pinAddMany(ctx context.Context, api coreiface.CoreAPI, enc cidenc.Encoder,
 			return nil, err
 		}
 
-		if err := api.Pin().Add(ctx, rp, options.Pin.Recursive(recursive)); err != nil {
+		if err := api.Pin().Add(ctx, rp, opts...); err != nil {
 			return nil, err
 		}
 		added[i] = enc.Encode(rp.Cid())
@@ -202,6 +232,22 @@ func pinAddMany(ctx context.Context, api coreiface.CoreAPI, enc cidenc.Encoder,
 	return added, nil
 }
 
+// parsePinMeta parses comma separated key=value pairs
+func parsePinMeta(s string) (map[string]string, error) {
+	meta := make(map[string]string)
+	for _, kv := range strings.Split(s, ",") {
+		if kv == "" {
+			continue
+		}
+		parts := strings.SplitN(kv, "=", 2)
+		if len(parts) != 2 || parts[0] == "" {
+			return nil, fmt.Errorf("invalid pin metadata '%s', expected key=value", kv)
+		}
+		meta[parts[0]] = parts[1]
+	}
+	return meta, nil
+}
+
 var rmPinCmd = &cmds.Command{
 	Helptext: cmdkit.HelpText{
 		Tagline: "Remove pinned objects from local storage.",
@@ -269,8 +315,10 @@ collected if needed. (By default, recursively. Use -r=false for direct pins.)
 }
 
 const (
-	pinTypeOptionName  = "type"
-	pinQuietOptionName = "quiet"
+	pinTypeOptionName   = "type"
+	pinQuietOptionName  = "quiet"
+	pinLsNameOptionName = "name"
+	pinLsMetaOptionName = "meta"
 )
 
 var listPinCmd = &cmds.Command{
@@ -300,6 +348,10 @@ With arguments, the command fails if any of the arguments is not a pinned
 object. And if --type=<type> is additionally used, the command will also fail
 if any of the arguments is not of the specified type.
 
+Use --name=<prefix> to only list the direct and recursive pins whose name
+starts with the prefix, and --meta=<key=value,...> to only list the ones with
+all the given metadata. The name of the pins is written after their type.
+
 Example:
 	$ echo "hello" | ipfs add -q
 	QmZULkCELmmk5XNfCgTnCyFgAVxBRBXyDHGGMVoLFLiXEN
@@ -323,6 +375,8 @@ Example:
 	Options: []cmdkit.Option{
 		cmdkit.StringOption(pinTypeOptionName, "t", "The type of pinned keys to list. Can be \"direct\", \"indirect\", \"recursive\", or \"all\".").WithDefault("all"),
 		cmdkit.BoolOption(pinQuietOptionName, "q", "Write just hashes of objects."),
+		cmdkit.StringOption(pinLsNameOptionName, "n", "Only list pins whose name starts with this prefix."),
+		cmdkit.StringOption(pinLsMetaOptionName, "Only list pins with this metadata, as comma separated key=value pairs."),
 	},
 	Run: func(req *cmds.Request, res cmds.ResponseEmitter, env cmds.Environment) error {
 		n, err := cmdenv.GetNode(env)
@@ -362,6 +416,23 @@ Example:
 			return err
 		}
 
+		namePrefix, _ := req.Options[pinLsNameOptionName].(string)
+		var meta map[string]string
+		if m, ok := req.Options[pinLsMetaOptionName].(string); ok {
+			meta, err = parsePinMeta(m)
+			if err != nil {
+				return err
+			}
+		}
+		if namePrefix != "" || len(meta) > 0 {
+			for k, v := range keys {
+				info := pin.Info{Name: v.Name, Meta: v.Meta}
+				if (v.Type != "direct" && v.Type != "recursive") || !info.Matches(namePrefix, meta) {
+					delete(keys, k)
+				}
+			}
+		}
+
 		refKeys := make(map[string]RefKeyObject, len(keys))
 		for k, v := range keys {
 			refKeys[enc.Encode(k)] = v
@@ -377,6 +448,8 @@ Example:
 			for k, v := range out.Keys {
 				if quiet {
 					fmt.Fprintf(w, "%s\n", k)
+				} else if v.Name != "" {
+					fmt.Fprintf(w, "%s %s %s\n", k, v.Type, v.Name)
 				} else {
 					fmt.Fprintf(w, "%s %s\n", k, v.Type)
 				}
@@ -498,6 +571,8 @@ var verifyPinCmd = &cmds.Command{
 
 type RefKeyObject struct {
 	Type string
+	Name string            `json:",omitempty"`
+	Meta map[string]string `json:",omitempty"`
 }
 
 type RefKeyList struct {
@@ -538,8 +613,11 @@ func pinLsKeys(ctx context.Context, args []string, typeStr string, n *core.IpfsN
 		default:
 			pinType = "indirect through " + pinType
 		}
+		info, _ := n.Pinning.Info(c.Cid())
 		keys[c.Cid()] = RefKeyObject{
 			Type: pinType,
+			Name: info.Name,
+			Meta: info.Meta,
 		}
 	}
 
@@ -552,8 +630,11 @@ func pinLsAll(ctx context.Context, typeStr string, n *core.IpfsNode) (map[cid.Ci
 
 	AddToResultKeys := func(keyList []cid.Cid, typeStr string) {
 		for _, c := range keyList {
+			info, _ := n.Pinning.Info(c)
 			keys[c] = RefKeyObject{
 				Type: typeStr,
+				Name: info.Name,
+				Meta: info.Meta,
 			}
 		}
 	}
@@ -0,0 +1,155 @@
+package commands
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+
+	oldcmds "github.com/ipfs/go-ipfs/commands"
+	plugin "github.com/ipfs/go-ipfs/plugin"
+
+	cmds "gx/ipfs/QmR77mMvvh8mJBBWQmBfQBu8oD38NUN4KE9SL2gDgAQNc6/go-ipfs-cmds"
+	cmdkit "gx/ipfs/Qmde5VP1qUkyQXKCfmEUA7bP64V2HAptbJ7phuPp7jXWwg/go-ipfs-cmdkit"
+)
+
+// PluginInfoOutput is the output of 'ipfs plugin ls' for a plugin
+type PluginInfoOutput struct {
+	Name    string
+	Version string
+	Types   []string
+	State   string
+	Source  string
+}
+
+// PluginLsOutput is the output of 'ipfs plugin ls'
+type PluginLsOutput struct {
+	Plugins []PluginInfoOutput
+}
+
+var PluginCmd = &cmds.Command{
+	Helptext: cmdkit.HelpText{
+		Tagline: "Inspect the plugins.",
+		ShortDescription: `
+The plugins are configured in the Plugins section of the config, by plugin
+name. A plugin is disabled by setting Disabled to true, and its own config
+goes in Config:
+
+    ipfs config --json Plugins.<name>.Disabled true
+`,
+	},
+	Subcommands: map[string]*cmds.Command{
+		"ls": pluginLsCmd,
+	},
+}
+
+var pluginLsCmd = &cmds.Command{
+	Helptext: cmdkit.HelpText{
+		Tagline: "List the plugins.",
+		ShortDescription: `
+Lists the plugins in the order they're initialized and started in, with their
+version, their types, their state and whether they're preloaded in the ipfs
+binary or loaded dynamically from the plugins directory of the repo.
+`,
+	},
+	Options: []cmdkit.Option{
+		cmdkit.BoolOption(verboseOptionName, "v", "Print table headers."),
+	},
+	Run: func(req *cmds.Request, res cmds.ResponseEmitter, env cmds.Environment) error {
+		ctx := env.(*oldcmds.Context)
+		if ctx.Plugins == nil {
+			return errors.New("plugins aren't loaded")
+		}
+
+		output := &PluginLsOutput{Plugins: []PluginInfoOutput{}}
+		for _, info := range ctx.Plugins.Plugins() {
+			output.Plugins = append(output.Plugins, PluginInfoOutput{
+				Name:    info.Name,
+				Version: info.Version,
+				Types:   info.Types,
+				State:   info.State.String(),
+				Source:  info.Source,
+			})
+		}
+		return cmds.EmitOnce(res, output)
+	},
+	Type: PluginLsOutput{},
+	Encoders: cmds.EncoderMap{
+		cmds.Text: cmds.MakeTypedEncoder(func(req *cmds.Request, w io.Writer, out *PluginLsOutput) error {
+			verbose, _ := req.Options[verboseOptionName].(bool)
+			tw := tabwriter.NewWriter(w, 1, 2, 1, ' ', 0)
+			if verbose {
+				fmt.Fprintln(tw, "Name\tVersion\tType\tState\tSource")
+			}
+			for _, pl := range out.Plugins {
+				fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", pl.Name, pl.Version, strings.Join(pl.Types, ","), pl.State, pl.Source)
+			}
+			return tw.Flush()
+		}),
+	},
+}
+
+// addPluginCommands adds the commands of the PluginCommands to the root
+// subcommands, and their read-only commands to the read-only ones. It adds
+// either all of the commands of a plugin or none, and fails if one of them
+// already exists.
+func addPluginCommands(root, rootRO map[string]*cmds.Command, plugins []plugin.Plugin) error {
+	for _, pl := range plugins {
+		pl, ok := pl.(plugin.PluginCommands)
+		if !ok {
+			continue
+		}
+
+		commands := make(map[string]*cmds.Command)
+		for name, cmd := range pl.Commands() {
+			if _, ok := root[name]; ok {
+				return fmt.Errorf("plugin %s: command %q already exists", pl.Name(), name)
+			}
+			commands[name] = pluginCommand(pl.Name(), cmd)
+		}
+
+		readOnly := make(map[string]*cmds.Command)
+		for _, name := range pl.ReadOnlyCommands() {
+			cmd, ok := commands[name]
+			if !ok {
+				return fmt.Errorf("plugin %s: read-only command %q is not one of its commands", pl.Name(), name)
+			}
+			if _, ok := rootRO[name]; ok {
+				return fmt.Errorf("plugin %s: command %q already exists", pl.Name(), name)
+			}
+			readOnly[name] = cmd
+		}
+
+		for name, cmd := range commands {
+			root[name] = cmd
+		}
+		for name, cmd := range readOnly {
+			rootRO[name] = cmd
+		}
+	}
+	return nil
+}
+
+// pluginCommand copies the command of the given plugin and its subcommands,
+// so that they fail when the plugin doesn't run, as when it's disabled in the
+// config.
+func pluginCommand(name string, cmd *cmds.Command) *cmds.Command {
+	c := *cmd
+	if run := cmd.Run; run != nil {
+		c.Run = func(req *cmds.Request, res cmds.ResponseEmitter, env cmds.Environment) error {
+			ctx := env.(*oldcmds.Context)
+			if ctx.Plugins == nil || !ctx.Plugins.Running(name) {
+				return fmt.Errorf("plugin %s isn't running", name)
+			}
+			return run(req, res, env)
+		}
+	}
+	if cmd.Subcommands != nil {
+		c.Subcommands = make(map[string]*cmds.Command, len(cmd.Subcommands))
+		for sub, subcmd := range cmd.Subcommands {
+			c.Subcommands[sub] = pluginCommand(name, subcmd)
+		}
+	}
+	return &c
+}
@@ -0,0 +1,89 @@
+package commands
+
+import (
+	"testing"
+
+	oldcmds "github.com/ipfs/go-ipfs/commands"
+	plugin "github.com/ipfs/go-ipfs/plugin"
+
+	cmds "gx/ipfs/QmR77mMvvh8mJBBWQmBfQBu8oD38NUN4KE9SL2gDgAQNc6/go-ipfs-cmds"
+)
+
+type testCommandsPlugin struct {
+	name     string
+	commands map[string]*cmds.Command
+	readOnly []string
+}
+
+func (p *testCommandsPlugin) Name() string                       { return p.name }
+func (p *testCommandsPlugin) Version() string                    { return "0.0.1" }
+func (p *testCommandsPlugin) Init(*plugin.Environment) error     { return nil }
+func (p *testCommandsPlugin) Commands() map[string]*cmds.Command { return p.commands }
+func (p *testCommandsPlugin) ReadOnlyCommands() []string         { return p.readOnly }
+
+func TestAddPluginCommands(t *testing.T) {
+	root := map[string]*cmds.Command{"add": {}}
+	rootRO := map[string]*cmds.Command{}
+
+	var ran bool
+	hello := &cmds.Command{
+		Run: func(*cmds.Request, cmds.ResponseEmitter, cmds.Environment) error {
+			ran = true
+			return nil
+		},
+	}
+	err := addPluginCommands(root, rootRO, []plugin.Plugin{&testCommandsPlugin{
+		name:     "hello",
+		commands: map[string]*cmds.Command{"hello": hello, "world": {}},
+		readOnly: []string{"hello"},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if root["hello"] == nil || root["world"] == nil {
+		t.Error("expected the commands to be added to the root")
+	}
+	if rootRO["hello"] != root["hello"] || rootRO["world"] != nil {
+		t.Error("expected only the read-only command to be added to the read-only root")
+	}
+
+	// the plugins aren't loaded, so the plugin doesn't run
+	if err := root["hello"].Run(nil, nil, &oldcmds.Context{}); err == nil {
+		t.Error("expected the command of a plugin that doesn't run to fail")
+	}
+	if ran {
+		t.Error("expected the command not to run")
+	}
+}
+
+func TestAddPluginCommandsCollision(t *testing.T) {
+	add := &cmds.Command{}
+	root := map[string]*cmds.Command{"add": add}
+	rootRO := map[string]*cmds.Command{}
+
+	err := addPluginCommands(root, rootRO, []plugin.Plugin{&testCommandsPlugin{
+		name:     "clash",
+		commands: map[string]*cmds.Command{"add": {}, "other": {}},
+	}})
+	if err == nil {
+		t.Fatal("expected the collision to fail")
+	}
+	if root["add"] != add {
+		t.Error("the built-in command was replaced")
+	}
+	if _, ok := root["other"]; ok {
+		t.Error("expected none of the commands of the plugin to be added")
+	}
+
+	err = addPluginCommands(root, rootRO, []plugin.Plugin{&testCommandsPlugin{
+		name:     "missing",
+		commands: map[string]*cmds.Command{"other": {}},
+		readOnly: []string{"nope"},
+	}})
+	if err == nil {
+		t.Fatal("expected a missing read-only command to fail")
+	}
+	if _, ok := root["other"]; ok {
+		t.Error("expected none of the commands of the plugin to be added")
+	}
+}
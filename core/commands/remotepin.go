@@ -0,0 +1,507 @@
+package commands
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	cmdenv "github.com/ipfs/go-ipfs/core/commands/cmdenv"
+	coreiface "github.com/ipfs/go-ipfs/core/coreapi/interface"
+	remote "github.com/ipfs/go-ipfs/pin/remote"
+	fsrepo "github.com/ipfs/go-ipfs/repo/fsrepo"
+
+	cmds "gx/ipfs/QmR77mMvvh8mJBBWQmBfQBu8oD38NUN4KE9SL2gDgAQNc6/go-ipfs-cmds"
+	cid "gx/ipfs/QmR8BauakNcBa3RbE4nbQu76PDiJgoQgz8AJdhJuiU4TAw/go-cid"
+	cmdkit "gx/ipfs/Qmde5VP1qUkyQXKCfmEUA7bP64V2HAptbJ7phuPp7jXWwg/go-ipfs-cmdkit"
+)
+
+// remotePinWaitInterval is how often a pin request is polled while waiting
+// for it to complete
+const remotePinWaitInterval = time.Second
+
+const (
+	remotePinServiceOptionName    = "service"
+	remotePinNameOptionName       = "name"
+	remotePinBackgroundOptionName = "background"
+	remotePinStatusOptionName     = "status"
+	remotePinRefreshOptionName    = "refresh"
+)
+
+// RemotePinOutput is a pin request made to a remote pinning service
+type RemotePinOutput struct {
+	Service   string
+	RequestID string
+	Cid       string
+	Name      string `json:",omitempty"`
+	Status    string
+	Error     string `json:",omitempty"`
+}
+
+var remotePinCmd = &cmds.Command{
+	Helptext: cmdkit.HelpText{
+		Tagline: "Pin (and unpin) objects to remote pinning services.",
+		ShortDescription: `
+Asks remote pinning services speaking the IPFS Pinning Service API to pin
+objects. The services are configured with 'ipfs pin remote service'.
+
+The pin requests are tracked locally, and the daemon polls the pending ones
+in the background until they are pinned or failed.
+`,
+	},
+
+	Subcommands: map[string]*cmds.Command{
+		"add":     addRemotePinCmd,
+		"ls":      listRemotePinCmd,
+		"rm":      rmRemotePinCmd,
+		"service": remotePinServiceCmd,
+	},
+}
+
+func remotePinOutput(r *remote.Request) *RemotePinOutput {
+	return &RemotePinOutput{
+		Service:   r.Service,
+		RequestID: r.RequestID,
+		Cid:       r.Cid.String(),
+		Name:      r.Name,
+		Status:    string(r.Status),
+		Error:     r.Error,
+	}
+}
+
+var remotePinEncoders = cmds.EncoderMap{
+	cmds.Text: cmds.MakeTypedEncoder(func(req *cmds.Request, w io.Writer, out *RemotePinOutput) error {
+		line := fmt.Sprintf("%s %s %s/%s", out.Cid, out.Status, out.Service, out.RequestID)
+		if out.Name != "" {
+			line += " " + out.Name
+		}
+		_, err := fmt.Fprintln(w, line)
+		return err
+	}),
+}
+
+func remotePinService(req *cmds.Request) (string, error) {
+	service, _ := req.Options[remotePinServiceOptionName].(string)
+	if service == "" {
+		return "", fmt.Errorf("a remote pinning service must be given with --%s", remotePinServiceOptionName)
+	}
+	return service, nil
+}
+
+var addRemotePinCmd = &cmds.Command{
+	Helptext: cmdkit.HelpText{
+		Tagline: "Pin an object to a remote pinning service.",
+		ShortDescription: `
+Asks the remote pinning service to pin the object, and waits until it is
+pinned. With --background, returns as soon as the service accepted the
+request; the daemon then polls its status.
+`,
+	},
+
+	Arguments: []cmdkit.Argument{
+		cmdkit.StringArg("ipfs-path", true, false, "Path to the object to be pinned."),
+	},
+	Options: []cmdkit.Option{
+		cmdkit.StringOption(remotePinServiceOptionName, "Name of the remote pinning service to use."),
+		cmdkit.StringOption(remotePinNameOptionName, "An optional name for the pin."),
+		cmdkit.BoolOption(remotePinBackgroundOptionName, "Don't wait for the object to be pinned."),
+	},
+	Type: RemotePinOutput{},
+	Run: func(req *cmds.Request, res cmds.ResponseEmitter, env cmds.Environment) error {
+		n, err := cmdenv.GetNode(env)
+		if err != nil {
+			return err
+		}
+
+		api, err := cmdenv.GetApi(env, req)
+		if err != nil {
+			return err
+		}
+
+		service, err := remotePinService(req)
+		if err != nil {
+			return err
+		}
+		name, _ := req.Options[remotePinNameOptionName].(string)
+		background, _ := req.Options[remotePinBackgroundOptionName].(bool)
+
+		p, err := coreiface.ParsePath(req.Arguments[0])
+		if err != nil {
+			return err
+		}
+		rp, err := api.ResolvePath(req.Context, p)
+		if err != nil {
+			return err
+		}
+
+		// let the service fetch the data from us
+		var origins []string
+		if n.PeerHost != nil {
+			for _, a := range n.PeerHost.Addrs() {
+				origins = append(origins, a.String()+"/ipfs/"+n.Identity.Pretty())
+			}
+		}
+
+		r, err := n.RemotePins.Add(req.Context, service, remote.Pin{
+			Cid:     rp.Cid(),
+			Name:    name,
+			Origins: origins,
+		})
+		if err != nil {
+			return err
+		}
+
+		if !background && !r.Status.Final() {
+			r, err = n.RemotePins.Wait(req.Context, service, r.RequestID, remotePinWaitInterval)
+			if err != nil {
+				return err
+			}
+		}
+		if r.Status == remote.Failed {
+			return fmt.Errorf("remote pinning service '%s' failed to pin %s", service, r.Cid)
+		}
+
+		return cmds.EmitOnce(res, remotePinOutput(r))
+	},
+	Encoders: remotePinEncoders,
+}
+
+var listRemotePinCmd = &cmds.Command{
+	Helptext: cmdkit.HelpText{
+		Tagline: "List the pin requests made to remote pinning services.",
+		ShortDescription: `
+Lists the tracked pin requests, oldest first. Use --refresh to fetch the
+status of the pending requests from the services first.
+`,
+	},
+
+	Options: []cmdkit.Option{
+		cmdkit.StringOption(remotePinServiceOptionName, "Only list the requests made to this service."),
+		cmdkit.StringOption(remotePinNameOptionName, "Only list the requests whose name starts with this prefix."),
+		cmdkit.StringOption(remotePinStatusOptionName, "Only list the requests with these statuses, comma separated: queued, pinning, pinned or failed."),
+		cmdkit.BoolOption(remotePinRefreshOptionName, "Poll the pending requests before listing."),
+	},
+	Type: RemotePinOutput{},
+	Run: func(req *cmds.Request, res cmds.ResponseEmitter, env cmds.Environment) error {
+		n, err := cmdenv.GetNode(env)
+		if err != nil {
+			return err
+		}
+
+		service, _ := req.Options[remotePinServiceOptionName].(string)
+		namePrefix, _ := req.Options[remotePinNameOptionName].(string)
+
+		statuses := make(map[remote.Status]bool)
+		if s, ok := req.Options[remotePinStatusOptionName].(string); ok {
+			for _, name := range strings.Split(s, ",") {
+				st, err := remote.ParseStatus(strings.TrimSpace(name))
+				if err != nil {
+					return err
+				}
+				statuses[st] = true
+			}
+		}
+
+		if refresh, _ := req.Options[remotePinRefreshOptionName].(bool); refresh {
+			if err := n.RemotePins.Poll(req.Context); err != nil {
+				log.Warning(err)
+			}
+		}
+
+		reqs, err := n.RemotePins.Requests(service)
+		if err != nil {
+			return err
+		}
+
+		for _, r := range reqs {
+			if len(statuses) > 0 && !statuses[r.Status] {
+				continue
+			}
+			if !strings.HasPrefix(r.Name, namePrefix) {
+				continue
+			}
+			if err := res.Emit(remotePinOutput(r)); err != nil {
+				return err
+			}
+		}
+		return nil
+	},
+	Encoders: remotePinEncoders,
+}
+
+var rmRemotePinCmd = &cmds.Command{
+	Helptext: cmdkit.HelpText{
+		Tagline: "Remove pin requests from a remote pinning service.",
+		ShortDescription: `
+Asks the remote pinning service to remove the pin requests and stops
+tracking them. The arguments are request IDs, or CIDs to remove all the
+requests made for them.
+`,
+	},
+
+	Arguments: []cmdkit.Argument{
+		cmdkit.StringArg("request-id", true, true, "Request ID or CID of the pin requests to remove."),
+	},
+	Options: []cmdkit.Option{
+		cmdkit.StringOption(remotePinServiceOptionName, "Name of the remote pinning service to use."),
+	},
+	Type: RemotePinOutput{},
+	Run: func(req *cmds.Request, res cmds.ResponseEmitter, env cmds.Environment) error {
+		n, err := cmdenv.GetNode(env)
+		if err != nil {
+			return err
+		}
+
+		service, err := remotePinService(req)
+		if err != nil {
+			return err
+		}
+
+		for _, arg := range req.Arguments {
+			var toRemove []*remote.Request
+
+			r, err := n.RemotePins.Get(service, arg)
+			switch err {
+			case nil:
+				toRemove = append(toRemove, r)
+			case remote.ErrNotFound:
+				c, cerr := cid.Decode(arg)
+				if cerr != nil {
+					return fmt.Errorf("no pin request '%s' on service '%s'", arg, service)
+				}
+
+				reqs, err := n.RemotePins.Requests(service)
+				if err != nil {
+					return err
+				}
+				for _, r := range reqs {
+					if r.Cid.Equals(c) {
+						toRemove = append(toRemove, r)
+					}
+				}
+				if len(toRemove) == 0 {
+					return fmt.Errorf("no pin request for %s on service '%s'", arg, service)
+				}
+			default:
+				return err
+			}
+
+			for _, r := range toRemove {
+				if err := n.RemotePins.Rm(req.Context, service, r.RequestID); err != nil {
+					return err
+				}
+				if err := res.Emit(remotePinOutput(r)); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	},
+	Encoders: cmds.EncoderMap{
+		cmds.Text: cmds.MakeTypedEncoder(func(req *cmds.Request, w io.Writer, out *RemotePinOutput) error {
+			_, err := fmt.Fprintf(w, "removed %s/%s\n", out.Service, out.RequestID)
+			return err
+		}),
+	},
+}
+
+// RemotePinServiceOutput is a configured remote pinning service. The access
+// key is never shown.
+type RemotePinServiceOutput struct {
+	Service  string
+	Endpoint string
+}
+
+// RemotePinServicesOutput lists the configured remote pinning services
+type RemotePinServicesOutput struct {
+	Services []RemotePinServiceOutput
+}
+
+var remotePinServiceCmd = &cmds.Command{
+	Helptext: cmdkit.HelpText{
+		Tagline: "Configure remote pinning services.",
+		ShortDescription: `
+Remote pinning services are stored in the Pinning.RemoteServices section of
+the config, by name.
+`,
+	},
+
+	Subcommands: map[string]*cmds.Command{
+		"add": addRemotePinServiceCmd,
+		"ls":  listRemotePinServiceCmd,
+		"rm":  rmRemotePinServiceCmd,
+	},
+}
+
+var addRemotePinServiceCmd = &cmds.Command{
+	Helptext: cmdkit.HelpText{
+		Tagline: "Add a remote pinning service.",
+		ShortDescription: `
+Adds a remote pinning service speaking the IPFS Pinning Service API at the
+given endpoint, authenticating with an access key. The key is read from stdin,
+or from a file, so that it doesn't show up in the shell history or the
+process list:
+
+  > ipfs pin remote service add mysrv https://pinning.example.com/api/v1 < key.txt
+`,
+	},
+
+	Arguments: []cmdkit.Argument{
+		cmdkit.StringArg("name", true, false, "Name of the service."),
+		cmdkit.StringArg("endpoint", true, false, "Base URL of the service API."),
+		cmdkit.FileArg("key", true, false, "File holding the access key of the service.").EnableStdin(),
+	},
+	Run: func(req *cmds.Request, res cmds.ResponseEmitter, env cmds.Environment) error {
+		name, endpoint := req.Arguments[0], req.Arguments[1]
+
+		if err := remote.CheckServiceName(name); err != nil {
+			return err
+		}
+		if err := remote.CheckEndpoint(endpoint); err != nil {
+			return err
+		}
+		key, err := readRemotePinServiceKey(req)
+		if err != nil {
+			return err
+		}
+
+		cfgRoot, err := cmdenv.GetConfigRoot(env)
+		if err != nil {
+			return err
+		}
+		r, err := fsrepo.Open(cfgRoot)
+		if err != nil {
+			return err
+		}
+		defer r.Close()
+
+		services, err := remote.Services(r)
+		if err != nil {
+			return err
+		}
+		if _, ok := services[name]; ok {
+			return fmt.Errorf("remote pinning service '%s' already exists", name)
+		}
+
+		services[name] = remote.Service{
+			API: remote.ServiceAPI{
+				Endpoint: endpoint,
+				Key:      key,
+			},
+		}
+		return remote.SetServices(r, services)
+	},
+}
+
+// maxRemotePinServiceKeySize bounds the access key read from the request
+const maxRemotePinServiceKeySize = 64 << 10
+
+// readRemotePinServiceKey reads the access key of a service from the file
+// argument of the request
+func readRemotePinServiceKey(req *cmds.Request) (string, error) {
+	file, err := cmdenv.GetFileArg(req.Files.Entries())
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	b, err := ioutil.ReadAll(io.LimitReader(file, maxRemotePinServiceKeySize+1))
+	if err != nil {
+		return "", fmt.Errorf("reading the access key: %s", err)
+	}
+	if len(b) > maxRemotePinServiceKeySize {
+		return "", fmt.Errorf("the access key is longer than %d bytes", maxRemotePinServiceKeySize)
+	}
+	key := strings.TrimSpace(string(b))
+	if key == "" {
+		return "", fmt.Errorf("the access key must not be empty")
+	}
+	return key, nil
+}
+
+var listRemotePinServiceCmd = &cmds.Command{
+	Helptext: cmdkit.HelpText{
+		Tagline: "List the remote pinning services.",
+	},
+
+	Run: func(req *cmds.Request, res cmds.ResponseEmitter, env cmds.Environment) error {
+		cfgRoot, err := cmdenv.GetConfigRoot(env)
+		if err != nil {
+			return err
+		}
+		r, err := fsrepo.Open(cfgRoot)
+		if err != nil {
+			return err
+		}
+		defer r.Close()
+
+		services, err := remote.Services(r)
+		if err != nil {
+			return err
+		}
+
+		out := &RemotePinServicesOutput{Services: make([]RemotePinServiceOutput, 0, len(services))}
+		for name, s := range services {
+			out.Services = append(out.Services, RemotePinServiceOutput{
+				Service:  name,
+				Endpoint: s.API.Endpoint,
+			})
+		}
+		sort.Slice(out.Services, func(i, j int) bool {
+			return out.Services[i].Service < out.Services[j].Service
+		})
+
+		return cmds.EmitOnce(res, out)
+	},
+	Type: RemotePinServicesOutput{},
+	Encoders: cmds.EncoderMap{
+		cmds.Text: cmds.MakeTypedEncoder(func(req *cmds.Request, w io.Writer, out *RemotePinServicesOutput) error {
+			tw := tabwriter.NewWriter(w, 1, 2, 1, ' ', 0)
+			for _, s := range out.Services {
+				fmt.Fprintf(tw, "%s\t%s\n", s.Service, s.Endpoint)
+			}
+			return tw.Flush()
+		}),
+	},
+}
+
+var rmRemotePinServiceCmd = &cmds.Command{
+	Helptext: cmdkit.HelpText{
+		Tagline: "Remove a remote pinning service.",
+		ShortDescription: `
+Removes the service from the config. The pin requests made to it are still
+listed by 'ipfs pin remote ls', but can't be polled or removed anymore.
+`,
+	},
+
+	Arguments: []cmdkit.Argument{
+		cmdkit.StringArg("name", true, false, "Name of the service to remove."),
+	},
+	Run: func(req *cmds.Request, res cmds.ResponseEmitter, env cmds.Environment) error {
+		name := req.Arguments[0]
+
+		cfgRoot, err := cmdenv.GetConfigRoot(env)
+		if err != nil {
+			return err
+		}
+		r, err := fsrepo.Open(cfgRoot)
+		if err != nil {
+			return err
+		}
+		defer r.Close()
+
+		services, err := remote.Services(r)
+		if err != nil {
+			return err
+		}
+		if _, ok := services[name]; !ok {
+			return fmt.Errorf("remote pinning service '%s' not found", name)
+		}
+
+		delete(services, name)
+		return remote.SetServices(r, services)
+	},
+}
@@ -1,24 +1,23 @@
 package commands
 
 import (
-	"context"
 	"errors"
 	"fmt"
 	"io"
 	"os"
 	"path/filepath"
-	"runtime"
 	"strings"
-	"sync"
 	"text/tabwriter"
+	"time"
 
 	cmdenv "github.com/ipfs/go-ipfs/core/commands/cmdenv"
 	corerepo "github.com/ipfs/go-ipfs/core/corerepo"
+	gc "github.com/ipfs/go-ipfs/pin/gc"
 	fsrepo "github.com/ipfs/go-ipfs/repo/fsrepo"
 
+	humanize "gx/ipfs/QmPSBJL4momYnE7DcUyk2DVhD6rH488ZmHBGLbxNdhU44K/go-humanize"
 	cmds "gx/ipfs/QmR77mMvvh8mJBBWQmBfQBu8oD38NUN4KE9SL2gDgAQNc6/go-ipfs-cmds"
 	cid "gx/ipfs/QmR8BauakNcBa3RbE4nbQu76PDiJgoQgz8AJdhJuiU4TAw/go-cid"
-	bstore "gx/ipfs/QmS2aqUZLJp8kF1ihE5rvDGE5LvmKDPnx32w9Z1BW9xLV5/go-ipfs-blockstore"
 	config "gx/ipfs/QmTbcMKv6GU3fxhnNcbzYChdox9Fdd7VpucM3PQ7UWjX3D/go-ipfs-config"
 	cmdkit "gx/ipfs/Qmde5VP1qUkyQXKCfmEUA7bP64V2HAptbJ7phuPp7jXWwg/go-ipfs-cmdkit"
 )
@@ -53,6 +52,8 @@ type GcResult struct {
 const (
 	repoStreamErrorsOptionName = "stream-errors"
 	repoQuietOptionName        = "quiet"
+	repoMaxDurationOptionName  = "max-duration"
+	repoTargetOptionName       = "target"
 )
 
 var repoGcCmd = &cmds.Command{
@@ -62,11 +63,26 @@ var repoGcCmd = &cmds.Command{
 'ipfs repo gc' is a plumbing command that will sweep the local
 set of stored objects and remove ones that are not pinned in
 order to reclaim hard disk space.
+`,
+		LongDescription: `
+'ipfs repo gc' is a plumbing command that will sweep the local
+set of stored objects and remove ones that are not pinned in
+order to reclaim hard disk space.
+
+With --max-duration or --target, an incremental collection is run
+instead: adds and pins are only blocked while a batch of blocks is
+swept, and the collection stops once it took the given time or freed
+the given amount of space. The next run continues where the previous
+one stopped.
+
+  > ipfs repo gc --max-duration 5m --target 50GB
 `,
 	},
 	Options: []cmdkit.Option{
 		cmdkit.BoolOption(repoStreamErrorsOptionName, "Stream errors."),
 		cmdkit.BoolOption(repoQuietOptionName, "q", "Write minimal output."),
+		cmdkit.StringOption(repoMaxDurationOptionName, "Stop an incremental collection after this duration, e.g. '5m'."),
+		cmdkit.StringOption(repoTargetOptionName, "Stop an incremental collection after freeing this much space, e.g. '50GB'."),
 	},
 	Run: func(req *cmds.Request, re cmds.ResponseEmitter, env cmds.Environment) error {
 		n, err := cmdenv.GetNode(env)
@@ -76,7 +92,35 @@ order to reclaim hard disk space.
 
 		streamErrors, _ := req.Options[repoStreamErrorsOptionName].(bool)
 
-		gcOutChan := corerepo.GarbageCollectAsync(n, req.Context)
+		incremental := false
+		var gcOpts gc.IncrementalOptions
+		if d, ok := req.Options[repoMaxDurationOptionName].(string); ok {
+			gcOpts.MaxDuration, err = time.ParseDuration(d)
+			if err != nil {
+				return fmt.Errorf("invalid max duration: %s", err)
+			}
+			if gcOpts.MaxDuration <= 0 {
+				return errors.New("max duration must be positive")
+			}
+			incremental = true
+		}
+		if t, ok := req.Options[repoTargetOptionName].(string); ok {
+			gcOpts.Target, err = humanize.ParseBytes(t)
+			if err != nil {
+				return fmt.Errorf("invalid target: %s", err)
+			}
+			if gcOpts.Target == 0 {
+				return errors.New("target must be positive")
+			}
+			incremental = true
+		}
+
+		var gcOutChan <-chan gc.Result
+		if incremental {
+			gcOutChan = corerepo.GarbageCollectIncremental(n, req.Context, gcOpts)
+		} else {
+			gcOutChan = corerepo.GarbageCollectAsync(n, req.Context)
+		}
 
 		if streamErrors {
 			errs := false
@@ -267,48 +311,6 @@ type VerifyProgress struct {
 	Progress int
 }
 
-func verifyWorkerRun(ctx context.Context, wg *sync.WaitGroup, keys <-chan cid.Cid, results chan<- string, bs bstore.Blockstore) {
-	defer wg.Done()
-
-	for k := range keys {
-		_, err := bs.Get(k)
-		if err != nil {
-			select {
-			case results <- fmt.Sprintf("block %s was corrupt (%s)", k, err):
-			case <-ctx.Done():
-				return
-			}
-
-			continue
-		}
-
-		select {
-		case results <- "":
-		case <-ctx.Done():
-			return
-		}
-	}
-}
-
-func verifyResultChan(ctx context.Context, keys <-chan cid.Cid, bs bstore.Blockstore) <-chan string {
-	results := make(chan string)
-
-	go func() {
-		defer close(results)
-
-		var wg sync.WaitGroup
-
-		for i := 0; i < runtime.NumCPU()*2; i++ {
-			wg.Add(1)
-			go verifyWorkerRun(ctx, &wg, keys, results, bs)
-		}
-
-		wg.Wait()
-	}()
-
-	return results
-}
-
 var repoVerifyCmd = &cmds.Command{
 	Helptext: cmdkit.HelpText{
 		Tagline: "Verify all blocks in repo are not corrupted.",
@@ -319,21 +321,17 @@ var repoVerifyCmd = &cmds.Command{
 			return err
 		}
 
-		bs := bstore.NewBlockstore(nd.Repo.Datastore())
-		bs.HashOnRead(true)
-
-		keys, err := bs.AllKeysChan(req.Context)
+		results, err := corerepo.Verify(req.Context, nd.Repo)
 		if err != nil {
 			log.Error(err)
 			return err
 		}
 
-		results := verifyResultChan(req.Context, keys, bs)
-
 		var fails int
 		var i int
-		for msg := range results {
-			if msg != "" {
+		for r := range results {
+			if r.Error != nil {
+				msg := fmt.Sprintf("block %s was corrupt (%s)", r.Key, r.Error)
 				if err := res.Emit(&VerifyProgress{Msg: msg}); err != nil {
 					return err
 				}
@@ -8,6 +8,7 @@ import (
 	name "github.com/ipfs/go-ipfs/core/commands/name"
 	ocmd "github.com/ipfs/go-ipfs/core/commands/object"
 	unixfs "github.com/ipfs/go-ipfs/core/commands/unixfs"
+	loader "github.com/ipfs/go-ipfs/plugin/loader"
 
 	cmds "gx/ipfs/QmR77mMvvh8mJBBWQmBfQBu8oD38NUN4KE9SL2gDgAQNc6/go-ipfs-cmds"
 	logging "gx/ipfs/QmcuXC5cxs79ro2cUuHs4HQ2bkDLJUYokwL8aivcX6HW3C/go-log"
@@ -71,6 +72,7 @@ TOOL COMMANDS
   version       Show ipfs version information
   update        Download and apply go-ipfs updates
   commands      List all available commands
+  plugin        Inspect the plugins
   cid           Convert and discover properties of CIDs
 
 Use 'ipfs <command> --help' to learn more about each command.
@@ -138,6 +140,7 @@ var rootSubcommands = map[string]*cmds.Command{
 	"object":    ocmd.ObjectCmd,
 	"pin":       PinCmd,
 	"ping":      PingCmd,
+	"plugin":    PluginCmd,
 	"p2p":       P2PCmd,
 	"refs":      RefsCmd,
 	"resolve":   ResolveCmd,
@@ -188,6 +191,7 @@ var rootROSubcommands = map[string]*cmds.Command{
 		Subcommands: map[string]*cmds.Command{
 			"get":     dag.DagGetCmd,
 			"resolve": dag.DagResolveCmd,
+			"export":  dag.DagExportCmd,
 		},
 	},
 	"resolve": ResolveCmd,
@@ -207,6 +211,12 @@ func init() {
 	// before the value is updated (:/sanitize readonly refs command/)
 	rootROSubcommands["refs"] = RefsROCmd
 
+	// the preloaded plugins are part of the binary, so a command clashing
+	// with a built-in one is a build error
+	if err := addPluginCommands(rootSubcommands, rootROSubcommands, loader.Preloaded()); err != nil {
+		panic(err)
+	}
+
 	Root.Subcommands = rootSubcommands
 
 	RootRO.Subcommands = rootROSubcommands
@@ -28,6 +28,8 @@ import (
 	ipnsrp "github.com/ipfs/go-ipfs/namesys/republisher"
 	p2p "github.com/ipfs/go-ipfs/p2p"
 	pin "github.com/ipfs/go-ipfs/pin"
+	gc "github.com/ipfs/go-ipfs/pin/gc"
+	remote "github.com/ipfs/go-ipfs/pin/remote"
 	repo "github.com/ipfs/go-ipfs/repo"
 
 	ma "gx/ipfs/QmNTCey11oxhb1AxDnQBRHtdhap6Ctud872NjAYPYYXPuc/go-multiaddr"
@@ -107,11 +109,15 @@ type IpfsNode struct {
 
 	Repo repo.Repo
 
+	IsDaemon bool // whether the node is run by 'ipfs daemon'
+
 	// Local node
-	Pinning         pin.Pinner // the pinning manager
-	Mounts          Mounts     // current mount state, if any.
-	PrivateKey      ic.PrivKey // the local node's private Key
-	PNetFingerprint []byte     // fingerprint of private network
+	Pinning         pin.Pinner      // the pinning manager
+	GCStats         gc.Stats        // the garbage collection runs
+	RemotePins      *remote.Tracker // the requests made to remote pinning services
+	Mounts          Mounts          // current mount state, if any.
+	PrivateKey      ic.PrivKey      // the local node's private Key
+	PNetFingerprint []byte          // fingerprint of private network
 
 	// Services
 	Peerstore       pstore.Peerstore     // storage for other Peer instances
@@ -135,6 +141,8 @@ type IpfsNode struct {
 	Namesys      namesys.NameSystem  // the name system, resolves paths to hashes
 	Reprovider   *rp.Reprovider      // the value reprovider system
 	IpnsRepub    *ipnsrp.Republisher
+	NamesysCache *namesys.ResolveCache
+	DNSResolver  *namesys.DNSResolver
 
 	AutoNAT  *autonat.AutoNATService
 	PubSub   *pubsub.PubSub
@@ -292,6 +300,11 @@ func (n *IpfsNode) startOnlineServices(ctx context.Context, routingOption Routin
 	}
 
 	n.P2P = p2p.NewP2P(n.Identity, n.PeerHost, n.Peerstore)
+	if cfg.Experimental.Libp2pStreamMounting {
+		if err := n.startP2PForwards(); err != nil {
+			return err
+		}
+	}
 
 	// setup local discovery
 	if do != nil {
@@ -326,25 +339,60 @@ func constructConnMgr(cfg config.ConnMgr) (ifconnmgr.ConnManager, error) {
 	}
 }
 
+// reproviderStrategies maps the names of reprovider strategies to their key
+// providers. Strategies can be combined with '+', as in "pinned+mfs".
+var reproviderStrategies = map[string]func(n *IpfsNode) rp.KeyChanFunc{
+	"all": func(n *IpfsNode) rp.KeyChanFunc {
+		return rp.NewBlockstoreProvider(n.Blockstore)
+	},
+	"roots": func(n *IpfsNode) rp.KeyChanFunc {
+		return rp.NewPinnedProvider(n.Pinning, n.DAG, true)
+	},
+	"pinned": func(n *IpfsNode) rp.KeyChanFunc {
+		return rp.NewPinnedProvider(n.Pinning, n.DAG, false)
+	},
+	"mfs": func(n *IpfsNode) rp.KeyChanFunc {
+		return rp.NewMFSProvider(func() *mfs.Root { return n.FilesRoot }, n.DAG, false)
+	},
+}
+
+func (n *IpfsNode) reproviderStrategy(strategy string) (rp.KeyChanFunc, error) {
+	if strategy == "" {
+		strategy = "all"
+	}
+
+	names := strings.Split(strategy, "+")
+	providers := make([]rp.KeyChanFunc, 0, len(names))
+	for _, name := range names {
+		mk, ok := reproviderStrategies[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown reprovider strategy '%s'", name)
+		}
+
+		// 'all' already covers everything, and deduplicating the whole
+		// blockstore would keep every key in memory
+		if name == "all" && len(names) > 1 {
+			return nil, fmt.Errorf("reprovider strategy 'all' can't be combined with other strategies")
+		}
+
+		providers = append(providers, mk(n))
+	}
+
+	if len(providers) == 1 {
+		return providers[0], nil
+	}
+	return rp.NewCombinedProvider(providers...), nil
+}
+
 func (n *IpfsNode) startLateOnlineServices(ctx context.Context) error {
 	cfg, err := n.Repo.Config()
 	if err != nil {
 		return err
 	}
 
-	var keyProvider rp.KeyChanFunc
-
-	switch cfg.Reprovider.Strategy {
-	case "all":
-		fallthrough
-	case "":
-		keyProvider = rp.NewBlockstoreProvider(n.Blockstore)
-	case "roots":
-		keyProvider = rp.NewPinnedProvider(n.Pinning, n.DAG, true)
-	case "pinned":
-		keyProvider = rp.NewPinnedProvider(n.Pinning, n.DAG, false)
-	default:
-		return fmt.Errorf("unknown reprovider strategy '%s'", cfg.Reprovider.Strategy)
+	keyProvider, err := n.reproviderStrategy(cfg.Reprovider.Strategy)
+	if err != nil {
+		return err
 	}
 	n.Reprovider = rp.NewReprovider(ctx, n.Routing, keyProvider)
 
@@ -360,6 +408,8 @@ func (n *IpfsNode) startLateOnlineServices(ctx context.Context) error {
 
 	go n.Reprovider.Run(reproviderInterval)
 
+	go n.RemotePins.Run(ctx, remote.DefaultPollInterval)
+
 	return nil
 }
 
@@ -581,13 +631,14 @@ func (n *IpfsNode) startOnlineServicesWithHost(ctx context.Context, routingOptio
 	bitswapNetwork := bsnet.NewFromIpfsHost(n.PeerHost, n.Routing)
 	n.Exchange = bitswap.New(ctx, bitswapNetwork, n.Blockstore)
 
-	size, err := n.getCacheSize()
+	n.NamesysCache, _, err = n.newNamesysCache()
 	if err != nil {
 		return err
 	}
 
 	// setup name system
-	n.Namesys = namesys.NewNameSystem(n.Routing, n.Repo.Datastore(), size)
+	ns := namesys.NewCustomNameSystem(n.Routing, n.Repo.Datastore(), n.DNSResolver, n.NamesysCache)
+	n.Namesys = namesys.WithMetrics(ctx, ns)
 
 	// setup ipns republishing
 	return n.setupIpnsRepublisher()
@@ -610,6 +661,88 @@ func (n *IpfsNode) getCacheSize() (int, error) {
 	return cs, nil
 }
 
+// startP2PForwards starts the p2p forwards and listeners persisted in the
+// config. The ones which fail to start are logged, and skipped.
+func (n *IpfsNode) startP2PForwards() error {
+	forwards, err := p2p.Forwards(n.Repo)
+	if err != nil {
+		return err
+	}
+
+	for _, f := range forwards {
+		if _, err := n.P2P.Start(n.Context(), f); err != nil {
+			log.Errorf("failed to start p2p forward of %s from %s to %s: %s", f.Protocol, f.ListenAddress, f.TargetAddress, err)
+		}
+	}
+	return nil
+}
+
+// dnsResolversConfigKey is the config section setting the DNS resolvers used
+// to look up DNSLinks, as a map of domain to resolver URL
+const dnsResolversConfigKey = "DNS.Resolvers"
+
+// newDNSResolver creates the DNSLink resolver, using the resolvers set in the
+// config for their domains and the system resolver for the others
+func (n *IpfsNode) newDNSResolver() (*namesys.DNSResolver, error) {
+	urls := make(map[string]string)
+	if _, err := repo.ReadConfigSection(n.Repo, dnsResolversConfigKey, &urls); err != nil {
+		return nil, fmt.Errorf("failure to parse config setting %s: %s", dnsResolversConfigKey, err)
+	}
+	if len(urls) == 0 {
+		return namesys.NewDNSResolver(), nil
+	}
+
+	resolvers := make(map[string]namesys.TXTResolver, len(urls))
+	for domain, u := range urls {
+		res, err := namesys.NewTXTResolver(u)
+		if err != nil {
+			return nil, fmt.Errorf("failure to parse config setting %s.%s: %s", dnsResolversConfigKey, domain, err)
+		}
+		resolvers[domain] = res
+	}
+	return namesys.NewDNSResolverWithResolvers(resolvers), nil
+}
+
+// ipnsPersistCacheConfigKey is the config setting backing the namesys resolve
+// cache with the datastore, so that it survives restarts. Like
+// ipnsKeysConfigKey, it's kept out of the Ipns section.
+const ipnsPersistCacheConfigKey = "Namesys.PersistResolveCache"
+
+// newNamesysCache creates the resolve cache of the name system, and returns
+// whether it is persisted in the datastore
+func (n *IpfsNode) newNamesysCache() (*namesys.ResolveCache, bool, error) {
+	size, err := n.getCacheSize()
+	if err != nil {
+		return nil, false, err
+	}
+
+	var persist bool
+	if _, err := repo.ReadConfigSection(n.Repo, ipnsPersistCacheConfigKey, &persist); err != nil {
+		return nil, false, fmt.Errorf("failure to parse config setting %s: %s", ipnsPersistCacheConfigKey, err)
+	}
+
+	var d ds.Datastore
+	if persist {
+		d = n.Repo.Datastore()
+	}
+	cache, err := namesys.NewResolveCache(size, d)
+	if err != nil {
+		return nil, false, err
+	}
+	return cache, persist, nil
+}
+
+// ipnsKeysConfigKey is the config section overriding the IPNS republishing
+// settings per key, by key name ("self" for the node's key). It can't be under
+// Ipns, which is rewritten from the config struct on every config change.
+const ipnsKeysConfigKey = "Namesys.Keys"
+
+type ipnsKeyConfig struct {
+	RepublishPeriod string
+	RecordLifetime  string
+	TTL             string
+}
+
 func (n *IpfsNode) setupIpnsRepublisher() error {
 	cfg, err := n.Repo.Config()
 	if err != nil {
@@ -640,6 +773,37 @@ func (n *IpfsNode) setupIpnsRepublisher() error {
 		n.IpnsRepub.RecordLifetime = d
 	}
 
+	keys := make(map[string]ipnsKeyConfig)
+	if _, err := repo.ReadConfigSection(n.Repo, ipnsKeysConfigKey, &keys); err != nil {
+		return fmt.Errorf("failure to parse config setting %s: %s", ipnsKeysConfigKey, err)
+	}
+	for name, kc := range keys {
+		var rkc ipnsrp.KeyConfig
+		for _, d := range []struct {
+			setting string
+			value   string
+			dst     *time.Duration
+		}{
+			{"RepublishPeriod", kc.RepublishPeriod, &rkc.Interval},
+			{"RecordLifetime", kc.RecordLifetime, &rkc.RecordLifetime},
+			{"TTL", kc.TTL, &rkc.TTL},
+		} {
+			if d.value == "" {
+				continue
+			}
+			v, err := time.ParseDuration(d.value)
+			if err != nil {
+				return fmt.Errorf("failure to parse config setting %s.%s.%s: %s", ipnsKeysConfigKey, name, d.setting, err)
+			}
+			*d.dst = v
+		}
+
+		if rkc.Interval != 0 && !u.Debug && (rkc.Interval < time.Minute || rkc.Interval > (time.Hour*24)) {
+			return fmt.Errorf("config setting %s.%s.RepublishPeriod is not between 1min and 1day: %s", ipnsKeysConfigKey, name, rkc.Interval)
+		}
+		n.IpnsRepub.Keys[name] = rkc
+	}
+
 	n.Process().Go(n.IpnsRepub.Run)
 
 	return nil
@@ -19,7 +19,9 @@ import (
 	"fmt"
 	"github.com/ipfs/go-ipfs/core"
 	"github.com/ipfs/go-ipfs/namesys"
+	"github.com/ipfs/go-ipfs/p2p"
 	"github.com/ipfs/go-ipfs/pin"
+	"github.com/ipfs/go-ipfs/pin/gc"
 	"github.com/ipfs/go-ipfs/repo"
 
 	coreiface "github.com/ipfs/go-ipfs/core/coreapi/interface"
@@ -34,6 +36,7 @@ import (
 	"gx/ipfs/QmRjT8Bkut84fHf9nxMQBxGsqLAkqzMdFaemDK7e61dBNZ/go-libp2p-routing"
 	"gx/ipfs/QmS2aqUZLJp8kF1ihE5rvDGE5LvmKDPnx32w9Z1BW9xLV5/go-ipfs-blockstore"
 	dag "gx/ipfs/QmUtsx89yiCY6F8mbpP6ecXckiSzCBH7EvkKZuZEHBcr1m/go-merkledag"
+	mfs "gx/ipfs/QmVBXaQqupXCFtS62xtr9EsKGkbK9LviqCKSzwcqzwvX9U/go-mfs"
 	pubsub "gx/ipfs/QmWL6MKfes1HuSiRUNzGmwy9YyQDwcZF9V1NaA2keYKhtE/go-libp2p-pubsub"
 	offlinexch "gx/ipfs/QmYZwey1thDTynSrvd6qQkX24UpTka6TFhQ2v569UpoqxD/go-ipfs-exchange-offline"
 	bserv "gx/ipfs/QmbgbNxC1PMyS2gbx7nf2jKNG7bZAfYJJebdK4ptBBWCz1/go-blockservice"
@@ -54,6 +57,7 @@ type CoreAPI struct {
 	blockstore blockstore.GCBlockstore
 	baseBlocks blockstore.Blockstore
 	pinning    pin.Pinner
+	gcStats    *gc.Stats
 
 	blocks bserv.BlockService
 	dag    ipld.DAGService
@@ -63,10 +67,14 @@ type CoreAPI struct {
 	recordValidator record.Validator
 	exchange        exchange.Interface
 
-	namesys namesys.NameSystem
-	routing routing.IpfsRouting
+	namesys     namesys.NameSystem
+	dnsResolver *namesys.DNSResolver
+	routing     routing.IpfsRouting
 
 	pubSub *pubsub.PubSub
+	p2p    *p2p.P2P
+
+	filesRoot *mfs.Root
 
 	checkPublishAllowed func() error
 	checkOnline         func(allowOffline bool) error
@@ -139,6 +147,21 @@ func (api *CoreAPI) PubSub() coreiface.PubSubAPI {
 	return (*PubSubAPI)(api)
 }
 
+// Files returns the FilesAPI interface implementation backed by the go-ipfs node
+func (api *CoreAPI) Files() coreiface.FilesAPI {
+	return (*FilesAPI)(api)
+}
+
+// P2P returns the P2PAPI interface implementation backed by the go-ipfs node
+func (api *CoreAPI) P2P() coreiface.P2PAPI {
+	return (*P2PAPI)(api)
+}
+
+// Repo returns the RepoAPI interface implementation backed by the go-ipfs node
+func (api *CoreAPI) Repo() coreiface.RepoAPI {
+	return (*RepoAPI)(api)
+}
+
 // WithOptions returns api with global options applied
 func (api *CoreAPI) WithOptions(opts ...options.ApiOption) (coreiface.CoreAPI, error) {
 	settings := api.parentOpts // make sure to copy
@@ -163,6 +186,7 @@ func (api *CoreAPI) WithOptions(opts ...options.ApiOption) (coreiface.CoreAPI, e
 		blockstore: n.Blockstore,
 		baseBlocks: n.BaseBlocks,
 		pinning:    n.Pinning,
+		gcStats:    &n.GCStats,
 
 		blocks: n.Blocks,
 		dag:    n.DAG,
@@ -170,11 +194,15 @@ func (api *CoreAPI) WithOptions(opts ...options.ApiOption) (coreiface.CoreAPI, e
 		peerstore:       n.Peerstore,
 		peerHost:        n.PeerHost,
 		namesys:         n.Namesys,
+		dnsResolver:     n.DNSResolver,
 		recordValidator: n.RecordValidator,
 		exchange:        n.Exchange,
 		routing:         n.Routing,
 
 		pubSub: n.PubSub,
+		p2p:    n.P2P,
+
+		filesRoot: n.FilesRoot,
 
 		nd:         n,
 		parentOpts: settings,
@@ -209,10 +237,15 @@ func (api *CoreAPI) WithOptions(opts ...options.ApiOption) (coreiface.CoreAPI, e
 		}
 
 		subApi.routing = offlineroute.NewOfflineRouter(subApi.repo.Datastore(), subApi.recordValidator)
-		subApi.namesys = namesys.NewNameSystem(subApi.routing, subApi.repo.Datastore(), cs)
+		cache, err := namesys.NewResolveCache(cs, nil)
+		if err != nil {
+			return nil, err
+		}
+		subApi.namesys = namesys.NewCustomNameSystem(subApi.routing, subApi.repo.Datastore(), subApi.dnsResolver, cache)
 
 		subApi.peerstore = nil
 		subApi.peerHost = nil
+		subApi.p2p = nil
 		subApi.recordValidator = nil
 	}
 
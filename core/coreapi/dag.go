@@ -2,7 +2,10 @@ package coreapi
 
 import (
 	"context"
+	"io"
 
+	"github.com/ipfs/go-ipfs/car"
+	caopts "github.com/ipfs/go-ipfs/core/coreapi/interface/options"
 	"github.com/ipfs/go-ipfs/pin"
 
 	cid "gx/ipfs/QmR8BauakNcBa3RbE4nbQu76PDiJgoQgz8AJdhJuiU4TAw/go-cid"
@@ -51,3 +54,41 @@ func (adder *pinningAdder) AddMany(ctx context.Context, nds []ipld.Node) error {
 func (api *dagAPI) Pinning() ipld.NodeAdder {
 	return (*pinningAdder)(api.core)
 }
+
+// Export writes every block reachable from the root to w as a CARv1 archive.
+func (api *dagAPI) Export(ctx context.Context, root cid.Cid, w io.Writer) error {
+	return car.WriteCar(ctx, api.core.dag, []cid.Cid{root}, w)
+}
+
+// Import stores the blocks of the CARv1 archive read from r in the blockstore
+// and returns the roots listed in its header.
+func (api *dagAPI) Import(ctx context.Context, r io.Reader, opts ...caopts.DagImportOption) ([]cid.Cid, error) {
+	settings, err := caopts.DagImportOptions(opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	defer api.core.blockstore.PinLock().Unlock()
+
+	h, err := car.LoadCar(api.core.blockstore, r)
+	if err != nil {
+		return nil, err
+	}
+
+	if !settings.PinRoots {
+		return h.Roots, nil
+	}
+
+	for _, c := range h.Roots {
+		nd, err := api.core.dag.Get(ctx, c)
+		if err != nil {
+			return nil, err
+		}
+
+		if err := api.core.pinning.Pin(ctx, nd, true); err != nil {
+			return nil, err
+		}
+	}
+
+	return h.Roots, api.core.pinning.Flush()
+}
@@ -0,0 +1,669 @@
+package coreapi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	gopath "path"
+	"strings"
+
+	coreiface "github.com/ipfs/go-ipfs/core/coreapi/interface"
+	caopts "github.com/ipfs/go-ipfs/core/coreapi/interface/options"
+
+	cid "gx/ipfs/QmR8BauakNcBa3RbE4nbQu76PDiJgoQgz8AJdhJuiU4TAw/go-cid"
+	ipld "gx/ipfs/QmRL22E4paat7ky7vx9MLpR97JHHbFPrg3ytFQw6qp1y1s/go-ipld-format"
+	dag "gx/ipfs/QmUtsx89yiCY6F8mbpP6ecXckiSzCBH7EvkKZuZEHBcr1m/go-merkledag"
+	mfs "gx/ipfs/QmVBXaQqupXCFtS62xtr9EsKGkbK9LviqCKSzwcqzwvX9U/go-mfs"
+	offlinexch "gx/ipfs/QmYZwey1thDTynSrvd6qQkX24UpTka6TFhQ2v569UpoqxD/go-ipfs-exchange-offline"
+	ft "gx/ipfs/QmZArMcsVDsXdcLbUx4844CuqKXBpbxdeiryM4cnmGTNRq/go-unixfs"
+	bserv "gx/ipfs/QmbgbNxC1PMyS2gbx7nf2jKNG7bZAfYJJebdK4ptBBWCz1/go-blockservice"
+)
+
+type FilesAPI CoreAPI
+
+// Stat returns information about the node at the path.
+func (api *FilesAPI) Stat(ctx context.Context, p string, opts ...caopts.FilesOption) (*coreiface.FilesStat, error) {
+	settings, err := caopts.FilesOptions(opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	p, err = checkFilesPath(p)
+	if err != nil {
+		return nil, err
+	}
+
+	nd, err := api.getNode(ctx, p)
+	if err != nil {
+		return nil, err
+	}
+
+	st, err := statNode(nd)
+	if err != nil {
+		return nil, err
+	}
+
+	if !settings.WithLocal {
+		return st, nil
+	}
+
+	// an offline DAGService will not fetch from the network
+	dagserv := dag.NewDAGService(bserv.New(
+		api.blockstore,
+		offlinexch.Exchange(api.blockstore),
+	))
+
+	local, sizeLocal, err := walkBlock(ctx, dagserv, nd)
+	if err != nil {
+		return nil, err
+	}
+
+	st.WithLocality = true
+	st.Local = local
+	st.SizeLocal = sizeLocal
+
+	return st, nil
+}
+
+// Ls lists the entries of the directory at the path.
+func (api *FilesAPI) Ls(ctx context.Context, p string, opts ...caopts.FilesOption) ([]coreiface.FilesEntry, error) {
+	settings, err := caopts.FilesOptions(opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	p, err = checkFilesPath(p)
+	if err != nil {
+		return nil, err
+	}
+
+	fsn, err := mfs.Lookup(api.filesRoot, p)
+	if err != nil {
+		return nil, err
+	}
+
+	switch fsn := fsn.(type) {
+	case *mfs.Directory:
+		if !settings.Long {
+			names, err := fsn.ListNames(ctx)
+			if err != nil {
+				return nil, err
+			}
+
+			out := make([]coreiface.FilesEntry, len(names))
+			for i, name := range names {
+				out[i].Name = name
+			}
+			return out, nil
+		}
+
+		listing, err := fsn.List(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		out := make([]coreiface.FilesEntry, len(listing))
+		for i, l := range listing {
+			c, err := cid.Decode(l.Hash)
+			if err != nil {
+				return nil, err
+			}
+
+			out[i] = coreiface.FilesEntry{
+				Name: l.Name,
+				Type: fileTypeFromMfs(mfs.NodeType(l.Type)),
+				Size: l.Size,
+				Cid:  c,
+			}
+		}
+		return out, nil
+	case *mfs.File:
+		_, name := gopath.Split(p)
+		out := []coreiface.FilesEntry{{Name: name}}
+		if settings.Long {
+			out[0].Type = coreiface.TFile
+
+			size, err := fsn.Size()
+			if err != nil {
+				return nil, err
+			}
+			out[0].Size = size
+
+			nd, err := fsn.GetNode()
+			if err != nil {
+				return nil, err
+			}
+			out[0].Cid = nd.Cid()
+		}
+		return out, nil
+	default:
+		return nil, errors.New("unrecognized type")
+	}
+}
+
+// Mkdir creates a directory at the path.
+func (api *FilesAPI) Mkdir(ctx context.Context, p string, opts ...caopts.FilesOption) error {
+	settings, err := caopts.FilesOptions(opts...)
+	if err != nil {
+		return err
+	}
+
+	p, err = checkFilesPath(p)
+	if err != nil {
+		return err
+	}
+
+	prefix, err := settings.CidBuilder()
+	if err != nil {
+		return err
+	}
+
+	return mfs.Mkdir(api.filesRoot, p, mfs.MkdirOpts{
+		Mkparents:  settings.Parents,
+		Flush:      settings.Flush,
+		CidBuilder: prefix,
+	})
+}
+
+// Write writes the data from the reader to the file at the path.
+func (api *FilesAPI) Write(ctx context.Context, p string, r io.Reader, opts ...caopts.FilesOption) (retErr error) {
+	settings, err := caopts.FilesOptions(opts...)
+	if err != nil {
+		return err
+	}
+
+	p, err = checkFilesPath(p)
+	if err != nil {
+		return err
+	}
+
+	prefix, err := settings.CidBuilder()
+	if err != nil {
+		return err
+	}
+
+	if settings.Parents {
+		err := ensureContainingDirectoryExists(api.filesRoot, p, prefix)
+		if err != nil {
+			return err
+		}
+	}
+
+	fi, err := getFileHandle(api.filesRoot, p, settings.Create, prefix)
+	if err != nil {
+		return err
+	}
+	if settings.RawLeavesSet {
+		fi.RawLeaves = settings.RawLeaves
+	}
+
+	wfd, err := fi.Open(mfs.Flags{Write: true, Sync: settings.Flush})
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		err := wfd.Close()
+		if err != nil {
+			if retErr == nil {
+				retErr = err
+			} else {
+				log.Error("files: error closing file mfs file descriptor", err)
+			}
+		}
+	}()
+
+	if settings.Truncate {
+		if err := wfd.Truncate(0); err != nil {
+			return err
+		}
+	}
+
+	_, err = wfd.Seek(settings.Offset, io.SeekStart)
+	if err != nil {
+		return err
+	}
+
+	if settings.Count >= 0 {
+		r = io.LimitReader(r, settings.Count)
+	}
+
+	_, err = io.Copy(wfd, r)
+	return err
+}
+
+// Read returns a reader for the contents of the file at the path.
+func (api *FilesAPI) Read(ctx context.Context, p string, opts ...caopts.FilesOption) (io.ReadCloser, error) {
+	settings, err := caopts.FilesOptions(opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	p, err = checkFilesPath(p)
+	if err != nil {
+		return nil, err
+	}
+
+	fsn, err := mfs.Lookup(api.filesRoot, p)
+	if err != nil {
+		return nil, err
+	}
+
+	fi, ok := fsn.(*mfs.File)
+	if !ok {
+		return nil, fmt.Errorf("%s was not a file", p)
+	}
+
+	rfd, err := fi.Open(mfs.Flags{Read: true})
+	if err != nil {
+		return nil, err
+	}
+
+	filen, err := rfd.Size()
+	if err != nil {
+		rfd.Close()
+		return nil, err
+	}
+
+	if settings.Offset > filen {
+		rfd.Close()
+		return nil, fmt.Errorf("offset was past end of file (%d > %d)", settings.Offset, filen)
+	}
+
+	_, err = rfd.Seek(settings.Offset, io.SeekStart)
+	if err != nil {
+		rfd.Close()
+		return nil, err
+	}
+
+	var r io.Reader = &contextReaderWrapper{R: rfd, ctx: ctx}
+	if settings.Count >= 0 {
+		r = io.LimitReader(r, settings.Count)
+	}
+
+	return &readCloser{Reader: r, Closer: rfd}, nil
+}
+
+// Mv moves the node at src to dst.
+func (api *FilesAPI) Mv(ctx context.Context, src string, dst string, opts ...caopts.FilesOption) error {
+	settings, err := caopts.FilesOptions(opts...)
+	if err != nil {
+		return err
+	}
+
+	src, err = checkFilesPath(src)
+	if err != nil {
+		return err
+	}
+	dst, err = checkFilesPath(dst)
+	if err != nil {
+		return err
+	}
+
+	err = mfs.Mv(api.filesRoot, src, dst)
+	if err == nil && settings.Flush {
+		err = mfs.FlushPath(api.filesRoot, "/")
+	}
+	return err
+}
+
+// Cp copies the node at src, which can also be an /ipfs/ path, to dst.
+func (api *FilesAPI) Cp(ctx context.Context, src string, dst string, opts ...caopts.FilesOption) error {
+	settings, err := caopts.FilesOptions(opts...)
+	if err != nil {
+		return err
+	}
+
+	src, err = checkFilesPath(src)
+	if err != nil {
+		return err
+	}
+	src = strings.TrimRight(src, "/")
+
+	dst, err = checkFilesPath(dst)
+	if err != nil {
+		return err
+	}
+
+	if dst[len(dst)-1] == '/' {
+		dst += gopath.Base(src)
+	}
+
+	node, err := api.getNode(ctx, src)
+	if err != nil {
+		return fmt.Errorf("cp: cannot get node from path %s: %s", src, err)
+	}
+
+	err = mfs.PutNode(api.filesRoot, dst, node)
+	if err != nil {
+		return fmt.Errorf("cp: cannot put node in path %s: %s", dst, err)
+	}
+
+	if settings.Flush {
+		err := mfs.FlushPath(api.filesRoot, dst)
+		if err != nil {
+			return fmt.Errorf("cp: cannot flush the created file %s: %s", dst, err)
+		}
+	}
+
+	return nil
+}
+
+// Rm removes the node at the path.
+func (api *FilesAPI) Rm(ctx context.Context, p string, opts ...caopts.FilesOption) error {
+	settings, err := caopts.FilesOptions(opts...)
+	if err != nil {
+		return err
+	}
+
+	p, err = checkFilesPath(p)
+	if err != nil {
+		return err
+	}
+
+	if p == "/" {
+		return fmt.Errorf("cannot delete root")
+	}
+
+	// 'rm a/b/c/' will fail unless we trim the slash at the end
+	if p[len(p)-1] == '/' {
+		p = p[:len(p)-1]
+	}
+
+	dir, name := gopath.Split(p)
+	parent, err := mfs.Lookup(api.filesRoot, dir)
+	if err != nil {
+		return fmt.Errorf("parent lookup: %s", err)
+	}
+
+	pdir, ok := parent.(*mfs.Directory)
+	if !ok {
+		return fmt.Errorf("no such file or directory: %s", p)
+	}
+
+	// if Force is specified, it will remove anything else,
+	// including file, directory, corrupted node, etc
+	if settings.Force {
+		err := pdir.Unlink(name)
+		if err != nil {
+			return err
+		}
+
+		return pdir.Flush()
+	}
+
+	// get child node by name, when the node is corrupted and nonexistent,
+	// it will return specific error.
+	child, err := pdir.Child(name)
+	if err != nil {
+		return err
+	}
+
+	switch child.(type) {
+	case *mfs.Directory:
+		if !settings.Recursive {
+			return fmt.Errorf("%s is a directory, use -r to remove directories", p)
+		}
+	}
+
+	err = pdir.Unlink(name)
+	if err != nil {
+		return err
+	}
+
+	return pdir.Flush()
+}
+
+// Flush writes the changes under the path up to the root.
+func (api *FilesAPI) Flush(ctx context.Context, p string) error {
+	return mfs.FlushPath(api.filesRoot, p)
+}
+
+// ChCid changes the CID version or hash function of the directory at the
+// path.
+func (api *FilesAPI) ChCid(ctx context.Context, p string, opts ...caopts.FilesOption) error {
+	settings, err := caopts.FilesOptions(opts...)
+	if err != nil {
+		return err
+	}
+
+	p, err = checkFilesPath(p)
+	if err != nil {
+		return err
+	}
+
+	prefix, err := settings.CidBuilder()
+	if err != nil {
+		return err
+	}
+
+	if prefix == nil {
+		return nil
+	}
+
+	nd, err := mfs.Lookup(api.filesRoot, p)
+	if err != nil {
+		return err
+	}
+
+	switch n := nd.(type) {
+	case *mfs.Directory:
+		n.SetCidBuilder(prefix)
+	default:
+		return fmt.Errorf("can only update directories")
+	}
+
+	if settings.Flush {
+		return mfs.FlushPath(api.filesRoot, p)
+	}
+	return nil
+}
+
+func (api *FilesAPI) getNode(ctx context.Context, p string) (ipld.Node, error) {
+	switch {
+	case strings.HasPrefix(p, "/ipfs/"):
+		np, err := coreiface.ParsePath(p)
+		if err != nil {
+			return nil, err
+		}
+
+		return api.core().ResolveNode(ctx, np)
+	default:
+		fsn, err := mfs.Lookup(api.filesRoot, p)
+		if err != nil {
+			return nil, err
+		}
+
+		return fsn.GetNode()
+	}
+}
+
+func (api *FilesAPI) core() coreiface.CoreAPI {
+	return (*CoreAPI)(api)
+}
+
+func fileTypeFromMfs(t mfs.NodeType) coreiface.FileType {
+	if t == mfs.TDir {
+		return coreiface.TDirectory
+	}
+	return coreiface.TFile
+}
+
+func statNode(nd ipld.Node) (*coreiface.FilesStat, error) {
+	c := nd.Cid()
+
+	cumulsize, err := nd.Size()
+	if err != nil {
+		return nil, err
+	}
+
+	switch n := nd.(type) {
+	case *dag.ProtoNode:
+		d, err := ft.FSNodeFromBytes(n.Data())
+		if err != nil {
+			return nil, err
+		}
+
+		var ndtype coreiface.FileType
+		switch d.Type() {
+		case ft.TDirectory, ft.THAMTShard:
+			ndtype = coreiface.TDirectory
+		case ft.TFile, ft.TMetadata, ft.TRaw:
+			ndtype = coreiface.TFile
+		default:
+			return nil, fmt.Errorf("unrecognized node type: %s", d.Type())
+		}
+
+		return &coreiface.FilesStat{
+			Cid:            c,
+			Blocks:         len(nd.Links()),
+			Size:           d.FileSize(),
+			CumulativeSize: cumulsize,
+			Type:           ndtype,
+		}, nil
+	case *dag.RawNode:
+		return &coreiface.FilesStat{
+			Cid:            c,
+			Blocks:         0,
+			Size:           cumulsize,
+			CumulativeSize: cumulsize,
+			Type:           coreiface.TFile,
+		}, nil
+	default:
+		return nil, fmt.Errorf("not unixfs node (proto or raw)")
+	}
+}
+
+func walkBlock(ctx context.Context, dagserv ipld.DAGService, nd ipld.Node) (bool, uint64, error) {
+	// Start with the block data size
+	sizeLocal := uint64(len(nd.RawData()))
+
+	local := true
+
+	for _, link := range nd.Links() {
+		child, err := dagserv.Get(ctx, link.Cid)
+
+		if err == ipld.ErrNotFound {
+			local = false
+			continue
+		}
+
+		if err != nil {
+			return local, sizeLocal, err
+		}
+
+		childLocal, childLocalSize, err := walkBlock(ctx, dagserv, child)
+
+		if err != nil {
+			return local, sizeLocal, err
+		}
+
+		// Recursively add the child size
+		local = local && childLocal
+		sizeLocal += childLocalSize
+	}
+
+	return local, sizeLocal, nil
+}
+
+type contextReader interface {
+	CtxReadFull(context.Context, []byte) (int, error)
+}
+
+type contextReaderWrapper struct {
+	R   contextReader
+	ctx context.Context
+}
+
+func (crw *contextReaderWrapper) Read(b []byte) (int, error) {
+	return crw.R.CtxReadFull(crw.ctx, b)
+}
+
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
+
+func ensureContainingDirectoryExists(r *mfs.Root, p string, builder cid.Builder) error {
+	dirtomake := gopath.Dir(p)
+
+	if dirtomake == "/" {
+		return nil
+	}
+
+	return mfs.Mkdir(r, dirtomake, mfs.MkdirOpts{
+		Mkparents:  true,
+		CidBuilder: builder,
+	})
+}
+
+func getFileHandle(r *mfs.Root, p string, create bool, builder cid.Builder) (*mfs.File, error) {
+	target, err := mfs.Lookup(r, p)
+	switch err {
+	case nil:
+		fi, ok := target.(*mfs.File)
+		if !ok {
+			return nil, fmt.Errorf("%s was not a file", p)
+		}
+		return fi, nil
+
+	case os.ErrNotExist:
+		if !create {
+			return nil, err
+		}
+
+		// if create is specified and the file doesnt exist, we create the file
+		dirname, fname := gopath.Split(p)
+		pdiri, err := mfs.Lookup(r, dirname)
+		if err != nil {
+			log.Error("lookupfail ", dirname)
+			return nil, err
+		}
+		pdir, ok := pdiri.(*mfs.Directory)
+		if !ok {
+			return nil, fmt.Errorf("%s was not a directory", dirname)
+		}
+		if builder == nil {
+			builder = pdir.GetCidBuilder()
+		}
+
+		nd := dag.NodeWithData(ft.FilePBData(nil, 0))
+		nd.SetCidBuilder(builder)
+		err = pdir.AddChild(fname, nd)
+		if err != nil {
+			return nil, err
+		}
+
+		fsn, err := pdir.Child(fname)
+		if err != nil {
+			return nil, err
+		}
+
+		fi, ok := fsn.(*mfs.File)
+		if !ok {
+			return nil, errors.New("expected *mfs.File, didnt get it. This is likely a race condition")
+		}
+		return fi, nil
+
+	default:
+		return nil, err
+	}
+}
+
+func checkFilesPath(p string) (string, error) {
+	if len(p) == 0 {
+		return "", fmt.Errorf("paths must not be empty")
+	}
+
+	if p[0] != '/' {
+		return "", fmt.Errorf("paths must start with a leading slash")
+	}
+
+	cleaned := gopath.Clean(p)
+	if p[len(p)-1] == '/' && p != "/" {
+		cleaned += "/"
+	}
+	return cleaned, nil
+}
@@ -42,6 +42,15 @@ type CoreAPI interface {
 	// PubSub returns an implementation of PubSub API
 	PubSub() PubSubAPI
 
+	// Files returns an implementation of Files API
+	Files() FilesAPI
+
+	// P2P returns an implementation of P2P API
+	P2P() P2PAPI
+
+	// Repo returns an implementation of Repo API
+	Repo() RepoAPI
+
 	// ResolvePath resolves the path using Unixfs resolver
 	ResolvePath(context.Context, Path) (ResolvedPath, error)
 
@@ -1,6 +1,12 @@
 package iface
 
 import (
+	"context"
+	"io"
+
+	options "github.com/ipfs/go-ipfs/core/coreapi/interface/options"
+
+	cid "gx/ipfs/QmR8BauakNcBa3RbE4nbQu76PDiJgoQgz8AJdhJuiU4TAw/go-cid"
 	ipld "gx/ipfs/QmRL22E4paat7ky7vx9MLpR97JHHbFPrg3ytFQw6qp1y1s/go-ipld-format"
 )
 
@@ -10,4 +16,12 @@ type APIDagService interface {
 
 	// Pinning returns special NodeAdder which recursively pins added nodes
 	Pinning() ipld.NodeAdder
+
+	// Export writes every block reachable from the root as a CARv1 archive to
+	// the writer
+	Export(ctx context.Context, root cid.Cid, w io.Writer) error
+
+	// Import reads a CARv1 archive and stores all of its blocks. Returns the
+	// roots listed in the archive header
+	Import(ctx context.Context, r io.Reader, opts ...options.DagImportOption) ([]cid.Cid, error)
 }
@@ -0,0 +1,87 @@
+package iface
+
+import (
+	"context"
+	"io"
+
+	options "github.com/ipfs/go-ipfs/core/coreapi/interface/options"
+
+	cid "gx/ipfs/QmR8BauakNcBa3RbE4nbQu76PDiJgoQgz8AJdhJuiU4TAw/go-cid"
+)
+
+// FilesStat holds information about a file or directory in the mutable
+// filesystem
+type FilesStat struct {
+	// Cid is the CID of the node
+	Cid cid.Cid
+
+	// Type is either TFile or TDirectory
+	Type FileType
+
+	// Size is the size of the file contents
+	Size uint64
+
+	// CumulativeSize is the size of the whole tree under the node
+	CumulativeSize uint64
+
+	// Blocks is the number of child blocks of the node
+	Blocks int
+
+	// WithLocality is set when Local and SizeLocal have been computed
+	WithLocality bool
+
+	// Local is true when the whole tree under the node is stored locally
+	Local bool
+
+	// SizeLocal is the size of the part of the tree stored locally
+	SizeLocal uint64
+}
+
+// FilesEntry is an entry in a mutable filesystem directory
+type FilesEntry struct {
+	// Name is the name of the entry
+	Name string
+
+	// Type, Size and Cid are only set when listing with the Long option
+	Type FileType
+	Size int64
+	Cid  cid.Cid
+}
+
+// FilesAPI specifies the interface to the mutable filesystem (MFS). All paths
+// are absolute paths within the filesystem. Stat and Cp additionally accept
+// /ipfs/ paths as their source
+type FilesAPI interface {
+	// Stat returns information about the node at the path
+	Stat(ctx context.Context, path string, opts ...options.FilesOption) (*FilesStat, error)
+
+	// Ls lists the entries of a directory. When the path points to a file,
+	// the file itself is listed
+	Ls(ctx context.Context, path string, opts ...options.FilesOption) ([]FilesEntry, error)
+
+	// Mkdir creates a directory
+	Mkdir(ctx context.Context, path string, opts ...options.FilesOption) error
+
+	// Write writes the data from the reader to a file
+	Write(ctx context.Context, path string, r io.Reader, opts ...options.FilesOption) error
+
+	// Read returns a reader for the contents of a file. The reader must be
+	// closed when done
+	Read(ctx context.Context, path string, opts ...options.FilesOption) (io.ReadCloser, error)
+
+	// Mv moves a file or directory
+	Mv(ctx context.Context, src string, dst string, opts ...options.FilesOption) error
+
+	// Cp copies a file or directory into the filesystem. If dst ends with a
+	// slash, the base name of src is appended to it
+	Cp(ctx context.Context, src string, dst string, opts ...options.FilesOption) error
+
+	// Rm removes a file or directory
+	Rm(ctx context.Context, path string, opts ...options.FilesOption) error
+
+	// Flush writes the changes under the path up to the root
+	Flush(ctx context.Context, path string) error
+
+	// ChCid changes the CID version or hash function of a directory
+	ChCid(ctx context.Context, path string, opts ...options.FilesOption) error
+}
@@ -2,6 +2,7 @@ package iface
 
 import (
 	"context"
+	"io"
 
 	options "github.com/ipfs/go-ipfs/core/coreapi/interface/options"
 
@@ -38,4 +39,12 @@ type KeyAPI interface {
 
 	// Remove removes keys from keystore. Returns ipns path of the removed key
 	Remove(ctx context.Context, name string) (Key, error)
+
+	// Export returns the private key stored under the name, encoded in the
+	// selected format
+	Export(ctx context.Context, name string, opts ...options.KeyEncodingOption) ([]byte, error)
+
+	// Import reads an encoded private key and stores it in the keystore under
+	// the specified name
+	Import(ctx context.Context, name string, r io.Reader, opts ...options.KeyEncodingOption) (Key, error)
 }
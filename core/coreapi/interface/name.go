@@ -43,4 +43,12 @@ type NameAPI interface {
 	// Note: by default, all paths read from the channel are considered unsafe,
 	// except the latest (last path in channel read buffer).
 	Search(ctx context.Context, name string, opts ...options.NameResolveOption) (<-chan IpnsResult, error)
+
+	// CreateRecord creates and signs an IPNS record pointing to the path
+	// without publishing it, and returns it serialized
+	CreateRecord(ctx context.Context, path Path, opts ...options.NameCreateOption) ([]byte, error)
+
+	// PutRecord verifies that the serialized record is signed by the key of
+	// the name and puts it to the routing system
+	PutRecord(ctx context.Context, name string, record []byte, opts ...options.NamePutOption) error
 }
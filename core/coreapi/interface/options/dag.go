@@ -0,0 +1,34 @@
+package options
+
+type DagImportSettings struct {
+	PinRoots bool
+}
+
+type DagImportOption func(*DagImportSettings) error
+
+func DagImportOptions(opts ...DagImportOption) (*DagImportSettings, error) {
+	options := &DagImportSettings{
+		PinRoots: false,
+	}
+
+	for _, opt := range opts {
+		err := opt(options)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return options, nil
+}
+
+type dagOpts struct{}
+
+var Dag dagOpts
+
+// PinRoots is an option for Dag.Import which specifies whether to
+// recursively pin the roots listed in the archive header. Default is false
+func (dagOpts) PinRoots(pin bool) DagImportOption {
+	return func(settings *DagImportSettings) error {
+		settings.PinRoots = pin
+		return nil
+	}
+}
@@ -0,0 +1,234 @@
+package options
+
+import (
+	"fmt"
+
+	cid "gx/ipfs/QmR8BauakNcBa3RbE4nbQu76PDiJgoQgz8AJdhJuiU4TAw/go-cid"
+	dag "gx/ipfs/QmUtsx89yiCY6F8mbpP6ecXckiSzCBH7EvkKZuZEHBcr1m/go-merkledag"
+)
+
+// FilesSettings holds the settings of all FilesAPI calls. Each call only
+// uses the settings documented on it.
+type FilesSettings struct {
+	Flush bool
+
+	Parents  bool
+	Create   bool
+	Truncate bool
+
+	Offset int64
+	Count  int64
+
+	RawLeaves    bool
+	RawLeavesSet bool
+
+	CidVersion int
+	MhType     uint64
+	MhTypeSet  bool
+
+	Recursive bool
+	Force     bool
+
+	WithLocal bool
+	Long      bool
+}
+
+type FilesOption func(*FilesSettings) error
+
+func FilesOptions(opts ...FilesOption) (*FilesSettings, error) {
+	options := &FilesSettings{
+		Flush: true,
+
+		Parents:  false,
+		Create:   false,
+		Truncate: false,
+
+		Offset: 0,
+		Count:  -1,
+
+		RawLeaves:    false,
+		RawLeavesSet: false,
+
+		CidVersion: -1,
+		MhTypeSet:  false,
+
+		Recursive: false,
+		Force:     false,
+
+		WithLocal: false,
+		Long:      false,
+	}
+
+	for _, opt := range opts {
+		err := opt(options)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if options.Offset < 0 {
+		return nil, fmt.Errorf("cannot specify negative offset")
+	}
+
+	return options, nil
+}
+
+// CidBuilder returns the cid builder selected with the CidVersion and Hash
+// options, or nil if neither was set
+func (s *FilesSettings) CidBuilder() (cid.Builder, error) {
+	if s.CidVersion == -1 && !s.MhTypeSet {
+		return nil, nil
+	}
+
+	cidVer := s.CidVersion
+	if cidVer == -1 {
+		cidVer = 0
+	}
+	if s.MhTypeSet && cidVer == 0 {
+		cidVer = 1
+	}
+
+	prefix, err := dag.PrefixForCidVersion(cidVer)
+	if err != nil {
+		return nil, err
+	}
+
+	if s.MhTypeSet {
+		prefix.MhType = s.MhType
+		prefix.MhLength = -1
+	}
+
+	return &prefix, nil
+}
+
+type filesOpts struct{}
+
+var Files filesOpts
+
+// Flush is an option for Files.Mkdir, Files.Write, Files.Mv, Files.Cp and
+// Files.ChCid which specifies whether to flush the changes up to the root.
+// Default is true
+func (filesOpts) Flush(flush bool) FilesOption {
+	return func(settings *FilesSettings) error {
+		settings.Flush = flush
+		return nil
+	}
+}
+
+// Parents is an option for Files.Mkdir and Files.Write which specifies whether
+// to create missing parent directories. For Files.Mkdir it also makes it not
+// fail when the directory already exists. Default is false
+func (filesOpts) Parents(parents bool) FilesOption {
+	return func(settings *FilesSettings) error {
+		settings.Parents = parents
+		return nil
+	}
+}
+
+// Create is an option for Files.Write which specifies whether to create the
+// file if it doesn't exist. Default is false
+func (filesOpts) Create(create bool) FilesOption {
+	return func(settings *FilesSettings) error {
+		settings.Create = create
+		return nil
+	}
+}
+
+// Truncate is an option for Files.Write which specifies whether to truncate
+// the file to size zero before writing. Default is false
+func (filesOpts) Truncate(truncate bool) FilesOption {
+	return func(settings *FilesSettings) error {
+		settings.Truncate = truncate
+		return nil
+	}
+}
+
+// Offset is an option for Files.Read and Files.Write which specifies the
+// byte offset to start at. Default is 0
+func (filesOpts) Offset(offset int64) FilesOption {
+	return func(settings *FilesSettings) error {
+		settings.Offset = offset
+		return nil
+	}
+}
+
+// Count is an option for Files.Read and Files.Write which specifies the
+// maximum number of bytes to read or write. Default is -1 (no limit)
+func (filesOpts) Count(count int64) FilesOption {
+	return func(settings *FilesSettings) error {
+		if count < 0 {
+			return fmt.Errorf("cannot specify negative count")
+		}
+		settings.Count = count
+		return nil
+	}
+}
+
+// RawLeaves is an option for Files.Write which specifies whether to use raw
+// blocks for newly created leaf nodes. By default this depends on the CID
+// version of the file
+func (filesOpts) RawLeaves(enable bool) FilesOption {
+	return func(settings *FilesSettings) error {
+		settings.RawLeaves = enable
+		settings.RawLeavesSet = true
+		return nil
+	}
+}
+
+// CidVersion is an option for Files.Mkdir, Files.Write and Files.ChCid which
+// specifies the CID version to use for new or updated nodes. By default the
+// CID version of the parent directory is used
+func (filesOpts) CidVersion(version int) FilesOption {
+	return func(settings *FilesSettings) error {
+		settings.CidVersion = version
+		return nil
+	}
+}
+
+// Hash is an option for Files.Mkdir, Files.Write and Files.ChCid which
+// specifies the multihash type to use for new or updated nodes. Setting it
+// implies CID version 1 unless a version is set with the CidVersion option.
+// By default the hash function of the parent directory is used
+func (filesOpts) Hash(mhType uint64) FilesOption {
+	return func(settings *FilesSettings) error {
+		settings.MhType = mhType
+		settings.MhTypeSet = true
+		return nil
+	}
+}
+
+// Recursive is an option for Files.Rm which specifies whether to remove
+// directories. Default is false
+func (filesOpts) Recursive(recursive bool) FilesOption {
+	return func(settings *FilesSettings) error {
+		settings.Recursive = recursive
+		return nil
+	}
+}
+
+// Force is an option for Files.Rm which specifies whether to remove anything
+// at the path, including corrupted nodes. Implies Recursive. Default is false
+func (filesOpts) Force(force bool) FilesOption {
+	return func(settings *FilesSettings) error {
+		settings.Force = force
+		return nil
+	}
+}
+
+// WithLocal is an option for Files.Stat which specifies whether to compute
+// how much of the DAG is available locally. Default is false
+func (filesOpts) WithLocal(withLocal bool) FilesOption {
+	return func(settings *FilesSettings) error {
+		settings.WithLocal = withLocal
+		return nil
+	}
+}
+
+// Long is an option for Files.Ls which specifies whether to resolve the type,
+// size and CID of the listed entries. Default is false
+func (filesOpts) Long(long bool) FilesOption {
+	return func(settings *FilesSettings) error {
+		settings.Long = long
+		return nil
+	}
+}
@@ -5,6 +5,10 @@ const (
 	Ed25519Key = "ed25519"
 
 	DefaultRSALen = 2048
+
+	KeyFormatLibp2pProtobuf = "libp2p-protobuf-cleartext"
+	KeyFormatPEMPKCS8       = "pem-pkcs8-cleartext"
+	KeyFormatEncrypted      = "libp2p-protobuf-encrypted"
 )
 
 type KeyGenerateSettings struct {
@@ -16,8 +20,14 @@ type KeyRenameSettings struct {
 	Force bool
 }
 
+type KeyEncodingSettings struct {
+	Format     string
+	Passphrase []byte
+}
+
 type KeyGenerateOption func(*KeyGenerateSettings) error
 type KeyRenameOption func(*KeyRenameSettings) error
+type KeyEncodingOption func(*KeyEncodingSettings) error
 
 func KeyGenerateOptions(opts ...KeyGenerateOption) (*KeyGenerateSettings, error) {
 	options := &KeyGenerateSettings{
@@ -48,6 +58,21 @@ func KeyRenameOptions(opts ...KeyRenameOption) (*KeyRenameSettings, error) {
 	return options, nil
 }
 
+func KeyEncodingOptions(opts ...KeyEncodingOption) (*KeyEncodingSettings, error) {
+	options := &KeyEncodingSettings{
+		Format:     KeyFormatLibp2pProtobuf,
+		Passphrase: nil,
+	}
+
+	for _, opt := range opts {
+		err := opt(options)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return options, nil
+}
+
 type keyOpts struct{}
 
 var Key keyOpts
@@ -85,3 +110,27 @@ func (keyOpts) Force(force bool) KeyRenameOption {
 		return nil
 	}
 }
+
+// Format is an option for Key.Export and Key.Import which specifies the
+// encoding of the key. Default is options.KeyFormatLibp2pProtobuf
+//
+// Supported formats:
+// * options.KeyFormatLibp2pProtobuf
+// * options.KeyFormatPEMPKCS8 (RSA and Ed25519 keys only)
+// * options.KeyFormatEncrypted
+func (keyOpts) Format(format string) KeyEncodingOption {
+	return func(settings *KeyEncodingSettings) error {
+		settings.Format = format
+		return nil
+	}
+}
+
+// Passphrase is an option for Key.Export and Key.Import which specifies the
+// passphrase used to encrypt or decrypt the key. It is required by, and only
+// allowed with, options.KeyFormatEncrypted
+func (keyOpts) Passphrase(passphrase string) KeyEncodingOption {
+	return func(settings *KeyEncodingSettings) error {
+		settings.Passphrase = []byte(passphrase)
+		return nil
+	}
+}
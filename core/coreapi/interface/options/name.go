@@ -25,8 +25,22 @@ type NameResolveSettings struct {
 	ResolveOpts []ropts.ResolveOpt
 }
 
+type NameCreateSettings struct {
+	ValidTime time.Duration
+	Key       string
+
+	TTL      *time.Duration
+	Sequence *uint64
+}
+
+type NamePutSettings struct {
+	AllowOffline bool
+}
+
 type NamePublishOption func(*NamePublishSettings) error
 type NameResolveOption func(*NameResolveSettings) error
+type NameCreateOption func(*NameCreateSettings) error
+type NamePutOption func(*NamePutSettings) error
 
 func NamePublishOptions(opts ...NamePublishOption) (*NamePublishSettings, error) {
 	options := &NamePublishSettings{
@@ -61,6 +75,37 @@ func NameResolveOptions(opts ...NameResolveOption) (*NameResolveSettings, error)
 	return options, nil
 }
 
+func NameCreateOptions(opts ...NameCreateOption) (*NameCreateSettings, error) {
+	options := &NameCreateSettings{
+		ValidTime: DefaultNameValidTime,
+		Key:       "self",
+	}
+
+	for _, opt := range opts {
+		err := opt(options)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return options, nil
+}
+
+func NamePutOptions(opts ...NamePutOption) (*NamePutSettings, error) {
+	options := &NamePutSettings{
+		AllowOffline: false,
+	}
+
+	for _, opt := range opts {
+		err := opt(options)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return options, nil
+}
+
 type nameOpts struct{}
 
 var Name nameOpts
@@ -120,3 +165,50 @@ func (nameOpts) ResolveOption(opt ropts.ResolveOpt) NameResolveOption {
 		return nil
 	}
 }
+
+// CreateValidTime is an option for Name.CreateRecord which specifies for how
+// long the record will remain valid. Default value is 24h
+func (nameOpts) CreateValidTime(validTime time.Duration) NameCreateOption {
+	return func(settings *NameCreateSettings) error {
+		settings.ValidTime = validTime
+		return nil
+	}
+}
+
+// CreateKey is an option for Name.CreateRecord which specifies the key to
+// sign the record with. Default value is "self" which is the node's own
+// PeerID. The key parameter must be either PeerID or keystore key alias.
+func (nameOpts) CreateKey(key string) NameCreateOption {
+	return func(settings *NameCreateSettings) error {
+		settings.Key = key
+		return nil
+	}
+}
+
+// CreateTTL is an option for Name.CreateRecord which specifies the time
+// duration the record should be cached for (caution: experimental).
+func (nameOpts) CreateTTL(ttl time.Duration) NameCreateOption {
+	return func(settings *NameCreateSettings) error {
+		settings.TTL = &ttl
+		return nil
+	}
+}
+
+// Sequence is an option for Name.CreateRecord which specifies the sequence
+// number of the record. By default, the sequence number of the last record
+// published locally with the key is used, incremented if the value changes.
+func (nameOpts) Sequence(seq uint64) NameCreateOption {
+	return func(settings *NameCreateSettings) error {
+		settings.Sequence = &seq
+		return nil
+	}
+}
+
+// PutAllowOffline is an option for Name.PutRecord which specifies whether to
+// allow storing the record when the node is offline. Default value is false
+func (nameOpts) PutAllowOffline(allow bool) NamePutOption {
+	return func(settings *NamePutSettings) error {
+		settings.AllowOffline = allow
+		return nil
+	}
+}
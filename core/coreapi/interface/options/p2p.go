@@ -0,0 +1,113 @@
+package options
+
+import (
+	peer "gx/ipfs/QmPJxxDsX2UbchSHobbYuvz7qnyJTFKvaKMzE2rZWJ4x5B/go-libp2p-peer"
+)
+
+type P2PForwardSettings struct {
+	AllowCustomProtocol bool
+	MaxRate             uint64
+}
+
+type P2PListenSettings struct {
+	AllowCustomProtocol bool
+	ReportPeerID        bool
+	AllowedPeers        []peer.ID
+	MaxRate             uint64
+}
+
+type P2PForwardOption func(*P2PForwardSettings) error
+type P2PListenOption func(*P2PListenSettings) error
+
+func P2PForwardOptions(opts ...P2PForwardOption) (*P2PForwardSettings, error) {
+	options := &P2PForwardSettings{
+		AllowCustomProtocol: false,
+		MaxRate:             0,
+	}
+
+	for _, opt := range opts {
+		err := opt(options)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return options, nil
+}
+
+func P2PListenOptions(opts ...P2PListenOption) (*P2PListenSettings, error) {
+	options := &P2PListenSettings{
+		AllowCustomProtocol: false,
+		ReportPeerID:        false,
+		AllowedPeers:        nil,
+		MaxRate:             0,
+	}
+
+	for _, opt := range opts {
+		err := opt(options)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return options, nil
+}
+
+type p2pOpts struct{}
+
+var P2P p2pOpts
+
+// ForwardAllowCustomProtocol is an option for P2P.Forward which allows
+// protocols outside of the /x/ namespace. Default: false
+func (p2pOpts) ForwardAllowCustomProtocol(allow bool) P2PForwardOption {
+	return func(settings *P2PForwardSettings) error {
+		settings.AllowCustomProtocol = allow
+		return nil
+	}
+}
+
+// ListenAllowCustomProtocol is an option for P2P.Listen which allows
+// protocols outside of the /x/ namespace. Default: false
+func (p2pOpts) ListenAllowCustomProtocol(allow bool) P2PListenOption {
+	return func(settings *P2PListenSettings) error {
+		settings.AllowCustomProtocol = allow
+		return nil
+	}
+}
+
+// ReportPeerID is an option for P2P.Listen which makes the listener send the
+// base58 peer ID of the remote peer to the target, followed by a newline,
+// before any data. Default: false
+func (p2pOpts) ReportPeerID(report bool) P2PListenOption {
+	return func(settings *P2PListenSettings) error {
+		settings.ReportPeerID = report
+		return nil
+	}
+}
+
+// AllowedPeers is an option for P2P.Listen which restricts the peers allowed
+// to open streams, the streams of the other peers are reset. Default: any peer
+func (p2pOpts) AllowedPeers(peers ...peer.ID) P2PListenOption {
+	return func(settings *P2PListenSettings) error {
+		settings.AllowedPeers = append(settings.AllowedPeers, peers...)
+		return nil
+	}
+}
+
+// ForwardMaxRate is an option for P2P.Forward which caps the bandwidth of the
+// streams of the forward in each direction, in bytes per second. Default: 0,
+// no cap
+func (p2pOpts) ForwardMaxRate(rate uint64) P2PForwardOption {
+	return func(settings *P2PForwardSettings) error {
+		settings.MaxRate = rate
+		return nil
+	}
+}
+
+// ListenMaxRate is an option for P2P.Listen which caps the bandwidth of the
+// streams of the listener in each direction, in bytes per second. Default: 0,
+// no cap
+func (p2pOpts) ListenMaxRate(rate uint64) P2PListenOption {
+	return func(settings *P2PListenSettings) error {
+		settings.MaxRate = rate
+		return nil
+	}
+}
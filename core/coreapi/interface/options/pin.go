@@ -1,11 +1,19 @@
 package options
 
+import (
+	"fmt"
+)
+
 type PinAddSettings struct {
 	Recursive bool
+	Name      string
+	Meta      map[string]string
 }
 
 type PinLsSettings struct {
-	Type string
+	Type       string
+	NamePrefix string
+	Meta       map[string]string
 }
 
 // PinRmSettings represents the settings of pin rm command
@@ -29,6 +37,8 @@ type PinUpdateOption func(*PinUpdateSettings) error
 func PinAddOptions(opts ...PinAddOption) (*PinAddSettings, error) {
 	options := &PinAddSettings{
 		Recursive: true,
+		Name:      "",
+		Meta:      nil,
 	}
 
 	for _, opt := range opts {
@@ -58,7 +68,9 @@ func PinRmOptions(opts ...PinRmOption) (*PinRmSettings, error) {
 
 func PinLsOptions(opts ...PinLsOption) (*PinLsSettings, error) {
 	options := &PinLsSettings{
-		Type: "all",
+		Type:       "all",
+		NamePrefix: "",
+		Meta:       nil,
 	}
 
 	for _, opt := range opts {
@@ -127,6 +139,53 @@ func (pinOpts) Recursive(recursive bool) PinAddOption {
 	}
 }
 
+// Name is an option for Pin.Add which specifies a name for the pin. Adding
+// an existing pin with a name renames it. Default is no name
+func (pinOpts) Name(name string) PinAddOption {
+	return func(settings *PinAddSettings) error {
+		settings.Name = name
+		return nil
+	}
+}
+
+// Meta is an option for Pin.Add which adds a key/value pair to the metadata
+// of the pin. It can be given multiple times. Adding an existing pin with
+// metadata replaces its metadata
+func (pinOpts) Meta(key, value string) PinAddOption {
+	return func(settings *PinAddSettings) error {
+		if key == "" {
+			return fmt.Errorf("pin metadata key cannot be empty")
+		}
+		if settings.Meta == nil {
+			settings.Meta = make(map[string]string)
+		}
+		settings.Meta[key] = value
+		return nil
+	}
+}
+
+// NamePrefix is an option for Pin.Ls which will make it only return the
+// direct and recursive pins whose name starts with the prefix
+func (pinOpts) NamePrefix(prefix string) PinLsOption {
+	return func(settings *PinLsSettings) error {
+		settings.NamePrefix = prefix
+		return nil
+	}
+}
+
+// MetaMatch is an option for Pin.Ls which will make it only return the direct
+// and recursive pins whose metadata has the key set to the value. It can be
+// given multiple times, all pairs must match
+func (pinOpts) MetaMatch(key, value string) PinLsOption {
+	return func(settings *PinLsSettings) error {
+		if settings.Meta == nil {
+			settings.Meta = make(map[string]string)
+		}
+		settings.Meta[key] = value
+		return nil
+	}
+}
+
 // RmRecursive is an option for Pin.Rm
 func (pinOpts) RmRecursive(recursive bool) PinRmOption {
 	return func(settings *PinRmSettings) error {
@@ -0,0 +1,87 @@
+package options
+
+import (
+	"fmt"
+	"time"
+)
+
+type RepoStatSettings struct {
+	SizeOnly bool
+}
+
+type RepoGcSettings struct {
+	MaxDuration time.Duration
+	Target      uint64
+}
+
+type RepoStatOption func(*RepoStatSettings) error
+type RepoGcOption func(*RepoGcSettings) error
+
+func RepoStatOptions(opts ...RepoStatOption) (*RepoStatSettings, error) {
+	options := &RepoStatSettings{
+		SizeOnly: false,
+	}
+
+	for _, opt := range opts {
+		err := opt(options)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return options, nil
+}
+
+func RepoGcOptions(opts ...RepoGcOption) (*RepoGcSettings, error) {
+	options := &RepoGcSettings{
+		MaxDuration: 0,
+		Target:      0,
+	}
+
+	for _, opt := range opts {
+		err := opt(options)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return options, nil
+}
+
+type repoOpts struct{}
+
+var Repo repoOpts
+
+// SizeOnly is an option for Repo.Stat which skips counting the objects of the
+// repo, only the repo size and the storage limit are returned. Default is
+// false
+func (repoOpts) SizeOnly(sizeOnly bool) RepoStatOption {
+	return func(settings *RepoStatSettings) error {
+		settings.SizeOnly = sizeOnly
+		return nil
+	}
+}
+
+// MaxDuration is an option for Repo.GC which runs an incremental collection,
+// stopping after the given duration. The next collection continues where it
+// stopped
+func (repoOpts) MaxDuration(d time.Duration) RepoGcOption {
+	return func(settings *RepoGcSettings) error {
+		if d <= 0 {
+			return fmt.Errorf("max duration must be positive")
+		}
+		settings.MaxDuration = d
+		return nil
+	}
+}
+
+// Target is an option for Repo.GC which runs an incremental collection,
+// stopping after freeing the given number of bytes. The next collection
+// continues where it stopped
+func (repoOpts) Target(bytes uint64) RepoGcOption {
+	return func(settings *RepoGcSettings) error {
+		if bytes == 0 {
+			return fmt.Errorf("target must be positive")
+		}
+		settings.Target = bytes
+		return nil
+	}
+}
@@ -0,0 +1,89 @@
+package iface
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	options "github.com/ipfs/go-ipfs/core/coreapi/interface/options"
+
+	ma "gx/ipfs/QmNTCey11oxhb1AxDnQBRHtdhap6Ctud872NjAYPYYXPuc/go-multiaddr"
+	peer "gx/ipfs/QmPJxxDsX2UbchSHobbYuvz7qnyJTFKvaKMzE2rZWJ4x5B/go-libp2p-peer"
+	protocol "gx/ipfs/QmZNkThpqfVXs9GNbexPrfBbXSLNYeKrE7jwFM2oqHbyqN/go-libp2p-protocol"
+)
+
+var (
+	ErrListenerNotFound = errors.New("listener not found")
+	ErrStreamNotFound   = errors.New("stream not found")
+)
+
+// P2PListener is a listener forwarding connections between local sockets and
+// libp2p streams
+type P2PListener struct {
+	// Protocol is the libp2p protocol of the streams
+	Protocol protocol.ID
+
+	// ListenAddress is the local address of a forward, or the /ipfs/ address
+	// of the node for a listener accepting libp2p streams
+	ListenAddress ma.Multiaddr
+
+	// TargetAddress is the /ipfs/ address of the remote peer of a forward, or
+	// the local address the libp2p streams are forwarded to
+	TargetAddress ma.Multiaddr
+
+	// AllowedPeers are the peers allowed to open streams, nil if any peer is
+	AllowedPeers []peer.ID
+
+	// BytesIn and BytesOut are the bytes received from and sent to the
+	// remote peers by the streams of the listener
+	BytesIn  uint64
+	BytesOut uint64
+
+	// MaxRate is the bandwidth cap of the streams in each direction, in bytes
+	// per second, 0 if there is none
+	MaxRate uint64
+}
+
+// P2PStream is an active stream between a local socket and a libp2p stream
+type P2PStream struct {
+	// ID identifies the stream on the node
+	ID uint64
+
+	Protocol      protocol.ID
+	OriginAddress ma.Multiaddr
+	TargetAddress ma.Multiaddr
+
+	// Peer is the remote peer of the stream
+	Peer peer.ID
+
+	// BytesIn and BytesOut are the bytes received from and sent to the
+	// remote peer
+	BytesIn  uint64
+	BytesOut uint64
+
+	// Started is when the stream was opened
+	Started time.Time
+}
+
+// P2PAPI specifies the interface to libp2p stream mounting
+type P2PAPI interface {
+	// Forward forwards the connections made to the listen address to the
+	// libp2p service of the target peer
+	Forward(ctx context.Context, proto protocol.ID, listen ma.Multiaddr, target peer.ID, opts ...options.P2PForwardOption) (P2PListener, error)
+
+	// Listen forwards the libp2p streams of the protocol to the target address
+	Listen(ctx context.Context, proto protocol.ID, target ma.Multiaddr, opts ...options.P2PListenOption) (P2PListener, error)
+
+	// ListListeners returns the active forwards and listeners
+	ListListeners(context.Context) ([]P2PListener, error)
+
+	// ListStreams returns the active streams
+	ListStreams(context.Context) ([]P2PStream, error)
+
+	// CloseListener stops the forward or listener with the protocol and
+	// addresses of the given one. Its active streams are left open.
+	CloseListener(context.Context, P2PListener) error
+
+	// CloseStream resets the stream with the given ID
+	CloseStream(ctx context.Context, id uint64) error
+}
@@ -13,6 +13,13 @@ type Pin interface {
 
 	// Type of the pin
 	Type() string
+
+	// Name of the pin, empty if it has none. Only direct and recursive pins
+	// can have a name
+	Name() string
+
+	// Meta returns the metadata of the pin, nil if it has none
+	Meta() map[string]string
 }
 
 // PinStatus holds information about pin health
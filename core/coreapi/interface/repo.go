@@ -0,0 +1,66 @@
+package iface
+
+import (
+	"context"
+
+	options "github.com/ipfs/go-ipfs/core/coreapi/interface/options"
+)
+
+// RepoStat holds information about the repo
+type RepoStat struct {
+	// RepoSize is the size in bytes the repo takes
+	RepoSize uint64
+
+	// StorageMax is the maximum size in bytes of the datastore, from the
+	// config. It's math.MaxUint64 when there is no limit
+	StorageMax uint64
+
+	// NumObjects is the number of blocks in the repo. It's only set when
+	// not stating with the SizeOnly option
+	NumObjects uint64
+
+	// RepoPath is the path of the repo, only set when not stating with the
+	// SizeOnly option
+	RepoPath string
+
+	// Version is the repo version, e.g. "fs-repo@7", only set when not
+	// stating with the SizeOnly option
+	Version string
+}
+
+// GcResult is a result of a garbage collection run: either a removed block
+// or an error
+type GcResult struct {
+	// Removed is the path of the removed block
+	Removed ResolvedPath
+
+	// Err is set when the run ran into an error
+	Err error
+}
+
+// VerifyResult is a corrupted block found by Repo.Verify
+type VerifyResult struct {
+	// Path is the path of the corrupted block
+	Path ResolvedPath
+
+	// Err is the reason why the block is corrupted
+	Err error
+}
+
+// RepoAPI specifies the interface to the repo
+type RepoAPI interface {
+	// Stat returns information about the repo
+	Stat(context.Context, ...options.RepoStatOption) (*RepoStat, error)
+
+	// GC removes the blocks which are neither pinned nor referenced by the
+	// mutable filesystem, streaming the removed blocks and the errors. The
+	// channel is closed once the run is done
+	GC(context.Context, ...options.RepoGcOption) (<-chan GcResult, error)
+
+	// Verify rehashes all the blocks of the repo, streaming the corrupted
+	// ones. The channel is closed once all blocks are verified
+	Verify(context.Context) (<-chan VerifyResult, error)
+
+	// Version returns the version of the repo format
+	Version(context.Context) (int, error)
+}
@@ -61,12 +61,15 @@ func TestApi(p Provider) func(t *testing.T) {
 		t.Run("Block", tp.TestBlock)
 		t.Run("Dag", tp.TestDag)
 		t.Run("Dht", tp.TestDht)
+		t.Run("Files", tp.TestFiles)
 		t.Run("Key", tp.TestKey)
 		t.Run("Name", tp.TestName)
 		t.Run("Object", tp.TestObject)
+		t.Run("P2P", tp.TestP2P)
 		t.Run("Path", tp.TestPath)
 		t.Run("Pin", tp.TestPin)
 		t.Run("PubSub", tp.TestPubSub)
+		t.Run("Repo", tp.TestRepo)
 		t.Run("Unixfs", tp.TestUnixfs)
 
 		apis <- -1
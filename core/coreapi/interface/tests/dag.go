@@ -1,6 +1,7 @@
 package tests
 
 import (
+	"bytes"
 	"context"
 	"math"
 	"path"
@@ -8,6 +9,7 @@ import (
 	"testing"
 
 	coreiface "github.com/ipfs/go-ipfs/core/coreapi/interface"
+	opt "github.com/ipfs/go-ipfs/core/coreapi/interface/options"
 
 	ipld "gx/ipfs/QmRL22E4paat7ky7vx9MLpR97JHHbFPrg3ytFQw6qp1y1s/go-ipld-format"
 	ipldcbor "gx/ipfs/QmRZxJ7oybgnnwriuRub9JXp5YdFM9wiGSyRq38QC7swpS/go-ipld-cbor"
@@ -27,6 +29,7 @@ func (tp *provider) TestDag(t *testing.T) {
 	t.Run("TestPath", tp.TestDagPath)
 	t.Run("TestTree", tp.TestTree)
 	t.Run("TestBatch", tp.TestBatch)
+	t.Run("TestExportImport", tp.TestDagExportImport)
 }
 
 var (
@@ -199,3 +202,55 @@ func (tp *provider) TestBatch(t *testing.T) {
 		t.Error(err)
 	}
 }
+
+func (tp *provider) TestDagExportImport(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	apis, err := tp.MakeAPISwarm(ctx, false, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	snd, err := ipldcbor.FromJSON(strings.NewReader(`"foo"`), math.MaxUint64, -1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	nd, err := ipldcbor.FromJSON(strings.NewReader(`{"lnk": {"/": "`+snd.Cid().String()+`"}}`), math.MaxUint64, -1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := apis[0].Dag().AddMany(ctx, []ipld.Node{snd, nd}); err != nil {
+		t.Fatal(err)
+	}
+
+	buf := new(bytes.Buffer)
+	if err := apis[0].Dag().Export(ctx, nd.Cid(), buf); err != nil {
+		t.Fatal(err)
+	}
+
+	roots, err := apis[1].Dag().Import(ctx, buf, opt.Dag.PinRoots(true))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(roots) != 1 || !roots[0].Equals(nd.Cid()) {
+		t.Fatalf("unexpected roots: %v", roots)
+	}
+
+	for _, c := range []ipld.Node{snd, nd} {
+		if _, err := apis[1].Dag().Get(ctx, c.Cid()); err != nil {
+			t.Error(err)
+		}
+	}
+
+	pins, err := apis[1].Pin().Ls(ctx, opt.Pin.Type.Recursive())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(pins) != 1 || !pins[0].Path().Cid().Equals(nd.Cid()) {
+		t.Errorf("expected imported root to be pinned, got %v", pins)
+	}
+}
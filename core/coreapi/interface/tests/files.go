@@ -0,0 +1,363 @@
+package tests
+
+import (
+	"context"
+	"io/ioutil"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/ipfs/go-ipfs/core/coreapi/interface"
+	opt "github.com/ipfs/go-ipfs/core/coreapi/interface/options"
+)
+
+func (tp *provider) TestFiles(t *testing.T) {
+	tp.hasApi(t, func(api iface.CoreAPI) error {
+		if api.Files() == nil {
+			return apiNotImplemented
+		}
+		return nil
+	})
+
+	t.Run("TestFilesWriteRead", tp.TestFilesWriteRead)
+	t.Run("TestFilesMkdirLs", tp.TestFilesMkdirLs)
+	t.Run("TestFilesStat", tp.TestFilesStat)
+	t.Run("TestFilesMvCp", tp.TestFilesMvCp)
+	t.Run("TestFilesCpIpfsPath", tp.TestFilesCpIpfsPath)
+	t.Run("TestFilesRm", tp.TestFilesRm)
+	t.Run("TestFilesChCid", tp.TestFilesChCid)
+	t.Run("TestFilesRelativePath", tp.TestFilesRelativePath)
+}
+
+func (tp *provider) TestFilesWriteRead(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	api, err := tp.makeAPI(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = api.Files().Write(ctx, "/foo", strings.NewReader("hello"))
+	if err == nil {
+		t.Fatal("expected write to a missing file without Create to fail")
+	}
+
+	err = api.Files().Write(ctx, "/a/b/foo", strings.NewReader("hello world"), opt.Files.Create(true), opt.Files.Parents(true))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := api.Files().Read(ctx, "/a/b/foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := ioutil.ReadAll(r)
+	r.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "hello world" {
+		t.Errorf("unexpected file contents: %q", data)
+	}
+
+	r, err = api.Files().Read(ctx, "/a/b/foo", opt.Files.Offset(6), opt.Files.Count(3))
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err = ioutil.ReadAll(r)
+	r.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "wor" {
+		t.Errorf("unexpected partial contents: %q", data)
+	}
+
+	err = api.Files().Write(ctx, "/a/b/foo", strings.NewReader("bye"), opt.Files.Truncate(true))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r, err = api.Files().Read(ctx, "/a/b/foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err = ioutil.ReadAll(r)
+	r.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "bye" {
+		t.Errorf("unexpected contents after truncate: %q", data)
+	}
+
+	if _, err := api.Files().Read(ctx, "/a/b/foo", opt.Files.Offset(10)); err == nil {
+		t.Error("expected read past the end of the file to fail")
+	}
+
+	if _, err := api.Files().Read(ctx, "/a/b"); err == nil {
+		t.Error("expected reading a directory to fail")
+	}
+}
+
+func (tp *provider) TestFilesMkdirLs(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	api, err := tp.makeAPI(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := api.Files().Mkdir(ctx, "/x/y"); err == nil {
+		t.Fatal("expected mkdir without Parents to fail")
+	}
+
+	if err := api.Files().Mkdir(ctx, "/x/y", opt.Files.Parents(true)); err != nil {
+		t.Fatal(err)
+	}
+
+	err = api.Files().Write(ctx, "/x/file", strings.NewReader("abc"), opt.Files.Create(true))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	entries, err := api.Files().Ls(ctx, "/x")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var names []string
+	for _, e := range entries {
+		names = append(names, e.Name)
+	}
+	sort.Strings(names)
+	if strings.Join(names, ",") != "file,y" {
+		t.Errorf("unexpected listing: %v", names)
+	}
+
+	entries, err = api.Files().Ls(ctx, "/x", opt.Files.Long(true))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, e := range entries {
+		switch e.Name {
+		case "file":
+			if e.Type != iface.TFile || e.Size != 3 {
+				t.Errorf("unexpected entry for file: %+v", e)
+			}
+		case "y":
+			if e.Type != iface.TDirectory {
+				t.Errorf("unexpected entry for dir: %+v", e)
+			}
+		}
+		if !e.Cid.Defined() {
+			t.Errorf("entry %s has no cid", e.Name)
+		}
+	}
+
+	entries, err = api.Files().Ls(ctx, "/x/file")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 || entries[0].Name != "file" {
+		t.Errorf("unexpected listing of a file: %+v", entries)
+	}
+}
+
+func (tp *provider) TestFilesStat(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	api, err := tp.makeAPI(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = api.Files().Write(ctx, "/file", strings.NewReader("hello"), opt.Files.Create(true))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	st, err := api.Files().Stat(ctx, "/file", opt.Files.WithLocal(true))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if st.Type != iface.TFile {
+		t.Errorf("expected a file, got %d", st.Type)
+	}
+	if st.Size != 5 {
+		t.Errorf("expected size 5, got %d", st.Size)
+	}
+	if !st.WithLocality || !st.Local {
+		t.Errorf("expected the file to be local: %+v", st)
+	}
+
+	st, err = api.Files().Stat(ctx, "/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if st.Type != iface.TDirectory {
+		t.Errorf("expected a directory, got %d", st.Type)
+	}
+	if st.WithLocality {
+		t.Error("locality should only be computed with WithLocal")
+	}
+}
+
+func (tp *provider) TestFilesMvCp(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	api, err := tp.makeAPI(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = api.Files().Write(ctx, "/src", strings.NewReader("data"), opt.Files.Create(true))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := api.Files().Mkdir(ctx, "/dir"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := api.Files().Cp(ctx, "/src", "/dir/"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := api.Files().Mv(ctx, "/src", "/moved"); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := api.Files().Stat(ctx, "/src"); err == nil {
+		t.Error("expected source to be gone after mv")
+	}
+
+	a, err := api.Files().Stat(ctx, "/dir/src")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := api.Files().Stat(ctx, "/moved")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !a.Cid.Equals(b.Cid) {
+		t.Errorf("copy and moved file differ: %s != %s", a.Cid, b.Cid)
+	}
+}
+
+func (tp *provider) TestFilesCpIpfsPath(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	api, err := tp.makeAPI(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = api.Files().Write(ctx, "/file", strings.NewReader("hello"), opt.Files.Create(true))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	st, err := api.Files().Stat(ctx, "/file")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := api.Files().Cp(ctx, "/ipfs/"+st.Cid.String(), "/copy"); err != nil {
+		t.Fatal(err)
+	}
+
+	cst, err := api.Files().Stat(ctx, "/copy")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !cst.Cid.Equals(st.Cid) {
+		t.Errorf("expected %s, got %s", st.Cid, cst.Cid)
+	}
+}
+
+func (tp *provider) TestFilesRm(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	api, err := tp.makeAPI(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := api.Files().Mkdir(ctx, "/dir"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := api.Files().Rm(ctx, "/"); err == nil {
+		t.Error("expected removing root to fail")
+	}
+
+	if err := api.Files().Rm(ctx, "/dir"); err == nil {
+		t.Error("expected removing a directory without Recursive to fail")
+	}
+
+	if err := api.Files().Rm(ctx, "/dir/", opt.Files.Recursive(true)); err != nil {
+		t.Fatal(err)
+	}
+
+	entries, err := api.Files().Ls(ctx, "/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 0 {
+		t.Errorf("expected root to be empty, got %+v", entries)
+	}
+}
+
+func (tp *provider) TestFilesChCid(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	api, err := tp.makeAPI(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := api.Files().Mkdir(ctx, "/dir"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := api.Files().ChCid(ctx, "/dir", opt.Files.CidVersion(1)); err != nil {
+		t.Fatal(err)
+	}
+
+	st, err := api.Files().Stat(ctx, "/dir")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if st.Cid.Prefix().Version != 1 {
+		t.Errorf("expected cidv1, got %s", st.Cid)
+	}
+
+	err = api.Files().Write(ctx, "/dir/file", strings.NewReader("x"), opt.Files.Create(true))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := api.Files().ChCid(ctx, "/dir/file", opt.Files.CidVersion(1)); err == nil {
+		t.Error("expected chcid on a file to fail")
+	}
+}
+
+func (tp *provider) TestFilesRelativePath(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	api, err := tp.makeAPI(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := api.Files().Mkdir(ctx, "dir"); err == nil {
+		t.Error("expected relative path to be rejected")
+	}
+
+	if _, err := api.Files().Stat(ctx, ""); err == nil {
+		t.Error("expected empty path to be rejected")
+	}
+}
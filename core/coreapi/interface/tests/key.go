@@ -1,6 +1,7 @@
 package tests
 
 import (
+	"bytes"
 	"context"
 	"strings"
 	"testing"
@@ -33,6 +34,10 @@ func (tp *provider) TestKey(t *testing.T) {
 	t.Run("TestRenameSameNameNoForce", tp.TestRenameSameNameNoForce)
 	t.Run("TestRenameSameName", tp.TestRenameSameName)
 	t.Run("TestRemove", tp.TestRemove)
+	t.Run("TestExportImport", tp.TestExportImport)
+	t.Run("TestExportImportEncrypted", tp.TestExportImportEncrypted)
+	t.Run("TestExportSelf", tp.TestExportSelf)
+	t.Run("TestImportExisting", tp.TestImportExisting)
 }
 
 func (tp *provider) TestListSelf(t *testing.T) {
@@ -521,3 +526,140 @@ func (tp *provider) TestRemove(t *testing.T) {
 		t.Errorf("expected the key to be called 'self', got '%s'", l[0].Name())
 	}
 }
+
+func (tp *provider) TestExportImport(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	apis, err := tp.MakeAPISwarm(ctx, true, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, typ := range []string{opt.RSAKey, opt.Ed25519Key} {
+		for _, format := range []string{opt.KeyFormatLibp2pProtobuf, opt.KeyFormatPEMPKCS8} {
+			name := typ + "-" + format
+
+			k, err := apis[0].Key().Generate(ctx, name, opt.Key.Type(typ), opt.Key.Size(1024))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			data, err := apis[0].Key().Export(ctx, name, opt.Key.Format(format))
+			if err != nil {
+				t.Fatalf("%s: %s", name, err)
+			}
+
+			imported, err := apis[1].Key().Import(ctx, "imported-"+name, bytes.NewReader(data), opt.Key.Format(format))
+			if err != nil {
+				t.Fatalf("%s: %s", name, err)
+			}
+
+			if imported.Name() != "imported-"+name {
+				t.Errorf("unexpected name: %s", imported.Name())
+			}
+
+			if imported.ID() != k.ID() {
+				t.Errorf("%s: imported key id %s doesn't match %s", name, imported.ID().Pretty(), k.ID().Pretty())
+			}
+		}
+	}
+}
+
+func (tp *provider) TestExportImportEncrypted(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	api, err := tp.makeAPI(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	k, err := api.Key().Generate(ctx, "foo", opt.Key.Type(opt.Ed25519Key))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = api.Key().Export(ctx, "foo", opt.Key.Format(opt.KeyFormatEncrypted))
+	if err == nil {
+		t.Fatal("expected encrypted export without a passphrase to fail")
+	}
+
+	data, err := api.Key().Export(ctx, "foo", opt.Key.Format(opt.KeyFormatEncrypted), opt.Key.Passphrase("secret"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = api.Key().Import(ctx, "bar", bytes.NewReader(data), opt.Key.Format(opt.KeyFormatEncrypted), opt.Key.Passphrase("wrong"))
+	if err == nil {
+		t.Fatal("expected import with a wrong passphrase to fail")
+	}
+
+	imported, err := api.Key().Import(ctx, "bar", bytes.NewReader(data), opt.Key.Format(opt.KeyFormatEncrypted), opt.Key.Passphrase("secret"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if imported.ID() != k.ID() {
+		t.Errorf("imported key id %s doesn't match %s", imported.ID().Pretty(), k.ID().Pretty())
+	}
+}
+
+func (tp *provider) TestExportSelf(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	apis, err := tp.MakeAPISwarm(ctx, true, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	self, err := apis[0].Key().Self(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := apis[0].Key().Export(ctx, "self")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = apis[1].Key().Import(ctx, "self", bytes.NewReader(data))
+	if err == nil {
+		t.Fatal("expected importing as 'self' to fail")
+	}
+
+	imported, err := apis[1].Key().Import(ctx, "other", bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if imported.ID() != self.ID() {
+		t.Errorf("imported key id %s doesn't match %s", imported.ID().Pretty(), self.ID().Pretty())
+	}
+}
+
+func (tp *provider) TestImportExisting(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	api, err := tp.makeAPI(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = api.Key().Generate(ctx, "foo", opt.Key.Type(opt.Ed25519Key))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := api.Key().Export(ctx, "foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = api.Key().Import(ctx, "foo", bytes.NewReader(data))
+	if err == nil {
+		t.Fatal("expected error")
+	}
+
+	if !strings.Contains(err.Error(), "already exists") {
+		t.Fatalf("expected error 'key with name 'foo' already exists', got '%s'", err.Error())
+	}
+}
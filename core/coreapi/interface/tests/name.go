@@ -26,6 +26,7 @@ func (tp *provider) TestName(t *testing.T) {
 	t.Run("TestPublishResolve", tp.TestPublishResolve)
 	t.Run("TestBasicPublishResolveKey", tp.TestBasicPublishResolveKey)
 	t.Run("TestBasicPublishResolveTimeout", tp.TestBasicPublishResolveTimeout)
+	t.Run("TestCreatePutRecord", tp.TestCreatePutRecord)
 }
 
 var rnd = rand.New(rand.NewSource(0x62796532303137))
@@ -275,4 +276,59 @@ func (tp *provider) TestBasicPublishResolveTimeout(t *testing.T) {
 	}
 }
 
+func (tp *provider) TestCreatePutRecord(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	apis, err := tp.MakeAPISwarm(ctx, true, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	api := apis[0]
+
+	k, err := api.Key().Generate(ctx, "foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	p, err := addTestObject(ctx, api)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rec, err := api.Name().CreateRecord(ctx, p, opt.Name.CreateKey(k.Name()), opt.Name.Sequence(3))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the record was only created, not published
+	_, err = api.Name().Resolve(ctx, k.ID().Pretty())
+	if err == nil {
+		t.Fatal("expected the record not to be published")
+	}
+
+	self, err := api.Key().Self(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = apis[1].Name().PutRecord(ctx, self.ID().Pretty(), rec)
+	if err == nil {
+		t.Fatal("expected putting the record under another name to fail")
+	}
+
+	err = apis[1].Name().PutRecord(ctx, k.Path().String(), rec)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resPath, err := apis[2].Name().Resolve(ctx, k.ID().Pretty())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if resPath.String() != p.String() {
+		t.Errorf("expected paths to match, '%s'!='%s'", resPath.String(), p.String())
+	}
+}
+
 //TODO: When swarm api is created, add multinode tests
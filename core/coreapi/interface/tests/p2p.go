@@ -0,0 +1,240 @@
+package tests
+
+import (
+	"context"
+	"io/ioutil"
+	"net"
+	"testing"
+	"time"
+
+	coreiface "github.com/ipfs/go-ipfs/core/coreapi/interface"
+	opt "github.com/ipfs/go-ipfs/core/coreapi/interface/options"
+
+	ma "gx/ipfs/QmNTCey11oxhb1AxDnQBRHtdhap6Ctud872NjAYPYYXPuc/go-multiaddr"
+	peer "gx/ipfs/QmPJxxDsX2UbchSHobbYuvz7qnyJTFKvaKMzE2rZWJ4x5B/go-libp2p-peer"
+	manet "gx/ipfs/QmZcLBXKaFe8ND5YHPkJRAwmhJGrVsi1JqDZNyJ4nRK5Mj/go-multiaddr-net"
+)
+
+func (tp *provider) TestP2P(t *testing.T) {
+	tp.hasApi(t, func(api coreiface.CoreAPI) error {
+		if api.P2P() == nil {
+			return apiNotImplemented
+		}
+		return nil
+	})
+
+	t.Run("TestP2PForward", tp.TestP2PForward)
+	t.Run("TestP2PAllowedPeers", tp.TestP2PAllowedPeers)
+	t.Run("TestP2PCustomProtocol", tp.TestP2PCustomProtocol)
+}
+
+// p2pTunnel makes the second node listen on a protocol forwarding to a local
+// server, and the first one forward connections to it. It returns the server
+// and the address of the forward.
+func (tp *provider) p2pTunnel(t *testing.T, ctx context.Context, apis []coreiface.CoreAPI, opts ...opt.P2PListenOption) (net.Listener, ma.Multiaddr) {
+	server, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	target, err := manet.FromNetAddr(server.Addr())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := apis[1].P2P().Listen(ctx, "/x/test", target, opts...); err != nil {
+		t.Fatal(err)
+	}
+
+	self1, err := apis[1].Key().Self(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	listen, err := ma.NewMultiaddr("/ip4/127.0.0.1/tcp/0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fwd, err := apis[0].P2P().Forward(ctx, "/x/test", listen, self1.ID())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return server, fwd.ListenAddress
+}
+
+func (tp *provider) TestP2PForward(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	apis, err := tp.MakeAPISwarm(ctx, true, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	server, addr := tp.p2pTunnel(t, ctx, apis)
+	defer server.Close()
+
+	listeners, err := apis[0].P2P().ListListeners(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(listeners) != 1 || listeners[0].Protocol != "/x/test" || !listeners[0].ListenAddress.Equal(addr) {
+		t.Fatalf("unexpected listeners: %v", listeners)
+	}
+
+	conn, err := manet.Dial(addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	if _, err := conn.Write([]byte("hello")); err != nil {
+		t.Fatal(err)
+	}
+
+	sconn, err := server.Accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sconn.Close()
+	buf := make([]byte, 5)
+	if _, err := sconn.Read(buf); err != nil {
+		t.Fatal(err)
+	}
+	if string(buf) != "hello" {
+		t.Fatalf("expected hello, got %q", buf)
+	}
+
+	// the bytes are counted once written to the server
+	var streams []coreiface.P2PStream
+	for i := 0; i < 50; i++ {
+		streams, err = apis[1].P2P().ListStreams(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(streams) == 1 && streams[0].BytesIn == 5 {
+			break
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	if len(streams) != 1 || streams[0].Protocol != "/x/test" {
+		t.Fatalf("unexpected streams: %v", streams)
+	}
+	self0, err := apis[0].Key().Self(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if streams[0].Peer != self0.ID() || streams[0].BytesIn != 5 || streams[0].BytesOut != 0 {
+		t.Fatalf("unexpected stream peer or traffic: %v", streams[0])
+	}
+
+	listeners, err = apis[1].P2P().ListListeners(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(listeners) != 1 || listeners[0].BytesIn != 5 {
+		t.Fatalf("unexpected listener traffic: %v", listeners)
+	}
+
+	if err := apis[1].P2P().CloseStream(ctx, streams[0].ID); err != nil {
+		t.Fatal(err)
+	}
+	if err := apis[1].P2P().CloseStream(ctx, streams[0].ID); err != coreiface.ErrStreamNotFound {
+		t.Fatalf("expected ErrStreamNotFound, got %v", err)
+	}
+	sconn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	if _, err := ioutil.ReadAll(sconn); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, api := range apis {
+		listeners, err := api.P2P().ListListeners(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, l := range listeners {
+			if err := api.P2P().CloseListener(ctx, l); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := api.P2P().CloseListener(ctx, listeners[0]); err != coreiface.ErrListenerNotFound {
+			t.Fatalf("expected ErrListenerNotFound, got %v", err)
+		}
+
+		listeners, err = api.P2P().ListListeners(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(listeners) != 0 {
+			t.Fatalf("expected no listeners, got %v", listeners)
+		}
+	}
+}
+
+func (tp *provider) TestP2PAllowedPeers(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	apis, err := tp.MakeAPISwarm(ctx, true, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	other, err := peer.IDB58Decode("QmQCU2EcMqAqQPR2i9bChDtGNJchTbq5TbXJJ16u19uLTa")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	server, addr := tp.p2pTunnel(t, ctx, apis, opt.P2P.AllowedPeers(other))
+	defer server.Close()
+
+	listeners, err := apis[1].P2P().ListListeners(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(listeners) != 1 || len(listeners[0].AllowedPeers) != 1 || listeners[0].AllowedPeers[0] != other {
+		t.Fatalf("unexpected listeners: %v", listeners)
+	}
+
+	conn, err := manet.Dial(addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	// the stream is reset by the listener, which closes the connection
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	_, err = conn.Read(make([]byte, 1))
+	if err == nil {
+		t.Fatal("expected the connection to be closed")
+	}
+	if nerr, ok := err.(net.Error); ok && nerr.Timeout() {
+		t.Fatal("expected the connection to be closed, it timed out")
+	}
+
+	streams, err := apis[1].P2P().ListStreams(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(streams) != 0 {
+		t.Fatalf("expected no streams, got %v", streams)
+	}
+}
+
+func (tp *provider) TestP2PCustomProtocol(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	apis, err := tp.MakeAPISwarm(ctx, true, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	api := apis[0]
+
+	target, err := ma.NewMultiaddr("/ip4/127.0.0.1/tcp/10101")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := api.P2P().Listen(ctx, "/custom", target); err == nil {
+		t.Fatal("expected an error for a protocol outside of /x/")
+	}
+	if _, err := api.P2P().Listen(ctx, "/custom", target, opt.P2P.ListenAllowCustomProtocol(true)); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -24,6 +24,7 @@ func (tp *provider) TestPin(t *testing.T) {
 	t.Run("TestPinAdd", tp.TestPinAdd)
 	t.Run("TestPinSimple", tp.TestPinSimple)
 	t.Run("TestPinRecursive", tp.TestPinRecursive)
+	t.Run("TestPinNameMeta", tp.TestPinNameMeta)
 }
 
 func (tp *provider) TestPinAdd(t *testing.T) {
@@ -237,3 +238,81 @@ func (tp *provider) TestPinRecursive(t *testing.T) {
 		}
 	*/
 }
+
+func (tp *provider) TestPinNameMeta(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	api, err := tp.makeAPI(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	p1, err := api.Unixfs().Add(ctx, strFile("release 1")())
+	if err != nil {
+		t.Fatal(err)
+	}
+	p2, err := api.Unixfs().Add(ctx, strFile("release 2")())
+	if err != nil {
+		t.Fatal(err)
+	}
+	p3, err := api.Unixfs().Add(ctx, strFile("nightly")())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = api.Pin().Add(ctx, p1, opt.Pin.Name("release-1"), opt.Pin.Meta("channel", "stable"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = api.Pin().Add(ctx, p2, opt.Pin.Name("release-2"), opt.Pin.Meta("channel", "beta"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = api.Pin().Add(ctx, p3, opt.Pin.Recursive(false))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	list, err := api.Pin().Ls(ctx, opt.Pin.NamePrefix("release-"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list) != 2 {
+		t.Fatalf("expected 2 pins, got %d", len(list))
+	}
+
+	list, err = api.Pin().Ls(ctx, opt.Pin.MetaMatch("channel", "stable"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list) != 1 {
+		t.Fatalf("expected 1 pin, got %d", len(list))
+	}
+	if list[0].Path().Cid().String() != p1.Cid().String() {
+		t.Error("unexpected pin matched")
+	}
+	if list[0].Name() != "release-1" || list[0].Meta()["channel"] != "stable" {
+		t.Errorf("unexpected name or metadata: %s %v", list[0].Name(), list[0].Meta())
+	}
+
+	list, err = api.Pin().Ls(ctx, opt.Pin.Type.Direct())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list) != 1 || list[0].Name() != "" || list[0].Meta() != nil {
+		t.Errorf("unexpected unnamed pin listing: %v", list)
+	}
+
+	// adding again with a name renames the pin
+	err = api.Pin().Add(ctx, p3, opt.Pin.Recursive(false), opt.Pin.Name("nightly"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	list, err = api.Pin().Ls(ctx, opt.Pin.NamePrefix("night"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list) != 1 || list[0].Type() != "direct" {
+		t.Errorf("expected the direct pin to be renamed: %v", list)
+	}
+}
@@ -0,0 +1,219 @@
+package tests
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	coreiface "github.com/ipfs/go-ipfs/core/coreapi/interface"
+	opt "github.com/ipfs/go-ipfs/core/coreapi/interface/options"
+)
+
+func (tp *provider) TestRepo(t *testing.T) {
+	tp.hasApi(t, func(api coreiface.CoreAPI) error {
+		if api.Repo() == nil {
+			return apiNotImplemented
+		}
+		return nil
+	})
+
+	t.Run("TestRepoStat", tp.TestRepoStat)
+	t.Run("TestRepoStatSizeOnly", tp.TestRepoStatSizeOnly)
+	t.Run("TestRepoGC", tp.TestRepoGC)
+	t.Run("TestRepoGCIncremental", tp.TestRepoGCIncremental)
+	t.Run("TestRepoGCOptions", tp.TestRepoGCOptions)
+	t.Run("TestRepoVerify", tp.TestRepoVerify)
+}
+
+func (tp *provider) TestRepoStat(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	api, err := tp.makeAPI(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	before, err := api.Repo().Stat(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = api.Block().Put(ctx, strings.NewReader(`Hello`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	after, err := api.Repo().Stat(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if after.NumObjects != before.NumObjects+1 {
+		t.Errorf("expected %d objects, got %d", before.NumObjects+1, after.NumObjects)
+	}
+
+	version, err := api.Repo().Version(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if after.Version != fmt.Sprintf("fs-repo@%d", version) {
+		t.Errorf("unexpected repo version %q, expected fs-repo@%d", after.Version, version)
+	}
+
+	if after.StorageMax == 0 {
+		t.Error("expected a storage max")
+	}
+}
+
+func (tp *provider) TestRepoStatSizeOnly(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	api, err := tp.makeAPI(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = api.Block().Put(ctx, strings.NewReader(`Hello`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stat, err := api.Repo().Stat(ctx, opt.Repo.SizeOnly(true))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if stat.NumObjects != 0 || stat.RepoPath != "" || stat.Version != "" {
+		t.Errorf("expected the size only, got %+v", stat)
+	}
+
+	if stat.StorageMax == 0 {
+		t.Error("expected a storage max")
+	}
+}
+
+func (tp *provider) TestRepoGC(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	api, err := tp.makeAPI(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	unpinned, err := api.Block().Put(ctx, strings.NewReader(`unpinned`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pinned, err := api.Block().Put(ctx, strings.NewReader(`pinned`), opt.Block.Pin(true))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	results, err := api.Repo().GC(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	removed := make(map[string]bool)
+	for res := range results {
+		if res.Err != nil {
+			t.Fatal(res.Err)
+		}
+		removed[res.Removed.Cid().String()] = true
+	}
+
+	if !removed[unpinned.Path().Cid().String()] {
+		t.Error("expected the unpinned block to be removed")
+	}
+	if removed[pinned.Path().Cid().String()] {
+		t.Error("expected the pinned block to be kept")
+	}
+
+	_, err = api.Block().Get(ctx, unpinned.Path())
+	if err == nil {
+		t.Error("expected the unpinned block to be gone")
+	}
+
+	_, err = api.Block().Get(ctx, pinned.Path())
+	if err != nil {
+		t.Error(err)
+	}
+}
+
+func (tp *provider) TestRepoGCIncremental(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	api, err := tp.makeAPI(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	unpinned, err := api.Block().Put(ctx, strings.NewReader(`unpinned`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	results, err := api.Repo().GC(ctx, opt.Repo.Target(1<<30))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var found bool
+	for res := range results {
+		if res.Err != nil {
+			t.Fatal(res.Err)
+		}
+		if res.Removed.Cid().Equals(unpinned.Path().Cid()) {
+			found = true
+		}
+	}
+
+	if !found {
+		t.Error("expected the unpinned block to be removed")
+	}
+}
+
+func (tp *provider) TestRepoGCOptions(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	api, err := tp.makeAPI(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = api.Repo().GC(ctx, opt.Repo.MaxDuration(0))
+	if err == nil {
+		t.Error("expected a zero max duration to be rejected")
+	}
+
+	_, err = api.Repo().GC(ctx, opt.Repo.Target(0))
+	if err == nil {
+		t.Error("expected a zero target to be rejected")
+	}
+}
+
+func (tp *provider) TestRepoVerify(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	api, err := tp.makeAPI(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = api.Block().Put(ctx, strings.NewReader(`Hello`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	results, err := api.Repo().Verify(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for res := range results {
+		t.Errorf("unexpected corrupted block %s: %s", res.Path.Cid(), res.Err)
+	}
+}
@@ -5,10 +5,13 @@ import (
 	"crypto/rand"
 	"errors"
 	"fmt"
+	"io"
+	"io/ioutil"
 	"sort"
 
 	coreiface "github.com/ipfs/go-ipfs/core/coreapi/interface"
 	caopts "github.com/ipfs/go-ipfs/core/coreapi/interface/options"
+	keystore "github.com/ipfs/go-ipfs/keystore"
 
 	crypto "gx/ipfs/QmNiJiXwWE3kRhZrC5ej3kSjWHm337pYfhjLGSCDNKJP2s/go-libp2p-crypto"
 	peer "gx/ipfs/QmPJxxDsX2UbchSHobbYuvz7qnyJTFKvaKMzE2rZWJ4x5B/go-libp2p-peer"
@@ -218,6 +221,61 @@ func (api *KeyAPI) Remove(ctx context.Context, name string) (coreiface.Key, erro
 	return &key{"", pid}, nil
 }
 
+// Export returns the private key stored under the name, encoded in the
+// selected format. The 'self' key can be exported as well.
+func (api *KeyAPI) Export(ctx context.Context, name string, opts ...caopts.KeyEncodingOption) ([]byte, error) {
+	options, err := caopts.KeyEncodingOptions(opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	if name == "self" {
+		if api.privateKey == nil {
+			return nil, errors.New("identity not loaded")
+		}
+		return keystore.MarshalKey(api.privateKey, options.Format, options.Passphrase)
+	}
+
+	data, err := keystore.Export(api.repo.Keystore(), name, options.Format, options.Passphrase)
+	if err == keystore.ErrNoSuchKey {
+		return nil, fmt.Errorf("no key named %s was found", name)
+	}
+	return data, err
+}
+
+// Import reads an encoded private key and stores it in the keystore under the
+// specified name.
+func (api *KeyAPI) Import(ctx context.Context, name string, r io.Reader, opts ...caopts.KeyEncodingOption) (coreiface.Key, error) {
+	options, err := caopts.KeyEncodingOptions(opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	if name == "self" {
+		return nil, fmt.Errorf("cannot import key with name 'self'")
+	}
+
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	sk, err := keystore.Import(api.repo.Keystore(), name, data, options.Format, options.Passphrase)
+	if err != nil {
+		if err == keystore.ErrKeyExists {
+			return nil, fmt.Errorf("key with name '%s' already exists", name)
+		}
+		return nil, err
+	}
+
+	pid, err := peer.IDFromPrivateKey(sk)
+	if err != nil {
+		return nil, err
+	}
+
+	return &key{name, pid}, nil
+}
+
 func (api *KeyAPI) Self(ctx context.Context) (coreiface.Key, error) {
 	if api.identity == "" {
 		return nil, errors.New("identity not loaded")
@@ -15,6 +15,8 @@ import (
 	ci "gx/ipfs/QmNiJiXwWE3kRhZrC5ej3kSjWHm337pYfhjLGSCDNKJP2s/go-libp2p-crypto"
 	"gx/ipfs/QmPJxxDsX2UbchSHobbYuvz7qnyJTFKvaKMzE2rZWJ4x5B/go-libp2p-peer"
 	ipath "gx/ipfs/QmQ3YSqfxunT5QBg6KBVskKyRE26q6hjSMyhpxchpm7jEN/go-path"
+	pb "gx/ipfs/QmVpC4PPSaoqZzWYEnQURnsQagimcWEzNKZouZyd7sNJdZ/go-ipns/pb"
+	proto "gx/ipfs/QmdxUuburamoF6zF9qjeQC4WYcWGbWuRmdLacMEsW8ioD8/gogo-protobuf/proto"
 )
 
 type NameAPI CoreAPI
@@ -95,7 +97,7 @@ func (api *NameAPI) Search(ctx context.Context, name string, opts ...caopts.Name
 	var resolver namesys.Resolver = api.namesys
 
 	if !options.Cache {
-		resolver = namesys.NewNameSystem(api.routing, api.repo.Datastore(), 0)
+		resolver = namesys.NewCustomNameSystem(api.routing, api.repo.Datastore(), api.dnsResolver, nil)
 	}
 
 	if !strings.HasPrefix(name, "/ipns/") {
@@ -140,6 +142,82 @@ func (api *NameAPI) Resolve(ctx context.Context, name string, opts ...caopts.Nam
 	return p, err
 }
 
+// CreateRecord creates and signs an IPNS record pointing to the path, without
+// storing or publishing it, and returns it serialized.
+func (api *NameAPI) CreateRecord(ctx context.Context, p coreiface.Path, opts ...caopts.NameCreateOption) ([]byte, error) {
+	options, err := caopts.NameCreateOptions(opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	pth, err := ipath.ParsePath(p.String())
+	if err != nil {
+		return nil, err
+	}
+
+	k, err := keylookup(api.privateKey, api.repo.Keystore(), options.Key)
+	if err != nil {
+		return nil, err
+	}
+
+	var seq uint64
+	if options.Sequence != nil {
+		seq = *options.Sequence
+	} else {
+		pid, err := peer.IDFromPrivateKey(k)
+		if err != nil {
+			return nil, err
+		}
+
+		// follow on from the record published locally, if any
+		prev, err := namesys.NewIpnsPublisher(api.routing, api.repo.Datastore()).GetPublished(ctx, pid, false)
+		if err != nil {
+			return nil, err
+		}
+		seq = prev.GetSequence()
+		if prev != nil && pth != ipath.Path(prev.GetValue()) {
+			seq++
+		}
+	}
+
+	var ttl time.Duration
+	if options.TTL != nil {
+		ttl = *options.TTL
+	}
+
+	entry, err := namesys.CreateRecord(k, pth, seq, time.Now().Add(options.ValidTime), ttl)
+	if err != nil {
+		return nil, err
+	}
+	return proto.Marshal(entry)
+}
+
+// PutRecord verifies that the serialized record is signed by the key of the
+// name, and puts it to the routing system.
+func (api *NameAPI) PutRecord(ctx context.Context, name string, record []byte, opts ...caopts.NamePutOption) error {
+	options, err := caopts.NamePutOptions(opts...)
+	if err != nil {
+		return err
+	}
+
+	err = api.checkOnline(options.AllowOffline)
+	if err != nil {
+		return err
+	}
+
+	pid, err := peer.IDB58Decode(strings.TrimPrefix(name, "/ipns/"))
+	if err != nil {
+		return fmt.Errorf("invalid IPNS name %q: %s", name, err)
+	}
+
+	entry := new(pb.IpnsEntry)
+	if err := proto.Unmarshal(record, entry); err != nil {
+		return fmt.Errorf("invalid IPNS record: %s", err)
+	}
+
+	return namesys.PutSignedRecord(ctx, api.routing, pid, entry)
+}
+
 func keylookup(self ci.PrivKey, kstore keystore.Keystore, k string) (crypto.PrivKey, error) {
 	if k == "self" {
 		return self, nil
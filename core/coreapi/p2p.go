@@ -0,0 +1,166 @@
+package coreapi
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"strings"
+
+	coreiface "github.com/ipfs/go-ipfs/core/coreapi/interface"
+	caopts "github.com/ipfs/go-ipfs/core/coreapi/interface/options"
+	p2p "github.com/ipfs/go-ipfs/p2p"
+
+	ma "gx/ipfs/QmNTCey11oxhb1AxDnQBRHtdhap6Ctud872NjAYPYYXPuc/go-multiaddr"
+	peer "gx/ipfs/QmPJxxDsX2UbchSHobbYuvz7qnyJTFKvaKMzE2rZWJ4x5B/go-libp2p-peer"
+	protocol "gx/ipfs/QmZNkThpqfVXs9GNbexPrfBbXSLNYeKrE7jwFM2oqHbyqN/go-libp2p-protocol"
+)
+
+// p2pProtoPrefix is the required prefix of the protocols, unless custom
+// protocols are allowed
+const p2pProtoPrefix = "/x/"
+
+var errP2PDisabled = errors.New("libp2p stream mounting not enabled")
+
+type P2PAPI CoreAPI
+
+func (api *P2PAPI) Forward(ctx context.Context, proto protocol.ID, listen ma.Multiaddr, target peer.ID, opts ...caopts.P2PForwardOption) (coreiface.P2PListener, error) {
+	settings, err := caopts.P2PForwardOptions(opts...)
+	if err != nil {
+		return coreiface.P2PListener{}, err
+	}
+
+	if err := api.checkP2P(proto, settings.AllowCustomProtocol); err != nil {
+		return coreiface.P2PListener{}, err
+	}
+
+	l, err := api.p2p.ForwardLocal(api.nctx, target, proto, listen, settings.MaxRate)
+	if err != nil {
+		return coreiface.P2PListener{}, err
+	}
+	return listenerInfo(l), nil
+}
+
+func (api *P2PAPI) Listen(ctx context.Context, proto protocol.ID, target ma.Multiaddr, opts ...caopts.P2PListenOption) (coreiface.P2PListener, error) {
+	settings, err := caopts.P2PListenOptions(opts...)
+	if err != nil {
+		return coreiface.P2PListener{}, err
+	}
+
+	if err := api.checkP2P(proto, settings.AllowCustomProtocol); err != nil {
+		return coreiface.P2PListener{}, err
+	}
+
+	l, err := api.p2p.ForwardRemote(api.nctx, proto, target, settings.ReportPeerID, settings.AllowedPeers, settings.MaxRate)
+	if err != nil {
+		return coreiface.P2PListener{}, err
+	}
+	return listenerInfo(l), nil
+}
+
+func (api *P2PAPI) ListListeners(ctx context.Context) ([]coreiface.P2PListener, error) {
+	if err := api.checkP2P("", true); err != nil {
+		return nil, err
+	}
+
+	var out []coreiface.P2PListener
+	for _, reg := range []*p2p.Listeners{api.p2p.ListenersLocal, api.p2p.ListenersP2P} {
+		reg.RLock()
+		for _, l := range reg.Listeners {
+			out = append(out, listenerInfo(l))
+		}
+		reg.RUnlock()
+	}
+	return out, nil
+}
+
+func (api *P2PAPI) ListStreams(ctx context.Context) ([]coreiface.P2PStream, error) {
+	if err := api.checkP2P("", true); err != nil {
+		return nil, err
+	}
+
+	var out []coreiface.P2PStream
+	api.p2p.Streams.Lock()
+	for id, s := range api.p2p.Streams.Streams {
+		out = append(out, coreiface.P2PStream{
+			ID:            id,
+			Protocol:      s.Protocol,
+			OriginAddress: s.OriginAddr,
+			TargetAddress: s.TargetAddr,
+			Peer:          s.Peer(),
+			BytesIn:       s.BytesIn(),
+			BytesOut:      s.BytesOut(),
+			Started:       s.Started(),
+		})
+	}
+	api.p2p.Streams.Unlock()
+
+	sort.Slice(out, func(i, j int) bool { return out[i].ID < out[j].ID })
+	return out, nil
+}
+
+func (api *P2PAPI) CloseListener(ctx context.Context, listener coreiface.P2PListener) error {
+	if err := api.checkP2P("", true); err != nil {
+		return err
+	}
+
+	match := func(l p2p.Listener) bool {
+		return l.Protocol() == listener.Protocol &&
+			l.ListenAddress().Equal(listener.ListenAddress) &&
+			l.TargetAddress().Equal(listener.TargetAddress)
+	}
+
+	done := api.p2p.ListenersLocal.Close(match)
+	done += api.p2p.ListenersP2P.Close(match)
+	if done == 0 {
+		return coreiface.ErrListenerNotFound
+	}
+	return nil
+}
+
+func (api *P2PAPI) CloseStream(ctx context.Context, id uint64) error {
+	if err := api.checkP2P("", true); err != nil {
+		return err
+	}
+
+	api.p2p.Streams.Lock()
+	s, ok := api.p2p.Streams.Streams[id]
+	api.p2p.Streams.Unlock()
+	if !ok {
+		return coreiface.ErrStreamNotFound
+	}
+
+	return api.p2p.Streams.Reset(s)
+}
+
+// checkP2P returns an error if stream mounting isn't available, or if the
+// protocol isn't allowed
+func (api *P2PAPI) checkP2P(proto protocol.ID, allowCustom bool) error {
+	if api.p2p == nil {
+		return coreiface.ErrOffline
+	}
+
+	cfg, err := api.repo.Config()
+	if err != nil {
+		return err
+	}
+	if !cfg.Experimental.Libp2pStreamMounting {
+		return errP2PDisabled
+	}
+
+	if !allowCustom && !strings.HasPrefix(string(proto), p2pProtoPrefix) {
+		return errors.New("protocol name must be within '" + p2pProtoPrefix + "' namespace")
+	}
+	return nil
+}
+
+func listenerInfo(l p2p.Listener) coreiface.P2PListener {
+	return coreiface.P2PListener{
+		Protocol:      l.Protocol(),
+		ListenAddress: l.ListenAddress(),
+		TargetAddress: l.TargetAddress(),
+		AllowedPeers:  l.AllowedPeers(),
+		BytesIn:       l.Traffic().BytesIn(),
+		BytesOut:      l.Traffic().BytesOut(),
+		MaxRate:       l.MaxRate(),
+	}
+}
@@ -6,6 +6,7 @@ import (
 
 	coreiface "github.com/ipfs/go-ipfs/core/coreapi/interface"
 	caopts "github.com/ipfs/go-ipfs/core/coreapi/interface/options"
+	pin "github.com/ipfs/go-ipfs/pin"
 	merkledag "gx/ipfs/QmUtsx89yiCY6F8mbpP6ecXckiSzCBH7EvkKZuZEHBcr1m/go-merkledag"
 	bserv "gx/ipfs/QmbgbNxC1PMyS2gbx7nf2jKNG7bZAfYJJebdK4ptBBWCz1/go-blockservice"
 
@@ -33,6 +34,20 @@ func (api *PinAPI) Add(ctx context.Context, p coreiface.Path, opts ...caopts.Pin
 		return fmt.Errorf("pin: %s", err)
 	}
 
+	if settings.Name != "" || settings.Meta != nil {
+		// only replace what was given
+		info, _ := api.pinning.Info(dagNode.Cid())
+		if settings.Name != "" {
+			info.Name = settings.Name
+		}
+		if settings.Meta != nil {
+			info.Meta = settings.Meta
+		}
+		if err := api.pinning.SetInfo(dagNode.Cid(), info); err != nil {
+			return fmt.Errorf("pin: %s", err)
+		}
+	}
+
 	return api.pinning.Flush()
 }
 
@@ -48,7 +63,24 @@ func (api *PinAPI) Ls(ctx context.Context, opts ...caopts.PinLsOption) ([]coreif
 		return nil, fmt.Errorf("invalid type '%s', must be one of {direct, indirect, recursive, all}", settings.Type)
 	}
 
-	return api.pinLsAll(settings.Type, ctx)
+	pins, err := api.pinLsAll(settings.Type, ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if settings.NamePrefix == "" && len(settings.Meta) == 0 {
+		return pins, nil
+	}
+
+	// only direct and recursive pins have infos to match
+	out := pins[:0]
+	for _, p := range pins {
+		info := p.(*pinInfo).info
+		if (p.Type() == "direct" || p.Type() == "recursive") && info.Matches(settings.NamePrefix, settings.Meta) {
+			out = append(out, p)
+		}
+	}
+	return out, nil
 }
 
 // Rm pin rm api
@@ -172,6 +204,7 @@ func (api *PinAPI) Verify(ctx context.Context) (<-chan coreiface.PinStatus, erro
 type pinInfo struct {
 	pinType string
 	path    coreiface.ResolvedPath
+	info    pin.Info
 }
 
 func (p *pinInfo) Path() coreiface.ResolvedPath {
@@ -182,15 +215,25 @@ func (p *pinInfo) Type() string {
 	return p.pinType
 }
 
+func (p *pinInfo) Name() string {
+	return p.info.Name
+}
+
+func (p *pinInfo) Meta() map[string]string {
+	return p.info.Meta
+}
+
 func (api *PinAPI) pinLsAll(typeStr string, ctx context.Context) ([]coreiface.Pin, error) {
 
 	keys := make(map[cid.Cid]*pinInfo)
 
 	AddToResultKeys := func(keyList []cid.Cid, typeStr string) {
 		for _, c := range keyList {
+			info, _ := api.pinning.Info(c)
 			keys[c] = &pinInfo{
 				pinType: typeStr,
 				path:    coreiface.IpldPath(c),
+				info:    info,
 			}
 		}
 	}
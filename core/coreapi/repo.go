@@ -0,0 +1,125 @@
+package coreapi
+
+import (
+	"context"
+
+	coreiface "github.com/ipfs/go-ipfs/core/coreapi/interface"
+	caopts "github.com/ipfs/go-ipfs/core/coreapi/interface/options"
+	corerepo "github.com/ipfs/go-ipfs/core/corerepo"
+	gc "github.com/ipfs/go-ipfs/pin/gc"
+	fsrepo "github.com/ipfs/go-ipfs/repo/fsrepo"
+
+	cid "gx/ipfs/QmR8BauakNcBa3RbE4nbQu76PDiJgoQgz8AJdhJuiU4TAw/go-cid"
+)
+
+type RepoAPI CoreAPI
+
+func (api *RepoAPI) Stat(ctx context.Context, opts ...caopts.RepoStatOption) (*coreiface.RepoStat, error) {
+	settings, err := caopts.RepoStatOptions(opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	if settings.SizeOnly {
+		sizeStat, err := corerepo.RepoSizeOf(api.repo)
+		if err != nil {
+			return nil, err
+		}
+
+		return &coreiface.RepoStat{
+			RepoSize:   sizeStat.RepoSize,
+			StorageMax: sizeStat.StorageMax,
+		}, nil
+	}
+
+	stat, err := corerepo.RepoStatOf(ctx, api.repo, api.blockstore)
+	if err != nil {
+		return nil, err
+	}
+
+	return &coreiface.RepoStat{
+		RepoSize:   stat.RepoSize,
+		StorageMax: stat.StorageMax,
+		NumObjects: stat.NumObjects,
+		RepoPath:   stat.RepoPath,
+		Version:    stat.Version,
+	}, nil
+}
+
+func (api *RepoAPI) GC(ctx context.Context, opts ...caopts.RepoGcOption) (<-chan coreiface.GcResult, error) {
+	settings, err := caopts.RepoGcOptions(opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	var results <-chan gc.Result
+	if settings.MaxDuration > 0 || settings.Target > 0 {
+		roots := func() ([]cid.Cid, error) {
+			return corerepo.BestEffortRoots(api.filesRoot)
+		}
+
+		results = gc.IncrementalGC(ctx, api.blockstore, api.repo.Datastore(), api.pinning, roots, gc.IncrementalOptions{
+			MaxDuration: settings.MaxDuration,
+			Target:      settings.Target,
+		})
+	} else {
+		roots, err := corerepo.BestEffortRoots(api.filesRoot)
+		if err != nil {
+			return nil, err
+		}
+
+		results = gc.GC(ctx, api.blockstore, api.repo.Datastore(), api.pinning, roots)
+	}
+	results = api.gcStats.Count(results)
+
+	out := make(chan coreiface.GcResult)
+	go func() {
+		defer close(out)
+
+		// keep draining the results once ctx is canceled, so that the run
+		// can finish
+		for res := range results {
+			r := coreiface.GcResult{Err: res.Error}
+			if res.Error == nil {
+				r.Removed = coreiface.IpldPath(res.KeyRemoved)
+			}
+
+			select {
+			case out <- r:
+			case <-ctx.Done():
+			}
+		}
+	}()
+
+	return out, nil
+}
+
+func (api *RepoAPI) Verify(ctx context.Context) (<-chan coreiface.VerifyResult, error) {
+	results, err := corerepo.Verify(ctx, api.repo)
+	if err != nil {
+		return nil, err
+	}
+
+	out := make(chan coreiface.VerifyResult)
+	go func() {
+		defer close(out)
+
+		for res := range results {
+			if res.Error == nil {
+				continue
+			}
+
+			select {
+			case out <- coreiface.VerifyResult{Path: coreiface.IpldPath(res.Key), Err: res.Error}:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return out, nil
+}
+
+func (api *RepoAPI) Version(context.Context) (int, error) {
+	return fsrepo.RepoVersion, nil
+}
@@ -69,6 +69,7 @@ func (NodeProvider) MakeAPISwarm(ctx context.Context, fullIdentity bool, n int)
 		c.Addresses.Swarm = []string{fmt.Sprintf("/ip4/127.0.%d.1/tcp/4001", i)}
 		c.Identity = ident
 		c.Experimental.FilestoreEnabled = true
+		c.Experimental.Libp2pStreamMounting = true
 
 		ds := datastore.NewMapDatastore()
 		r := &repo.Mock{
@@ -93,8 +93,13 @@ func GatewayOption(writable bool, paths ...string) ServeOption {
 			PathPrefixes: cfg.Gateway.PathPrefixes,
 		}, api)
 
+		responses, err := newGatewayResponsesCounter()
+		if err != nil {
+			return nil, err
+		}
+
 		for _, p := range paths {
-			mux.Handle(p+"/", gateway)
+			mux.Handle(p+"/", instrumentGatewayRoute(responses, p, gateway))
 		}
 		return mux, nil
 	}
@@ -0,0 +1,130 @@
+package corehttp
+
+import (
+	"bytes"
+	"encoding/json"
+	"html/template"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+
+	cid "gx/ipfs/QmR8BauakNcBa3RbE4nbQu76PDiJgoQgz8AJdhJuiU4TAw/go-cid"
+)
+
+// structs for the IPLD tree view
+type dagTemplateData struct {
+	Path  string
+	Hash  string
+	Codec string
+	Tree  template.HTML
+}
+
+var dagTemplate = template.Must(template.New("dag").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>{{ .Path }}</title>
+<style>
+body { font-family: monospace; }
+ul { list-style: none; padding-left: 1.5em; margin: 0; }
+.key { font-weight: bold; }
+.string { color: #0b6623; }
+.number, .bool, .null { color: #1f3f8f; }
+</style>
+</head>
+<body>
+<h1>{{ .Path }}</h1>
+<p>{{ .Codec }} node {{ .Hash }}</p>
+{{ .Tree }}
+</body>
+</html>
+`))
+
+// renderDagPage renders IPLD data as an HTML tree. The fields link to their
+// own path, and the CIDs to the URL cidLink returns for them.
+func renderDagPage(value interface{}, c cid.Cid, cidLink func(string) string, urlPath string) ([]byte, error) {
+	// go through dag-json to get the generic form of the data, with the
+	// links as {"/": "<cid>"}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var generic interface{}
+	if err := dec.Decode(&generic); err != nil {
+		return nil, err
+	}
+
+	codec, ok := cid.CodecToStr[c.Type()]
+	if !ok {
+		codec = "unknown"
+	}
+
+	var tree bytes.Buffer
+	renderDagValue(&tree, generic, cidLink, strings.TrimSuffix(urlPath, "/"))
+
+	var buf bytes.Buffer
+	err = dagTemplate.Execute(&buf, &dagTemplateData{
+		Path:  urlPath,
+		Hash:  c.String(),
+		Codec: codec,
+		Tree:  template.HTML(tree.String()),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func renderDagValue(buf *bytes.Buffer, v interface{}, cidLink func(string) string, path string) {
+	esc := template.HTMLEscapeString
+
+	switch v := v.(type) {
+	case map[string]interface{}:
+		if l, ok := v["/"].(string); ok && len(v) == 1 {
+			if _, err := cid.Decode(l); err == nil {
+				buf.WriteString(`<a class="link" href="` + esc(cidLink(l)) + `">` + esc(l) + `</a>`)
+				return
+			}
+		}
+
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+
+		buf.WriteString("<ul>")
+		for _, k := range keys {
+			sub := path + "/" + url.PathEscape(k)
+			buf.WriteString(`<li><a class="key" href="` + esc(sub) + `">` + esc(k) + `</a>: `)
+			renderDagValue(buf, v[k], cidLink, sub)
+			buf.WriteString("</li>")
+		}
+		buf.WriteString("</ul>")
+	case []interface{}:
+		buf.WriteString("<ul>")
+		for i, e := range v {
+			sub := path + "/" + strconv.Itoa(i)
+			buf.WriteString(`<li><a class="key" href="` + esc(sub) + `">` + strconv.Itoa(i) + `</a>: `)
+			renderDagValue(buf, e, cidLink, sub)
+			buf.WriteString("</li>")
+		}
+		buf.WriteString("</ul>")
+	case string:
+		data, _ := json.Marshal(v)
+		buf.WriteString(`<span class="string">` + esc(string(data)) + `</span>`)
+	case json.Number:
+		buf.WriteString(`<span class="number">` + esc(v.String()) + `</span>`)
+	case bool:
+		if v {
+			buf.WriteString(`<span class="bool">true</span>`)
+		} else {
+			buf.WriteString(`<span class="bool">false</span>`)
+		}
+	case nil:
+		buf.WriteString(`<span class="null">null</span>`)
+	}
+}
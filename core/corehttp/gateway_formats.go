@@ -0,0 +1,255 @@
+package corehttp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	coreiface "github.com/ipfs/go-ipfs/core/coreapi/interface"
+
+	cid "gx/ipfs/QmR8BauakNcBa3RbE4nbQu76PDiJgoQgz8AJdhJuiU4TAw/go-cid"
+	cbor "gx/ipfs/QmRZxJ7oybgnnwriuRub9JXp5YdFM9wiGSyRq38QC7swpS/go-ipld-cbor"
+)
+
+// Response formats which can be asked instead of the UnixFS view of a path,
+// with the `format` query parameter or the Accept header. They let clients
+// verify what they get against the CIDs instead of trusting the gateway.
+const (
+	// rawFormat is the bytes of the block the path resolves to
+	rawFormat = "raw"
+	// carFormat is a CARv1 archive of the DAG the path resolves to
+	carFormat = "car"
+	// dagJSONFormat is the IPLD data the path resolves to, as JSON
+	dagJSONFormat = "dag-json"
+	// dagCBORFormat is the IPLD data the path resolves to, as CBOR
+	dagCBORFormat = "dag-cbor"
+)
+
+// formatContentTypes maps the response formats to their media types
+var formatContentTypes = map[string]string{
+	rawFormat:     "application/vnd.ipld.raw",
+	carFormat:     "application/vnd.ipld.car",
+	dagJSONFormat: "application/vnd.ipld.dag-json",
+	dagCBORFormat: "application/vnd.ipld.dag-cbor",
+}
+
+// formatExtensions are the file extensions of the formats served as
+// attachments
+var formatExtensions = map[string]string{
+	rawFormat: "bin",
+	carFormat: "car",
+}
+
+// responseFormat returns the response format asked by the request, or ""
+// for the default UnixFS response. The format query parameter takes
+// precedence over the Accept header.
+func responseFormat(r *http.Request) (string, error) {
+	if f := r.URL.Query().Get("format"); f != "" {
+		if _, ok := formatContentTypes[f]; !ok {
+			return "", fmt.Errorf("unsupported format %q", f)
+		}
+		return f, nil
+	}
+
+	for _, accept := range r.Header["Accept"] {
+		for _, mt := range strings.Split(accept, ",") {
+			// ignore the media type parameters, such as q= or version=
+			if i := strings.IndexByte(mt, ';'); i >= 0 {
+				mt = mt[:i]
+			}
+			mt = strings.ToLower(strings.TrimSpace(mt))
+			for f, ct := range formatContentTypes {
+				if mt == ct {
+					return f, nil
+				}
+			}
+		}
+	}
+	return "", nil
+}
+
+// setFormatHeaders sets the headers common to all the response formats and
+// returns false if the client already has the response cached
+func (i *gatewayHandler) setFormatHeaders(w http.ResponseWriter, r *http.Request, resolvedPath coreiface.ResolvedPath, format, contentType string) bool {
+	c := resolvedPath.Cid()
+
+	// the body differs from the UnixFS response, so does the etag. It also
+	// depends on the fields traversed inside the node.
+	tag := c.String()
+	if rem := resolvedPath.Remainder(); rem != "" {
+		tag += "/" + rem
+	}
+	etag := "\"" + tag + "." + format + "\""
+	if r.Header.Get("If-None-Match") == etag || r.Header.Get("If-None-Match") == "W/"+etag {
+		w.WriteHeader(http.StatusNotModified)
+		return false
+	}
+
+	i.addUserHeaders(w)
+	w.Header().Set("X-IPFS-Path", r.URL.Path)
+	w.Header().Set("Etag", etag)
+	w.Header().Set("Vary", "Accept")
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	if ext, ok := formatExtensions[format]; ok {
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"%s.%s\"", c, ext))
+	}
+	if strings.HasPrefix(r.URL.Path, ipfsPathPrefix) {
+		w.Header().Set("Cache-Control", "public, max-age=29030400, immutable")
+	}
+	return true
+}
+
+// requireWholeBlock rejects the paths resolving to a field inside a block, as
+// the format can only be served for whole blocks
+func requireWholeBlock(w http.ResponseWriter, resolvedPath coreiface.ResolvedPath, format string) bool {
+	if rem := resolvedPath.Remainder(); rem != "" {
+		err := fmt.Errorf("the path resolves to %q inside %s", rem, resolvedPath.Cid())
+		webError(w, "format "+format+" is only supported for whole blocks", err, http.StatusBadRequest)
+		return false
+	}
+	return true
+}
+
+// serveRawBlock writes the block the path resolves to
+func (i *gatewayHandler) serveRawBlock(ctx context.Context, w http.ResponseWriter, r *http.Request, resolvedPath coreiface.ResolvedPath) {
+	if !requireWholeBlock(w, resolvedPath, rawFormat) {
+		return
+	}
+
+	block, err := i.api.Block().Get(ctx, resolvedPath)
+	if err != nil {
+		webError(w, "ipfs block get "+resolvedPath.Cid().String(), err, http.StatusNotFound)
+		return
+	}
+
+	if !i.setFormatHeaders(w, r, resolvedPath, rawFormat, formatContentTypes[rawFormat]) {
+		return
+	}
+	if r.Method == "HEAD" {
+		return
+	}
+
+	if _, err := io.Copy(w, block); err != nil {
+		log.Debugf("error writing block %s: %s", resolvedPath.Cid(), err)
+	}
+}
+
+// serveCar streams a CAR archive of the DAG the path resolves to
+func (i *gatewayHandler) serveCar(ctx context.Context, w http.ResponseWriter, r *http.Request, resolvedPath coreiface.ResolvedPath) {
+	if !requireWholeBlock(w, resolvedPath, carFormat) {
+		return
+	}
+	if !i.setFormatHeaders(w, r, resolvedPath, carFormat, formatContentTypes[carFormat]) {
+		return
+	}
+	if r.Method == "HEAD" {
+		return
+	}
+
+	// The archive is streamed as the DAG is walked, so the status is sent
+	// before we know whether all the blocks can be fetched. On failure the
+	// response is cut short, which the client detects as the archive is
+	// incomplete.
+	if err := i.api.Dag().Export(ctx, resolvedPath.Cid(), w); err != nil {
+		log.Errorf("error streaming the CAR of %s: %s", resolvedPath.Cid(), err)
+		panic(http.ErrAbortHandler)
+	}
+}
+
+// acceptsHTML returns whether the request comes from a browser
+func acceptsHTML(r *http.Request) bool {
+	for _, accept := range r.Header["Accept"] {
+		if strings.Contains(accept, "text/html") {
+			return true
+		}
+	}
+	return false
+}
+
+// dagPageLinker returns the function building the links to CIDs on the tree
+// view. On a subdomain, /ipfs/<cid> paths would stay on the origin of the
+// current site, the links go to the subdomain of the CID instead.
+func dagPageLinker(r *http.Request, prefix string) func(string) string {
+	pathLink := func(c string) string {
+		return prefix + ipfsPathPrefix + c
+	}
+
+	gw, ok := r.Context().Value(subdomainGatewayKey{}).(subdomainGateway)
+	if !ok {
+		return pathLink
+	}
+	return func(c string) string {
+		u, err := gw.contentURL("ipfs", c)
+		if err != nil {
+			// too long for a subdomain, go through the gateway itself
+			return gw.scheme + "://" + gw.host + pathLink(c)
+		}
+		return u
+	}
+}
+
+// serveCodec renders the IPLD data the path resolves to, following the
+// fields of the path left after the last node as 'ipfs dag get' does. The
+// default format is an HTML tree view for browsers, and dag-json otherwise.
+func (i *gatewayHandler) serveCodec(ctx context.Context, w http.ResponseWriter, r *http.Request, resolvedPath coreiface.ResolvedPath, format, prefix, originalUrlPath string) {
+	nd, err := i.api.Dag().Get(ctx, resolvedPath.Cid())
+	if err != nil {
+		webError(w, "ipfs dag get "+resolvedPath.Cid().String(), err, http.StatusNotFound)
+		return
+	}
+
+	var value interface{} = nd
+	if rem := resolvedPath.Remainder(); rem != "" {
+		value, _, err = nd.Resolve(strings.Split(rem, "/"))
+		if err != nil {
+			webError(w, "ipfs dag get "+r.URL.Path, err, http.StatusNotFound)
+			return
+		}
+	}
+
+	var body []byte
+	contentType := formatContentTypes[format]
+	switch format {
+	case dagCBORFormat:
+		if resolvedPath.Cid().Type() != cid.DagCBOR {
+			webError(w, "ipfs dag get "+r.URL.Path, fmt.Errorf("%s is not a dag-cbor node", resolvedPath.Cid()), http.StatusNotAcceptable)
+			return
+		}
+		if resolvedPath.Remainder() == "" {
+			body = nd.RawData()
+		} else {
+			body, err = cbor.DumpObject(value)
+		}
+	case dagJSONFormat:
+		body, err = json.Marshal(value)
+	default:
+		if !acceptsHTML(r) {
+			format = dagJSONFormat
+			contentType = "application/json"
+			body, err = json.Marshal(value)
+			break
+		}
+		format = "html"
+		contentType = "text/html; charset=utf-8"
+		body, err = renderDagPage(value, resolvedPath.Cid(), dagPageLinker(r, prefix), originalUrlPath)
+	}
+	if err != nil {
+		internalWebError(w, err)
+		return
+	}
+
+	if !i.setFormatHeaders(w, r, resolvedPath, format, contentType) {
+		return
+	}
+	if r.Method == "HEAD" {
+		return
+	}
+
+	if _, err := w.Write(body); err != nil {
+		log.Debugf("error writing %s: %s", resolvedPath.Cid(), err)
+	}
+}
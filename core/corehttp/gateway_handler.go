@@ -68,6 +68,10 @@ func (i *gatewayHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 
 	defer func() {
 		if r := recover(); r != nil {
+			if r == http.ErrAbortHandler {
+				// let net/http cut the response short
+				panic(r)
+			}
 			log.Error("A panic occurred in the gateway handler!")
 			log.Error(r)
 			debug.PrintStack()
@@ -163,6 +167,30 @@ func (i *gatewayHandler) getOrHeadHandler(ctx context.Context, w http.ResponseWr
 		return
 	}
 
+	format, err := responseFormat(r)
+	if err != nil {
+		webError(w, "invalid response format", err, http.StatusBadRequest)
+		return
+	}
+	switch format {
+	case rawFormat:
+		i.serveRawBlock(ctx, w, r, resolvedPath)
+		return
+	case carFormat:
+		i.serveCar(ctx, w, r, resolvedPath)
+		return
+	case dagJSONFormat, dagCBORFormat:
+		i.serveCodec(ctx, w, r, resolvedPath, format, prefix, originalUrlPath)
+		return
+	}
+
+	// UnixFS only uses dag-pb and raw nodes, render the other codecs as
+	// IPLD data
+	if t := resolvedPath.Cid().Type(); t != cid.DagProtobuf && t != cid.Raw {
+		i.serveCodec(ctx, w, r, resolvedPath, "", prefix, originalUrlPath)
+		return
+	}
+
 	dr, err := i.api.Unixfs().Get(ctx, resolvedPath)
 	if err != nil {
 		webError(w, "ipfs cat "+escapedURLPath, err, http.StatusNotFound)
@@ -181,6 +209,8 @@ func (i *gatewayHandler) getOrHeadHandler(ctx context.Context, w http.ResponseWr
 	i.addUserHeaders(w) // ok, _now_ write user's headers.
 	w.Header().Set("X-IPFS-Path", urlPath)
 	w.Header().Set("Etag", etag)
+	// the response depends on the format asked in the Accept header
+	w.Header().Set("Vary", "Accept")
 
 	// Suborigin header, sandboxes apps from each other in the browser (even
 	// though they are served from the same gateway domain).
@@ -1,8 +1,10 @@
 package corehttp
 
 import (
+	"bytes"
 	"context"
 	"errors"
+	"io"
 	"io/ioutil"
 	"math"
 	"net/http"
@@ -12,6 +14,7 @@ import (
 	"time"
 
 	version "github.com/ipfs/go-ipfs"
+	car "github.com/ipfs/go-ipfs/car"
 	core "github.com/ipfs/go-ipfs/core"
 	"github.com/ipfs/go-ipfs/core/coreapi"
 	"github.com/ipfs/go-ipfs/core/coreapi/interface"
@@ -22,9 +25,14 @@ import (
 
 	ci "gx/ipfs/QmNiJiXwWE3kRhZrC5ej3kSjWHm337pYfhjLGSCDNKJP2s/go-libp2p-crypto"
 	path "gx/ipfs/QmQ3YSqfxunT5QBg6KBVskKyRE26q6hjSMyhpxchpm7jEN/go-path"
+	cid "gx/ipfs/QmR8BauakNcBa3RbE4nbQu76PDiJgoQgz8AJdhJuiU4TAw/go-cid"
+	ipld "gx/ipfs/QmRL22E4paat7ky7vx9MLpR97JHHbFPrg3ytFQw6qp1y1s/go-ipld-format"
+	ipldcbor "gx/ipfs/QmRZxJ7oybgnnwriuRub9JXp5YdFM9wiGSyRq38QC7swpS/go-ipld-cbor"
 	id "gx/ipfs/QmSgtf5vHyugoxcwMbyNy6bZ9qPDDTJSYEED2GkWjLwitZ/go-libp2p/p2p/protocol/identify"
 	config "gx/ipfs/QmTbcMKv6GU3fxhnNcbzYChdox9Fdd7VpucM3PQ7UWjX3D/go-ipfs-config"
 	files "gx/ipfs/QmaXvvAVAQ5ABqM5xtjYmV85xmN5MkWAZsX9H9Fwo4FVXp/go-ipfs-files"
+	multibase "gx/ipfs/QmekxXDhCxCJRNuzmHreuaT3BsuJcsjcXWNrtV9C8DRHtd/go-multibase"
+	mh "gx/ipfs/QmerPMzPk1mJVowm8KgmoknWa4yCYvvugMPsgWmDNUvDLW/go-multihash"
 	datastore "gx/ipfs/Qmf4xQhNomPNhrtZc67qSnfJSjxjXs9LWvknJtSXwimPrM/go-datastore"
 	syncds "gx/ipfs/Qmf4xQhNomPNhrtZc67qSnfJSjxjXs9LWvknJtSXwimPrM/go-datastore/sync"
 )
@@ -586,6 +594,379 @@ func TestGoGetSupport(t *testing.T) {
 	}
 }
 
+func TestGatewayRawAndCar(t *testing.T) {
+	ns := mockNamesys{}
+	ts, api, ctx := newTestServerAndNode(t, ns)
+	defer ts.Close()
+
+	dir := files.NewMapDirectory(map[string]files.Node{
+		"a": files.NewBytesFile([]byte("fnord")),
+		"b": files.NewBytesFile([]byte("florp")),
+	})
+	k, err := api.Unixfs().Add(ctx, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	get := func(path, accept string) *http.Response {
+		req, err := http.NewRequest("GET", ts.URL+path, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if accept != "" {
+			req.Header.Set("Accept", accept)
+		}
+		res, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return res
+	}
+
+	// raw block of a file, both with the Accept header and the query
+	fp, err := api.ResolvePath(ctx, iface.Join(k, "a"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	br, err := api.Block().Get(ctx, fp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected, err := ioutil.ReadAll(br)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, res := range []*http.Response{
+		get(k.String()+"/a", "application/vnd.ipld.raw"),
+		get(k.String()+"/a?format=raw", ""),
+	} {
+		body, err := ioutil.ReadAll(res.Body)
+		res.Body.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if res.StatusCode != http.StatusOK {
+			t.Fatalf("expected 200, got %d: %s", res.StatusCode, body)
+		}
+		if ct := res.Header.Get("Content-Type"); ct != "application/vnd.ipld.raw" {
+			t.Errorf("unexpected content type %s", ct)
+		}
+		if etag := res.Header.Get("Etag"); etag != "\""+fp.Cid().String()+".raw\"" {
+			t.Errorf("unexpected etag %s", etag)
+		}
+		if !bytes.Equal(body, expected) {
+			t.Errorf("expected the raw block, got %q", body)
+		}
+	}
+
+	// CAR of the directory
+	res := get(k.String(), "application/vnd.ipld.car; version=1")
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		t.Fatalf("expected 200, got %d", res.StatusCode)
+	}
+	if ct := res.Header.Get("Content-Type"); ct != "application/vnd.ipld.car" {
+		t.Errorf("unexpected content type %s", ct)
+	}
+
+	cr, err := car.NewReader(res.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cr.Header.Roots) != 1 || !cr.Header.Roots[0].Equals(k.Cid()) {
+		t.Fatalf("unexpected CAR roots %v", cr.Header.Roots)
+	}
+	n := 0
+	for {
+		_, err := cr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		n++
+	}
+	if n != 3 {
+		t.Errorf("expected 3 blocks in the CAR, got %d", n)
+	}
+
+	// unknown formats are rejected
+	res = get(k.String()+"?format=foo", "")
+	res.Body.Close()
+	if res.StatusCode != http.StatusBadRequest {
+		t.Errorf("expected 400 for an unknown format, got %d", res.StatusCode)
+	}
+}
+
+func TestGatewayDagCbor(t *testing.T) {
+	ns := mockNamesys{}
+	ts, api, ctx := newTestServerAndNode(t, ns)
+	defer ts.Close()
+
+	child, err := ipldcbor.FromJSON(strings.NewReader(`"foo"`), math.MaxUint64, -1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	nd, err := ipldcbor.FromJSON(strings.NewReader(`{"a": {"b": 1}, "lnk": {"/": "`+child.Cid().String()+`"}}`), math.MaxUint64, -1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := api.Dag().AddMany(ctx, []ipld.Node{child, nd}); err != nil {
+		t.Fatal(err)
+	}
+
+	root := "/ipfs/" + nd.Cid().String()
+	for i, test := range []struct {
+		path   string
+		accept string
+		ctype  string
+		text   string
+	}{
+		{root + "/a", "", "application/json", `{"b":1}`},
+		{root + "/a/b", "", "application/json", `1`},
+		{root + "/lnk", "", "application/json", `"foo"`},
+		{root + "/a?format=dag-json", "", "application/vnd.ipld.dag-json", `{"b":1}`},
+		{root, "application/vnd.ipld.dag-cbor", "application/vnd.ipld.dag-cbor", string(nd.RawData())},
+	} {
+		req, err := http.NewRequest("GET", ts.URL+test.path, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if test.accept != "" {
+			req.Header.Set("Accept", test.accept)
+		}
+		res, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, err := ioutil.ReadAll(res.Body)
+		res.Body.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if res.StatusCode != http.StatusOK {
+			t.Errorf("(%d) expected 200 from %s, got %d: %s", i, test.path, res.StatusCode, body)
+			continue
+		}
+		if ct := res.Header.Get("Content-Type"); ct != test.ctype {
+			t.Errorf("(%d) expected content type %s from %s, got %s", i, test.ctype, test.path, ct)
+		}
+		if string(body) != test.text {
+			t.Errorf("(%d) unexpected response body from %s: expected %q; got %q", i, test.path, test.text, body)
+		}
+	}
+
+	// browsers get a tree view linking back into the gateway
+	req, err := http.NewRequest("GET", ts.URL+root, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Accept", "text/html,application/xhtml+xml,*/*;q=0.8")
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, err := ioutil.ReadAll(res.Body)
+	res.Body.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(res.Header.Get("Content-Type"), "text/html") {
+		t.Errorf("expected an html page, got %s", res.Header.Get("Content-Type"))
+	}
+	for _, s := range []string{
+		`href="/ipfs/` + child.Cid().String() + `"`,
+		`href="` + root + `/a/b"`,
+	} {
+		if !strings.Contains(string(body), s) {
+			t.Errorf("expected the page to contain %s", s)
+		}
+	}
+
+	// raw blocks and CARs can't be asked for a field inside a block
+	for _, format := range []string{"raw", "car"} {
+		req, err := http.NewRequest("GET", ts.URL+root+"/a?format="+format, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		res, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		res.Body.Close()
+		if res.StatusCode != http.StatusBadRequest {
+			t.Errorf("expected 400 for format=%s, got %d", format, res.StatusCode)
+		}
+	}
+
+	// dag-cbor can't be asked for dag-pb nodes
+	req, err = http.NewRequest("GET", ts.URL+emptyDir+"?format=dag-cbor", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res, err = http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+	if res.StatusCode != http.StatusNotAcceptable {
+		t.Errorf("expected 406, got %d", res.StatusCode)
+	}
+}
+
+func TestSubdomainGateway(t *testing.T) {
+	ns := mockNamesys{}
+	n, err := newNodeWithMockNamesys(ns)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dh := &delegatedHandler{}
+	ts := httptest.NewServer(dh)
+	defer ts.Close()
+
+	dh.Handler, err = makeHandler(n,
+		ts.Listener,
+		SubdomainGatewayOption(map[string]*GatewaySpec{
+			"example.org": {UseSubdomains: true},
+			"paths.org":   {UseSubdomains: false},
+		}),
+		IPNSHostnameOption(),
+		GatewayOption(false, "/ipfs", "/ipns"),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	api, err := coreapi.NewCoreAPI(n)
+	if err != nil {
+		t.Fatal(err)
+	}
+	k, err := api.Unixfs().Add(n.Context(), files.NewBytesFile([]byte("fnord")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ns["/ipns/my-site.example.net"] = path.FromString(k.String())
+
+	v1, err := cid.NewCidV1(k.Cid().Type(), k.Cid().Hash()).StringOfBase(multibase.Base32)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	long, err := cid.Prefix{Version: 1, Codec: cid.Raw, MhType: mh.SHA2_512, MhLength: -1}.Sum([]byte("fnord"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i, test := range []struct {
+		host     string
+		path     string
+		status   int
+		location string
+		text     string
+	}{
+		// CIDv0 paths are redirected to the base32 CIDv1 subdomain
+		{"example.org", k.String(), http.StatusMovedPermanently, "http://" + v1 + ".ipfs.example.org/", ""},
+		{"example.org", k.String() + "/?filename=x", http.StatusMovedPermanently, "http://" + v1 + ".ipfs.example.org/?filename=x", ""},
+		{"example.org", "/ipns/my-site.example.net", http.StatusMovedPermanently, "http://my--site-example-net.ipns.example.org/", ""},
+		{"example.org", "/ipfs/" + long.String(), http.StatusBadRequest, "", ""},
+		{v1 + ".ipfs.example.org", "/", http.StatusOK, "", "fnord"},
+		{"my--site-example-net.ipns.example.org", "/", http.StatusOK, "", "fnord"},
+		{"notacid.ipfs.example.org", "/", http.StatusBadRequest, "", ""},
+		// hostnames without subdomains keep serving paths
+		{"paths.org", k.String(), http.StatusOK, "", "fnord"},
+		{"localhost", k.String(), http.StatusOK, "", "fnord"},
+	} {
+		req, err := http.NewRequest("GET", ts.URL+test.path, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Host = test.host
+
+		res, err := doWithoutRedirect(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, err := ioutil.ReadAll(res.Body)
+		res.Body.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if res.StatusCode != test.status {
+			t.Errorf("(%d) got %d, expected %d from http://%s%s: %s", i, res.StatusCode, test.status, test.host, test.path, body)
+			continue
+		}
+		if loc := res.Header.Get("Location"); loc != test.location {
+			t.Errorf("(%d) expected redirect to %q, got %q", i, test.location, loc)
+		}
+		if test.text != "" && string(body) != test.text {
+			t.Errorf("(%d) expected %q, got %q", i, test.text, body)
+		}
+	}
+
+	// the header set by the subdomain gateway must not let clients skip
+	// the DNSLink rewrite
+	req, err := http.NewRequest("GET", ts.URL+"/", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Host = "my-site.example.net"
+	req.Header.Set("X-Ipns-Original-Path", "/")
+
+	res, err := doWithoutRedirect(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, err := ioutil.ReadAll(res.Body)
+	res.Body.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.StatusCode != http.StatusOK || string(body) != "fnord" {
+		t.Errorf("expected the DNSLink to be served despite the header, got %d: %s", res.StatusCode, body)
+	}
+
+	// the tree view of a subdomain links to the subdomains of the CIDs
+	nd, err := ipldcbor.FromJSON(strings.NewReader(`{"lnk": {"/": "`+k.Cid().String()+`"}}`), math.MaxUint64, -1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := api.Dag().Add(n.Context(), nd); err != nil {
+		t.Fatal(err)
+	}
+	ndv1, err := cidV1Base32(nd.Cid())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req, err = http.NewRequest("GET", ts.URL+"/", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Host = ndv1 + ".ipfs.example.org"
+	req.Header.Set("Accept", "text/html")
+
+	res, err = doWithoutRedirect(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, err = ioutil.ReadAll(res.Body)
+	res.Body.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.StatusCode != http.StatusOK {
+		t.Fatalf("expected 200 from the tree view, got %d: %s", res.StatusCode, body)
+	}
+	if link := `href="http://` + v1 + `.ipfs.example.org/"`; !strings.Contains(string(body), link) {
+		t.Errorf("expected the page to contain %s, got %s", link, body)
+	}
+}
+
 func TestVersion(t *testing.T) {
 	version.CurrentCommit = "theshortcommithash"
 
@@ -24,7 +24,9 @@ func IPNSHostnameOption() ServeOption {
 			defer cancel()
 
 			host := strings.SplitN(r.Host, ":", 2)[0]
-			if len(host) > 0 && isd.IsDomain(host) {
+			// the subdomain gateway may have rewritten the request already
+			_, rewritten := r.Context().Value(subdomainGatewayKey{}).(subdomainGateway)
+			if len(host) > 0 && !rewritten && isd.IsDomain(host) {
 				name := "/ipns/" + host
 				_, err := n.Namesys.Resolve(ctx, name, nsopts.Depth(1))
 				if err == nil || err == namesys.ErrResolveRecursion {
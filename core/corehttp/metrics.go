@@ -3,11 +3,17 @@ package corehttp
 import (
 	"net"
 	"net/http"
+	"strconv"
+	"sync"
+	"time"
 
 	core "github.com/ipfs/go-ipfs/core"
+	corerepo "github.com/ipfs/go-ipfs/core/corerepo"
+	p2p "github.com/ipfs/go-ipfs/p2p"
 
 	prometheus "gx/ipfs/QmTQuFQWHAWy4wMH6ZyPfGiawA5u9T8rs79FENoV8yXaoS/client_golang/prometheus"
 	promhttp "gx/ipfs/QmTQuFQWHAWy4wMH6ZyPfGiawA5u9T8rs79FENoV8yXaoS/client_golang/prometheus/promhttp"
+	bitswap "gx/ipfs/QmYJ48z7NEzo3u2yCvUvNtBQ7wJWd5dX2nxxc7FeA6nHq1/go-bitswap"
 )
 
 // This adds the scraping endpoint which Prometheus uses to fetch metrics.
@@ -93,21 +99,220 @@ func MetricsCollectionOption(handlerName string) ServeOption {
 	}
 }
 
+// newGatewayResponsesCounter returns the counter of the gateway responses by
+// route and status code
+func newGatewayResponsesCounter() (*prometheus.CounterVec, error) {
+	responses := prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "ipfs",
+			Subsystem: "http",
+			Name:      "gateway_responses_total",
+			Help:      "Number of gateway responses by route and status code.",
+		},
+		[]string{"route", "code"},
+	)
+	if err := prometheus.Register(responses); err != nil {
+		if are, ok := err.(prometheus.AlreadyRegisteredError); ok {
+			return are.ExistingCollector.(*prometheus.CounterVec), nil
+		}
+		return nil, err
+	}
+	return responses, nil
+}
+
+// instrumentGatewayRoute counts the responses of the handler of a gateway
+// route
+func instrumentGatewayRoute(responses *prometheus.CounterVec, route string, h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		sw := &statusResponseWriter{ResponseWriter: w}
+		h.ServeHTTP(sw, r)
+		if sw.status == 0 {
+			sw.status = http.StatusOK
+		}
+		responses.WithLabelValues(route, strconv.Itoa(sw.status)).Inc()
+	})
+}
+
+// statusResponseWriter records the status code of a response
+type statusResponseWriter struct {
+	http.ResponseWriter
+	status int
+}
+
+func (w *statusResponseWriter) WriteHeader(code int) {
+	if w.status == 0 {
+		w.status = code
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *statusResponseWriter) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *statusResponseWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
 var (
 	peersTotalMetric = prometheus.NewDesc(
 		prometheus.BuildFQName("ipfs", "p2p", "peers_total"),
 		"Number of connected peers", []string{"transport"}, nil)
+
+	reproviderProvidedMetric = prometheus.NewDesc(
+		prometheus.BuildFQName("ipfs", "reprovider", "keys_provided_total"),
+		"Number of keys announced by the reprovider", nil, nil)
+	reproviderFailedMetric = prometheus.NewDesc(
+		prometheus.BuildFQName("ipfs", "reprovider", "failures_total"),
+		"Number of keys the reprovider failed to announce", nil, nil)
+	reproviderLastRunMetric = prometheus.NewDesc(
+		prometheus.BuildFQName("ipfs", "reprovider", "last_run_timestamp_seconds"),
+		"Time the last completed reprovider run started at", nil, nil)
+	reproviderLastDurationMetric = prometheus.NewDesc(
+		prometheus.BuildFQName("ipfs", "reprovider", "last_run_duration_seconds"),
+		"Duration of the last completed reprovider run", nil, nil)
+
+	namesysCacheHitsMetric = prometheus.NewDesc(
+		prometheus.BuildFQName("ipfs", "namesys", "cache_hits_total"),
+		"Number of names resolved from the resolve cache", nil, nil)
+	namesysCacheMissesMetric = prometheus.NewDesc(
+		prometheus.BuildFQName("ipfs", "namesys", "cache_misses_total"),
+		"Number of names not found in the resolve cache", nil, nil)
+	namesysCacheEntriesMetric = prometheus.NewDesc(
+		prometheus.BuildFQName("ipfs", "namesys", "cache_entries"),
+		"Number of entries in the resolve cache", nil, nil)
+
+	reproviderRunningMetric = prometheus.NewDesc(
+		prometheus.BuildFQName("ipfs", "reprovider", "running"),
+		"Whether a reprovider run is in progress", nil, nil)
+
+	repoSizeMetric = prometheus.NewDesc(
+		prometheus.BuildFQName("ipfs", "repo", "size_bytes"),
+		"Size of the repo", nil, nil)
+	repoStorageMaxMetric = prometheus.NewDesc(
+		prometheus.BuildFQName("ipfs", "repo", "storage_max_bytes"),
+		"Datastore.StorageMax of the repo, unset if there is no limit", nil, nil)
+
+	pinsMetric = prometheus.NewDesc(
+		prometheus.BuildFQName("ipfs", "pin", "pins"),
+		"Number of pins", []string{"type"}, nil)
+
+	gcRunsMetric = prometheus.NewDesc(
+		prometheus.BuildFQName("ipfs", "gc", "runs_total"),
+		"Number of completed garbage collection runs", nil, nil)
+	gcBlocksRemovedMetric = prometheus.NewDesc(
+		prometheus.BuildFQName("ipfs", "gc", "blocks_removed_total"),
+		"Number of blocks removed by the garbage collection", nil, nil)
+
+	bitswapWantlistMetric = prometheus.NewDesc(
+		prometheus.BuildFQName("ipfs", "bitswap", "wantlist_keys"),
+		"Number of keys in the wantlist", nil, nil)
+	bitswapBlocksMetric = prometheus.NewDesc(
+		prometheus.BuildFQName("ipfs", "bitswap", "blocks_total"),
+		"Number of blocks exchanged", []string{"direction"}, nil)
+	bitswapBytesMetric = prometheus.NewDesc(
+		prometheus.BuildFQName("ipfs", "bitswap", "bytes_total"),
+		"Number of bytes exchanged", []string{"direction"}, nil)
+
+	dhtRoutingTableMetric = prometheus.NewDesc(
+		prometheus.BuildFQName("ipfs", "dht", "routing_table_peers"),
+		"Number of peers in the DHT routing table", nil, nil)
+
+	p2pListenerBytesMetric = prometheus.NewDesc(
+		prometheus.BuildFQName("ipfs", "p2p", "listener_bytes_total"),
+		"Number of bytes forwarded by the streams of the p2p listeners",
+		[]string{"protocol", "listen_address", "target_address", "direction"}, nil)
+	p2pStreamsMetric = prometheus.NewDesc(
+		prometheus.BuildFQName("ipfs", "p2p", "streams"),
+		"Number of active p2p streams", []string{"protocol"}, nil)
 )
 
+// slowStatsTTL is how long the stats which are expensive to gather, such as
+// the repo size, are reused across scrapes
+const slowStatsTTL = time.Minute
+
 type IpfsNodeCollector struct {
 	Node *core.IpfsNode
+
+	lk     sync.Mutex
+	slow   slowStats
+	slowAt time.Time
+}
+
+// slowStats are the stats which are expensive to gather, the fields are
+// unset when they couldn't be gathered
+type slowStats struct {
+	repo          *corerepo.SizeStat
+	recursivePins int
+	directPins    int
+	bitswap       *bitswap.Stat
 }
 
-func (_ IpfsNodeCollector) Describe(ch chan<- *prometheus.Desc) {
+func (_ *IpfsNodeCollector) Describe(ch chan<- *prometheus.Desc) {
 	ch <- peersTotalMetric
+	ch <- reproviderProvidedMetric
+	ch <- reproviderFailedMetric
+	ch <- reproviderLastRunMetric
+	ch <- reproviderLastDurationMetric
+	ch <- reproviderRunningMetric
+	ch <- repoSizeMetric
+	ch <- repoStorageMaxMetric
+	ch <- pinsMetric
+	ch <- gcRunsMetric
+	ch <- gcBlocksRemovedMetric
+	ch <- bitswapWantlistMetric
+	ch <- bitswapBlocksMetric
+	ch <- bitswapBytesMetric
+	ch <- dhtRoutingTableMetric
+	ch <- namesysCacheHitsMetric
+	ch <- namesysCacheMissesMetric
+	ch <- namesysCacheEntriesMetric
+	ch <- p2pListenerBytesMetric
+	ch <- p2pStreamsMetric
 }
 
-func (c IpfsNodeCollector) Collect(ch chan<- prometheus.Metric) {
+// slowStats returns the cached slow stats, gathering them first if they are
+// older than the TTL
+func (c *IpfsNodeCollector) slowStats() slowStats {
+	c.lk.Lock()
+	defer c.lk.Unlock()
+
+	if !c.slowAt.IsZero() && time.Since(c.slowAt) < slowStatsTTL {
+		return c.slow
+	}
+
+	var st slowStats
+	if c.Node.Repo != nil {
+		if s, err := corerepo.RepoSize(c.Node.Context(), c.Node); err == nil {
+			st.repo = &s
+		} else {
+			log.Warningf("failed to collect the repo size: %s", err)
+		}
+	}
+
+	if c.Node.Pinning != nil {
+		st.recursivePins = len(c.Node.Pinning.RecursiveKeys())
+		st.directPins = len(c.Node.Pinning.DirectKeys())
+	}
+
+	if bs, ok := c.Node.Exchange.(*bitswap.Bitswap); ok {
+		if s, err := bs.Stat(); err == nil {
+			st.bitswap = s
+		} else {
+			log.Warningf("failed to collect the bitswap stats: %s", err)
+		}
+	}
+
+	c.slow, c.slowAt = st, time.Now()
+	return st
+}
+
+func (c *IpfsNodeCollector) Collect(ch chan<- prometheus.Metric) {
 	for tr, val := range c.PeersTotalValues() {
 		ch <- prometheus.MustNewConstMetric(
 			peersTotalMetric,
@@ -116,9 +321,77 @@ func (c IpfsNodeCollector) Collect(ch chan<- prometheus.Metric) {
 			tr,
 		)
 	}
+
+	if c.Node.Reprovider != nil {
+		s := c.Node.Reprovider.Stat()
+		ch <- prometheus.MustNewConstMetric(reproviderProvidedMetric, prometheus.CounterValue, float64(s.TotalProvided))
+		ch <- prometheus.MustNewConstMetric(reproviderFailedMetric, prometheus.CounterValue, float64(s.TotalFailed))
+		if !s.LastRun.IsZero() {
+			ch <- prometheus.MustNewConstMetric(reproviderLastRunMetric, prometheus.GaugeValue, float64(s.LastRun.Unix()))
+			ch <- prometheus.MustNewConstMetric(reproviderLastDurationMetric, prometheus.GaugeValue, s.LastRunDuration.Seconds())
+		}
+		ch <- prometheus.MustNewConstMetric(reproviderRunningMetric, prometheus.GaugeValue, boolValue(s.Running))
+	}
+
+	slow := c.slowStats()
+	if s := slow.repo; s != nil {
+		ch <- prometheus.MustNewConstMetric(repoSizeMetric, prometheus.GaugeValue, float64(s.RepoSize))
+		if s.StorageMax != corerepo.NoLimit {
+			ch <- prometheus.MustNewConstMetric(repoStorageMaxMetric, prometheus.GaugeValue, float64(s.StorageMax))
+		}
+	}
+
+	if c.Node.Pinning != nil {
+		ch <- prometheus.MustNewConstMetric(pinsMetric, prometheus.GaugeValue, float64(slow.recursivePins), "recursive")
+		ch <- prometheus.MustNewConstMetric(pinsMetric, prometheus.GaugeValue, float64(slow.directPins), "direct")
+	}
+
+	ch <- prometheus.MustNewConstMetric(gcRunsMetric, prometheus.CounterValue, float64(c.Node.GCStats.Runs()))
+	ch <- prometheus.MustNewConstMetric(gcBlocksRemovedMetric, prometheus.CounterValue, float64(c.Node.GCStats.BlocksRemoved()))
+
+	if s := slow.bitswap; s != nil {
+		ch <- prometheus.MustNewConstMetric(bitswapWantlistMetric, prometheus.GaugeValue, float64(len(s.Wantlist)))
+		ch <- prometheus.MustNewConstMetric(bitswapBlocksMetric, prometheus.CounterValue, float64(s.BlocksReceived), "received")
+		ch <- prometheus.MustNewConstMetric(bitswapBlocksMetric, prometheus.CounterValue, float64(s.BlocksSent), "sent")
+		ch <- prometheus.MustNewConstMetric(bitswapBytesMetric, prometheus.CounterValue, float64(s.DataReceived), "received")
+		ch <- prometheus.MustNewConstMetric(bitswapBytesMetric, prometheus.CounterValue, float64(s.DataSent), "sent")
+	}
+
+	if c.Node.DHT != nil {
+		ch <- prometheus.MustNewConstMetric(dhtRoutingTableMetric, prometheus.GaugeValue, float64(c.Node.DHT.RoutingTable().Size()))
+	}
+
+	if c.Node.NamesysCache != nil {
+		s := c.Node.NamesysCache.Stats()
+		ch <- prometheus.MustNewConstMetric(namesysCacheHitsMetric, prometheus.CounterValue, float64(s.Hits))
+		ch <- prometheus.MustNewConstMetric(namesysCacheMissesMetric, prometheus.CounterValue, float64(s.Misses))
+		ch <- prometheus.MustNewConstMetric(namesysCacheEntriesMetric, prometheus.GaugeValue, float64(s.Entries))
+	}
+
+	if c.Node.P2P != nil {
+		for _, reg := range []*p2p.Listeners{c.Node.P2P.ListenersLocal, c.Node.P2P.ListenersP2P} {
+			reg.RLock()
+			for _, l := range reg.Listeners {
+				proto, listen, target := string(l.Protocol()), l.ListenAddress().String(), l.TargetAddress().String()
+				ch <- prometheus.MustNewConstMetric(p2pListenerBytesMetric, prometheus.CounterValue, float64(l.Traffic().BytesIn()), proto, listen, target, "in")
+				ch <- prometheus.MustNewConstMetric(p2pListenerBytesMetric, prometheus.CounterValue, float64(l.Traffic().BytesOut()), proto, listen, target, "out")
+			}
+			reg.RUnlock()
+		}
+
+		streams := make(map[string]float64)
+		c.Node.P2P.Streams.Lock()
+		for _, s := range c.Node.P2P.Streams.Streams {
+			streams[string(s.Protocol)]++
+		}
+		c.Node.P2P.Streams.Unlock()
+		for proto, val := range streams {
+			ch <- prometheus.MustNewConstMetric(p2pStreamsMetric, prometheus.GaugeValue, val, proto)
+		}
+	}
 }
 
-func (c IpfsNodeCollector) PeersTotalValues() map[string]float64 {
+func (c *IpfsNodeCollector) PeersTotalValues() map[string]float64 {
 	vals := make(map[string]float64)
 	if c.Node.PeerHost == nil {
 		return vals
@@ -132,3 +405,10 @@ func (c IpfsNodeCollector) PeersTotalValues() map[string]float64 {
 	}
 	return vals
 }
+
+func boolValue(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
@@ -9,6 +9,7 @@ import (
 
 	bhost "gx/ipfs/QmSgtf5vHyugoxcwMbyNy6bZ9qPDDTJSYEED2GkWjLwitZ/go-libp2p/p2p/host/basic"
 	swarmt "gx/ipfs/QmTJCJaS8Cpjc2MkoS32iwr4zMZtbLkaF9GJsUgH1uwtN9/go-libp2p-swarm/testing"
+	mdag "gx/ipfs/QmUtsx89yiCY6F8mbpP6ecXckiSzCBH7EvkKZuZEHBcr1m/go-merkledag"
 	inet "gx/ipfs/QmZ7cBWUXkyWTMN4qH6NGoyMVs7JugyFChBNP4ZUp5rJHH/go-libp2p-net"
 )
 
@@ -48,3 +49,34 @@ func TestPeersTotal(t *testing.T) {
 		t.Fatalf("expected 3 peers, got %f", actual["/ip4/tcp"])
 	}
 }
+
+func TestSlowStatsCached(t *testing.T) {
+	n, err := newNodeWithMockNamesys(mockNamesys{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := n.Context()
+
+	collector := &IpfsNodeCollector{Node: n}
+	if pins := collector.slowStats().directPins; pins != 0 {
+		t.Fatalf("expected no pins, got %d", pins)
+	}
+
+	nd := mdag.NodeWithData([]byte("metrics"))
+	if err := n.DAG.Add(ctx, nd); err != nil {
+		t.Fatal(err)
+	}
+	if err := n.Pinning.Pin(ctx, nd, false); err != nil {
+		t.Fatal(err)
+	}
+
+	if pins := collector.slowStats().directPins; pins != 0 {
+		t.Fatalf("expected the cached stats to be reused, got %d pins", pins)
+	}
+
+	// expire the cached stats
+	collector.slowAt = time.Now().Add(-slowStatsTTL)
+	if pins := collector.slowStats().directPins; pins != 1 {
+		t.Fatalf("expected the stats to be gathered again, got %d pins", pins)
+	}
+}
@@ -0,0 +1,221 @@
+package corehttp
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+
+	core "github.com/ipfs/go-ipfs/core"
+	repo "github.com/ipfs/go-ipfs/repo"
+
+	peer "gx/ipfs/QmPJxxDsX2UbchSHobbYuvz7qnyJTFKvaKMzE2rZWJ4x5B/go-libp2p-peer"
+	cid "gx/ipfs/QmR8BauakNcBa3RbE4nbQu76PDiJgoQgz8AJdhJuiU4TAw/go-cid"
+	multibase "gx/ipfs/QmekxXDhCxCJRNuzmHreuaT3BsuJcsjcXWNrtV9C8DRHtd/go-multibase"
+)
+
+// PublicGatewaysConfigKey is the config section holding the public gateway
+// hostnames, as a map of hostname to GatewaySpec. It isn't kept under Gateway,
+// as the sections the config struct knows about are rewritten from the struct
+// on every config change.
+const PublicGatewaysConfigKey = "PublicGateways"
+
+// maxLabelLength is the maximum length of a DNS label
+const maxLabelLength = 63
+
+// libp2pKeyCodec is the multicodec of the CIDs of peer IDs in subdomains
+const libp2pKeyCodec = 0x72
+
+// subdomainGatewayKey holds the subdomainGateway of the requests rewritten by
+// SubdomainGatewayOption in their context, so that IPNSHostnameOption leaves
+// them alone. A header can't be used for this, as the client sets those.
+type subdomainGatewayKey struct{}
+
+// subdomainGateway is the origin of the public gateway a subdomain request
+// came in through
+type subdomainGateway struct {
+	scheme string
+	host   string
+}
+
+// contentURL returns the URL of the subdomain serving the root of a content
+// path
+func (g subdomainGateway) contentURL(ns, root string) (string, error) {
+	label, err := toSubdomainLabel(ns, root)
+	if err != nil {
+		return "", err
+	}
+	u := url.URL{Scheme: g.scheme, Host: label + "." + ns + "." + g.host, Path: "/"}
+	return u.String(), nil
+}
+
+// requestScheme returns the scheme the client used, behind a proxy too
+func requestScheme(r *http.Request) string {
+	if r.TLS != nil || r.Header.Get("X-Forwarded-Proto") == "https" {
+		return "https"
+	}
+	return "http"
+}
+
+// GatewaySpec is the config of a public gateway hostname
+type GatewaySpec struct {
+	// UseSubdomains redirects the /ipfs/ and /ipns/ path requests on the
+	// hostname to <cid>.ipfs.<hostname> and <name>.ipns.<hostname>, so
+	// that every site gets its own browser origin
+	UseSubdomains bool
+}
+
+// PublicGateways returns the public gateway hostnames configured in the repo
+func PublicGateways(r repo.Repo) (map[string]*GatewaySpec, error) {
+	gateways := make(map[string]*GatewaySpec)
+	if _, err := repo.ReadConfigSection(r, PublicGatewaysConfigKey, &gateways); err != nil {
+		return nil, fmt.Errorf("invalid %s config: %s", PublicGatewaysConfigKey, err)
+	}
+	return gateways, nil
+}
+
+// SubdomainGatewayOption serves content from subdomains of the given public
+// gateway hostnames. Path requests on a hostname using subdomains are
+// redirected to the subdomain of their root, and subdomain requests are
+// rewritten to point at the path on the gateway handler.
+func SubdomainGatewayOption(gateways map[string]*GatewaySpec) ServeOption {
+	return func(n *core.IpfsNode, _ net.Listener, mux *http.ServeMux) (*http.ServeMux, error) {
+		childMux := http.NewServeMux()
+		mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+			host := strings.ToLower(strings.SplitN(r.Host, ":", 2)[0])
+
+			// <cid>.ipfs.<hostname> or <name>.ipns.<hostname>
+			if parts := strings.SplitN(host, ".", 3); len(parts) == 3 {
+				ns, hostname := parts[1], parts[2]
+				if spec, ok := gateways[hostname]; ok && spec.UseSubdomains && (ns == "ipfs" || ns == "ipns") {
+					name, err := fromSubdomainLabel(ns, parts[0])
+					if err != nil {
+						webError(w, "invalid subdomain "+host, err, http.StatusBadRequest)
+						return
+					}
+
+					// links and redirects must use the path the browser
+					// sees, the rewritten one is internal
+					r.Header.Set("X-Ipns-Original-Path", r.URL.Path)
+					r.URL.Path = "/" + ns + "/" + name + r.URL.Path
+					gw := subdomainGateway{
+						scheme: requestScheme(r),
+						// keep the port of the request
+						host: strings.SplitN(r.Host, ".", 3)[2],
+					}
+					r = r.WithContext(context.WithValue(r.Context(), subdomainGatewayKey{}, gw))
+					childMux.ServeHTTP(w, r)
+					return
+				}
+			}
+
+			if spec, ok := gateways[host]; ok && spec.UseSubdomains {
+				if ns, root, rest, ok := splitContentPath(r.URL.Path); ok {
+					label, err := toSubdomainLabel(ns, root)
+					if err != nil {
+						webError(w, "invalid path "+r.URL.Path, err, http.StatusBadRequest)
+						return
+					}
+
+					u := *r.URL
+					u.Scheme = requestScheme(r)
+					u.Host = label + "." + ns + "." + r.Host
+					u.Path = rest
+					http.Redirect(w, r, u.String(), http.StatusMovedPermanently)
+					return
+				}
+			}
+
+			childMux.ServeHTTP(w, r)
+		})
+		return childMux, nil
+	}
+}
+
+// splitContentPath splits /<ns>/<root>/<rest> paths of the ipfs and ipns
+// namespaces
+func splitContentPath(p string) (ns, root, rest string, ok bool) {
+	parts := strings.SplitN(p, "/", 4)
+	if len(parts) < 3 || parts[0] != "" || parts[2] == "" {
+		return "", "", "", false
+	}
+	if parts[1] != "ipfs" && parts[1] != "ipns" {
+		return "", "", "", false
+	}
+
+	rest = "/"
+	if len(parts) == 4 {
+		rest += parts[3]
+	}
+	return parts[1], parts[2], rest, true
+}
+
+// toSubdomainLabel converts the root of a content path to a DNS label, which
+// is case insensitive. CIDs and peer IDs become base32 CIDv1s, and the dots
+// and dashes of DNSLink names are encoded as dashes and double dashes.
+func toSubdomainLabel(ns, root string) (string, error) {
+	var label string
+	switch ns {
+	case "ipfs":
+		c, err := cid.Decode(root)
+		if err != nil {
+			return "", err
+		}
+		label, err = cidV1Base32(cid.NewCidV1(c.Type(), c.Hash()))
+		if err != nil {
+			return "", err
+		}
+	case "ipns":
+		if id, err := peer.IDB58Decode(root); err == nil {
+			label, err = cidV1Base32(cid.NewCidV1(libp2pKeyCodec, []byte(id)))
+			if err != nil {
+				return "", err
+			}
+			break
+		}
+		label = strings.Replace(root, "-", "--", -1)
+		label = strings.Replace(label, ".", "-", -1)
+	}
+
+	if len(label) > maxLabelLength {
+		return "", fmt.Errorf("%s is too long to be used as a subdomain: %d characters, %d maximum", root, len(label), maxLabelLength)
+	}
+	return label, nil
+}
+
+// fromSubdomainLabel converts a subdomain label back to the root of a
+// content path
+func fromSubdomainLabel(ns, label string) (string, error) {
+	if ns == "ipfs" {
+		c, err := cid.Decode(label)
+		if err != nil {
+			return "", err
+		}
+		return c.String(), nil
+	}
+
+	if c, err := cid.Decode(label); err == nil && c.Type() == libp2pKeyCodec {
+		return peer.ID(c.Hash()).Pretty(), nil
+	}
+
+	// DNSLink name, with the dots encoded
+	var name strings.Builder
+	for i := 0; i < len(label); i++ {
+		switch {
+		case label[i] == '-' && i+1 < len(label) && label[i+1] == '-':
+			name.WriteByte('-')
+			i++
+		case label[i] == '-':
+			name.WriteByte('.')
+		default:
+			name.WriteByte(label[i])
+		}
+	}
+	return name.String(), nil
+}
+
+func cidV1Base32(c cid.Cid) (string, error) {
+	return c.StringOfBase(multibase.Base32)
+}
@@ -86,11 +86,21 @@ func GarbageCollect(n *core.IpfsNode, ctx context.Context) error {
 	if err != nil {
 		return err
 	}
-	rmed := gc.GC(ctx, n.Blockstore, n.Repo.Datastore(), n.Pinning, roots)
+	rmed := n.GCStats.Count(gc.GC(ctx, n.Blockstore, n.Repo.Datastore(), n.Pinning, roots))
 
 	return CollectResult(ctx, rmed, nil)
 }
 
+// GarbageCollectIncremental runs an incremental garbage collection within the
+// budget set in opts. See gc.IncrementalGC.
+func GarbageCollectIncremental(n *core.IpfsNode, ctx context.Context, opts gc.IncrementalOptions) <-chan gc.Result {
+	roots := func() ([]cid.Cid, error) {
+		return BestEffortRoots(n.FilesRoot)
+	}
+
+	return n.GCStats.Count(gc.IncrementalGC(ctx, n.Blockstore, n.Repo.Datastore(), n.Pinning, roots, opts))
+}
+
 // CollectResult collects the output of a garbage collection run and calls the
 // given callback for each object removed.  It also collects all errors into a
 // MultiError which is returned after the gc is completed.
@@ -154,7 +164,7 @@ func GarbageCollectAsync(n *core.IpfsNode, ctx context.Context) <-chan gc.Result
 		return out
 	}
 
-	return gc.GC(ctx, n.Blockstore, n.Repo.Datastore(), n.Pinning, roots)
+	return n.GCStats.Count(gc.GC(ctx, n.Blockstore, n.Repo.Datastore(), n.Pinning, roots))
 }
 
 func PeriodicGC(ctx context.Context, node *core.IpfsNode) error {
@@ -213,14 +223,23 @@ func (gc *GC) maybeGC(ctx context.Context, offset uint64) error {
 			log.Warningf("pre-GC: %s", ErrMaxStorageExceeded)
 		}
 
-		// Do GC here
+		// Do GC here, only freeing what takes us back under the watermark
+		// so that writers aren't stalled longer than needed
 		log.Info("Watermark exceeded. Starting repo GC...")
 		defer log.EventBegin(ctx, "repoGC").Done()
 
-		if err := GarbageCollect(gc.Node, ctx); err != nil {
+		if err := garbageCollectTarget(gc.Node, ctx, storage+offset-gc.StorageGC); err != nil {
 			return err
 		}
 		log.Infof("Repo GC done. See `ipfs repo stat` to see how much space got freed.\n")
 	}
 	return nil
 }
+
+func garbageCollectTarget(n *core.IpfsNode, ctx context.Context, target uint64) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	rmed := GarbageCollectIncremental(n, ctx, gc.IncrementalOptions{Target: target})
+	return CollectResult(ctx, rmed, nil)
+}
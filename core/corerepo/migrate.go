@@ -0,0 +1,23 @@
+package corerepo
+
+import (
+	pin "github.com/ipfs/go-ipfs/pin"
+	repo "github.com/ipfs/go-ipfs/repo"
+	fsrepo "github.com/ipfs/go-ipfs/repo/fsrepo"
+
+	bstore "gx/ipfs/QmS2aqUZLJp8kF1ihE5rvDGE5LvmKDPnx32w9Z1BW9xLV5/go-ipfs-blockstore"
+	dag "gx/ipfs/QmUtsx89yiCY6F8mbpP6ecXckiSzCBH7EvkKZuZEHBcr1m/go-merkledag"
+	offline "gx/ipfs/QmYZwey1thDTynSrvd6qQkX24UpTka6TFhQ2v569UpoqxD/go-ipfs-exchange-offline"
+	bserv "gx/ipfs/QmbgbNxC1PMyS2gbx7nf2jKNG7bZAfYJJebdK4ptBBWCz1/go-blockservice"
+)
+
+func init() {
+	fsrepo.RegisterMigration(8, migratePinInfos)
+}
+
+// migratePinInfos stores the pin state with the pin infos set (repo version 8)
+func migratePinInfos(d repo.Datastore) error {
+	bs := bstore.NewBlockstore(d)
+	dserv := dag.NewDAGService(bserv.New(bs, offline.Exchange(bs)))
+	return pin.MigrateInfos(d, dserv, dserv)
+}
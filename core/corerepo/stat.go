@@ -7,9 +7,11 @@ import (
 	context "context"
 
 	"github.com/ipfs/go-ipfs/core"
+	repo "github.com/ipfs/go-ipfs/repo"
 	fsrepo "github.com/ipfs/go-ipfs/repo/fsrepo"
 
 	humanize "gx/ipfs/QmPSBJL4momYnE7DcUyk2DVhD6rH488ZmHBGLbxNdhU44K/go-humanize"
+	bstore "gx/ipfs/QmS2aqUZLJp8kF1ihE5rvDGE5LvmKDPnx32w9Z1BW9xLV5/go-ipfs-blockstore"
 )
 
 // SizeStat wraps information about the repository size and its limit.
@@ -31,12 +33,18 @@ const NoLimit uint64 = math.MaxUint64
 
 // RepoStat returns a *Stat object with all the fields set.
 func RepoStat(ctx context.Context, n *core.IpfsNode) (Stat, error) {
-	sizeStat, err := RepoSize(ctx, n)
+	return RepoStatOf(ctx, n.Repo, n.Blockstore)
+}
+
+// RepoStatOf returns a *Stat object with all the fields set, counting the
+// objects of the given blockstore of the repo.
+func RepoStatOf(ctx context.Context, r repo.Repo, bs bstore.Blockstore) (Stat, error) {
+	sizeStat, err := RepoSizeOf(r)
 	if err != nil {
 		return Stat{}, err
 	}
 
-	allKeys, err := n.Blockstore.AllKeysChan(ctx)
+	allKeys, err := bs.AllKeysChan(ctx)
 	if err != nil {
 		return Stat{}, err
 	}
@@ -64,8 +72,12 @@ func RepoStat(ctx context.Context, n *core.IpfsNode) (Stat, error) {
 
 // RepoSize returns a *Stat object with the RepoSize and StorageMax fields set.
 func RepoSize(ctx context.Context, n *core.IpfsNode) (SizeStat, error) {
-	r := n.Repo
+	return RepoSizeOf(n.Repo)
+}
 
+// RepoSizeOf returns a *Stat object with the RepoSize and StorageMax fields
+// set for the given repo.
+func RepoSizeOf(r repo.Repo) (SizeStat, error) {
 	cfg, err := r.Config()
 	if err != nil {
 		return SizeStat{}, err
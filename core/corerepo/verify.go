@@ -0,0 +1,63 @@
+package corerepo
+
+import (
+	"context"
+	"runtime"
+	"sync"
+
+	repo "github.com/ipfs/go-ipfs/repo"
+
+	cid "gx/ipfs/QmR8BauakNcBa3RbE4nbQu76PDiJgoQgz8AJdhJuiU4TAw/go-cid"
+	bstore "gx/ipfs/QmS2aqUZLJp8kF1ihE5rvDGE5LvmKDPnx32w9Z1BW9xLV5/go-ipfs-blockstore"
+)
+
+// VerifyResult is the result of the verification of a block. Error is set
+// when the block is corrupt.
+type VerifyResult struct {
+	Key   cid.Cid
+	Error error
+}
+
+// Verify rehashes all the blocks stored in the repo, sending a result for
+// each of them. The channel is closed once all blocks are verified or ctx is
+// canceled.
+func Verify(ctx context.Context, r repo.Repo) (<-chan VerifyResult, error) {
+	bs := bstore.NewBlockstore(r.Datastore())
+	bs.HashOnRead(true)
+
+	keys, err := bs.AllKeysChan(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make(chan VerifyResult)
+
+	go func() {
+		defer close(results)
+
+		var wg sync.WaitGroup
+
+		for i := 0; i < runtime.NumCPU()*2; i++ {
+			wg.Add(1)
+			go verifyWorkerRun(ctx, &wg, keys, results, bs)
+		}
+
+		wg.Wait()
+	}()
+
+	return results, nil
+}
+
+func verifyWorkerRun(ctx context.Context, wg *sync.WaitGroup, keys <-chan cid.Cid, results chan<- VerifyResult, bs bstore.Blockstore) {
+	defer wg.Done()
+
+	for k := range keys {
+		_, err := bs.Get(k)
+
+		select {
+		case results <- VerifyResult{Key: k, Error: err}:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
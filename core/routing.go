@@ -0,0 +1,41 @@
+package core
+
+import (
+	"fmt"
+	"sync"
+)
+
+var routingOptionsLk sync.Mutex
+
+// routingOptions are the routing options selectable with Routing.Type, by
+// name
+var routingOptions = map[string]RoutingOption{
+	"dht":       DHTOption,
+	"dhtclient": DHTClientOption,
+	"none":      NilRouterOption,
+}
+
+// AddRoutingOption makes a routing option selectable with the given name in
+// Routing.Type, or with 'ipfs daemon --routing'.
+func AddRoutingOption(name string, opt RoutingOption) error {
+	routingOptionsLk.Lock()
+	defer routingOptionsLk.Unlock()
+
+	if _, ok := routingOptions[name]; ok {
+		return fmt.Errorf("already have a routing option named %q", name)
+	}
+	routingOptions[name] = opt
+	return nil
+}
+
+// RoutingOptionByName returns the routing option of the given name
+func RoutingOptionByName(name string) (RoutingOption, error) {
+	routingOptionsLk.Lock()
+	defer routingOptionsLk.Unlock()
+
+	opt, ok := routingOptions[name]
+	if !ok {
+		return nil, fmt.Errorf("unrecognized routing option: %s", name)
+	}
+	return opt, nil
+}
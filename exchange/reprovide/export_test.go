@@ -0,0 +1,15 @@
+package reprovide
+
+import "time"
+
+// SetRetryTimeout lets tests fail keys without waiting for the default
+// backoff
+func SetRetryTimeout(rp *Reprovider, d time.Duration) {
+	rp.retryTimeout = d
+}
+
+// SetMaxFailures sets the number of keys in a row which can fail before a run
+// is stopped
+func SetMaxFailures(rp *Reprovider, n int) {
+	rp.maxFailures = n
+}
@@ -2,6 +2,7 @@ package reprovide
 
 import (
 	"context"
+	"fmt"
 
 	pin "github.com/ipfs/go-ipfs/pin"
 
@@ -9,6 +10,7 @@ import (
 	ipld "gx/ipfs/QmRL22E4paat7ky7vx9MLpR97JHHbFPrg3ytFQw6qp1y1s/go-ipld-format"
 	blocks "gx/ipfs/QmS2aqUZLJp8kF1ihE5rvDGE5LvmKDPnx32w9Z1BW9xLV5/go-ipfs-blockstore"
 	merkledag "gx/ipfs/QmUtsx89yiCY6F8mbpP6ecXckiSzCBH7EvkKZuZEHBcr1m/go-merkledag"
+	mfs "gx/ipfs/QmVBXaQqupXCFtS62xtr9EsKGkbK9LviqCKSzwcqzwvX9U/go-mfs"
 	cidutil "gx/ipfs/QmdPQx9fvN5ExVwMhRmh7YpCQJzJrFhd1AjVBwJmRMFJeX/go-cidutil"
 )
 
@@ -71,3 +73,80 @@ func pinSet(ctx context.Context, pinning pin.Pinner, dag ipld.DAGService, onlyRo
 
 	return set, nil
 }
+
+// NewMFSProvider returns provider supplying the keys of the mutable filesystem
+// tree, as of the time the provider is called. The root is looked up on each
+// call, so the provider can be created before the filesystem is loaded.
+func NewMFSProvider(root func() *mfs.Root, dag ipld.DAGService, onlyRoots bool) KeyChanFunc {
+	return func(ctx context.Context) (<-chan cid.Cid, error) {
+		r := root()
+		if r == nil {
+			return nil, fmt.Errorf("mfs root not loaded")
+		}
+
+		nd, err := r.GetDirectory().GetNode()
+		if err != nil {
+			return nil, err
+		}
+
+		set := cidutil.NewStreamingSet()
+
+		go func() {
+			ctx, cancel := context.WithCancel(ctx)
+			defer cancel()
+			defer close(set.New)
+
+			set.Visitor(ctx)(nd.Cid())
+
+			if !onlyRoots {
+				err := merkledag.EnumerateChildren(ctx, merkledag.GetLinksWithDAG(dag), nd.Cid(), set.Visitor(ctx))
+				if err != nil {
+					log.Errorf("reprovide mfs: %s", err)
+				}
+			}
+		}()
+
+		return set.New, nil
+	}
+}
+
+// NewCombinedProvider returns provider supplying the keys of all the given
+// providers, each key only once
+func NewCombinedProvider(providers ...KeyChanFunc) KeyChanFunc {
+	return func(ctx context.Context) (<-chan cid.Cid, error) {
+		ctx, cancel := context.WithCancel(ctx)
+
+		chans := make([]<-chan cid.Cid, 0, len(providers))
+		for _, p := range providers {
+			ch, err := p(ctx)
+			if err != nil {
+				cancel()
+				return nil, err
+			}
+			chans = append(chans, ch)
+		}
+
+		outCh := make(chan cid.Cid)
+		go func() {
+			defer cancel()
+			defer close(outCh)
+
+			seen := cid.NewSet()
+			for _, ch := range chans {
+				for c := range ch {
+					if !seen.Visit(c) {
+						continue
+					}
+
+					select {
+					case <-ctx.Done():
+						return
+					case outCh <- c:
+					}
+				}
+			}
+		}()
+
+		return outCh, nil
+	}
+}
@@ -3,6 +3,7 @@ package reprovide
 import (
 	"context"
 	"fmt"
+	"sync"
 	"time"
 
 	backoff "gx/ipfs/QmPJUtEJsm5YLUWhF6imvyCH8KZXRJa9Wup7FDMwTy5Ufz/backoff"
@@ -14,10 +15,38 @@ import (
 
 var log = logging.Logger("reprovider")
 
+// defaultRetryTimeout is how long providing a single key is retried before
+// it is counted as failed
+const defaultRetryTimeout = 15 * time.Minute
+
+// defaultMaxFailures is the number of keys in a row which can fail before a
+// run is stopped, as the routing system is then most likely unreachable
+const defaultMaxFailures = 10
+
 //KeyChanFunc is function streaming CIDs to pass to content routing
 type KeyChanFunc func(context.Context) (<-chan cid.Cid, error)
 type doneFunc func(error)
 
+// Stats holds statistics about reprovider runs
+type Stats struct {
+	// Running is true while a run is in progress
+	Running bool
+
+	// LastRun is the time the last completed run started at, and
+	// LastRunDuration how long it took
+	LastRun         time.Time
+	LastRunDuration time.Duration
+
+	// KeysProvided and KeysFailed count the keys of the last completed run
+	KeysProvided uint64
+	KeysFailed   uint64
+
+	// TotalProvided and TotalFailed count the keys of all runs, including
+	// the one in progress
+	TotalProvided uint64
+	TotalFailed   uint64
+}
+
 type Reprovider struct {
 	ctx     context.Context
 	trigger chan doneFunc
@@ -26,6 +55,12 @@ type Reprovider struct {
 	rsys routing.ContentRouting
 
 	keyProvider KeyChanFunc
+
+	retryTimeout time.Duration
+	maxFailures  int
+
+	statLk sync.Mutex
+	stats  Stats
 }
 
 // NewReprovider creates new Reprovider instance.
@@ -36,9 +71,19 @@ func NewReprovider(ctx context.Context, rsys routing.ContentRouting, keyProvider
 
 		rsys:        rsys,
 		keyProvider: keyProvider,
+
+		retryTimeout: defaultRetryTimeout,
+		maxFailures:  defaultMaxFailures,
 	}
 }
 
+// Stat returns statistics about the reprovider runs
+func (rp *Reprovider) Stat() Stats {
+	rp.statLk.Lock()
+	defer rp.statLk.Unlock()
+	return rp.stats
+}
+
 // Run re-provides keys with 'tick' interval or when triggered
 func (rp *Reprovider) Run(tick time.Duration) {
 	// dont reprovide immediately.
@@ -77,12 +122,38 @@ func (rp *Reprovider) Run(tick time.Duration) {
 	}
 }
 
-// Reprovide registers all keys given by rp.keyProvider to libp2p content routing
+// Reprovide registers all keys given by rp.keyProvider to libp2p content
+// routing. Keys which can't be provided are skipped; an error is returned
+// when at least one of them failed. The run is stopped when too many keys in a
+// row fail.
 func (rp *Reprovider) Reprovide() error {
+	start := time.Now()
+	var provided, failed uint64
+	ran := false
+
+	rp.statLk.Lock()
+	rp.stats.Running = true
+	rp.statLk.Unlock()
+
+	defer func() {
+		rp.statLk.Lock()
+		rp.stats.Running = false
+		if ran {
+			rp.stats.LastRun = start
+			rp.stats.LastRunDuration = time.Since(start)
+			rp.stats.KeysProvided = provided
+			rp.stats.KeysFailed = failed
+		}
+		rp.statLk.Unlock()
+	}()
+
 	keychan, err := rp.keyProvider(rp.ctx)
 	if err != nil {
 		return fmt.Errorf("failed to get key chan: %s", err)
 	}
+	ran = true
+
+	consecutiveFailures := 0
 	for c := range keychan {
 		// hash security
 		if err := verifcid.ValidateCid(c); err != nil {
@@ -97,13 +168,39 @@ func (rp *Reprovider) Reprovide() error {
 			return err
 		}
 
-		// TODO: this backoff library does not respect our context, we should
-		// eventually work contexts into it. low priority.
-		err := backoff.Retry(op, backoff.NewExponentialBackOff())
+		ebo := backoff.NewExponentialBackOff()
+		ebo.MaxElapsedTime = rp.retryTimeout
+
+		err := backoff.Retry(op, backoff.WithContext(ebo, rp.ctx))
+
+		rp.statLk.Lock()
 		if err != nil {
-			log.Debugf("Providing failed after number of retries: %s", err)
-			return err
+			failed++
+			rp.stats.TotalFailed++
+		} else {
+			provided++
+			rp.stats.TotalProvided++
+		}
+		rp.statLk.Unlock()
+
+		if rp.ctx.Err() != nil {
+			return rp.ctx.Err()
 		}
+
+		if err == nil {
+			consecutiveFailures = 0
+			continue
+		}
+
+		log.Debugf("Providing %s failed after number of retries: %s", c, err)
+		consecutiveFailures++
+		if consecutiveFailures >= rp.maxFailures {
+			return fmt.Errorf("stopped providing after %d keys failed in a row, last error: %s", consecutiveFailures, err)
+		}
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("failed to provide %d of %d keys", failed, failed+provided)
 	}
 	return nil
 }
@@ -2,10 +2,15 @@ package reprovide_test
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"testing"
+	"time"
 
 	pstore "gx/ipfs/QmQFFp4ntkd4C14sP3FaH9WJyBuetuGUVo6dShNHvnoEvC/go-libp2p-peerstore"
+	cid "gx/ipfs/QmR8BauakNcBa3RbE4nbQu76PDiJgoQgz8AJdhJuiU4TAw/go-cid"
 	mock "gx/ipfs/QmRJvdmKJoDcQEhhTt5NYXJPQFnJYPo1kfapxtjZLfDDqH/go-ipfs-routing/mock"
+	routing "gx/ipfs/QmRjT8Bkut84fHf9nxMQBxGsqLAkqzMdFaemDK7e61dBNZ/go-libp2p-routing"
 	blockstore "gx/ipfs/QmS2aqUZLJp8kF1ihE5rvDGE5LvmKDPnx32w9Z1BW9xLV5/go-ipfs-blockstore"
 	testutil "gx/ipfs/QmVnJMgafh5MBYiyqbvDtoCL8pcQvbEGD2k9o9GFpBWPzY/go-testutil"
 	blocks "gx/ipfs/QmWoXtvgC8inqFkAATB7cp2Dax7XBi9VDvSg9RCCZufmRk/go-block-format"
@@ -55,3 +60,173 @@ func TestReprovide(t *testing.T) {
 		t.Fatal("Somehow got the wrong peer back as a provider.")
 	}
 }
+
+type failingRouting struct {
+	routing.ContentRouting
+	fail cid.Cid
+}
+
+func (r *failingRouting) Provide(ctx context.Context, c cid.Cid, announce bool) error {
+	if c.Equals(r.fail) {
+		return errors.New("provide failed")
+	}
+	return r.ContentRouting.Provide(ctx, c, announce)
+}
+
+func TestReprovideContinuesOnFailure(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	mrserv := mock.NewServer()
+
+	idA := testutil.RandIdentityOrFatal(t)
+	idB := testutil.RandIdentityOrFatal(t)
+
+	clA := mrserv.Client(idA)
+	clB := mrserv.Client(idB)
+
+	bstore := blockstore.NewBlockstore(dssync.MutexWrap(ds.NewMapDatastore()))
+
+	bad := blocks.NewBlock([]byte("this one fails"))
+	good := blocks.NewBlock([]byte("this one works"))
+	bstore.Put(bad)
+	bstore.Put(good)
+
+	rsys := &failingRouting{ContentRouting: clA, fail: bad.Cid()}
+	reprov := NewReprovider(ctx, rsys, NewBlockstoreProvider(bstore))
+	SetRetryTimeout(reprov, 10*time.Millisecond)
+
+	if err := reprov.Reprovide(); err == nil {
+		t.Fatal("expected an error for the failed key")
+	}
+
+	stat := reprov.Stat()
+	if stat.KeysProvided != 1 || stat.KeysFailed != 1 {
+		t.Fatalf("expected 1 provided and 1 failed key, got %+v", stat)
+	}
+	if stat.TotalProvided != 1 || stat.TotalFailed != 1 {
+		t.Fatalf("unexpected totals: %+v", stat)
+	}
+	if stat.Running || stat.LastRun.IsZero() {
+		t.Fatalf("unexpected run state: %+v", stat)
+	}
+
+	var providers []pstore.PeerInfo
+	for p := range clB.FindProvidersAsync(ctx, good.Cid(), 1) {
+		providers = append(providers, p)
+	}
+	if len(providers) == 0 {
+		t.Fatal("the key after the failed one should have been provided")
+	}
+}
+
+type downRouting struct {
+	routing.ContentRouting
+}
+
+func (r *downRouting) Provide(ctx context.Context, c cid.Cid, announce bool) error {
+	return errors.New("routing is down")
+}
+
+func TestReprovideStopsAfterFailures(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	bstore := blockstore.NewBlockstore(dssync.MutexWrap(ds.NewMapDatastore()))
+	for i := 0; i < 10; i++ {
+		bstore.Put(blocks.NewBlock([]byte(fmt.Sprintf("block %d", i))))
+	}
+
+	reprov := NewReprovider(ctx, &downRouting{}, NewBlockstoreProvider(bstore))
+	SetRetryTimeout(reprov, 10*time.Millisecond)
+	SetMaxFailures(reprov, 3)
+
+	if err := reprov.Reprovide(); err == nil {
+		t.Fatal("expected the run to fail")
+	}
+
+	stat := reprov.Stat()
+	if stat.KeysFailed != 3 || stat.KeysProvided != 0 {
+		t.Fatalf("expected the run to stop after 3 failed keys, got %+v", stat)
+	}
+}
+
+func TestReprovideStopsOnShutdown(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	bstore := blockstore.NewBlockstore(dssync.MutexWrap(ds.NewMapDatastore()))
+	bstore.Put(blocks.NewBlock([]byte("this one fails")))
+
+	// the default retry timeout is way longer than the test
+	reprov := NewReprovider(ctx, &downRouting{}, NewBlockstoreProvider(bstore))
+
+	errc := make(chan error)
+	go func() {
+		errc <- reprov.Reprovide()
+	}()
+
+	time.Sleep(50 * time.Millisecond)
+	cancel()
+
+	select {
+	case err := <-errc:
+		if err != context.Canceled {
+			t.Fatalf("expected context.Canceled, got %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("the run didn't stop on shutdown")
+	}
+}
+
+func TestReprovideKeyProviderError(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	keyProvider := func(context.Context) (<-chan cid.Cid, error) {
+		return nil, errors.New("no keys")
+	}
+
+	reprov := NewReprovider(ctx, &downRouting{}, keyProvider)
+	if err := reprov.Reprovide(); err == nil {
+		t.Fatal("expected the key provider error")
+	}
+
+	if stat := reprov.Stat(); stat.Running || !stat.LastRun.IsZero() {
+		t.Fatalf("a run without keys must not be recorded: %+v", stat)
+	}
+}
+
+func TestCombinedProvider(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	a := blocks.NewBlock([]byte("a"))
+	b := blocks.NewBlock([]byte("b"))
+	c := blocks.NewBlock([]byte("c"))
+
+	bsA := blockstore.NewBlockstore(dssync.MutexWrap(ds.NewMapDatastore()))
+	bsA.PutMany([]blocks.Block{a, b})
+	bsB := blockstore.NewBlockstore(dssync.MutexWrap(ds.NewMapDatastore()))
+	bsB.PutMany([]blocks.Block{b, c})
+
+	kp := NewCombinedProvider(NewBlockstoreProvider(bsA), NewBlockstoreProvider(bsB))
+	ch, err := kp(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	seen := make(map[string]int)
+	for k := range ch {
+		seen[k.KeyString()]++
+	}
+
+	if len(seen) != 3 {
+		t.Fatalf("expected 3 keys, got %d", len(seen))
+	}
+	for _, blk := range []blocks.Block{a, b, c} {
+		if seen[blk.Cid().KeyString()] != 1 {
+			t.Errorf("key %s seen %d times", blk.Cid(), seen[blk.Cid().KeyString()])
+		}
+	}
+}
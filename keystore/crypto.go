@@ -0,0 +1,133 @@
+package keystore
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"io"
+
+	scrypt "gx/ipfs/QmW7VUmSvhvSGbYbdsh7uRjhGmsYkc9fL8aJ5CorxxrU5N/go-crypto/scrypt"
+)
+
+// sealed data layout:
+//
+//	magic | logN | r | p | salt | nonce | AES-256-GCM ciphertext
+//
+// The scrypt parameters are stored alongside the data so that they can be
+// raised in the future without breaking existing files.
+var sealMagic = []byte("ipfs-sealed/1\n")
+
+const (
+	sealLogN    = 15
+	sealR       = 8
+	sealP       = 1
+	sealSaltLen = 16
+	sealKeyLen  = 32
+
+	// The parameters of sealed data are read from untrusted files, e.g. on
+	// key import. They're capped so that scrypt can't be made to allocate
+	// more than 1GiB (128 * r * 2^logN bytes).
+	maxSealLogN = 20
+	maxSealR    = 8
+	maxSealP    = 1
+)
+
+// ErrBadPassphrase is returned when sealed data can't be opened with the given
+// passphrase
+var ErrBadPassphrase = errors.New("wrong passphrase or corrupted data")
+
+// seal encrypts data with a key derived from the passphrase
+func seal(passphrase []byte, data []byte) ([]byte, error) {
+	if len(passphrase) == 0 {
+		return nil, errors.New("passphrase must not be empty")
+	}
+
+	salt := make([]byte, sealSaltLen)
+	if _, err := io.ReadFull(rand.Reader, salt); err != nil {
+		return nil, err
+	}
+
+	aead, err := sealCipher(passphrase, salt, sealLogN, sealR, sealP)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, err
+	}
+
+	out := make([]byte, 0, len(sealMagic)+3+len(salt)+len(nonce)+len(data)+aead.Overhead())
+	out = append(out, sealMagic...)
+	out = append(out, sealLogN, sealR, sealP)
+	out = append(out, salt...)
+	out = append(out, nonce...)
+	header := out
+
+	return aead.Seal(out, nonce, data, header), nil
+}
+
+// open decrypts data sealed with seal
+func open(passphrase []byte, data []byte) ([]byte, error) {
+	if !isSealed(data) {
+		return nil, errors.New("data is not sealed")
+	}
+
+	hlen := len(sealMagic) + 3 + sealSaltLen
+	if len(data) < hlen {
+		return nil, ErrBadPassphrase
+	}
+
+	params := data[len(sealMagic) : len(sealMagic)+3]
+	salt := data[len(sealMagic)+3 : hlen]
+
+	aead, err := sealCipher(passphrase, salt, params[0], int(params[1]), int(params[2]))
+	if err != nil {
+		return nil, err
+	}
+
+	if len(data) < hlen+aead.NonceSize()+aead.Overhead() {
+		return nil, ErrBadPassphrase
+	}
+
+	header := data[:hlen+aead.NonceSize()]
+	nonce := data[hlen:len(header)]
+
+	out, err := aead.Open(nil, nonce, data[len(header):], header)
+	if err != nil {
+		return nil, ErrBadPassphrase
+	}
+	return out, nil
+}
+
+// isSealed returns whether data looks like it was produced by seal
+func isSealed(data []byte) bool {
+	return bytes.HasPrefix(data, sealMagic)
+}
+
+func sealCipher(passphrase, salt []byte, logN byte, r, p int) (cipher.AEAD, error) {
+	if logN < 1 || logN > maxSealLogN {
+		return nil, fmt.Errorf("invalid scrypt cost parameter: %d", logN)
+	}
+	if r < 1 || r > maxSealR {
+		return nil, fmt.Errorf("invalid scrypt block size: %d", r)
+	}
+	if p < 1 || p > maxSealP {
+		return nil, fmt.Errorf("invalid scrypt parallelization parameter: %d", p)
+	}
+
+	key, err := scrypt.Key(passphrase, salt, 1<<logN, r, p, sealKeyLen)
+	if err != nil {
+		return nil, err
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,353 @@
+package keystore
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	ci "gx/ipfs/QmNiJiXwWE3kRhZrC5ej3kSjWHm337pYfhjLGSCDNKJP2s/go-libp2p-crypto"
+)
+
+// encryptedMarker is the file holding the data key of an encrypted keystore,
+// sealed with the passphrase. Its name starts with a period, so it can't
+// collide with a key name.
+const encryptedMarker = ".encrypted"
+
+// convertingMarker holds the sealed data key while a plaintext keystore is
+// being converted to the encrypted format. It's renamed to encryptedMarker
+// once all the keys are encrypted, so that an interrupted conversion isn't
+// mistaken for a complete one.
+const convertingMarker = ".converting"
+
+const dataKeyLen = 32
+
+var keyFileMagic = []byte("ipfs-key/1\n")
+
+// ErrNotEncrypted is returned when opening a keystore directory which
+// doesn't use the encrypted format
+var ErrNotEncrypted = errors.New("keystore is not encrypted")
+
+// EncryptedKeystore is a keystore backed by files in a given directory stored
+// on disk, where each key is encrypted with AES-256-GCM.
+//
+// The keys are encrypted with a random data key, which is itself stored in
+// the directory sealed with a key derived from the passphrase. This way the
+// expensive key derivation only runs once, when the keystore is opened, and
+// changing the passphrase doesn't need to touch the keys.
+type EncryptedKeystore struct {
+	dir     string
+	dataKey []byte
+	aead    cipher.AEAD
+}
+
+// IsEncrypted returns whether the keystore in the directory uses the
+// encrypted format
+func IsEncrypted(dir string) (bool, error) {
+	return exists(filepath.Join(dir, encryptedMarker))
+}
+
+// IsConverting returns whether the conversion of the keystore in the
+// directory to the encrypted format was interrupted. EncryptKeystore finishes
+// it.
+func IsConverting(dir string) (bool, error) {
+	return exists(filepath.Join(dir, convertingMarker))
+}
+
+func exists(path string) (bool, error) {
+	_, err := os.Stat(path)
+	if os.IsNotExist(err) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// NewEncryptedKeystore opens the encrypted keystore in the directory. It
+// returns ErrNotEncrypted if the directory holds a plaintext keystore, and
+// ErrBadPassphrase if the passphrase is wrong.
+func NewEncryptedKeystore(dir string, passphrase []byte) (*EncryptedKeystore, error) {
+	sealed, err := ioutil.ReadFile(filepath.Join(dir, encryptedMarker))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, ErrNotEncrypted
+		}
+		return nil, err
+	}
+
+	dataKey, err := open(passphrase, sealed)
+	if err != nil {
+		return nil, err
+	}
+
+	return newEncryptedKeystore(dir, dataKey)
+}
+
+// EncryptKeystore converts the plaintext keystore in the directory, as
+// written by FSKeystore, to the encrypted format and returns it. The
+// directory is created if it doesn't exist. An already encrypted keystore is
+// opened.
+//
+// The keystore is only marked as encrypted once all its keys are, and an
+// interrupted conversion is resumed by calling EncryptKeystore again with the
+// same passphrase.
+func EncryptKeystore(dir string, passphrase []byte) (*EncryptedKeystore, error) {
+	if _, err := NewFSKeystore(dir); err != nil {
+		return nil, err
+	}
+
+	ks, err := NewEncryptedKeystore(dir, passphrase)
+	if err != ErrNotEncrypted {
+		return ks, err
+	}
+
+	converting := filepath.Join(dir, convertingMarker)
+
+	var dataKey []byte
+	sealed, err := ioutil.ReadFile(converting)
+	switch {
+	case err == nil:
+		dataKey, err = open(passphrase, sealed)
+		if err != nil {
+			return nil, err
+		}
+	case os.IsNotExist(err):
+		dataKey = make([]byte, dataKeyLen)
+		if _, err := io.ReadFull(rand.Reader, dataKey); err != nil {
+			return nil, err
+		}
+
+		sealed, err := seal(passphrase, dataKey)
+		if err != nil {
+			return nil, err
+		}
+
+		if err := writeFileAtomic(converting, sealed); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, err
+	}
+
+	ks, err = newEncryptedKeystore(dir, dataKey)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := ks.encryptPlaintextKeys(); err != nil {
+		return nil, err
+	}
+
+	if err := os.Rename(converting, filepath.Join(dir, encryptedMarker)); err != nil {
+		return nil, err
+	}
+
+	return ks, nil
+}
+
+// encryptPlaintextKeys encrypts the keys of the directory still in the
+// plaintext format of FSKeystore. Files which aren't keys are left alone.
+func (ks *EncryptedKeystore) encryptPlaintextKeys() error {
+	names, err := ks.List()
+	if err != nil {
+		return err
+	}
+
+	for _, name := range names {
+		kp := filepath.Join(ks.dir, name)
+
+		data, err := ioutil.ReadFile(kp)
+		if err != nil {
+			return err
+		}
+
+		if bytes.HasPrefix(data, keyFileMagic) {
+			continue
+		}
+
+		sk, err := ci.UnmarshalPrivateKey(data)
+		if err != nil {
+			log.Warningf("not encrypting the keystore file %s, it isn't a key: %s", name, err)
+			continue
+		}
+
+		enc, err := ks.encrypt(name, sk)
+		if err != nil {
+			return err
+		}
+
+		if err := writeFileAtomic(kp, enc); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func newEncryptedKeystore(dir string, dataKey []byte) (*EncryptedKeystore, error) {
+	block, err := aes.NewCipher(dataKey)
+	if err != nil {
+		return nil, err
+	}
+
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+
+	return &EncryptedKeystore{dir: dir, dataKey: dataKey, aead: aead}, nil
+}
+
+// ChangePassphrase changes the passphrase needed to open the keystore
+func (ks *EncryptedKeystore) ChangePassphrase(passphrase []byte) error {
+	sealed, err := seal(passphrase, ks.dataKey)
+	if err != nil {
+		return err
+	}
+
+	return writeFileAtomic(filepath.Join(ks.dir, encryptedMarker), sealed)
+}
+
+func (ks *EncryptedKeystore) encrypt(name string, k ci.PrivKey) ([]byte, error) {
+	b, err := k.Bytes()
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, ks.aead.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, err
+	}
+
+	out := make([]byte, 0, len(keyFileMagic)+len(nonce)+len(b)+ks.aead.Overhead())
+	out = append(out, keyFileMagic...)
+	out = append(out, nonce...)
+
+	// the name is authenticated so that key files can't be swapped around
+	return ks.aead.Seal(out, nonce, b, []byte(name)), nil
+}
+
+func (ks *EncryptedKeystore) decrypt(name string, data []byte) (ci.PrivKey, error) {
+	if !bytes.HasPrefix(data, keyFileMagic) {
+		return nil, fmt.Errorf("key %s is not encrypted", name)
+	}
+	data = data[len(keyFileMagic):]
+
+	if len(data) < ks.aead.NonceSize() {
+		return nil, fmt.Errorf("key %s is corrupted", name)
+	}
+
+	nonce := data[:ks.aead.NonceSize()]
+	b, err := ks.aead.Open(nil, nonce, data[len(nonce):], []byte(name))
+	if err != nil {
+		return nil, fmt.Errorf("key %s is corrupted", name)
+	}
+
+	return ci.UnmarshalPrivateKey(b)
+}
+
+// Has returns whether or not a key exist in the Keystore
+func (ks *EncryptedKeystore) Has(name string) (bool, error) {
+	if err := validateName(name); err != nil {
+		return false, err
+	}
+
+	_, err := os.Stat(filepath.Join(ks.dir, name))
+	if os.IsNotExist(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// Put stores a key in the Keystore, if a key with the same name already exists, returns ErrKeyExists
+func (ks *EncryptedKeystore) Put(name string, k ci.PrivKey) error {
+	if err := validateName(name); err != nil {
+		return err
+	}
+
+	b, err := ks.encrypt(name, k)
+	if err != nil {
+		return err
+	}
+
+	fi, err := os.OpenFile(filepath.Join(ks.dir, name), os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		if os.IsExist(err) {
+			return ErrKeyExists
+		}
+		return err
+	}
+	defer fi.Close()
+
+	_, err = fi.Write(b)
+
+	return err
+}
+
+// Get retrieves a key from the Keystore if it exists, and returns ErrNoSuchKey
+// otherwise.
+func (ks *EncryptedKeystore) Get(name string) (ci.PrivKey, error) {
+	if err := validateName(name); err != nil {
+		return nil, err
+	}
+
+	data, err := ioutil.ReadFile(filepath.Join(ks.dir, name))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, ErrNoSuchKey
+		}
+		return nil, err
+	}
+
+	return ks.decrypt(name, data)
+}
+
+// Delete removes a key from the Keystore
+func (ks *EncryptedKeystore) Delete(name string) error {
+	if err := validateName(name); err != nil {
+		return err
+	}
+
+	return os.Remove(filepath.Join(ks.dir, name))
+}
+
+// List return a list of key identifier
+func (ks *EncryptedKeystore) List() ([]string, error) {
+	return listDir(ks.dir)
+}
+
+// writeFileAtomic replaces the file at path with data, so that readers either
+// see the old or the new contents
+func writeFileAtomic(path string, data []byte) error {
+	tmp, err := ioutil.TempFile(filepath.Dir(path), ".tmp-")
+	if err != nil {
+		return err
+	}
+
+	_, err = tmp.Write(data)
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Chmod(tmp.Name(), 0600)
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), path)
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+	}
+	return err
+}
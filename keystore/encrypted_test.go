@@ -0,0 +1,325 @@
+package keystore
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+
+	ci "gx/ipfs/QmNiJiXwWE3kRhZrC5ej3kSjWHm337pYfhjLGSCDNKJP2s/go-libp2p-crypto"
+)
+
+func TestEncryptedKeystoreBasics(t *testing.T) {
+	tdir, err := ioutil.TempDir("", "keystore-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tdir)
+
+	if _, err := NewEncryptedKeystore(tdir, []byte("secret")); err != ErrNotEncrypted {
+		t.Fatalf("expected ErrNotEncrypted, got %v", err)
+	}
+
+	ks, err := EncryptKeystore(tdir, []byte("secret"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	enc, err := IsEncrypted(tdir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !enc {
+		t.Fatal("keystore should be encrypted")
+	}
+
+	l, err := ks.List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(l) != 0 {
+		t.Fatal("expected no keys")
+	}
+
+	k1 := privKeyOrFatal(t)
+	k2 := privKeyOrFatal(t)
+
+	if err := ks.Put("foo", k1); err != nil {
+		t.Fatal(err)
+	}
+	if err := ks.Put("bar", k2); err != nil {
+		t.Fatal(err)
+	}
+	if err := ks.Put("foo", k2); err != ErrKeyExists {
+		t.Fatalf("expected ErrKeyExists, got %v", err)
+	}
+
+	l, err = ks.List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sort.Strings(l)
+	if len(l) != 2 || l[0] != "bar" || l[1] != "foo" {
+		t.Fatalf("unexpected keys: %v", l)
+	}
+
+	raw, err := k1.Bytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := ioutil.ReadFile(filepath.Join(tdir, "foo"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(data, raw) {
+		t.Fatal("key is stored in plaintext")
+	}
+
+	// reopen
+	ks, err = NewEncryptedKeystore(tdir, []byte("secret"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	k, err := ks.Get("foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !k.Equals(k1) {
+		t.Fatal("keys don't match")
+	}
+
+	if _, err := ks.Get("baz"); err != ErrNoSuchKey {
+		t.Fatalf("expected ErrNoSuchKey, got %v", err)
+	}
+
+	if err := ks.Delete("bar"); err != nil {
+		t.Fatal(err)
+	}
+	if has, _ := ks.Has("bar"); has {
+		t.Fatal("key should have been deleted")
+	}
+
+	if _, err := NewEncryptedKeystore(tdir, []byte("wrong")); err != ErrBadPassphrase {
+		t.Fatalf("expected ErrBadPassphrase, got %v", err)
+	}
+}
+
+func TestEncryptedKeystoreSwappedFiles(t *testing.T) {
+	tdir, err := ioutil.TempDir("", "keystore-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tdir)
+
+	ks, err := EncryptKeystore(tdir, []byte("secret"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := ks.Put("foo", privKeyOrFatal(t)); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.Rename(filepath.Join(tdir, "foo"), filepath.Join(tdir, "bar")); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := ks.Get("bar"); err == nil {
+		t.Fatal("expected a renamed key file to be rejected")
+	}
+}
+
+func TestEncryptKeystoreMigration(t *testing.T) {
+	tdir, err := ioutil.TempDir("", "keystore-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tdir)
+
+	fks, err := NewFSKeystore(tdir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	k1 := privKeyOrFatal(t)
+	k2 := privKeyOrFatal(t)
+	if err := fks.Put("foo", k1); err != nil {
+		t.Fatal(err)
+	}
+	if err := fks.Put("bar", k2); err != nil {
+		t.Fatal(err)
+	}
+
+	// files which aren't keys don't stop the migration
+	if err := ioutil.WriteFile(filepath.Join(tdir, "stray"), []byte("not a key"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	if enc, _ := IsEncrypted(tdir); enc {
+		t.Fatal("plaintext keystore reported as encrypted")
+	}
+
+	if _, err := EncryptKeystore(tdir, []byte("secret")); err != nil {
+		t.Fatal(err)
+	}
+
+	// an encrypted keystore is opened
+	if _, err := EncryptKeystore(tdir, []byte("wrong")); err != ErrBadPassphrase {
+		t.Fatalf("expected ErrBadPassphrase, got %v", err)
+	}
+
+	ks, err := EncryptKeystore(tdir, []byte("secret"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for name, expected := range map[string]ci.PrivKey{"foo": k1, "bar": k2} {
+		k, err := ks.Get(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !k.Equals(expected) {
+			t.Fatalf("key %s doesn't match after migration", name)
+		}
+	}
+
+	data, err := ioutil.ReadFile(filepath.Join(tdir, "stray"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "not a key" {
+		t.Fatal("a file which isn't a key was modified")
+	}
+}
+
+// interruptedKeystore sets up the directory as left by a conversion to the
+// encrypted format interrupted after converting the "foo" key
+func interruptedKeystore(t *testing.T, dir string, k1, k2 ci.PrivKey) {
+	fks, err := NewFSKeystore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := fks.Put("foo", k1); err != nil {
+		t.Fatal(err)
+	}
+	if err := fks.Put("bar", k2); err != nil {
+		t.Fatal(err)
+	}
+
+	dataKey := bytes.Repeat([]byte{42}, dataKeyLen)
+	sealed, err := seal([]byte("secret"), dataKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := writeFileAtomic(filepath.Join(dir, convertingMarker), sealed); err != nil {
+		t.Fatal(err)
+	}
+
+	ks, err := newEncryptedKeystore(dir, dataKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	enc, err := ks.encrypt("foo", k1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := writeFileAtomic(filepath.Join(dir, "foo"), enc); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestEncryptKeystoreInterrupted(t *testing.T) {
+	tdir, err := ioutil.TempDir("", "keystore-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tdir)
+
+	k1 := privKeyOrFatal(t)
+	k2 := privKeyOrFatal(t)
+	interruptedKeystore(t, tdir, k1, k2)
+
+	if enc, _ := IsEncrypted(tdir); enc {
+		t.Fatal("interrupted conversion reported as encrypted")
+	}
+	if conv, _ := IsConverting(tdir); !conv {
+		t.Fatal("interrupted conversion not detected")
+	}
+
+	if _, err := EncryptKeystore(tdir, []byte("wrong")); err != ErrBadPassphrase {
+		t.Fatalf("expected ErrBadPassphrase, got %v", err)
+	}
+
+	ks, err := EncryptKeystore(tdir, []byte("secret"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for name, expected := range map[string]ci.PrivKey{"foo": k1, "bar": k2} {
+		k, err := ks.Get(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !k.Equals(expected) {
+			t.Fatalf("key %s doesn't match after the conversion", name)
+		}
+	}
+
+	if enc, _ := IsEncrypted(tdir); !enc {
+		t.Fatal("keystore not marked as encrypted after the conversion")
+	}
+	if conv, _ := IsConverting(tdir); conv {
+		t.Fatal("conversion still marked as pending")
+	}
+
+	names, err := ks.List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sort.Strings(names)
+	if len(names) != 2 || names[0] != "bar" || names[1] != "foo" {
+		t.Fatalf("unexpected keys %v", names)
+	}
+}
+
+func TestEncryptedKeystoreChangePassphrase(t *testing.T) {
+	tdir, err := ioutil.TempDir("", "keystore-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tdir)
+
+	ks, err := EncryptKeystore(tdir, []byte("old"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	k1 := privKeyOrFatal(t)
+	if err := ks.Put("foo", k1); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := ks.ChangePassphrase([]byte("new")); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := NewEncryptedKeystore(tdir, []byte("old")); err != ErrBadPassphrase {
+		t.Fatalf("expected ErrBadPassphrase, got %v", err)
+	}
+
+	ks, err = NewEncryptedKeystore(tdir, []byte("new"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	k, err := ks.Get("foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !k.Equals(k1) {
+		t.Fatal("keys don't match")
+	}
+}
@@ -0,0 +1,182 @@
+package keystore
+
+import (
+	"bytes"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/pem"
+	"errors"
+	"fmt"
+
+	ci "gx/ipfs/QmNiJiXwWE3kRhZrC5ej3kSjWHm337pYfhjLGSCDNKJP2s/go-libp2p-crypto"
+)
+
+// Key formats supported by Export and Import
+const (
+	// FormatLibp2pProtobuf is the libp2p protobuf encoding of the key, which
+	// is also how keys are stored in the keystore
+	FormatLibp2pProtobuf = "libp2p-protobuf-cleartext"
+
+	// FormatPEMPKCS8 is a PEM encoded PKCS#8 private key. Only RSA and
+	// Ed25519 keys can be represented in this format
+	FormatPEMPKCS8 = "pem-pkcs8-cleartext"
+
+	// FormatEncrypted is the libp2p protobuf encoding of the key, encrypted
+	// with a key derived from a passphrase
+	FormatEncrypted = "libp2p-protobuf-encrypted"
+)
+
+const pemPKCS8Type = "PRIVATE KEY"
+
+var oidEd25519 = asn1.ObjectIdentifier{1, 3, 101, 112}
+
+// pkcs8 is the ASN.1 structure of a PKCS#8 private key, see RFC 5208
+type pkcs8 struct {
+	Version    int
+	Algo       pkix.AlgorithmIdentifier
+	PrivateKey []byte
+}
+
+// Export returns the key stored under the name encoded in the given format.
+// The passphrase is only used, and required, by FormatEncrypted
+func Export(ks Keystore, name string, format string, passphrase []byte) ([]byte, error) {
+	sk, err := ks.Get(name)
+	if err != nil {
+		return nil, err
+	}
+
+	return MarshalKey(sk, format, passphrase)
+}
+
+// Import decodes a key in the given format and stores it under the name. The
+// passphrase is only used, and required, by FormatEncrypted
+func Import(ks Keystore, name string, data []byte, format string, passphrase []byte) (ci.PrivKey, error) {
+	sk, err := UnmarshalKey(data, format, passphrase)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := ks.Put(name, sk); err != nil {
+		return nil, err
+	}
+	return sk, nil
+}
+
+// MarshalKey encodes a private key in the given format
+func MarshalKey(sk ci.PrivKey, format string, passphrase []byte) ([]byte, error) {
+	if format != FormatEncrypted && passphrase != nil {
+		return nil, fmt.Errorf("a passphrase can only be used with the %s format", FormatEncrypted)
+	}
+
+	switch format {
+	case FormatLibp2pProtobuf:
+		return sk.Bytes()
+	case FormatEncrypted:
+		data, err := sk.Bytes()
+		if err != nil {
+			return nil, err
+		}
+		return seal(passphrase, data)
+	case FormatPEMPKCS8:
+		der, err := marshalPKCS8(sk)
+		if err != nil {
+			return nil, err
+		}
+		return pem.EncodeToMemory(&pem.Block{Type: pemPKCS8Type, Bytes: der}), nil
+	default:
+		return nil, fmt.Errorf("unrecognized key format: %s", format)
+	}
+}
+
+// UnmarshalKey decodes a private key in the given format
+func UnmarshalKey(data []byte, format string, passphrase []byte) (ci.PrivKey, error) {
+	if format != FormatEncrypted && passphrase != nil {
+		return nil, fmt.Errorf("a passphrase can only be used with the %s format", FormatEncrypted)
+	}
+
+	switch format {
+	case FormatLibp2pProtobuf:
+		return ci.UnmarshalPrivateKey(data)
+	case FormatEncrypted:
+		if len(passphrase) == 0 {
+			return nil, errors.New("a passphrase is required to import an encrypted key")
+		}
+		plain, err := open(passphrase, data)
+		if err != nil {
+			return nil, err
+		}
+		return ci.UnmarshalPrivateKey(plain)
+	case FormatPEMPKCS8:
+		block, _ := pem.Decode(data)
+		if block == nil || block.Type != pemPKCS8Type {
+			return nil, errors.New("no PKCS#8 private key found in PEM data")
+		}
+		return unmarshalPKCS8(block.Bytes)
+	default:
+		return nil, fmt.Errorf("unrecognized key format: %s", format)
+	}
+}
+
+func marshalPKCS8(sk ci.PrivKey) ([]byte, error) {
+	raw, err := sk.Raw()
+	if err != nil {
+		return nil, err
+	}
+
+	switch sk.(type) {
+	case *ci.RsaPrivateKey:
+		rsk, err := x509.ParsePKCS1PrivateKey(raw)
+		if err != nil {
+			return nil, err
+		}
+		return x509.MarshalPKCS8PrivateKey(rsk)
+	case *ci.Ed25519PrivateKey:
+		// RFC 8410: the private key is the 32 byte seed, wrapped in an
+		// octet string
+		seed, err := asn1.Marshal(raw[:32])
+		if err != nil {
+			return nil, err
+		}
+		return asn1.Marshal(pkcs8{
+			Algo:       pkix.AlgorithmIdentifier{Algorithm: oidEd25519},
+			PrivateKey: seed,
+		})
+	default:
+		return nil, fmt.Errorf("key type %T can't be exported as PKCS#8", sk)
+	}
+}
+
+func unmarshalPKCS8(der []byte) (ci.PrivKey, error) {
+	var p pkcs8
+	if _, err := asn1.Unmarshal(der, &p); err != nil {
+		return nil, err
+	}
+
+	if p.Algo.Algorithm.Equal(oidEd25519) {
+		var seed []byte
+		if _, err := asn1.Unmarshal(p.PrivateKey, &seed); err != nil {
+			return nil, err
+		}
+		if len(seed) != 32 {
+			return nil, fmt.Errorf("invalid ed25519 seed length: %d", len(seed))
+		}
+		// key generation only reads the seed from the reader, so this
+		// rebuilds the key pair deterministically
+		sk, _, err := ci.GenerateEd25519Key(bytes.NewReader(seed))
+		return sk, err
+	}
+
+	k, err := x509.ParsePKCS8PrivateKey(der)
+	if err != nil {
+		return nil, err
+	}
+
+	switch k := k.(type) {
+	case *rsa.PrivateKey:
+		return ci.UnmarshalRsaPrivateKey(x509.MarshalPKCS1PrivateKey(k))
+	default:
+		return nil, fmt.Errorf("unsupported PKCS#8 key type %T", k)
+	}
+}
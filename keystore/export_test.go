@@ -0,0 +1,140 @@
+package keystore
+
+import (
+	"crypto/rand"
+	"testing"
+
+	ci "gx/ipfs/QmNiJiXwWE3kRhZrC5ej3kSjWHm337pYfhjLGSCDNKJP2s/go-libp2p-crypto"
+)
+
+func TestExportImport(t *testing.T) {
+	rsk, _, err := ci.GenerateKeyPairWithReader(ci.RSA, 1024, rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	esk := privKeyOrFatal(t)
+
+	cases := []struct {
+		name       string
+		key        ci.PrivKey
+		format     string
+		passphrase []byte
+	}{
+		{"rsa-protobuf", rsk, FormatLibp2pProtobuf, nil},
+		{"rsa-pem", rsk, FormatPEMPKCS8, nil},
+		{"rsa-encrypted", rsk, FormatEncrypted, []byte("secret")},
+		{"ed-protobuf", esk, FormatLibp2pProtobuf, nil},
+		{"ed-pem", esk, FormatPEMPKCS8, nil},
+		{"ed-encrypted", esk, FormatEncrypted, []byte("secret")},
+	}
+
+	for _, c := range cases {
+		src := NewMemKeystore()
+		dst := NewMemKeystore()
+
+		if err := src.Put("key", c.key); err != nil {
+			t.Fatal(err)
+		}
+
+		data, err := Export(src, "key", c.format, c.passphrase)
+		if err != nil {
+			t.Fatalf("%s: %s", c.name, err)
+		}
+
+		sk, err := Import(dst, "imported", data, c.format, c.passphrase)
+		if err != nil {
+			t.Fatalf("%s: %s", c.name, err)
+		}
+
+		if !sk.Equals(c.key) {
+			t.Fatalf("%s: imported key doesn't match", c.name)
+		}
+
+		stored, err := dst.Get("imported")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !stored.Equals(c.key) {
+			t.Fatalf("%s: stored key doesn't match", c.name)
+		}
+	}
+}
+
+func TestImportExisting(t *testing.T) {
+	ks := NewMemKeystore()
+	sk := privKeyOrFatal(t)
+
+	if err := ks.Put("key", sk); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := Export(ks, "key", FormatLibp2pProtobuf, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := Import(ks, "key", data, FormatLibp2pProtobuf, nil); err != ErrKeyExists {
+		t.Fatalf("expected ErrKeyExists, got %v", err)
+	}
+}
+
+func TestImportBadPassphrase(t *testing.T) {
+	ks := NewMemKeystore()
+	sk := privKeyOrFatal(t)
+
+	data, err := MarshalKey(sk, FormatEncrypted, []byte("right"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := Import(ks, "key", data, FormatEncrypted, []byte("wrong")); err != ErrBadPassphrase {
+		t.Fatalf("expected ErrBadPassphrase, got %v", err)
+	}
+
+	if _, err := Import(ks, "key", data, FormatEncrypted, nil); err == nil {
+		t.Fatal("expected import without a passphrase to fail")
+	}
+
+	if has, _ := ks.Has("key"); has {
+		t.Fatal("failed import must not store the key")
+	}
+}
+
+func TestImportOversizedParams(t *testing.T) {
+	ks := NewMemKeystore()
+	sk := privKeyOrFatal(t)
+
+	data, err := MarshalKey(sk, FormatEncrypted, []byte("secret"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	params := len(sealMagic)
+	for i, p := range [][3]byte{{30, sealR, sealP}, {sealLogN, 255, sealP}, {sealLogN, sealR, 255}, {0, sealR, sealP}} {
+		crafted := append([]byte(nil), data...)
+		copy(crafted[params:], p[:])
+
+		// the parameters must be rejected before running scrypt, not by the
+		// authentication of the header
+		_, err := Import(ks, "key", crafted, FormatEncrypted, []byte("secret"))
+		if err == nil || err == ErrBadPassphrase {
+			t.Fatalf("case %d: expected the scrypt parameters %v to be rejected, got %v", i, p, err)
+		}
+	}
+
+	if has, _ := ks.Has("key"); has {
+		t.Fatal("failed import must not store the key")
+	}
+}
+
+func TestExportBadFormat(t *testing.T) {
+	sk := privKeyOrFatal(t)
+
+	if _, err := MarshalKey(sk, "foo", nil); err == nil {
+		t.Fatal("expected unknown format to fail")
+	}
+
+	if _, err := MarshalKey(sk, FormatLibp2pProtobuf, []byte("secret")); err == nil {
+		t.Fatal("expected passphrase with a cleartext format to fail")
+	}
+}
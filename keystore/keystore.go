@@ -151,10 +151,15 @@ func (ks *FSKeystore) Delete(name string) error {
 
 // List return a list of key identifier
 func (ks *FSKeystore) List() ([]string, error) {
-	dir, err := os.Open(ks.dir)
+	return listDir(ks.dir)
+}
+
+func listDir(path string) ([]string, error) {
+	dir, err := os.Open(path)
 	if err != nil {
 		return nil, err
 	}
+	defer dir.Close()
 
 	dirs, err := dir.Readdirnames(0)
 	if err != nil {
@@ -164,6 +169,10 @@ func (ks *FSKeystore) List() ([]string, error) {
 	list := make([]string, 0, len(dirs))
 
 	for _, name := range dirs {
+		if name == encryptedMarker || name == convertingMarker || strings.HasPrefix(name, ".tmp-") {
+			continue
+		}
+
 		err := validateName(name)
 		if err == nil {
 			list = append(list, name)
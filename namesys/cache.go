@@ -1,18 +1,125 @@
 package namesys
 
 import (
+	"encoding/json"
+	"sort"
+	"sync/atomic"
 	"time"
 
 	path "gx/ipfs/QmQ3YSqfxunT5QBg6KBVskKyRE26q6hjSMyhpxchpm7jEN/go-path"
+
+	lru "gx/ipfs/QmQjMHF8ptRgx4E57UFMiT4YM6kqaJeYxZ1MCDX23aw4rK/golang-lru"
+	ds "gx/ipfs/Qmf4xQhNomPNhrtZc67qSnfJSjxjXs9LWvknJtSXwimPrM/go-datastore"
+	dsquery "gx/ipfs/Qmf4xQhNomPNhrtZc67qSnfJSjxjXs9LWvknJtSXwimPrM/go-datastore/query"
+	base32 "gx/ipfs/QmfVj3x4D6Jkq9SEoi5n2NmoUomLwoeiwnYz2KQa15wRw6/base32"
 )
 
-func (ns *mpns) cacheGet(name string) (path.Path, bool) {
-	if ns.cache == nil {
-		return "", false
+// cachePrefix is the datastore namespace of the persisted cache entries
+var cachePrefix = ds.NewKey("/namesys/cache")
+
+// CacheEntry is a resolved name held by the resolve cache
+type CacheEntry struct {
+	Name  string
+	Value path.Path
+	EOL   time.Time
+}
+
+// CacheStats are the counters of the resolve cache
+type CacheStats struct {
+	Entries int
+	Hits    uint64
+	Misses  uint64
+}
+
+// persistedEntry is the datastore value of a persisted cache entry
+type persistedEntry struct {
+	Value path.Path
+	EOL   time.Time
+}
+
+// ResolveCache caches resolved names until their TTL runs out. When it is
+// backed by a datastore, the entries and their EOL survive restarts. The
+// datastore only holds the entries of the in-memory LRU, evicted entries
+// are removed from it.
+type ResolveCache struct {
+	// accessed atomically, keep first for alignment
+	hits, misses uint64
+
+	lru *lru.Cache
+	ds  ds.Datastore
+}
+
+// NewResolveCache creates a resolve cache of the given size. If d isn't nil,
+// the entries are persisted in it, and the unexpired ones are loaded back.
+func NewResolveCache(size int, d ds.Datastore) (*ResolveCache, error) {
+	c := &ResolveCache{ds: d}
+
+	var err error
+	c.lru, err = lru.NewWithEvict(size, c.evicted)
+	if err != nil {
+		return nil, err
+	}
+
+	if d != nil {
+		if err := c.load(); err != nil {
+			return nil, err
+		}
 	}
+	return c, nil
+}
+
+func cacheDsKey(name string) ds.Key {
+	return cachePrefix.ChildString(base32.RawStdEncoding.EncodeToString([]byte(name)))
+}
 
-	ientry, ok := ns.cache.Get(name)
+func (c *ResolveCache) load() error {
+	res, err := c.ds.Query(dsquery.Query{Prefix: cachePrefix.String()})
+	if err != nil {
+		return err
+	}
+	entries, err := res.Rest()
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	for _, e := range entries {
+		k := ds.RawKey(e.Key)
+		name, err := base32.RawStdEncoding.DecodeString(k.BaseNamespace())
+		if err != nil {
+			log.Warningf("invalid name in the persisted resolve cache: %s", k)
+			continue
+		}
+
+		var pe persistedEntry
+		if err := json.Unmarshal(e.Value, &pe); err != nil || !now.Before(pe.EOL) {
+			if err := c.ds.Delete(k); err != nil {
+				return err
+			}
+			continue
+		}
+
+		// loading past the size of the cache evicts, and deletes, the
+		// extra entries
+		c.lru.Add(string(name), cacheEntry{val: pe.Value, eol: pe.EOL})
+	}
+	return nil
+}
+
+func (c *ResolveCache) evicted(key, _ interface{}) {
+	if c.ds == nil {
+		return
+	}
+	if err := c.ds.Delete(cacheDsKey(key.(string))); err != nil && err != ds.ErrNotFound {
+		log.Warningf("failed to remove %s from the persisted resolve cache: %s", key, err)
+	}
+}
+
+// Get returns the cached value of the name, if it hasn't expired
+func (c *ResolveCache) Get(name string) (path.Path, bool) {
+	ientry, ok := c.lru.Get(name)
 	if !ok {
+		atomic.AddUint64(&c.misses, 1)
 		return "", false
 	}
 
@@ -23,22 +130,105 @@ func (ns *mpns) cacheGet(name string) (path.Path, bool) {
 	}
 
 	if time.Now().Before(entry.eol) {
+		atomic.AddUint64(&c.hits, 1)
 		return entry.val, true
 	}
 
-	ns.cache.Remove(name)
+	c.lru.Remove(name)
+	atomic.AddUint64(&c.misses, 1)
 
 	return "", false
 }
 
-func (ns *mpns) cacheSet(name string, val path.Path, ttl time.Duration) {
-	if ns.cache == nil || ttl <= 0 {
+// Set caches the value of the name for the ttl
+func (c *ResolveCache) Set(name string, val path.Path, ttl time.Duration) {
+	if ttl <= 0 {
 		return
 	}
-	ns.cache.Add(name, cacheEntry{
+
+	eol := time.Now().Add(ttl)
+	c.lru.Add(name, cacheEntry{
 		val: val,
-		eol: time.Now().Add(ttl),
+		eol: eol,
 	})
+
+	if c.ds == nil {
+		return
+	}
+	data, err := json.Marshal(&persistedEntry{Value: val, EOL: eol})
+	if err != nil {
+		log.Warningf("failed to persist the resolve cache entry of %s: %s", name, err)
+		return
+	}
+	if err := c.ds.Put(cacheDsKey(name), data); err != nil {
+		log.Warningf("failed to persist the resolve cache entry of %s: %s", name, err)
+	}
+}
+
+// List returns the unexpired entries of the cache, sorted by name
+func (c *ResolveCache) List() []CacheEntry {
+	now := time.Now()
+
+	var out []CacheEntry
+	for _, k := range c.lru.Keys() {
+		ientry, ok := c.lru.Peek(k)
+		if !ok {
+			continue
+		}
+		entry := ientry.(cacheEntry)
+		if !now.Before(entry.eol) {
+			continue
+		}
+		out = append(out, CacheEntry{
+			Name:  k.(string),
+			Value: entry.val,
+			EOL:   entry.eol,
+		})
+	}
+
+	sort.Slice(out, func(i, j int) bool {
+		return out[i].Name < out[j].Name
+	})
+	return out
+}
+
+// Remove evicts the name from the cache, and returns whether it was cached
+func (c *ResolveCache) Remove(name string) bool {
+	if !c.lru.Contains(name) {
+		return false
+	}
+	c.lru.Remove(name)
+	return true
+}
+
+// Clear evicts every entry of the cache, and returns how many there were
+func (c *ResolveCache) Clear() int {
+	n := c.lru.Len()
+	c.lru.Purge()
+	return n
+}
+
+// Stats returns the counters of the cache
+func (c *ResolveCache) Stats() CacheStats {
+	return CacheStats{
+		Entries: c.lru.Len(),
+		Hits:    atomic.LoadUint64(&c.hits),
+		Misses:  atomic.LoadUint64(&c.misses),
+	}
+}
+
+func (ns *mpns) cacheGet(name string) (path.Path, bool) {
+	if ns.cache == nil {
+		return "", false
+	}
+	return ns.cache.Get(name)
+}
+
+func (ns *mpns) cacheSet(name string, val path.Path, ttl time.Duration) {
+	if ns.cache == nil {
+		return
+	}
+	ns.cache.Set(name, val, ttl)
 }
 
 type cacheEntry struct {
@@ -0,0 +1,71 @@
+package namesys
+
+import (
+	"testing"
+	"time"
+
+	path "gx/ipfs/QmQ3YSqfxunT5QBg6KBVskKyRE26q6hjSMyhpxchpm7jEN/go-path"
+	ds "gx/ipfs/Qmf4xQhNomPNhrtZc67qSnfJSjxjXs9LWvknJtSXwimPrM/go-datastore"
+	dssync "gx/ipfs/Qmf4xQhNomPNhrtZc67qSnfJSjxjXs9LWvknJtSXwimPrM/go-datastore/sync"
+)
+
+func TestResolveCachePersistence(t *testing.T) {
+	dstore := dssync.MutexWrap(ds.NewMapDatastore())
+
+	c, err := NewResolveCache(2, dstore)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	val := path.FromString("/ipfs/QmatmE9msSfkKxoffpHwNLNKgwZG8eT9Bud6YoPab52vpy")
+	c.Set("ipfs.io", val, time.Hour)
+	c.Set("expiring.example.com", val, 50*time.Millisecond)
+
+	if p, ok := c.Get("ipfs.io"); !ok || p != val {
+		t.Fatalf("expected %s to be cached, got %s", val, p)
+	}
+	if _, ok := c.Get("missing.example.com"); ok {
+		t.Fatal("expected a miss")
+	}
+	if s := c.Stats(); s.Hits != 1 || s.Misses != 1 || s.Entries != 2 {
+		t.Fatalf("unexpected stats: %+v", s)
+	}
+
+	time.Sleep(100 * time.Millisecond)
+
+	// reload from the datastore, the expired entry is dropped
+	c, err = NewResolveCache(2, dstore)
+	if err != nil {
+		t.Fatal(err)
+	}
+	entries := c.List()
+	if len(entries) != 1 || entries[0].Name != "ipfs.io" || entries[0].Value != val {
+		t.Fatalf("unexpected entries after reload: %+v", entries)
+	}
+
+	// evicted entries are removed from the datastore too
+	c.Set("a.example.com", val, time.Hour)
+	c.Set("b.example.com", val, time.Hour)
+	c, err = NewResolveCache(10, dstore)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if entries := c.List(); len(entries) != 2 {
+		t.Fatalf("expected the evicted entry not to be persisted, got %+v", entries)
+	}
+
+	if !c.Remove("a.example.com") || c.Remove("a.example.com") {
+		t.Fatal("expected a.example.com to be removed once")
+	}
+	if n := c.Clear(); n != 1 {
+		t.Fatalf("expected 1 cleared entry, got %d", n)
+	}
+
+	c, err = NewResolveCache(10, dstore)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if entries := c.List(); len(entries) != 0 {
+		t.Fatalf("expected the cleared cache to be empty, got %+v", entries)
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"errors"
 	"net"
 	"strings"
+	"time"
 
 	opts "github.com/ipfs/go-ipfs/core/coreapi/interface/options/namesys"
 
@@ -17,8 +18,10 @@ type LookupTXTFunc func(name string) (txt []string, err error)
 // DNSResolver implements a Resolver on DNS domains
 type DNSResolver struct {
 	lookupTXT LookupTXTFunc
-	// TODO: maybe some sort of caching?
-	// cache would need a timeout
+
+	// resolvers override lookupTXT for the names under a domain, by fully
+	// qualified domain. The root domain "." overrides every name.
+	resolvers map[string]TXTResolver
 }
 
 // NewDNSResolver constructs a name resolver using DNS TXT records.
@@ -26,6 +29,20 @@ func NewDNSResolver() *DNSResolver {
 	return &DNSResolver{lookupTXT: net.LookupTXT}
 }
 
+// NewDNSResolverWithResolvers constructs a name resolver using DNS TXT
+// records, looked up with the resolver of the closest domain the name is
+// under, or the system resolver. The domains are given without the trailing
+// dot, the "." domain sets the resolver of every other name.
+func NewDNSResolverWithResolvers(resolvers map[string]TXTResolver) *DNSResolver {
+	r := NewDNSResolver()
+	r.resolvers = make(map[string]TXTResolver, len(resolvers))
+	for domain, res := range resolvers {
+		domain = strings.ToLower(strings.Trim(domain, "."))
+		r.resolvers[domain+"."] = res
+	}
+	return r
+}
+
 // Resolve implements Resolver.
 func (r *DNSResolver) Resolve(ctx context.Context, name string, options ...opts.ResolveOpt) (path.Path, error) {
 	return resolve(ctx, r, name, opts.ProcessOpts(options))
@@ -38,6 +55,7 @@ func (r *DNSResolver) ResolveAsync(ctx context.Context, name string, options ...
 
 type lookupRes struct {
 	path  path.Path
+	ttl   time.Duration
 	error error
 }
 
@@ -64,10 +82,10 @@ func (r *DNSResolver) resolveOnceAsync(ctx context.Context, name string, options
 	}
 
 	rootChan := make(chan lookupRes, 1)
-	go workDomain(r, fqdn, rootChan)
+	go workDomain(ctx, r, fqdn, rootChan)
 
 	subChan := make(chan lookupRes, 1)
-	go workDomain(r, "_dnslink."+fqdn, subChan)
+	go workDomain(ctx, r, "_dnslink."+fqdn, subChan)
 
 	appendPath := func(p path.Path) (path.Path, error) {
 		if len(segments) > 1 {
@@ -87,7 +105,7 @@ func (r *DNSResolver) resolveOnceAsync(ctx context.Context, name string, options
 				}
 				if subRes.error == nil {
 					p, err := appendPath(subRes.path)
-					emitOnceResult(ctx, out, onceResult{value: p, err: err})
+					emitOnceResult(ctx, out, onceResult{value: p, ttl: subRes.ttl, err: err})
 					return
 				}
 			case rootRes, ok := <-rootChan:
@@ -97,7 +115,7 @@ func (r *DNSResolver) resolveOnceAsync(ctx context.Context, name string, options
 				}
 				if rootRes.error == nil {
 					p, err := appendPath(rootRes.path)
-					emitOnceResult(ctx, out, onceResult{value: p, err: err})
+					emitOnceResult(ctx, out, onceResult{value: p, ttl: rootRes.ttl, err: err})
 				}
 			case <-ctx.Done():
 				return
@@ -111,24 +129,48 @@ func (r *DNSResolver) resolveOnceAsync(ctx context.Context, name string, options
 	return out
 }
 
-func workDomain(r *DNSResolver, name string, res chan lookupRes) {
+func workDomain(ctx context.Context, r *DNSResolver, name string, res chan lookupRes) {
 	defer close(res)
 
-	txt, err := r.lookupTXT(name)
+	txt, ttl, err := r.lookup(ctx, name)
 	if err != nil {
 		// Error is != nil
-		res <- lookupRes{"", err}
+		res <- lookupRes{"", 0, err}
 		return
 	}
 
 	for _, t := range txt {
 		p, err := parseEntry(t)
 		if err == nil {
-			res <- lookupRes{p, nil}
+			res <- lookupRes{p, ttl, nil}
 			return
 		}
 	}
-	res <- lookupRes{"", ErrResolveFailed}
+	res <- lookupRes{"", 0, ErrResolveFailed}
+}
+
+// lookup looks up the TXT records of the fully qualified name, with the
+// resolver of the closest domain it's under. The TTL of the records is
+// unknown with the system resolver.
+func (r *DNSResolver) lookup(ctx context.Context, name string) ([]string, time.Duration, error) {
+	if len(r.resolvers) > 0 {
+		domain := strings.ToLower(name)
+		for {
+			if res, ok := r.resolvers[domain]; ok {
+				return res(ctx, name)
+			}
+			if domain == "." {
+				break
+			}
+			domain = domain[strings.IndexByte(domain, '.')+1:]
+			if domain == "" {
+				domain = "."
+			}
+		}
+	}
+
+	txt, err := r.lookupTXT(name)
+	return txt, 0, err
 }
 
 func parseEntry(txt string) (path.Path, error) {
@@ -0,0 +1,329 @@
+package namesys
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// TXTResolver looks up the TXT records of a fully qualified domain name, and
+// returns how long they may be cached for, zero if unknown.
+type TXTResolver func(ctx context.Context, name string) (txt []string, ttl time.Duration, err error)
+
+const (
+	dnsTypeTXT  = 16
+	dnsClassIN  = 1
+	dnsHeaderSz = 12
+
+	// maxDNSMessageSize is the largest DNS message over TCP and HTTPS
+	maxDNSMessageSize = 65535
+
+	// dnsQueryTimeout bounds the queries when the context has no deadline
+	dnsQueryTimeout = 10 * time.Second
+
+	dohContentType = "application/dns-message"
+)
+
+var (
+	// ErrNoSuchDomain is returned by the TXT resolvers when the domain
+	// doesn't exist
+	ErrNoSuchDomain = errors.New("no such domain")
+
+	errInvalidDNSName     = errors.New("invalid domain name")
+	errMalformedDNSAnswer = errors.New("malformed DNS answer")
+)
+
+// NewTXTResolver returns a TXTResolver querying the DNS resolver at the URL.
+// udp://<host>[:<port>] URLs use plain DNS, over TCP when the answer is
+// truncated, tcp://<host>[:<port>] URLs plain DNS over TCP, and https:// URLs
+// DNS over HTTPS (RFC 8484).
+func NewTXTResolver(resolverURL string) (TXTResolver, error) {
+	u, err := url.Parse(resolverURL)
+	if err != nil {
+		return nil, err
+	}
+
+	switch u.Scheme {
+	case "udp", "tcp":
+		if u.Host == "" {
+			return nil, fmt.Errorf("missing host in DNS resolver URL %q", resolverURL)
+		}
+		addr := u.Host
+		if u.Port() == "" {
+			addr = net.JoinHostPort(u.Hostname(), "53")
+		}
+		return dnsClient{addr: addr, tcp: u.Scheme == "tcp"}.lookupTXT, nil
+	case "https":
+		if u.Host == "" {
+			return nil, fmt.Errorf("missing host in DNS resolver URL %q", resolverURL)
+		}
+		return dohClient{url: u.String(), client: http.DefaultClient}.lookupTXT, nil
+	default:
+		return nil, fmt.Errorf("unsupported DNS resolver URL %q: the scheme must be udp, tcp or https", resolverURL)
+	}
+}
+
+type dnsClient struct {
+	addr string
+	tcp  bool
+}
+
+func (c dnsClient) lookupTXT(ctx context.Context, name string) ([]string, time.Duration, error) {
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, dnsQueryTimeout)
+		defer cancel()
+	}
+
+	id, err := dnsQueryID()
+	if err != nil {
+		return nil, 0, err
+	}
+	query, err := packTXTQuery(id, name)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	if !c.tcp {
+		answer, err := c.exchange(ctx, "udp", query)
+		if err != nil {
+			return nil, 0, err
+		}
+		txt, ttl, truncated, err := parseTXTAnswer(id, answer)
+		if !truncated {
+			return txt, ttl, err
+		}
+		// retry over TCP, for the full answer
+	}
+
+	answer, err := c.exchange(ctx, "tcp", query)
+	if err != nil {
+		return nil, 0, err
+	}
+	txt, ttl, _, err := parseTXTAnswer(id, answer)
+	return txt, ttl, err
+}
+
+func (c dnsClient) exchange(ctx context.Context, network string, query []byte) ([]byte, error) {
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, network, c.addr)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	if network == "udp" {
+		if _, err := conn.Write(query); err != nil {
+			return nil, err
+		}
+		buf := make([]byte, maxDNSMessageSize)
+		n, err := conn.Read(buf)
+		if err != nil {
+			return nil, err
+		}
+		return buf[:n], nil
+	}
+
+	// TCP messages are prefixed by their length
+	msg := make([]byte, 2, 2+len(query))
+	binary.BigEndian.PutUint16(msg, uint16(len(query)))
+	if _, err := conn.Write(append(msg, query...)); err != nil {
+		return nil, err
+	}
+	if _, err := io.ReadFull(conn, msg[:2]); err != nil {
+		return nil, err
+	}
+	buf := make([]byte, binary.BigEndian.Uint16(msg[:2]))
+	if _, err := io.ReadFull(conn, buf); err != nil {
+		return nil, err
+	}
+	return buf, nil
+}
+
+type dohClient struct {
+	url    string
+	client *http.Client
+}
+
+func (c dohClient) lookupTXT(ctx context.Context, name string) ([]string, time.Duration, error) {
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, dnsQueryTimeout)
+		defer cancel()
+	}
+
+	// the ID is 0 over HTTPS, so that the answers can be cached by HTTP
+	// caches
+	query, err := packTXTQuery(0, name)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	req, err := http.NewRequest("POST", c.url, bytes.NewReader(query))
+	if err != nil {
+		return nil, 0, err
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Content-Type", dohContentType)
+	req.Header.Set("Accept", dohContentType)
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, 0, fmt.Errorf("DNS over HTTPS query failed: %s", resp.Status)
+	}
+
+	answer, err := ioutil.ReadAll(io.LimitReader(resp.Body, maxDNSMessageSize))
+	if err != nil {
+		return nil, 0, err
+	}
+	txt, ttl, _, err := parseTXTAnswer(0, answer)
+	return txt, ttl, err
+}
+
+func dnsQueryID() (uint16, error) {
+	var b [2]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return 0, err
+	}
+	return binary.BigEndian.Uint16(b[:]), nil
+}
+
+// packTXTQuery encodes the DNS query of the TXT records of a name
+func packTXTQuery(id uint16, name string) ([]byte, error) {
+	name = strings.TrimSuffix(name, ".")
+	if name == "" || len(name) > 253 {
+		return nil, errInvalidDNSName
+	}
+
+	msg := make([]byte, dnsHeaderSz, dnsHeaderSz+len(name)+6)
+	binary.BigEndian.PutUint16(msg[0:], id)
+	binary.BigEndian.PutUint16(msg[2:], 0x0100) // recursion desired
+	binary.BigEndian.PutUint16(msg[4:], 1)      // one question
+
+	for _, label := range strings.Split(name, ".") {
+		if label == "" || len(label) > 63 {
+			return nil, errInvalidDNSName
+		}
+		msg = append(msg, byte(len(label)))
+		msg = append(msg, label...)
+	}
+	msg = append(msg, 0)
+
+	return append(msg, 0, dnsTypeTXT, 0, dnsClassIN), nil
+}
+
+// parseTXTAnswer decodes the TXT records of a DNS answer, and the smallest
+// TTL among them. The character strings of a record are joined.
+func parseTXTAnswer(id uint16, msg []byte) (txt []string, ttl time.Duration, truncated bool, err error) {
+	if len(msg) < dnsHeaderSz {
+		return nil, 0, false, errMalformedDNSAnswer
+	}
+	if binary.BigEndian.Uint16(msg[0:]) != id {
+		return nil, 0, false, errors.New("mismatched DNS answer ID")
+	}
+
+	flags := binary.BigEndian.Uint16(msg[2:])
+	if flags&0x8000 == 0 {
+		return nil, 0, false, errMalformedDNSAnswer
+	}
+	if flags&0x0200 != 0 {
+		return nil, 0, true, nil
+	}
+	switch rcode := flags & 0xf; rcode {
+	case 0:
+	case 3:
+		return nil, 0, false, ErrNoSuchDomain
+	default:
+		return nil, 0, false, fmt.Errorf("DNS query failed with rcode %d", rcode)
+	}
+
+	qdcount := int(binary.BigEndian.Uint16(msg[4:]))
+	ancount := int(binary.BigEndian.Uint16(msg[6:]))
+
+	off := dnsHeaderSz
+	for i := 0; i < qdcount; i++ {
+		if off, err = skipDNSName(msg, off); err != nil {
+			return nil, 0, false, err
+		}
+		off += 4 // type and class
+	}
+
+	minTTL := uint32(0)
+	for i := 0; i < ancount; i++ {
+		if off, err = skipDNSName(msg, off); err != nil {
+			return nil, 0, false, err
+		}
+		if off+10 > len(msg) {
+			return nil, 0, false, errMalformedDNSAnswer
+		}
+		typ := binary.BigEndian.Uint16(msg[off:])
+		rrTTL := binary.BigEndian.Uint32(msg[off+4:])
+		rdlen := int(binary.BigEndian.Uint16(msg[off+8:]))
+		off += 10
+		if off+rdlen > len(msg) {
+			return nil, 0, false, errMalformedDNSAnswer
+		}
+		rdata := msg[off : off+rdlen]
+		off += rdlen
+
+		if typ != dnsTypeTXT {
+			// CNAMEs leading to the records
+			continue
+		}
+
+		var record strings.Builder
+		for len(rdata) > 0 {
+			l := int(rdata[0])
+			if 1+l > len(rdata) {
+				return nil, 0, false, errMalformedDNSAnswer
+			}
+			record.Write(rdata[1 : 1+l])
+			rdata = rdata[1+l:]
+		}
+		txt = append(txt, record.String())
+
+		if len(txt) == 1 || rrTTL < minTTL {
+			minTTL = rrTTL
+		}
+	}
+
+	return txt, time.Duration(minTTL) * time.Second, false, nil
+}
+
+// skipDNSName returns the offset following the name at off
+func skipDNSName(msg []byte, off int) (int, error) {
+	for {
+		if off >= len(msg) {
+			return 0, errMalformedDNSAnswer
+		}
+		l := int(msg[off])
+		switch {
+		case l == 0:
+			return off + 1, nil
+		case l&0xc0 == 0xc0:
+			// compression pointer, which ends the name
+			return off + 2, nil
+		default:
+			off += 1 + l
+		}
+	}
+}
@@ -0,0 +1,148 @@
+package namesys
+
+import (
+	"context"
+	"encoding/binary"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	opts "github.com/ipfs/go-ipfs/core/coreapi/interface/options/namesys"
+)
+
+type stubRecord struct {
+	txt []string
+	ttl uint32
+}
+
+// stubAnswer answers a TXT query from the records, by query name
+func stubAnswer(t *testing.T, query []byte, records map[string]stubRecord) []byte {
+	end, err := skipDNSName(query, dnsHeaderSz)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var name string
+	for off := dnsHeaderSz; query[off] != 0; off += 1 + int(query[off]) {
+		name += string(query[off+1:off+1+int(query[off])]) + "."
+	}
+
+	rec, ok := records[name]
+	msg := append([]byte{}, query[:end+4]...)
+	binary.BigEndian.PutUint16(msg[2:], 0x8180)
+	if !ok {
+		binary.BigEndian.PutUint16(msg[2:], 0x8183)
+		return msg
+	}
+	binary.BigEndian.PutUint16(msg[6:], uint16(len(rec.txt)))
+
+	for _, txt := range rec.txt {
+		rr := []byte{0xc0, dnsHeaderSz, 0, dnsTypeTXT, 0, dnsClassIN, 0, 0, 0, 0, 0, 0}
+		binary.BigEndian.PutUint32(rr[6:], rec.ttl)
+		binary.BigEndian.PutUint16(rr[10:], uint16(len(txt)+1))
+		rr = append(rr, byte(len(txt)))
+		msg = append(append(msg, rr...), txt...)
+	}
+	return msg
+}
+
+func startUDPStub(t *testing.T, records map[string]stubRecord) (string, func()) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	go func() {
+		buf := make([]byte, 512)
+		for {
+			n, addr, err := conn.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+			conn.WriteTo(stubAnswer(t, buf[:n], records), addr)
+		}
+	}()
+	return conn.LocalAddr().String(), func() { conn.Close() }
+}
+
+func startDoHStub(t *testing.T, records map[string]stubRecord) *httptest.Server {
+	return httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "POST" || r.Header.Get("Content-Type") != dohContentType {
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+		query, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w.Header().Set("Content-Type", dohContentType)
+		w.Write(stubAnswer(t, query, records))
+	}))
+}
+
+func TestTXTResolvers(t *testing.T) {
+	records := map[string]stubRecord{
+		"_dnslink.example.com.": {
+			txt: []string{"dnslink=/ipfs/QmY3hE8xgFCjGcz6PHgnvJz5HZi1BaKRfPkn1ghZUcYMjD"},
+			ttl: 300,
+		},
+		"example.eth.": {
+			txt: []string{"v=spf1 -all", "dnslink=/ipfs/QmYvMB9yrsSf7RKBghkfwmHJkzJhW2ZgVwq3LxBXXPasFr"},
+			ttl: 60,
+		},
+	}
+
+	addr, stop := startUDPStub(t, records)
+	defer stop()
+	doh := startDoHStub(t, records)
+	defer doh.Close()
+
+	udp, err := NewTXTResolver("udp://" + addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewTXTResolver(doh.URL + "/dns-query"); err != nil {
+		t.Fatal(err)
+	}
+	// the stub has a self-signed certificate
+	https := dohClient{url: doh.URL + "/dns-query", client: doh.Client()}.lookupTXT
+
+	if _, err := NewTXTResolver("ftp://example.com"); err == nil {
+		t.Fatal("expected an error for an unsupported scheme")
+	}
+
+	ctx := context.Background()
+	for _, res := range []TXTResolver{udp, https} {
+		txt, ttl, err := res(ctx, "example.eth.")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(txt) != 2 || txt[1] != records["example.eth."].txt[1] {
+			t.Fatalf("unexpected TXT records: %v", txt)
+		}
+		if ttl != time.Minute {
+			t.Fatalf("expected a TTL of 1m, got %s", ttl)
+		}
+
+		if _, _, err := res(ctx, "missing.example.com."); err != ErrNoSuchDomain {
+			t.Fatalf("expected ErrNoSuchDomain, got %v", err)
+		}
+	}
+
+	// .eth names go over DoH, the others over UDP
+	r := NewDNSResolverWithResolvers(map[string]TXTResolver{
+		"eth": https,
+		".":   udp,
+	})
+	testResolution(t, r, "example.com", opts.DefaultDepthLimit, "/ipfs/QmY3hE8xgFCjGcz6PHgnvJz5HZi1BaKRfPkn1ghZUcYMjD", nil)
+	testResolution(t, r, "example.eth", opts.DefaultDepthLimit, "/ipfs/QmYvMB9yrsSf7RKBghkfwmHJkzJhW2ZgVwq3LxBXXPasFr", nil)
+
+	// the TTL of the records is passed on to the namesys cache
+	res := <-r.resolveOnceAsync(ctx, "example.com", opts.DefaultResolveOpts())
+	if res.err != nil || res.ttl != 5*time.Minute {
+		t.Fatalf("expected a TTL of 5m, got %s (%v)", res.ttl, res.err)
+	}
+}
@@ -0,0 +1,83 @@
+package namesys
+
+import (
+	"context"
+	"time"
+
+	opts "github.com/ipfs/go-ipfs/core/coreapi/interface/options/namesys"
+
+	ci "gx/ipfs/QmNiJiXwWE3kRhZrC5ej3kSjWHm337pYfhjLGSCDNKJP2s/go-libp2p-crypto"
+	path "gx/ipfs/QmQ3YSqfxunT5QBg6KBVskKyRE26q6hjSMyhpxchpm7jEN/go-path"
+	metrics "gx/ipfs/QmekzFM3hPZjTjUFGTABdQkEnQ3PTiMstY198PwSFr5w1Q/go-metrics-interface"
+)
+
+// latencyBuckets are the buckets, in seconds, of the resolve and publish
+// latencies. Publishing to the DHT routinely takes tens of seconds.
+var latencyBuckets = []float64{0.01, 0.05, 0.1, 0.5, 1, 5, 10, 30, 60, 120}
+
+// meteredNameSystem records the latencies of the calls to a NameSystem
+type meteredNameSystem struct {
+	ns NameSystem
+
+	resolveLatency metrics.Histogram
+	publishLatency metrics.Histogram
+}
+
+// WithMetrics returns a NameSystem recording the resolve and publish
+// latencies of ns in the metrics of ctx. It must be called once per metrics
+// scope, as the metrics are registered when it's called.
+func WithMetrics(ctx context.Context, ns NameSystem) NameSystem {
+	ctx = metrics.CtxSubScope(ctx, "namesys")
+	return &meteredNameSystem{
+		ns: ns,
+
+		resolveLatency: metrics.NewCtx(ctx, "resolve_duration_seconds",
+			"Latency of name resolutions").Histogram(latencyBuckets),
+		publishLatency: metrics.NewCtx(ctx, "publish_duration_seconds",
+			"Latency of name publications").Histogram(latencyBuckets),
+	}
+}
+
+// Resolve implements Resolver.
+func (m *meteredNameSystem) Resolve(ctx context.Context, name string, options ...opts.ResolveOpt) (path.Path, error) {
+	defer m.observe(m.resolveLatency, time.Now())
+	return m.ns.Resolve(ctx, name, options...)
+}
+
+// ResolveAsync implements Resolver. The latency is the time until the last
+// result is returned.
+func (m *meteredNameSystem) ResolveAsync(ctx context.Context, name string, options ...opts.ResolveOpt) <-chan Result {
+	start := time.Now()
+	in := m.ns.ResolveAsync(ctx, name, options...)
+	out := make(chan Result)
+
+	go func() {
+		defer close(out)
+		defer m.observe(m.resolveLatency, start)
+		for res := range in {
+			select {
+			case out <- res:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return out
+}
+
+// Publish implements Publisher
+func (m *meteredNameSystem) Publish(ctx context.Context, name ci.PrivKey, value path.Path) error {
+	defer m.observe(m.publishLatency, time.Now())
+	return m.ns.Publish(ctx, name, value)
+}
+
+// PublishWithEOL implements Publisher
+func (m *meteredNameSystem) PublishWithEOL(ctx context.Context, name ci.PrivKey, value path.Path, eol time.Time) error {
+	defer m.observe(m.publishLatency, time.Now())
+	return m.ns.PublishWithEOL(ctx, name, value, eol)
+}
+
+func (m *meteredNameSystem) observe(h metrics.Histogram, start time.Time) {
+	h.Observe(time.Since(start).Seconds())
+}
@@ -11,7 +11,6 @@ import (
 
 	ci "gx/ipfs/QmNiJiXwWE3kRhZrC5ej3kSjWHm337pYfhjLGSCDNKJP2s/go-libp2p-crypto"
 	peer "gx/ipfs/QmPJxxDsX2UbchSHobbYuvz7qnyJTFKvaKMzE2rZWJ4x5B/go-libp2p-peer"
-	lru "gx/ipfs/QmQjMHF8ptRgx4E57UFMiT4YM6kqaJeYxZ1MCDX23aw4rK/golang-lru"
 	routing "gx/ipfs/QmRjT8Bkut84fHf9nxMQBxGsqLAkqzMdFaemDK7e61dBNZ/go-libp2p-routing"
 	isd "gx/ipfs/QmZmmuAXgX73UQmX1jRKjTGmjzq24Jinqkq8vzkBtno4uX/go-is-domain"
 	mh "gx/ipfs/QmerPMzPk1mJVowm8KgmoknWa4yCYvvugMPsgWmDNUvDLW/go-multihash"
@@ -31,18 +30,30 @@ type mpns struct {
 	dnsResolver, proquintResolver, ipnsResolver resolver
 	ipnsPublisher                               Publisher
 
-	cache *lru.Cache
+	cache *ResolveCache
 }
 
 // NewNameSystem will construct the IPFS naming system based on Routing
 func NewNameSystem(r routing.ValueStore, ds ds.Datastore, cachesize int) NameSystem {
-	var cache *lru.Cache
+	var cache *ResolveCache
 	if cachesize > 0 {
-		cache, _ = lru.New(cachesize)
+		cache, _ = NewResolveCache(cachesize, nil)
+	}
+
+	return NewCustomNameSystem(r, ds, nil, cache)
+}
+
+// NewCustomNameSystem constructs the IPFS naming system based on Routing,
+// resolving DNSLinks with the given DNS resolver and caching the resolved
+// names in the given cache. A nil DNS resolver uses the system resolver, and
+// a nil cache disables caching.
+func NewCustomNameSystem(r routing.ValueStore, ds ds.Datastore, dnsResolver *DNSResolver, cache *ResolveCache) NameSystem {
+	if dnsResolver == nil {
+		dnsResolver = NewDNSResolver()
 	}
 
 	return &mpns{
-		dnsResolver:      NewDNSResolver(),
+		dnsResolver:      dnsResolver,
 		proquintResolver: new(ProquintResolver),
 		ipnsResolver:     NewIpnsResolver(r),
 		ipnsPublisher:    NewIpnsPublisher(r, ds),
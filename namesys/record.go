@@ -0,0 +1,66 @@
+package namesys
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	path "gx/ipfs/QmQ3YSqfxunT5QBg6KBVskKyRE26q6hjSMyhpxchpm7jEN/go-path"
+
+	ci "gx/ipfs/QmNiJiXwWE3kRhZrC5ej3kSjWHm337pYfhjLGSCDNKJP2s/go-libp2p-crypto"
+	peer "gx/ipfs/QmPJxxDsX2UbchSHobbYuvz7qnyJTFKvaKMzE2rZWJ4x5B/go-libp2p-peer"
+	routing "gx/ipfs/QmRjT8Bkut84fHf9nxMQBxGsqLAkqzMdFaemDK7e61dBNZ/go-libp2p-routing"
+	ipns "gx/ipfs/QmVpC4PPSaoqZzWYEnQURnsQagimcWEzNKZouZyd7sNJdZ/go-ipns"
+	pb "gx/ipfs/QmVpC4PPSaoqZzWYEnQURnsQagimcWEzNKZouZyd7sNJdZ/go-ipns/pb"
+	proto "gx/ipfs/QmdxUuburamoF6zF9qjeQC4WYcWGbWuRmdLacMEsW8ioD8/gogo-protobuf/proto"
+)
+
+// ErrNoRecordPublicKey is returned when verifying a record which doesn't
+// embed the public key of a peer ID it can't be extracted from
+var ErrNoRecordPublicKey = errors.New("the public key isn't embedded in the record nor in the peer ID")
+
+// CreateRecord creates an IPNS record of the value signed with the key,
+// without storing or publishing it. The public key is embedded in the record
+// when it can't be extracted from the peer ID, so that the record can be
+// verified on its own. A zero ttl leaves the TTL unset.
+func CreateRecord(k ci.PrivKey, value path.Path, seq uint64, eol time.Time, ttl time.Duration) (*pb.IpnsEntry, error) {
+	entry, err := ipns.Create(k, []byte(value), seq, eol)
+	if err != nil {
+		return nil, err
+	}
+
+	if ttl != 0 {
+		entry.Ttl = proto.Uint64(uint64(ttl.Nanoseconds()))
+	}
+
+	if err := ipns.EmbedPublicKey(k.GetPublic(), entry); err != nil {
+		return nil, err
+	}
+	return entry, nil
+}
+
+// VerifyRecord checks that the record is signed by the key of the peer ID
+// and isn't expired, and returns the public key
+func VerifyRecord(id peer.ID, entry *pb.IpnsEntry) (ci.PubKey, error) {
+	pk, err := ipns.ExtractPublicKey(id, entry)
+	if err != nil {
+		return nil, err
+	}
+	if pk == nil {
+		return nil, ErrNoRecordPublicKey
+	}
+	if err := ipns.Validate(pk, entry); err != nil {
+		return nil, err
+	}
+	return pk, nil
+}
+
+// PutSignedRecord verifies a record signed elsewhere, and puts it to the
+// routing system along with its public key
+func PutSignedRecord(ctx context.Context, r routing.ValueStore, id peer.ID, entry *pb.IpnsEntry) error {
+	pk, err := VerifyRecord(id, entry)
+	if err != nil {
+		return err
+	}
+	return PutRecordToRouting(ctx, r, pk, entry)
+}
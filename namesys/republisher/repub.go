@@ -3,6 +3,8 @@ package republisher
 import (
 	"context"
 	"errors"
+	"sort"
+	"sync"
 	"time"
 
 	keystore "github.com/ipfs/go-ipfs/keystore"
@@ -13,14 +15,13 @@ import (
 	peer "gx/ipfs/QmPJxxDsX2UbchSHobbYuvz7qnyJTFKvaKMzE2rZWJ4x5B/go-libp2p-peer"
 	goprocess "gx/ipfs/QmSF8fPo3jgVBAy8fpdjjYqgG87dkJgUprRBHRd2tmfgpP/goprocess"
 	gpctx "gx/ipfs/QmSF8fPo3jgVBAy8fpdjjYqgG87dkJgUprRBHRd2tmfgpP/goprocess/context"
+	ipns "gx/ipfs/QmVpC4PPSaoqZzWYEnQURnsQagimcWEzNKZouZyd7sNJdZ/go-ipns"
 	pb "gx/ipfs/QmVpC4PPSaoqZzWYEnQURnsQagimcWEzNKZouZyd7sNJdZ/go-ipns/pb"
 	logging "gx/ipfs/QmcuXC5cxs79ro2cUuHs4HQ2bkDLJUYokwL8aivcX6HW3C/go-log"
 	proto "gx/ipfs/QmdxUuburamoF6zF9qjeQC4WYcWGbWuRmdLacMEsW8ioD8/gogo-protobuf/proto"
 	ds "gx/ipfs/Qmf4xQhNomPNhrtZc67qSnfJSjxjXs9LWvknJtSXwimPrM/go-datastore"
 )
 
-var errNoEntry = errors.New("no previous entry")
-
 var log = logging.Logger("ipns-repub")
 
 // DefaultRebroadcastInterval is the default interval at which we rebroadcast IPNS records
@@ -32,9 +33,59 @@ var InitialRebroadcastDelay = time.Minute * 1
 // FailureRetryInterval is the interval at which we retry IPNS records broadcasts (when they fail)
 var FailureRetryInterval = time.Minute * 5
 
+// RecordCheckInterval is the longest time between two checks of the stored
+// records. Records published in between, e.g. by 'ipfs name publish' with a
+// short lifetime, may expire before their key is due otherwise.
+var RecordCheckInterval = time.Minute * 5
+
 // DefaultRecordLifetime is the default lifetime for IPNS records
 const DefaultRecordLifetime = time.Hour * 24
 
+// maxExpiryMargin is how long before they expire records are republished at
+// most. Short lived records are republished when a quarter of their lifetime
+// is left.
+const maxExpiryMargin = time.Hour
+
+// SelfKeyName is the name of the node's own key
+const SelfKeyName = "self"
+
+// ErrUnknownKey is returned when republishing a key which isn't in the
+// keystore
+var ErrUnknownKey = errors.New("no key by the given name was found")
+
+// ErrNoRecord is returned when republishing a key which no record was
+// published with yet
+var ErrNoRecord = errors.New("no record was published with the key")
+
+// KeyConfig overrides the republishing settings for a key. The zero values
+// use the settings of the Republisher.
+type KeyConfig struct {
+	// Interval is the longest time between two republishes of the key
+	Interval time.Duration
+
+	// RecordLifetime is how long republished records are valid for
+	RecordLifetime time.Duration
+
+	// TTL is how long republished records may be cached for
+	TTL time.Duration
+}
+
+// KeyStatus is the republishing status of a key
+type KeyStatus struct {
+	Name string
+	ID   peer.ID
+
+	// LastSuccess is when the record of the key was last republished, zero
+	// if it hasn't been yet
+	LastSuccess time.Time
+
+	// NextRun is when the record of the key will be republished next
+	NextRun time.Time
+
+	// LastError is the error of the last republish, if it failed
+	LastError string
+}
+
 type Republisher struct {
 	ns   namesys.Publisher
 	ds   ds.Datastore
@@ -45,6 +96,19 @@ type Republisher struct {
 
 	// how long records that are republished should be valid for
 	RecordLifetime time.Duration
+
+	// Keys overrides the settings for the keys, by name
+	Keys map[string]KeyConfig
+
+	lk     sync.Mutex
+	status map[string]*KeyStatus
+
+	// eols are the EOLs of the stored records the keys were last scheduled
+	// for, by name
+	eols map[string]time.Time
+
+	// wake reschedules the run loop after an on-demand republish
+	wake chan struct{}
 }
 
 // NewRepublisher creates a new Republisher
@@ -56,68 +120,263 @@ func NewRepublisher(ns namesys.Publisher, ds ds.Datastore, self ic.PrivKey, ks k
 		ks:             ks,
 		Interval:       DefaultRebroadcastInterval,
 		RecordLifetime: DefaultRecordLifetime,
+		Keys:           make(map[string]KeyConfig),
+		status:         make(map[string]*KeyStatus),
+		eols:           make(map[string]time.Time),
+		wake:           make(chan struct{}, 1),
 	}
 }
 
+// Run republishes the records of every key when they are due: every
+// interval of the key, and shortly before the stored record expires. The
+// records are republished once on start, as the routing system may have lost
+// them.
 func (rp *Republisher) Run(proc goprocess.Process) {
-	timer := time.NewTimer(InitialRebroadcastDelay)
-	defer timer.Stop()
-	if rp.Interval < InitialRebroadcastDelay {
-		timer.Reset(rp.Interval)
+	delay := InitialRebroadcastDelay
+	if rp.Interval < delay {
+		delay = rp.Interval
 	}
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
 
 	for {
 		select {
 		case <-timer.C:
-			timer.Reset(rp.Interval)
-			err := rp.republishEntries(proc)
-			if err != nil {
-				log.Info("republisher failed to republish: ", err)
-				if FailureRetryInterval < rp.Interval {
-					timer.Reset(FailureRetryInterval)
+		case <-rp.wake:
+			if !timer.Stop() {
+				select {
+				case <-timer.C:
+				default:
 				}
 			}
 		case <-proc.Closing():
 			return
 		}
+
+		next := rp.republishDue(proc)
+		timer.Reset(time.Until(next))
 	}
 }
 
-func (rp *Republisher) republishEntries(p goprocess.Process) error {
+// republishDue republishes the keys due for it, and returns when the next
+// key is due
+func (rp *Republisher) republishDue(p goprocess.Process) time.Time {
 	ctx, cancel := context.WithCancel(gpctx.OnClosingContext(p))
 	defer cancel()
 
-	// TODO: Use rp.ipns.ListPublished(). We can't currently *do* that
-	// because:
-	// 1. There's no way to get keys from the keystore by ID.
-	// 2. We don't actually have access to the IPNS publisher.
-	err := rp.republishEntry(ctx, rp.self)
+	next := time.Now().Add(rp.Interval)
+	if check := time.Now().Add(RecordCheckInterval); check.Before(next) {
+		next = check
+	}
+
+	keys, err := rp.keys()
 	if err != nil {
-		return err
+		log.Info("republisher failed to list keys: ", err)
+		return time.Now().Add(FailureRetryInterval)
 	}
 
-	if rp.ks != nil {
-		keyNames, err := rp.ks.List()
-		if err != nil {
-			return err
+	for _, name := range keys {
+		rp.lk.Lock()
+		st, ok := rp.status[name]
+		if ok {
+			rp.scheduleExpiry(st)
 		}
-		for _, name := range keyNames {
-			priv, err := rp.ks.Get(name)
-			if err != nil {
-				return err
+		due := !ok || !st.NextRun.After(time.Now())
+		rp.lk.Unlock()
+
+		if due {
+			if err := rp.republish(ctx, name); err != nil && err != ErrNoRecord {
+				log.Infof("republisher failed to republish %s: %s", name, err)
 			}
-			err = rp.republishEntry(ctx, priv)
-			if err != nil {
-				return err
+			if ctx.Err() != nil {
+				return next
 			}
+		}
 
+		rp.lk.Lock()
+		if st, ok := rp.status[name]; ok && st.NextRun.Before(next) {
+			next = st.NextRun
 		}
+		rp.lk.Unlock()
+	}
+	return next
+}
+
+// keys returns the names of the keys to republish
+func (rp *Republisher) keys() ([]string, error) {
+	keys := []string{SelfKeyName}
+	if rp.ks == nil {
+		return keys, nil
+	}
+
+	names, err := rp.ks.List()
+	if err != nil {
+		return nil, err
+	}
+	return append(keys, names...), nil
+}
+
+func (rp *Republisher) privKey(name string) (ic.PrivKey, error) {
+	if name == SelfKeyName {
+		return rp.self, nil
+	}
+	if rp.ks == nil {
+		return nil, ErrUnknownKey
+	}
+
+	priv, err := rp.ks.Get(name)
+	if err == keystore.ErrNoSuchKey {
+		return nil, ErrUnknownKey
 	}
+	return priv, err
+}
 
-	return nil
+// keyConfig returns the settings of a key, with the defaults filled in
+func (rp *Republisher) keyConfig(name string) KeyConfig {
+	kc := rp.Keys[name]
+	if kc.Interval == 0 {
+		kc.Interval = rp.Interval
+	}
+	if kc.RecordLifetime == 0 {
+		kc.RecordLifetime = rp.RecordLifetime
+	}
+	return kc
+}
+
+// Republish republishes the record of a key right away, and schedules its
+// next republish. It returns ErrNoRecord if no record was published with the
+// key yet.
+func (rp *Republisher) Republish(ctx context.Context, name string) error {
+	err := rp.republish(ctx, name)
+
+	// the next run of the loop may have changed
+	select {
+	case rp.wake <- struct{}{}:
+	default:
+	}
+	return err
 }
 
-func (rp *Republisher) republishEntry(ctx context.Context, priv ic.PrivKey) error {
+func (rp *Republisher) republish(ctx context.Context, name string) error {
+	priv, err := rp.privKey(name)
+	if err != nil {
+		return err
+	}
+	id, err := peer.IDFromPrivateKey(priv)
+	if err != nil {
+		return err
+	}
+
+	kc := rp.keyConfig(name)
+	err = rp.republishEntry(ctx, priv, kc)
+
+	now := time.Now()
+	rp.lk.Lock()
+	st, ok := rp.status[name]
+	if !ok {
+		st = &KeyStatus{Name: name, ID: id}
+		rp.status[name] = st
+	}
+	switch err {
+	case nil:
+		st.LastSuccess = now
+		st.LastError = ""
+		st.NextRun = now.Add(nextRepublish(kc))
+	case ErrNoRecord:
+		// nothing to republish yet, which isn't a failure of the key
+		st.LastError = ""
+		st.NextRun = now.Add(kc.Interval)
+	default:
+		st.LastError = err.Error()
+		retry := FailureRetryInterval
+		if kc.Interval < retry {
+			retry = kc.Interval
+		}
+		st.NextRun = now.Add(retry)
+	}
+	rp.lk.Unlock()
+	return err
+}
+
+// nextRepublish returns how long after a republish the key is due again:
+// after its interval, or shortly before the record expires if it's sooner
+func nextRepublish(kc KeyConfig) time.Duration {
+	if beforeExpiry := kc.RecordLifetime - expiryMargin(kc.RecordLifetime); beforeExpiry < kc.Interval {
+		return beforeExpiry
+	}
+	return kc.Interval
+}
+
+// expiryMargin returns how long before it expires a record valid for the
+// given time is republished
+func expiryMargin(lifetime time.Duration) time.Duration {
+	margin := lifetime / 4
+	if margin > maxExpiryMargin {
+		margin = maxExpiryMargin
+	}
+	return margin
+}
+
+// scheduleExpiry moves the next run of the key before the expiry of its
+// stored record, which may have been published with another lifetime than
+// the republisher's, e.g. by 'ipfs name publish'. The margin is computed
+// from the time left when the record is first seen. rp.lk must be held.
+func (rp *Republisher) scheduleExpiry(st *KeyStatus) {
+	eol, err := rp.getLastEOL(st.ID)
+	if err != nil {
+		if err != ErrNoRecord {
+			log.Debugf("republisher failed to read the record of %s: %s", st.Name, err)
+		}
+		return
+	}
+	if last, ok := rp.eols[st.Name]; ok && last.Equal(eol) {
+		return
+	}
+	rp.eols[st.Name] = eol
+
+	due := eol.Add(-expiryMargin(time.Until(eol)))
+	if due.Before(st.NextRun) {
+		st.NextRun = due
+	}
+}
+
+// Status returns the republishing status of the keys, by name. Keys which
+// weren't considered for republishing yet are due on the next run.
+func (rp *Republisher) Status() ([]KeyStatus, error) {
+	keys, err := rp.keys()
+	if err != nil {
+		return nil, err
+	}
+
+	rp.lk.Lock()
+	defer rp.lk.Unlock()
+
+	out := make([]KeyStatus, 0, len(keys))
+	for _, name := range keys {
+		if st, ok := rp.status[name]; ok {
+			rp.scheduleExpiry(st)
+			out = append(out, *st)
+			continue
+		}
+
+		priv, err := rp.privKey(name)
+		if err != nil {
+			return nil, err
+		}
+		id, err := peer.IDFromPrivateKey(priv)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, KeyStatus{Name: name, ID: id})
+	}
+
+	sort.Slice(out, func(i, j int) bool {
+		return out[i].Name < out[j].Name
+	})
+	return out, nil
+}
+
+func (rp *Republisher) republishEntry(ctx context.Context, priv ic.PrivKey, kc KeyConfig) error {
 	id, err := peer.IDFromPrivateKey(priv)
 	if err != nil {
 		return err
@@ -128,31 +387,49 @@ func (rp *Republisher) republishEntry(ctx context.Context, priv ic.PrivKey) erro
 	// Look for it locally only
 	p, err := rp.getLastVal(id)
 	if err != nil {
-		if err == errNoEntry {
-			return nil
-		}
 		return err
 	}
 
+	if kc.TTL != 0 {
+		ctx = context.WithValue(ctx, "ipns-publish-ttl", kc.TTL)
+	}
+
 	// update record with same sequence number
-	eol := time.Now().Add(rp.RecordLifetime)
+	eol := time.Now().Add(kc.RecordLifetime)
 	return rp.ns.PublishWithEOL(ctx, priv, p, eol)
 }
 
 func (rp *Republisher) getLastVal(id peer.ID) (path.Path, error) {
+	e, err := rp.getLastEntry(id)
+	if err != nil {
+		return "", err
+	}
+	return path.Path(e.Value), nil
+}
+
+// getLastEOL returns the EOL of the stored record of the key
+func (rp *Republisher) getLastEOL(id peer.ID) (time.Time, error) {
+	e, err := rp.getLastEntry(id)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return ipns.GetEOL(e)
+}
+
+func (rp *Republisher) getLastEntry(id peer.ID) (*pb.IpnsEntry, error) {
 	// Look for it locally only
 	val, err := rp.ds.Get(namesys.IpnsDsKey(id))
 	switch err {
 	case nil:
 	case ds.ErrNotFound:
-		return "", errNoEntry
+		return nil, ErrNoRecord
 	default:
-		return "", err
+		return nil, err
 	}
 
 	e := new(pb.IpnsEntry)
 	if err := proto.Unmarshal(val, e); err != nil {
-		return "", err
+		return nil, err
 	}
-	return path.Path(e.Value), nil
+	return e, nil
 }
@@ -8,13 +8,21 @@ import (
 
 	"github.com/ipfs/go-ipfs/core"
 	mock "github.com/ipfs/go-ipfs/core/mock"
+	keystore "github.com/ipfs/go-ipfs/keystore"
 	namesys "github.com/ipfs/go-ipfs/namesys"
 	. "github.com/ipfs/go-ipfs/namesys/republisher"
 	path "gx/ipfs/QmQ3YSqfxunT5QBg6KBVskKyRE26q6hjSMyhpxchpm7jEN/go-path"
 
+	ci "gx/ipfs/QmNiJiXwWE3kRhZrC5ej3kSjWHm337pYfhjLGSCDNKJP2s/go-libp2p-crypto"
+	peer "gx/ipfs/QmPJxxDsX2UbchSHobbYuvz7qnyJTFKvaKMzE2rZWJ4x5B/go-libp2p-peer"
 	pstore "gx/ipfs/QmQFFp4ntkd4C14sP3FaH9WJyBuetuGUVo6dShNHvnoEvC/go-libp2p-peerstore"
 	goprocess "gx/ipfs/QmSF8fPo3jgVBAy8fpdjjYqgG87dkJgUprRBHRd2tmfgpP/goprocess"
 	mocknet "gx/ipfs/QmSgtf5vHyugoxcwMbyNy6bZ9qPDDTJSYEED2GkWjLwitZ/go-libp2p/p2p/net/mock"
+	ipns "gx/ipfs/QmVpC4PPSaoqZzWYEnQURnsQagimcWEzNKZouZyd7sNJdZ/go-ipns"
+	pb "gx/ipfs/QmVpC4PPSaoqZzWYEnQURnsQagimcWEzNKZouZyd7sNJdZ/go-ipns/pb"
+	proto "gx/ipfs/QmdxUuburamoF6zF9qjeQC4WYcWGbWuRmdLacMEsW8ioD8/gogo-protobuf/proto"
+	ds "gx/ipfs/Qmf4xQhNomPNhrtZc67qSnfJSjxjXs9LWvknJtSXwimPrM/go-datastore"
+	dssync "gx/ipfs/Qmf4xQhNomPNhrtZc67qSnfJSjxjXs9LWvknJtSXwimPrM/go-datastore/sync"
 )
 
 func TestRepublish(t *testing.T) {
@@ -109,6 +117,191 @@ func TestRepublish(t *testing.T) {
 	}
 }
 
+type fakePublisher struct {
+	fail map[peer.ID]bool
+	eols map[peer.ID]time.Time
+	ttls map[peer.ID]time.Duration
+}
+
+func (fp *fakePublisher) Publish(ctx context.Context, k ci.PrivKey, value path.Path) error {
+	return fp.PublishWithEOL(ctx, k, value, time.Now().Add(namesys.DefaultRecordTTL))
+}
+
+func (fp *fakePublisher) PublishWithEOL(ctx context.Context, k ci.PrivKey, value path.Path, eol time.Time) error {
+	id, err := peer.IDFromPrivateKey(k)
+	if err != nil {
+		return err
+	}
+	if fp.fail[id] {
+		return errors.New("publish failed")
+	}
+	fp.eols[id] = eol
+	if ttl, ok := ctx.Value("ipns-publish-ttl").(time.Duration); ok {
+		fp.ttls[id] = ttl
+	}
+	return nil
+}
+
+func TestRepublishStatus(t *testing.T) {
+	ctx := context.Background()
+	dstore := dssync.MutexWrap(ds.NewMapDatastore())
+	ks := keystore.NewMemKeystore()
+
+	genKey := func(name string, published bool) (ci.PrivKey, peer.ID) {
+		priv, _, err := ci.GenerateKeyPair(ci.Ed25519, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		id, err := peer.IDFromPrivateKey(priv)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if name != SelfKeyName {
+			if err := ks.Put(name, priv); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if published {
+			data, err := proto.Marshal(&pb.IpnsEntry{Value: []byte("/ipfs/QmUNLLsPACCz1vLxQVkXqqLX5R1X345qqfHbsf67hvA3Nn")})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := dstore.Put(namesys.IpnsDsKey(id), data); err != nil {
+				t.Fatal(err)
+			}
+		}
+		return priv, id
+	}
+
+	self, selfID := genKey(SelfKeyName, true)
+	_, otherID := genKey("other", false)
+	_, badID := genKey("bad", true)
+
+	fp := &fakePublisher{
+		fail: map[peer.ID]bool{badID: true},
+		eols: make(map[peer.ID]time.Time),
+		ttls: make(map[peer.ID]time.Duration),
+	}
+	repub := NewRepublisher(fp, dstore, self, ks)
+	repub.Keys[SelfKeyName] = KeyConfig{
+		RecordLifetime: 2 * time.Hour,
+		TTL:            time.Minute,
+	}
+
+	start := time.Now()
+	if err := repub.Republish(ctx, SelfKeyName); err != nil {
+		t.Fatal(err)
+	}
+	if err := repub.Republish(ctx, "other"); err != ErrNoRecord {
+		t.Fatalf("expected ErrNoRecord, got %v", err)
+	}
+	if err := repub.Republish(ctx, "bad"); err == nil {
+		t.Fatal("expected republishing the bad key to fail")
+	}
+
+	if eol := fp.eols[selfID]; eol.Before(start.Add(2*time.Hour)) || eol.After(time.Now().Add(2*time.Hour)) {
+		t.Fatalf("expected the record to be valid for 2h, got eol %s", eol)
+	}
+	if fp.ttls[selfID] != time.Minute {
+		t.Fatalf("expected the record ttl to be 1m, got %s", fp.ttls[selfID])
+	}
+	if _, ok := fp.eols[otherID]; ok {
+		t.Fatal("a key without record shouldn't be republished")
+	}
+
+	status, err := repub.Status()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(status) != 3 {
+		t.Fatalf("expected 3 keys, got %d", len(status))
+	}
+
+	// sorted by name: bad, other, self
+	bad, other, st := status[0], status[1], status[2]
+	if bad.ID != badID || bad.LastError == "" || !bad.LastSuccess.IsZero() {
+		t.Errorf("unexpected status of the failed key: %+v", bad)
+	}
+	if next := bad.NextRun.Sub(start); next < FailureRetryInterval || next > FailureRetryInterval+time.Minute {
+		t.Errorf("expected the failed key to be retried after %s, got %s", FailureRetryInterval, next)
+	}
+	if other.ID != otherID || other.LastError != "" || !other.LastSuccess.IsZero() {
+		t.Errorf("unexpected status of the unpublished key: %+v", other)
+	}
+	if st.ID != selfID || st.LastError != "" || st.LastSuccess.Before(start) {
+		t.Errorf("unexpected status of the self key: %+v", st)
+	}
+	// republished a quarter of the lifetime before expiring, as it's sooner
+	// than the interval
+	if next := st.NextRun.Sub(st.LastSuccess); next != 90*time.Minute {
+		t.Errorf("expected the self key to be republished in 1h30m, got %s", next)
+	}
+
+	if err := repub.Republish(ctx, "missing"); err != ErrUnknownKey {
+		t.Fatalf("expected ErrUnknownKey, got %v", err)
+	}
+}
+
+func TestRepublishBeforeStoredExpiry(t *testing.T) {
+	ctx := context.Background()
+	dstore := dssync.MutexWrap(ds.NewMapDatastore())
+
+	self, _, err := ci.GenerateKeyPair(ci.Ed25519, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	id, err := peer.IDFromPrivateKey(self)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	putRecord := func(eol time.Time) {
+		e, err := ipns.Create(self, []byte("/ipfs/QmUNLLsPACCz1vLxQVkXqqLX5R1X345qqfHbsf67hvA3Nn"), 0, eol)
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, err := proto.Marshal(e)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := dstore.Put(namesys.IpnsDsKey(id), data); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	putRecord(time.Now().Add(24 * time.Hour))
+
+	fp := &fakePublisher{
+		eols: make(map[peer.ID]time.Time),
+		ttls: make(map[peer.ID]time.Duration),
+	}
+	repub := NewRepublisher(fp, dstore, self, nil)
+	if err := repub.Republish(ctx, SelfKeyName); err != nil {
+		t.Fatal(err)
+	}
+
+	status, err := repub.Status()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if next := time.Until(status[0].NextRun); next < DefaultRebroadcastInterval-time.Minute {
+		t.Fatalf("expected the key to be due after the interval, got %s", next)
+	}
+
+	// a record published with a short lifetime in between must be
+	// republished before it expires, not after the interval
+	eol := time.Now().Add(time.Hour)
+	putRecord(eol)
+
+	status, err = repub.Status()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if next := status[0].NextRun; !next.Before(eol.Add(-10*time.Minute)) || next.Before(eol.Add(-20*time.Minute)) {
+		t.Fatalf("expected the key to be due 15m before the record expires at %s, got %s", eol, next)
+	}
+}
+
 func verifyResolution(nodes []*core.IpfsNode, key string, exp path.Path) error {
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
@@ -0,0 +1,102 @@
+package p2p
+
+import (
+	"context"
+	"fmt"
+
+	repo "github.com/ipfs/go-ipfs/repo"
+
+	ma "gx/ipfs/QmNTCey11oxhb1AxDnQBRHtdhap6Ctud872NjAYPYYXPuc/go-multiaddr"
+	peer "gx/ipfs/QmPJxxDsX2UbchSHobbYuvz7qnyJTFKvaKMzE2rZWJ4x5B/go-libp2p-peer"
+	protocol "gx/ipfs/QmZNkThpqfVXs9GNbexPrfBbXSLNYeKrE7jwFM2oqHbyqN/go-libp2p-protocol"
+)
+
+// ForwardsConfigKey is the config section holding the forwards and listeners
+// started along with the daemon
+const ForwardsConfigKey = "P2P.Forwards"
+
+// PeerGroupsConfigKey is the config section mapping the names of peer groups
+// to their peer IDs
+const PeerGroupsConfigKey = "P2P.PeerGroups"
+
+// Forward is the config of a persisted forward or listener, with the
+// addresses listed by 'ipfs p2p ls'. The ListenAddress of a listener is the
+// /ipfs/ address of the node, and the TargetAddress of a forward the /ipfs/
+// address of the remote peer.
+type Forward struct {
+	Protocol      string
+	ListenAddress string
+	TargetAddress string
+
+	// AllowedPeers restricts the peers a listener accepts streams from
+	AllowedPeers []string `json:",omitempty"`
+
+	// ReportPeerID makes a listener send the remote peer ID to the target
+	ReportPeerID bool `json:",omitempty"`
+
+	// MaxRate caps the bandwidth of the streams in each direction, in bytes
+	// per second
+	MaxRate uint64 `json:",omitempty"`
+}
+
+// Forwards returns the forwards and listeners persisted in the repo
+func Forwards(r repo.Repo) ([]Forward, error) {
+	var forwards []Forward
+	if _, err := repo.ReadConfigSection(r, ForwardsConfigKey, &forwards); err != nil {
+		return nil, fmt.Errorf("invalid %s config: %s", ForwardsConfigKey, err)
+	}
+	return forwards, nil
+}
+
+// SetForwards replaces the forwards and listeners persisted in the repo
+func SetForwards(r repo.Repo, forwards []Forward) error {
+	if forwards == nil {
+		forwards = []Forward{}
+	}
+	return repo.WriteConfigSection(r, ForwardsConfigKey, forwards)
+}
+
+// PeerGroups returns the peer groups configured in the repo, by name
+func PeerGroups(r repo.Repo) (map[string][]string, error) {
+	groups := make(map[string][]string)
+	if _, err := repo.ReadConfigSection(r, PeerGroupsConfigKey, &groups); err != nil {
+		return nil, fmt.Errorf("invalid %s config: %s", PeerGroupsConfigKey, err)
+	}
+	return groups, nil
+}
+
+// Start starts the forward or listener of a persisted config
+func (p2p *P2P) Start(ctx context.Context, f Forward) (Listener, error) {
+	proto := protocol.ID(f.Protocol)
+
+	listen, err := ma.NewMultiaddr(f.ListenAddress)
+	if err != nil {
+		return nil, err
+	}
+	target, err := ma.NewMultiaddr(f.TargetAddress)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := listen.ValueForProtocol(ma.P_IPFS); err == nil {
+		allowed := make([]peer.ID, 0, len(f.AllowedPeers))
+		for _, id := range f.AllowedPeers {
+			p, err := peer.IDB58Decode(id)
+			if err != nil {
+				return nil, fmt.Errorf("invalid allowed peer %q: %s", id, err)
+			}
+			allowed = append(allowed, p)
+		}
+		return p2p.ForwardRemote(ctx, proto, target, f.ReportPeerID, allowed, f.MaxRate)
+	}
+
+	id, err := target.ValueForProtocol(ma.P_IPFS)
+	if err != nil {
+		return nil, fmt.Errorf("target address %s has no peer ID", target)
+	}
+	p, err := peer.IDB58Decode(id)
+	if err != nil {
+		return nil, err
+	}
+	return p2p.ForwardLocal(ctx, p, proto, listen, f.MaxRate)
+}
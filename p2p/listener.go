@@ -5,6 +5,7 @@ import (
 	"sync"
 
 	ma "gx/ipfs/QmNTCey11oxhb1AxDnQBRHtdhap6Ctud872NjAYPYYXPuc/go-multiaddr"
+	peer "gx/ipfs/QmPJxxDsX2UbchSHobbYuvz7qnyJTFKvaKMzE2rZWJ4x5B/go-libp2p-peer"
 	net "gx/ipfs/QmZ7cBWUXkyWTMN4qH6NGoyMVs7JugyFChBNP4ZUp5rJHH/go-libp2p-net"
 	"gx/ipfs/QmZNkThpqfVXs9GNbexPrfBbXSLNYeKrE7jwFM2oqHbyqN/go-libp2p-protocol"
 	p2phost "gx/ipfs/QmfRHxh8bt4jWLKRhNvR5fn7mFACrQBFLqV4wyoymEExKV/go-libp2p-host"
@@ -16,6 +17,17 @@ type Listener interface {
 	ListenAddress() ma.Multiaddr
 	TargetAddress() ma.Multiaddr
 
+	// AllowedPeers returns the peers allowed to use the listener, or nil if
+	// any peer is
+	AllowedPeers() []peer.ID
+
+	// Traffic returns the bytes forwarded by the streams of the listener
+	Traffic() *Traffic
+
+	// MaxRate returns the bandwidth cap of the streams in each direction, in
+	// bytes per second, or 0
+	MaxRate() uint64
+
 	key() string
 
 	// close closes the listener. Does not affect child streams
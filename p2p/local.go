@@ -14,6 +14,9 @@ import (
 
 // localListener manet streams and proxies them to libp2p services
 type localListener struct {
+	// first, for the alignment of its atomic counters
+	flow flow
+
 	ctx context.Context
 
 	p2p *P2P
@@ -25,13 +28,16 @@ type localListener struct {
 	listener manet.Listener
 }
 
-// ForwardLocal creates new P2P stream to a remote listener
-func (p2p *P2P) ForwardLocal(ctx context.Context, peer peer.ID, proto protocol.ID, bindAddr ma.Multiaddr) (Listener, error) {
+// ForwardLocal creates new P2P stream to a remote listener. maxRate caps the
+// bandwidth of the streams in each direction, in bytes per second, 0 meaning
+// no cap.
+func (p2p *P2P) ForwardLocal(ctx context.Context, peer peer.ID, proto protocol.ID, bindAddr ma.Multiaddr, maxRate uint64) (Listener, error) {
 	listener := &localListener{
 		ctx:   ctx,
 		p2p:   p2p,
 		proto: proto,
 		peer:  peer,
+		flow:  newFlow(maxRate),
 	}
 
 	maListener, err := manet.Listen(bindAddr)
@@ -91,6 +97,8 @@ func (l *localListener) setupStream(local manet.Conn) {
 		Remote: remote,
 
 		Registry: l.p2p.Streams,
+
+		flow: &l.flow,
 	}
 
 	l.p2p.Streams.Register(stream)
@@ -116,6 +124,18 @@ func (l *localListener) TargetAddress() ma.Multiaddr {
 	return addr
 }
 
+func (l *localListener) AllowedPeers() []peer.ID {
+	return nil
+}
+
+func (l *localListener) Traffic() *Traffic {
+	return &l.flow.Traffic
+}
+
+func (l *localListener) MaxRate() uint64 {
+	return l.flow.maxRate
+}
+
 func (l *localListener) key() string {
 	return l.ListenAddress().String()
 }
@@ -3,8 +3,10 @@ package p2p
 import (
 	"context"
 	"fmt"
+	"sort"
 
 	ma "gx/ipfs/QmNTCey11oxhb1AxDnQBRHtdhap6Ctud872NjAYPYYXPuc/go-multiaddr"
+	peer "gx/ipfs/QmPJxxDsX2UbchSHobbYuvz7qnyJTFKvaKMzE2rZWJ4x5B/go-libp2p-peer"
 	net "gx/ipfs/QmZ7cBWUXkyWTMN4qH6NGoyMVs7JugyFChBNP4ZUp5rJHH/go-libp2p-net"
 	protocol "gx/ipfs/QmZNkThpqfVXs9GNbexPrfBbXSLNYeKrE7jwFM2oqHbyqN/go-libp2p-protocol"
 	manet "gx/ipfs/QmZcLBXKaFe8ND5YHPkJRAwmhJGrVsi1JqDZNyJ4nRK5Mj/go-multiaddr-net"
@@ -14,6 +16,9 @@ var maPrefix = "/" + ma.ProtocolWithCode(ma.P_IPFS).Name + "/"
 
 // remoteListener accepts libp2p streams and proxies them to a manet host
 type remoteListener struct {
+	// first, for the alignment of its atomic counters
+	flow flow
+
 	p2p *P2P
 
 	// Application proto identifier.
@@ -25,10 +30,16 @@ type remoteListener struct {
 	// reportRemote if set to true makes the handler send '<base58 remote peerid>\n'
 	// to target before any data is forwarded
 	reportRemote bool
+
+	// allowed is the set of peers allowed to open streams, any peer is
+	// allowed when nil
+	allowed map[peer.ID]struct{}
 }
 
-// ForwardRemote creates new p2p listener
-func (p2p *P2P) ForwardRemote(ctx context.Context, proto protocol.ID, addr ma.Multiaddr, reportRemote bool) (Listener, error) {
+// ForwardRemote creates new p2p listener. When allowedPeers isn't empty,
+// streams from other peers are reset. maxRate caps the bandwidth of the
+// streams in each direction, in bytes per second, 0 meaning no cap.
+func (p2p *P2P) ForwardRemote(ctx context.Context, proto protocol.ID, addr ma.Multiaddr, reportRemote bool, allowedPeers []peer.ID, maxRate uint64) (Listener, error) {
 	listener := &remoteListener{
 		p2p: p2p,
 
@@ -36,6 +47,15 @@ func (p2p *P2P) ForwardRemote(ctx context.Context, proto protocol.ID, addr ma.Mu
 		addr:  addr,
 
 		reportRemote: reportRemote,
+
+		flow: newFlow(maxRate),
+	}
+
+	if len(allowedPeers) > 0 {
+		listener.allowed = make(map[peer.ID]struct{}, len(allowedPeers))
+		for _, p := range allowedPeers {
+			listener.allowed[p] = struct{}{}
+		}
 	}
 
 	if err := p2p.ListenersP2P.Register(listener); err != nil {
@@ -46,14 +66,20 @@ func (p2p *P2P) ForwardRemote(ctx context.Context, proto protocol.ID, addr ma.Mu
 }
 
 func (l *remoteListener) handleStream(remote net.Stream) {
+	peer := remote.Conn().RemotePeer()
+
+	if !l.allows(peer) {
+		log.Warningf("rejected %s stream from %s: peer not allowed", l.proto, peer.Pretty())
+		remote.Reset()
+		return
+	}
+
 	local, err := manet.Dial(l.addr)
 	if err != nil {
 		remote.Reset()
 		return
 	}
 
-	peer := remote.Conn().RemotePeer()
-
 	if l.reportRemote {
 		if _, err := fmt.Fprintf(local, "%s\n", peer.Pretty()); err != nil {
 			remote.Reset()
@@ -78,6 +104,8 @@ func (l *remoteListener) handleStream(remote net.Stream) {
 		Remote: remote,
 
 		Registry: l.p2p.Streams,
+
+		flow: &l.flow,
 	}
 
 	l.p2p.Streams.Register(stream)
@@ -99,6 +127,37 @@ func (l *remoteListener) TargetAddress() ma.Multiaddr {
 	return l.addr
 }
 
+// AllowedPeers returns the peers allowed to open streams, or nil if any peer
+// is allowed
+func (l *remoteListener) AllowedPeers() []peer.ID {
+	if l.allowed == nil {
+		return nil
+	}
+
+	peers := make([]peer.ID, 0, len(l.allowed))
+	for p := range l.allowed {
+		peers = append(peers, p)
+	}
+	sort.Slice(peers, func(i, j int) bool { return peers[i] < peers[j] })
+	return peers
+}
+
+func (l *remoteListener) Traffic() *Traffic {
+	return &l.flow.Traffic
+}
+
+func (l *remoteListener) MaxRate() uint64 {
+	return l.flow.maxRate
+}
+
+func (l *remoteListener) allows(p peer.ID) bool {
+	if l.allowed == nil {
+		return true
+	}
+	_, ok := l.allowed[p]
+	return ok
+}
+
 func (l *remoteListener) close() {}
 
 func (l *remoteListener) key() string {
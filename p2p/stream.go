@@ -1,8 +1,8 @@
 package p2p
 
 import (
-	"io"
 	"sync"
+	"time"
 
 	ma "gx/ipfs/QmNTCey11oxhb1AxDnQBRHtdhap6Ctud872NjAYPYYXPuc/go-multiaddr"
 	peer "gx/ipfs/QmPJxxDsX2UbchSHobbYuvz7qnyJTFKvaKMzE2rZWJ4x5B/go-libp2p-peer"
@@ -16,6 +16,9 @@ const cmgrTag = "stream-fwd"
 
 // Stream holds information on active incoming and outgoing p2p streams.
 type Stream struct {
+	// first, for the alignment of its atomic counters
+	traffic Traffic
+
 	id uint64
 
 	Protocol protocol.ID
@@ -28,6 +31,31 @@ type Stream struct {
 	Remote net.Stream
 
 	Registry *StreamRegistry
+
+	started time.Time
+
+	// flow of the listener of the stream
+	flow *flow
+}
+
+// Peer returns the remote peer of the stream
+func (s *Stream) Peer() peer.ID {
+	return s.peer
+}
+
+// Started returns when the stream was opened
+func (s *Stream) Started() time.Time {
+	return s.started
+}
+
+// BytesIn returns the number of bytes received from the remote peer
+func (s *Stream) BytesIn() uint64 {
+	return s.traffic.BytesIn()
+}
+
+// BytesOut returns the number of bytes sent to the remote peer
+func (s *Stream) BytesOut() uint64 {
+	return s.traffic.BytesOut()
 }
 
 // close stream endpoints and deregister it
@@ -43,8 +71,12 @@ func (s *Stream) reset() error {
 }
 
 func (s *Stream) startStreaming() {
+	if s.flow == nil {
+		s.flow = &flow{}
+	}
+
 	go func() {
-		_, err := io.Copy(s.Local, s.Remote)
+		err := forward(s.Local, s.Remote, s.flow.limitIn, &s.traffic.in, &s.flow.in)
 		if err != nil {
 			s.reset()
 		} else {
@@ -53,7 +85,7 @@ func (s *Stream) startStreaming() {
 	}()
 
 	go func() {
-		_, err := io.Copy(s.Remote, s.Local)
+		err := forward(s.Remote, s.Local, s.flow.limitOut, &s.traffic.out, &s.flow.out)
 		if err != nil {
 			s.reset()
 		} else {
@@ -82,6 +114,7 @@ func (r *StreamRegistry) Register(streamInfo *Stream) {
 	r.conns[streamInfo.peer]++
 
 	streamInfo.id = r.nextID
+	streamInfo.started = time.Now()
 	r.Streams[r.nextID] = streamInfo
 	r.nextID++
 
@@ -0,0 +1,130 @@
+package p2p
+
+import (
+	"io"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// copyBufferSize is the size of the reads forwarded between the endpoints of
+// the streams
+const copyBufferSize = 32 * 1024
+
+// Traffic counts the bytes forwarded by streams. The bytes in are the ones
+// received from the remote peers, the bytes out the ones sent to them.
+type Traffic struct {
+	in  uint64
+	out uint64
+}
+
+// BytesIn returns the number of bytes received from the remote peers
+func (t *Traffic) BytesIn() uint64 {
+	return atomic.LoadUint64(&t.in)
+}
+
+// BytesOut returns the number of bytes sent to the remote peers
+func (t *Traffic) BytesOut() uint64 {
+	return atomic.LoadUint64(&t.out)
+}
+
+// flow is the traffic of a listener, and the bandwidth cap shared by its
+// streams
+type flow struct {
+	Traffic
+
+	maxRate  uint64
+	limitIn  *rateLimiter
+	limitOut *rateLimiter
+}
+
+func newFlow(maxRate uint64) flow {
+	return flow{
+		maxRate:  maxRate,
+		limitIn:  newRateLimiter(maxRate),
+		limitOut: newRateLimiter(maxRate),
+	}
+}
+
+// rateLimiter is a token bucket holding up to a second worth of bytes
+type rateLimiter struct {
+	mu sync.Mutex
+
+	rate  float64
+	avail float64
+	last  time.Time
+}
+
+// newRateLimiter returns a limiter of rate bytes per second, nil if the rate
+// is 0
+func newRateLimiter(rate uint64) *rateLimiter {
+	if rate == 0 {
+		return nil
+	}
+	return &rateLimiter{
+		rate:  float64(rate),
+		avail: float64(rate),
+		last:  time.Now(),
+	}
+}
+
+// maxChunk returns how many bytes may be read at once, so that a single
+// chunk doesn't exceed the bucket
+func (l *rateLimiter) maxChunk(size int) int {
+	if l == nil || float64(size) <= l.rate {
+		return size
+	}
+	return int(l.rate)
+}
+
+// wait takes n bytes from the bucket, waiting for the bucket to refill if it
+// doesn't hold enough
+func (l *rateLimiter) wait(n int) {
+	if l == nil {
+		return
+	}
+
+	l.mu.Lock()
+	now := time.Now()
+	l.avail += now.Sub(l.last).Seconds() * l.rate
+	if l.avail > l.rate {
+		l.avail = l.rate
+	}
+	l.last = now
+
+	// the bytes are taken right away, so that the next callers wait for
+	// them too
+	l.avail -= float64(n)
+	var delay time.Duration
+	if l.avail < 0 {
+		delay = time.Duration(-l.avail / l.rate * float64(time.Second))
+	}
+	l.mu.Unlock()
+
+	time.Sleep(delay)
+}
+
+// forward copies src to dst until EOF, adding the forwarded bytes to the
+// counters
+func forward(dst io.Writer, src io.Reader, limit *rateLimiter, counters ...*uint64) error {
+	buf := make([]byte, copyBufferSize)
+	for {
+		chunk := buf[:limit.maxChunk(len(buf))]
+		n, err := src.Read(chunk)
+		if n > 0 {
+			limit.wait(n)
+			if _, werr := dst.Write(chunk[:n]); werr != nil {
+				return werr
+			}
+			for _, c := range counters {
+				atomic.AddUint64(c, uint64(n))
+			}
+		}
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
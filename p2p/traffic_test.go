@@ -0,0 +1,45 @@
+package p2p
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+func TestForwardCounts(t *testing.T) {
+	data := bytes.Repeat([]byte("a"), 3*copyBufferSize+10)
+
+	var f flow
+	var own uint64
+	var out bytes.Buffer
+	if err := forward(&out, bytes.NewReader(data), nil, &own, &f.in); err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.Equal(out.Bytes(), data) {
+		t.Fatal("the forwarded data differs")
+	}
+	if own != uint64(len(data)) || f.BytesIn() != uint64(len(data)) || f.BytesOut() != 0 {
+		t.Fatalf("unexpected counters: %d, %d in, %d out", own, f.BytesIn(), f.BytesOut())
+	}
+}
+
+func TestForwardRateLimit(t *testing.T) {
+	// a second worth of burst, and another second to wait for
+	f := newFlow(10 * 1024)
+	data := bytes.Repeat([]byte("a"), 20*1024)
+
+	start := time.Now()
+	var out bytes.Buffer
+	if err := forward(&out, bytes.NewReader(data), f.limitOut, &f.out); err != nil {
+		t.Fatal(err)
+	}
+	took := time.Since(start)
+
+	if out.Len() != len(data) || f.BytesOut() != uint64(len(data)) {
+		t.Fatalf("expected %d bytes forwarded, got %d", len(data), out.Len())
+	}
+	if took < 900*time.Millisecond || took > 3*time.Second {
+		t.Fatalf("expected the copy to take about a second, took %s", took)
+	}
+}
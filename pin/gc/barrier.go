@@ -0,0 +1,80 @@
+package gc
+
+import (
+	"sync"
+
+	cid "gx/ipfs/QmR8BauakNcBa3RbE4nbQu76PDiJgoQgz8AJdhJuiU4TAw/go-cid"
+	bstore "gx/ipfs/QmS2aqUZLJp8kF1ihE5rvDGE5LvmKDPnx32w9Z1BW9xLV5/go-ipfs-blockstore"
+	blocks "gx/ipfs/QmWoXtvgC8inqFkAATB7cp2Dax7XBi9VDvSg9RCCZufmRk/go-block-format"
+)
+
+// WriteBarrier is a blockstore wrapper which records the keys of the blocks
+// written while an incremental garbage collection is running. IncrementalGC
+// releases the GC lock between sweep batches when it runs on a WriteBarrier,
+// and never removes the recorded blocks.
+type WriteBarrier struct {
+	bstore.GCBlockstore
+
+	// running is held for the whole incremental GC run
+	running sync.Mutex
+
+	lk      sync.Mutex
+	written *cid.Set
+}
+
+// NewWriteBarrier wraps the blockstore in a WriteBarrier
+func NewWriteBarrier(bs bstore.GCBlockstore) *WriteBarrier {
+	return &WriteBarrier{GCBlockstore: bs}
+}
+
+// Put records the key of the block and writes it to the blockstore
+func (wb *WriteBarrier) Put(b blocks.Block) error {
+	wb.record(b.Cid())
+	return wb.GCBlockstore.Put(b)
+}
+
+// PutMany records the keys of the blocks and writes them to the blockstore
+func (wb *WriteBarrier) PutMany(bs []blocks.Block) error {
+	for _, b := range bs {
+		wb.record(b.Cid())
+	}
+	return wb.GCBlockstore.PutMany(bs)
+}
+
+// record must happen before the write, so that a concurrent sweep either sees
+// the key or deletes the block before it is written
+func (wb *WriteBarrier) record(c cid.Cid) {
+	wb.lk.Lock()
+	if wb.written != nil {
+		wb.written.Add(c)
+	}
+	wb.lk.Unlock()
+}
+
+func (wb *WriteBarrier) begin() {
+	wb.running.Lock()
+
+	wb.lk.Lock()
+	wb.written = cid.NewSet()
+	wb.lk.Unlock()
+}
+
+func (wb *WriteBarrier) end() {
+	wb.lk.Lock()
+	wb.written = nil
+	wb.lk.Unlock()
+
+	wb.running.Unlock()
+}
+
+// deleteUnlessWritten removes the block unless it was written since the
+// barrier was started. It returns whether the block was removed.
+func (wb *WriteBarrier) deleteUnlessWritten(c cid.Cid) (bool, error) {
+	wb.lk.Lock()
+	defer wb.lk.Unlock()
+
+	if wb.written != nil && wb.written.Has(c) {
+		return false, nil
+	}
+	return true, wb.GCBlockstore.DeleteBlock(c)
+}
@@ -0,0 +1,439 @@
+package gc
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	pin "github.com/ipfs/go-ipfs/pin"
+	dag "gx/ipfs/QmUtsx89yiCY6F8mbpP6ecXckiSzCBH7EvkKZuZEHBcr1m/go-merkledag"
+	bserv "gx/ipfs/QmbgbNxC1PMyS2gbx7nf2jKNG7bZAfYJJebdK4ptBBWCz1/go-blockservice"
+
+	cid "gx/ipfs/QmR8BauakNcBa3RbE4nbQu76PDiJgoQgz8AJdhJuiU4TAw/go-cid"
+	ipld "gx/ipfs/QmRL22E4paat7ky7vx9MLpR97JHHbFPrg3ytFQw6qp1y1s/go-ipld-format"
+	bstore "gx/ipfs/QmS2aqUZLJp8kF1ihE5rvDGE5LvmKDPnx32w9Z1BW9xLV5/go-ipfs-blockstore"
+	offline "gx/ipfs/QmYZwey1thDTynSrvd6qQkX24UpTka6TFhQ2v569UpoqxD/go-ipfs-exchange-offline"
+	dstore "gx/ipfs/Qmf4xQhNomPNhrtZc67qSnfJSjxjXs9LWvknJtSXwimPrM/go-datastore"
+)
+
+// checkpointKey is where incremental runs store the shard the sweep got to,
+// so that a run stopped by its budget is continued by the next one
+var checkpointKey = dstore.NewKey("/local/gc/checkpoint")
+
+const (
+	defaultMarkBatch  = 256
+	defaultSweepBatch = 4096
+)
+
+// numShards is the number of shards the keys are swept in. The order of
+// AllKeysChan isn't stable across runs, so the sweep position is a shard of
+// the keyspace rather than an offset into the keys.
+const numShards = 16
+
+// IncrementalOptions configures an IncrementalGC run
+type IncrementalOptions struct {
+	// MaxDuration stops the sweep once the run took that long. Marking is
+	// not limited, as it doesn't block writers. Zero means no limit.
+	MaxDuration time.Duration
+
+	// Target stops the sweep once at least that many bytes were freed.
+	// Zero means no limit.
+	Target uint64
+
+	// MarkBatch is the number of pin roots marked at a time between
+	// cancellation checks. Default is 256.
+	MarkBatch int
+
+	// SweepBatch is the number of keys examined while holding the GC lock.
+	// Default is 4096.
+	SweepBatch int
+}
+
+// IncrementalGC is a garbage collection that doesn't block writers for the
+// whole run, for use on large repos.
+//
+// The pins are marked without holding the GC lock. The blockstore is then
+// swept in batches of opts.SweepBatch keys; the GC lock is held for each
+// batch, during which pins added since the last batch are marked. When bs is
+// a WriteBarrier the lock is released between batches and blocks written
+// during the run are never removed; otherwise the lock is held for the
+// whole sweep.
+//
+// The blockstore is listed once per run, and the keys which aren't marked
+// are swept by shard, picked from the last byte of their multihash. Budgets
+// are checked between batches. The shard the sweep got to is stored in
+// dstor, and the next run starts from it; a run wraps around to the first
+// shard until its budget is used up or every shard was swept.
+func IncrementalGC(ctx context.Context, bs bstore.GCBlockstore, dstor dstore.Datastore, pn pin.Pinner, bestEffortRoots func() ([]cid.Cid, error), opts IncrementalOptions) <-chan Result {
+	if opts.MarkBatch <= 0 {
+		opts.MarkBatch = defaultMarkBatch
+	}
+	if opts.SweepBatch <= 0 {
+		opts.SweepBatch = defaultSweepBatch
+	}
+
+	output := make(chan Result, 128)
+
+	go func() {
+		defer close(output)
+
+		err := incrementalGC(ctx, bs, dstor, pn, bestEffortRoots, opts, output)
+		if err != nil {
+			select {
+			case output <- Result{Error: err}:
+			case <-ctx.Done():
+			}
+		}
+	}()
+
+	return output
+}
+
+func incrementalGC(ctx context.Context, bs bstore.GCBlockstore, dstor dstore.Datastore, pn pin.Pinner, bestEffortRoots func() ([]cid.Cid, error), opts IncrementalOptions, output chan<- Result) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	start := time.Now()
+
+	// the barrier must be up before marking, so that blocks written while
+	// we mark are protected as well
+	wb, barrier := bs.(*WriteBarrier)
+	if barrier {
+		wb.begin()
+		defer wb.end()
+	}
+
+	del := func(c cid.Cid) (bool, error) {
+		return true, bs.DeleteBlock(c)
+	}
+	if barrier {
+		del = wb.deleteUnlessWritten
+	}
+
+	bsrv := bserv.New(bs, offline.Exchange(bs))
+	m := &marker{
+		ctx:    ctx,
+		ng:     dag.NewDAGService(bsrv),
+		pn:     pn,
+		roots:  bestEffortRoots,
+		set:    cid.NewSet(),
+		direct: cid.NewSet(),
+		batch:  opts.MarkBatch,
+		output: output,
+	}
+
+	emark := log.EventBegin(ctx, "GC.incremental.mark")
+	err := m.mark()
+	emark.Done()
+	if err != nil {
+		return err
+	}
+
+	first, err := loadCheckpoint(dstor)
+	if err != nil {
+		return err
+	}
+
+	var unlocker bstore.Unlocker
+	defer func() {
+		if unlocker != nil {
+			unlocker.Unlock()
+		}
+	}()
+
+	// without a barrier the lock is held for the whole sweep, listing the
+	// keys included
+	if !barrier {
+		unlocker = bs.GCLock()
+	}
+
+	esweep := log.EventBegin(ctx, "GC.incremental.sweep")
+	defer esweep.Done()
+
+	shards, err := unmarkedKeys(ctx, bs, m)
+	if err != nil {
+		return err
+	}
+
+	var freed uint64
+	errors := false
+	sweep := func(batch []cid.Cid) error {
+		if unlocker == nil {
+			unlocker = bs.GCLock()
+		}
+		if barrier {
+			defer func() {
+				unlocker.Unlock()
+				unlocker = nil
+			}()
+		}
+
+		// pick up what was pinned since the keys were listed
+		if err := m.mark(); err != nil {
+			return err
+		}
+
+		for _, k := range batch {
+			if m.set.Has(k) || m.direct.Has(k) {
+				continue
+			}
+
+			size, err := bs.GetSize(k)
+			if err == bstore.ErrNotFound {
+				continue
+			}
+
+			var ok bool
+			if err == nil {
+				ok, err = del(k)
+			}
+			if err != nil {
+				errors = true
+				select {
+				case output <- Result{Error: &CannotDeleteBlockError{k, err}}:
+				case <-ctx.Done():
+					return ctx.Err()
+				}
+				continue
+			}
+			if !ok {
+				continue
+			}
+
+			if size > 0 {
+				freed += uint64(size)
+			}
+
+			select {
+			case output <- Result{KeyRemoved: k}:
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+		return nil
+	}
+
+	overBudget := func() bool {
+		if opts.Target > 0 && freed >= opts.Target {
+			log.Infof("incremental gc freed %d bytes, target reached", freed)
+			return true
+		}
+		if opts.MaxDuration > 0 && time.Since(start) >= opts.MaxDuration {
+			log.Infof("incremental gc freed %d bytes, out of time", freed)
+			return true
+		}
+		return false
+	}
+
+shardLoop:
+	for i := 0; i < numShards; i++ {
+		shard := (first + i) % numShards
+		keys := shards[shard]
+
+		for len(keys) > 0 {
+			n := opts.SweepBatch
+			if n > len(keys) {
+				n = len(keys)
+			}
+			if err := sweep(keys[:n]); err != nil {
+				return err
+			}
+			keys = keys[n:]
+
+			if len(keys) > 0 && overBudget() {
+				// the next run starts over with this shard
+				break shardLoop
+			}
+		}
+
+		// the next run starts with the next shard
+		if err := saveCheckpoint(dstor, (shard+1)%numShards); err != nil {
+			return err
+		}
+
+		if overBudget() {
+			break
+		}
+	}
+
+	if errors {
+		select {
+		case output <- Result{Error: ErrCannotDeleteSomeBlocks}:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	defer log.EventBegin(ctx, "GC.datastore").Done()
+	gds, ok := dstor.(dstore.GCDatastore)
+	if !ok {
+		return nil
+	}
+
+	return gds.CollectGarbage()
+}
+
+// shardOf returns the shard of a key
+func shardOf(c cid.Cid) int {
+	h := c.Hash()
+	if len(h) == 0 {
+		return 0
+	}
+	return int(h[len(h)-1]) % numShards
+}
+
+// unmarkedKeys lists the keys of the blockstore which aren't marked, by
+// shard. The blockstore is only listed once per run, and only the keys which
+// may be removed are kept in memory.
+func unmarkedKeys(ctx context.Context, bs bstore.Blockstore, m *marker) ([numShards][]cid.Cid, error) {
+	var shards [numShards][]cid.Cid
+
+	keychan, err := bs.AllKeysChan(ctx)
+	if err != nil {
+		return shards, err
+	}
+
+	for {
+		select {
+		case k, ok := <-keychan:
+			if !ok {
+				return shards, nil
+			}
+			if m.set.Has(k) || m.direct.Has(k) {
+				continue
+			}
+			s := shardOf(k)
+			shards[s] = append(shards[s], k)
+		case <-ctx.Done():
+			return shards, ctx.Err()
+		}
+	}
+}
+
+func loadCheckpoint(dstor dstore.Datastore) (int, error) {
+	val, err := dstor.Get(checkpointKey)
+	switch err {
+	case nil:
+	case dstore.ErrNotFound:
+		return 0, nil
+	default:
+		return 0, err
+	}
+
+	shard, err := strconv.Atoi(string(val))
+	if err != nil || shard < 0 || shard >= numShards {
+		log.Warningf("ignoring invalid gc checkpoint: %q", val)
+		return 0, nil
+	}
+	return shard, nil
+}
+
+func saveCheckpoint(dstor dstore.Datastore, shard int) error {
+	return dstor.Put(checkpointKey, []byte(strconv.Itoa(shard)))
+}
+
+// marker builds the marked set of an incremental run. It can be called
+// repeatedly to add what was pinned since the last call.
+type marker struct {
+	ctx    context.Context
+	ng     ipld.NodeGetter
+	pn     pin.Pinner
+	roots  func() ([]cid.Cid, error)
+	batch  int
+	output chan<- Result
+
+	// set holds the keys whose descendants are marked too, direct the
+	// directly pinned keys
+	set    *cid.Set
+	direct *cid.Set
+}
+
+func (m *marker) mark() error {
+	errors := false
+	emit := func(err error) error {
+		errors = true
+		select {
+		case m.output <- Result{Error: err}:
+			return nil
+		case <-m.ctx.Done():
+			return m.ctx.Err()
+		}
+	}
+
+	getLinks := func(ctx context.Context, c cid.Cid) ([]*ipld.Link, error) {
+		links, err := ipld.GetLinks(ctx, m.ng, c)
+		if err != nil {
+			if err := emit(&CannotFetchLinksError{c, err}); err != nil {
+				return nil, err
+			}
+		}
+		return links, nil
+	}
+
+	bestEffortGetLinks := func(ctx context.Context, c cid.Cid) ([]*ipld.Link, error) {
+		links, err := ipld.GetLinks(ctx, m.ng, c)
+		if err != nil && err != ipld.ErrNotFound {
+			if err := emit(&CannotFetchLinksError{c, err}); err != nil {
+				return nil, err
+			}
+		}
+		return links, nil
+	}
+
+	if err := m.markRoots(m.pn.RecursiveKeys(), getLinks, emit); err != nil {
+		return err
+	}
+
+	if m.roots != nil {
+		roots, err := m.roots()
+		if err != nil {
+			return err
+		}
+		if err := m.markRoots(roots, bestEffortGetLinks, emit); err != nil {
+			return err
+		}
+	}
+
+	for _, k := range m.pn.DirectKeys() {
+		m.direct.Add(k)
+	}
+
+	if err := m.markRoots(m.pn.InternalPins(), getLinks, emit); err != nil {
+		return err
+	}
+
+	if errors {
+		return ErrCannotFetchAllLinks
+	}
+	return nil
+}
+
+// markRoots marks the roots which aren't marked yet, and their descendants,
+// a batch of roots at a time
+func (m *marker) markRoots(roots []cid.Cid, getLinks dag.GetLinks, emit func(error) error) error {
+	for len(roots) > 0 {
+		n := m.batch
+		if n > len(roots) {
+			n = len(roots)
+		}
+
+		todo := make([]cid.Cid, 0, n)
+		for _, c := range roots[:n] {
+			if !m.set.Has(c) {
+				todo = append(todo, c)
+			}
+		}
+		roots = roots[n:]
+
+		if err := Descendants(m.ctx, getLinks, m.set, todo); err != nil {
+			if err := emit(err); err != nil {
+				return err
+			}
+		}
+
+		if err := m.ctx.Err(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
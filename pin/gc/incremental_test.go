@@ -0,0 +1,311 @@
+package gc
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"testing"
+
+	pin "github.com/ipfs/go-ipfs/pin"
+	dag "gx/ipfs/QmUtsx89yiCY6F8mbpP6ecXckiSzCBH7EvkKZuZEHBcr1m/go-merkledag"
+	bserv "gx/ipfs/QmbgbNxC1PMyS2gbx7nf2jKNG7bZAfYJJebdK4ptBBWCz1/go-blockservice"
+
+	cid "gx/ipfs/QmR8BauakNcBa3RbE4nbQu76PDiJgoQgz8AJdhJuiU4TAw/go-cid"
+	ipld "gx/ipfs/QmRL22E4paat7ky7vx9MLpR97JHHbFPrg3ytFQw6qp1y1s/go-ipld-format"
+	bstore "gx/ipfs/QmS2aqUZLJp8kF1ihE5rvDGE5LvmKDPnx32w9Z1BW9xLV5/go-ipfs-blockstore"
+	offline "gx/ipfs/QmYZwey1thDTynSrvd6qQkX24UpTka6TFhQ2v569UpoqxD/go-ipfs-exchange-offline"
+	ds "gx/ipfs/Qmf4xQhNomPNhrtZc67qSnfJSjxjXs9LWvknJtSXwimPrM/go-datastore"
+	dssync "gx/ipfs/Qmf4xQhNomPNhrtZc67qSnfJSjxjXs9LWvknJtSXwimPrM/go-datastore/sync"
+)
+
+type gcTest struct {
+	dstore ds.Datastore
+	bs     *WriteBarrier
+	dserv  ipld.DAGService
+	pinner pin.Pinner
+
+	kept    []cid.Cid
+	garbage []cid.Cid
+}
+
+func setupGCTest(t *testing.T, ngarbage int) *gcTest {
+	ctx := context.Background()
+
+	dstore := dssync.MutexWrap(ds.NewMapDatastore())
+	bs := NewWriteBarrier(bstore.NewGCBlockstore(bstore.NewBlockstore(dstore), bstore.NewGCLocker()))
+	dserv := dag.NewDAGService(bserv.New(bs, offline.Exchange(bs)))
+	pinner := pin.NewPinner(dstore, dserv, dserv)
+
+	gt := &gcTest{dstore: dstore, bs: bs, dserv: dserv, pinner: pinner}
+
+	child := dag.NodeWithData([]byte("child"))
+	root := dag.NodeWithData([]byte("root"))
+	if err := root.AddNodeLink("child", child); err != nil {
+		t.Fatal(err)
+	}
+	direct := dag.NodeWithData([]byte("direct"))
+
+	if err := dserv.AddMany(ctx, []ipld.Node{child, root, direct}); err != nil {
+		t.Fatal(err)
+	}
+	if err := pinner.Pin(ctx, root, true); err != nil {
+		t.Fatal(err)
+	}
+	if err := pinner.Pin(ctx, direct, false); err != nil {
+		t.Fatal(err)
+	}
+	if err := pinner.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	gt.kept = []cid.Cid{child.Cid(), root.Cid(), direct.Cid()}
+
+	for i := 0; i < ngarbage; i++ {
+		nd := dag.NodeWithData([]byte{'g', byte(i)})
+		if err := dserv.Add(ctx, nd); err != nil {
+			t.Fatal(err)
+		}
+		gt.garbage = append(gt.garbage, nd.Cid())
+	}
+
+	return gt
+}
+
+func (gt *gcTest) run(t *testing.T, opts IncrementalOptions) int {
+	removed := 0
+	out := IncrementalGC(context.Background(), gt.bs, gt.dstore, gt.pinner, nil, opts)
+	for res := range out {
+		if res.Error != nil {
+			t.Fatal(res.Error)
+		}
+		removed++
+	}
+	return removed
+}
+
+func (gt *gcTest) count(t *testing.T, keys []cid.Cid) int {
+	n := 0
+	for _, c := range keys {
+		has, err := gt.bs.Has(c)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if has {
+			n++
+		}
+	}
+	return n
+}
+
+func TestIncrementalGC(t *testing.T) {
+	gt := setupGCTest(t, 10)
+
+	if removed := gt.run(t, IncrementalOptions{SweepBatch: 3, MarkBatch: 1}); removed != 10 {
+		t.Errorf("expected 10 blocks to be removed, got %d", removed)
+	}
+
+	if n := gt.count(t, gt.garbage); n != 0 {
+		t.Errorf("%d unpinned blocks survived", n)
+	}
+	if n := gt.count(t, gt.kept); n != len(gt.kept) {
+		t.Errorf("%d pinned blocks were removed", len(gt.kept)-n)
+	}
+}
+
+func TestIncrementalGCTarget(t *testing.T) {
+	gt := setupGCTest(t, 10)
+
+	// every run stops after freeing a block, and the next one continues
+	// from the shard it stopped in
+	for i := 0; i < 10; i++ {
+		if removed := gt.run(t, IncrementalOptions{SweepBatch: 1, Target: 1}); removed != 1 {
+			t.Fatalf("run %d: expected one block to be removed, got %d", i, removed)
+		}
+	}
+
+	if n := gt.count(t, gt.garbage); n != 0 {
+		t.Errorf("%d unpinned blocks survived", n)
+	}
+	if n := gt.count(t, gt.kept); n != len(gt.kept) {
+		t.Errorf("%d pinned blocks were removed", len(gt.kept)-n)
+	}
+}
+
+func TestIncrementalGCWrapsAround(t *testing.T) {
+	gt := setupGCTest(t, 10)
+
+	// a run resuming from the last shard continues from the first one
+	if err := saveCheckpoint(gt.dstore, numShards-1); err != nil {
+		t.Fatal(err)
+	}
+
+	if removed := gt.run(t, IncrementalOptions{SweepBatch: 1, Target: 1 << 20}); removed != 10 {
+		t.Errorf("expected 10 blocks to be removed, got %d", removed)
+	}
+
+	shard, err := loadCheckpoint(gt.dstore)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if shard != numShards-1 {
+		t.Errorf("expected the checkpoint to be back at shard %d, got %d", numShards-1, shard)
+	}
+}
+
+// shuffledBlockstore returns the keys in a different order on every call
+type shuffledBlockstore struct {
+	bstore.GCBlockstore
+}
+
+func (bs shuffledBlockstore) AllKeysChan(ctx context.Context) (<-chan cid.Cid, error) {
+	in, err := bs.GCBlockstore.AllKeysChan(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var keys []cid.Cid
+	for k := range in {
+		keys = append(keys, k)
+	}
+	rand.Shuffle(len(keys), func(i, j int) {
+		keys[i], keys[j] = keys[j], keys[i]
+	})
+
+	out := make(chan cid.Cid, len(keys))
+	for _, k := range keys {
+		out <- k
+	}
+	close(out)
+	return out, nil
+}
+
+func TestIncrementalGCUnstableOrder(t *testing.T) {
+	gt := setupGCTest(t, 10)
+	bs := shuffledBlockstore{gt.bs}
+
+	for i := 0; i < 10; i++ {
+		removed := 0
+		out := IncrementalGC(context.Background(), bs, gt.dstore, gt.pinner, nil, IncrementalOptions{SweepBatch: 1, Target: 1})
+		for res := range out {
+			if res.Error != nil {
+				t.Fatal(res.Error)
+			}
+			removed++
+		}
+		if removed != 1 {
+			t.Fatalf("run %d: expected one block to be removed, got %d", i, removed)
+		}
+	}
+
+	if n := gt.count(t, gt.garbage); n != 0 {
+		t.Errorf("%d unpinned blocks survived", n)
+	}
+	if n := gt.count(t, gt.kept); n != len(gt.kept) {
+		t.Errorf("%d pinned blocks were removed", len(gt.kept)-n)
+	}
+}
+
+// countingBlockstore counts the listings of the blockstore and fails to get
+// the size of a key
+type countingBlockstore struct {
+	bstore.GCBlockstore
+	listings int
+	badSize  cid.Cid
+}
+
+func (bs *countingBlockstore) AllKeysChan(ctx context.Context) (<-chan cid.Cid, error) {
+	bs.listings++
+	return bs.GCBlockstore.AllKeysChan(ctx)
+}
+
+func (bs *countingBlockstore) GetSize(c cid.Cid) (int, error) {
+	if c.Equals(bs.badSize) {
+		return -1, errors.New("can't read the size")
+	}
+	return bs.GCBlockstore.GetSize(c)
+}
+
+func TestIncrementalGCSinglePass(t *testing.T) {
+	gt := setupGCTest(t, 10)
+	bs := &countingBlockstore{GCBlockstore: gt.bs, badSize: gt.garbage[0]}
+
+	removed := 0
+	var errs []error
+	out := IncrementalGC(context.Background(), bs, gt.dstore, gt.pinner, nil, IncrementalOptions{SweepBatch: 1})
+	for res := range out {
+		if res.Error != nil {
+			errs = append(errs, res.Error)
+			continue
+		}
+		removed++
+	}
+
+	if bs.listings != 1 {
+		t.Errorf("expected the blockstore to be listed once, got %d", bs.listings)
+	}
+
+	// the block whose size can't be read is reported and kept
+	if removed != 9 {
+		t.Errorf("expected 9 blocks to be removed, got %d", removed)
+	}
+	if len(errs) != 2 || errs[1] != ErrCannotDeleteSomeBlocks {
+		t.Fatalf("unexpected errors %v", errs)
+	}
+	if _, ok := errs[0].(*CannotDeleteBlockError); !ok {
+		t.Errorf("expected a CannotDeleteBlockError, got %v", errs[0])
+	}
+	if n := gt.count(t, gt.garbage[:1]); n != 1 {
+		t.Error("the block whose size can't be read was removed")
+	}
+}
+
+func TestWriteBarrier(t *testing.T) {
+	gt := setupGCTest(t, 2)
+
+	gt.bs.begin()
+	defer gt.bs.end()
+
+	written := dag.NodeWithData([]byte("written during gc"))
+	if err := gt.bs.Put(written); err != nil {
+		t.Fatal(err)
+	}
+
+	removed, err := gt.bs.deleteUnlessWritten(written.Cid())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if removed {
+		t.Error("a block written during the run was removed")
+	}
+
+	removed, err = gt.bs.deleteUnlessWritten(gt.garbage[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !removed {
+		t.Error("expected an older block to be removed")
+	}
+}
+
+func TestStatsCount(t *testing.T) {
+	gt := setupGCTest(t, 4)
+
+	var stats Stats
+	for i := 0; i < 2; i++ {
+		out := stats.Count(IncrementalGC(context.Background(), gt.bs, gt.dstore, gt.pinner, nil, IncrementalOptions{}))
+		for res := range out {
+			if res.Error != nil {
+				t.Fatal(res.Error)
+			}
+		}
+	}
+
+	if runs := stats.Runs(); runs != 2 {
+		t.Errorf("expected 2 runs, got %d", runs)
+	}
+	if removed := stats.BlocksRemoved(); removed != 4 {
+		t.Errorf("expected 4 blocks to be removed, got %d", removed)
+	}
+	if stats.LastRun().IsZero() {
+		t.Error("expected the last run to be set")
+	}
+}
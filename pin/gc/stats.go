@@ -0,0 +1,64 @@
+package gc
+
+import (
+	"sync"
+	"time"
+)
+
+// Stats counts the garbage collection runs of a node and the blocks they
+// removed. The zero value is ready to use.
+type Stats struct {
+	lk sync.Mutex
+
+	runs          uint64
+	blocksRemoved uint64
+	lastRun       time.Time
+}
+
+// Runs returns the number of completed garbage collection runs
+func (s *Stats) Runs() uint64 {
+	s.lk.Lock()
+	defer s.lk.Unlock()
+	return s.runs
+}
+
+// BlocksRemoved returns the number of blocks removed by all runs, including
+// the ones in progress
+func (s *Stats) BlocksRemoved() uint64 {
+	s.lk.Lock()
+	defer s.lk.Unlock()
+	return s.blocksRemoved
+}
+
+// LastRun returns the time the last completed run ended at, the zero time if
+// no run completed yet
+func (s *Stats) LastRun() time.Time {
+	s.lk.Lock()
+	defer s.lk.Unlock()
+	return s.lastRun
+}
+
+// Count passes the results of a run through, counting the removed blocks and
+// the run once the results are consumed.
+func (s *Stats) Count(in <-chan Result) <-chan Result {
+	out := make(chan Result, cap(in))
+
+	go func() {
+		defer close(out)
+		for res := range in {
+			if res.Error == nil {
+				s.lk.Lock()
+				s.blocksRemoved++
+				s.lk.Unlock()
+			}
+			out <- res
+		}
+
+		s.lk.Lock()
+		s.runs++
+		s.lastRun = time.Now()
+		s.lk.Unlock()
+	}()
+
+	return out
+}
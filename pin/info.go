@@ -0,0 +1,143 @@
+package pin
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"gx/ipfs/QmUtsx89yiCY6F8mbpP6ecXckiSzCBH7EvkKZuZEHBcr1m/go-merkledag"
+
+	cid "gx/ipfs/QmR8BauakNcBa3RbE4nbQu76PDiJgoQgz8AJdhJuiU4TAw/go-cid"
+	ipld "gx/ipfs/QmRL22E4paat7ky7vx9MLpR97JHHbFPrg3ytFQw6qp1y1s/go-ipld-format"
+	ds "gx/ipfs/Qmf4xQhNomPNhrtZc67qSnfJSjxjXs9LWvknJtSXwimPrM/go-datastore"
+)
+
+// linkInfo is the link of the pin root to the set of info nodes. Pin roots
+// written before pins had names don't have it, MigrateInfos adds it.
+const linkInfo = "info"
+
+// Info holds the user supplied name and metadata of a direct or recursive pin
+type Info struct {
+	Name string            `json:",omitempty"`
+	Meta map[string]string `json:",omitempty"`
+}
+
+// Empty returns whether the info has neither a name nor metadata
+func (i Info) Empty() bool {
+	return i.Name == "" && len(i.Meta) == 0
+}
+
+// Matches returns whether the name starts with namePrefix and the metadata
+// has all the given key/value pairs
+func (i Info) Matches(namePrefix string, meta map[string]string) bool {
+	if !strings.HasPrefix(i.Name, namePrefix) {
+		return false
+	}
+	for k, v := range meta {
+		if mv, ok := i.Meta[k]; !ok || mv != v {
+			return false
+		}
+	}
+	return true
+}
+
+// infoNode is what an info node holds. The pinned cid is stored in the data
+// rather than linked, so that walking the internal pins doesn't walk the
+// pinned dags.
+type infoNode struct {
+	Cid string
+	Info
+}
+
+// storeInfos stores the set of info nodes. Only the infos missing from nodes
+// are written, nodes is updated with them.
+func storeInfos(ctx context.Context, dag ipld.DAGService, infos map[cid.Cid]Info, nodes map[cid.Cid]cid.Cid, internalKeys keyObserver) (*merkledag.ProtoNode, error) {
+	keys := make([]cid.Cid, 0, len(infos))
+	for c, info := range infos {
+		if nc, ok := nodes[c]; ok {
+			internalKeys(nc)
+			keys = append(keys, nc)
+			continue
+		}
+
+		data, err := json.Marshal(&infoNode{Cid: c.String(), Info: info})
+		if err != nil {
+			return nil, err
+		}
+
+		n := merkledag.NodeWithData(data)
+		if err := dag.Add(ctx, n); err != nil {
+			return nil, err
+		}
+		internalKeys(n.Cid())
+		nodes[c] = n.Cid()
+		keys = append(keys, n.Cid())
+	}
+
+	return storeSet(ctx, dag, keys, internalKeys)
+}
+
+// loadInfos loads the set of info nodes, and returns the infos and their
+// nodes by pinned cid. A pin root without infos has none.
+func loadInfos(ctx context.Context, dag ipld.DAGService, root *merkledag.ProtoNode, internalKeys keyObserver) (map[cid.Cid]Info, map[cid.Cid]cid.Cid, error) {
+	infos := make(map[cid.Cid]Info)
+	nodes := make(map[cid.Cid]cid.Cid)
+	if !hasInfoSet(root) {
+		return infos, nodes, nil
+	}
+
+	keys, err := loadSet(ctx, dag, root, linkInfo, internalKeys)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	for _, nc := range keys {
+		internalKeys(nc)
+
+		nd, err := dag.Get(ctx, nc)
+		if err != nil {
+			return nil, nil, err
+		}
+		pbn, ok := nd.(*merkledag.ProtoNode)
+		if !ok {
+			return nil, nil, merkledag.ErrNotProtobuf
+		}
+
+		var in infoNode
+		if err := json.Unmarshal(pbn.Data(), &in); err != nil {
+			return nil, nil, fmt.Errorf("invalid pin info node %s: %s", nc, err)
+		}
+		c, err := cid.Decode(in.Cid)
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid pin info node %s: %s", nc, err)
+		}
+		infos[c] = in.Info
+		nodes[c] = nc
+	}
+	return infos, nodes, nil
+}
+
+// hasInfoSet returns whether the pin root was written with pin infos
+func hasInfoSet(root *merkledag.ProtoNode) bool {
+	_, err := root.GetNodeLink(linkInfo)
+	return err == nil
+}
+
+// MigrateInfos rewrites the pin state stored in the datastore by a version
+// of go-ipfs which didn't have pin names and metadata, so that it links to
+// an empty set of infos. Versions which predate pin infos drop the link when
+// they write the pin state, which is why this is a repo migration.
+func MigrateInfos(d ds.Datastore, dserv, internal ipld.DAGService) error {
+	has, err := d.Has(pinDatastoreKey)
+	if err != nil || !has {
+		// the pin state wasn't written yet
+		return err
+	}
+
+	p, err := LoadPinner(d, dserv, internal)
+	if err != nil {
+		return err
+	}
+	return p.Flush()
+}
@@ -149,6 +149,14 @@ type Pinner interface {
 	// InternalPins returns all cids kept pinned for the internal state of the
 	// pinner
 	InternalPins() []cid.Cid
+
+	// SetInfo sets the name and metadata of a direct or recursive pin. An
+	// empty Info removes them.
+	SetInfo(cid.Cid, Info) error
+
+	// Info returns the name and metadata of a direct or recursive pin, and
+	// whether it has any
+	Info(cid.Cid) (Info, bool)
 }
 
 // Pinned represents CID which has been pinned with a pinning strategy.
@@ -188,9 +196,16 @@ type pinner struct {
 	// Track the keys used for storing the pinning state, so gc does
 	// not delete them.
 	internalPin *cid.Set
-	dserv       ipld.DAGService
-	internal    ipld.DAGService // dagservice used to store internal objects
-	dstore      ds.Datastore
+
+	// info holds the names and metadata of the pins which have any, and
+	// infoNodes the stored node of each info which didn't change since
+	// the last flush
+	info      map[cid.Cid]Info
+	infoNodes map[cid.Cid]cid.Cid
+
+	dserv    ipld.DAGService
+	internal ipld.DAGService // dagservice used to store internal objects
+	dstore   ds.Datastore
 }
 
 // NewPinner creates a new pinner using the given datastore as a backend
@@ -206,6 +221,8 @@ func NewPinner(dstore ds.Datastore, serv, internal ipld.DAGService) Pinner {
 		dstore:      dstore,
 		internal:    internal,
 		internalPin: cid.NewSet(),
+		info:        make(map[cid.Cid]Info),
+		infoNodes:   make(map[cid.Cid]cid.Cid),
 	}
 }
 
@@ -280,11 +297,13 @@ func (p *pinner) Unpin(ctx context.Context, c cid.Cid, recursive bool) error {
 	case "recursive":
 		if recursive {
 			p.recursePin.Remove(c)
+			p.removeInfo(c)
 			return nil
 		}
 		return fmt.Errorf("%s is pinned recursively", c)
 	case "direct":
 		p.directPin.Remove(c)
+		p.removeInfo(c)
 		return nil
 	default:
 		return fmt.Errorf("%s is pinned indirectly under %s", c, reason)
@@ -438,6 +457,9 @@ func (p *pinner) RemovePinWithMode(c cid.Cid, mode Mode) {
 		// programmer error, panic OK
 		panic("unrecognized pin type")
 	}
+	if !p.recursePin.Has(c) && !p.directPin.Has(c) {
+		p.removeInfo(c)
+	}
 }
 
 func cidSetWithValues(cids []cid.Cid) *cid.Set {
@@ -494,6 +516,13 @@ func LoadPinner(d ds.Datastore, dserv, internal ipld.DAGService) (Pinner, error)
 		p.directPin = cidSetWithValues(directKeys)
 	}
 
+	{ // load pin infos
+		p.info, p.infoNodes, err = loadInfos(ctx, internal, rootpb, recordInternal)
+		if err != nil {
+			return nil, fmt.Errorf("cannot load pin infos: %v", err)
+		}
+	}
+
 	p.internalPin = internalset
 
 	// assign services
@@ -531,8 +560,14 @@ func (p *pinner) Update(ctx context.Context, from, to cid.Cid, unpin bool) error
 	}
 
 	p.recursePin.Add(to)
+	if info, ok := p.info[from]; ok {
+		if _, ok := p.info[to]; !ok {
+			p.info[to] = info
+		}
+	}
 	if unpin {
 		p.recursePin.Remove(from)
+		p.removeInfo(from)
 	}
 	return nil
 }
@@ -568,6 +603,16 @@ func (p *pinner) Flush() error {
 		}
 	}
 
+	{
+		n, err := storeInfos(ctx, p.internal, p.info, p.infoNodes, recordInternal)
+		if err != nil {
+			return err
+		}
+		if err := root.AddNodeLink(linkInfo, n); err != nil {
+			return err
+		}
+	}
+
 	// add the empty node, its referenced by the pin sets but never created
 	err := p.internal.Add(ctx, new(mdag.ProtoNode))
 	if err != nil {
@@ -599,6 +644,40 @@ func (p *pinner) InternalPins() []cid.Cid {
 	return out
 }
 
+// SetInfo sets the name and metadata of a direct or recursive pin
+func (p *pinner) SetInfo(c cid.Cid, info Info) error {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	if !p.recursePin.Has(c) && !p.directPin.Has(c) {
+		return ErrNotPinned
+	}
+	if info.Empty() {
+		p.removeInfo(c)
+		return nil
+	}
+	meta := make(map[string]string, len(info.Meta))
+	for k, v := range info.Meta {
+		meta[k] = v
+	}
+	p.info[c] = Info{Name: info.Name, Meta: meta}
+	delete(p.infoNodes, c)
+	return nil
+}
+
+// removeInfo removes the name and metadata of a pin
+func (p *pinner) removeInfo(c cid.Cid) {
+	delete(p.info, c)
+	delete(p.infoNodes, c)
+}
+
+// Info returns the name and metadata of a direct or recursive pin
+func (p *pinner) Info(c cid.Cid) (Info, bool) {
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+	info, ok := p.info[c]
+	return info, ok
+}
+
 // PinWithMode allows the user to have fine grained control over pin
 // counts
 func (p *pinner) PinWithMode(c cid.Cid, mode Mode) {
@@ -10,6 +10,7 @@ import (
 
 	util "gx/ipfs/QmNohiVssaPw3KVLZik59DBVGTSm2dGvYT9eoXt5DQ36Yz/go-ipfs-util"
 	cid "gx/ipfs/QmR8BauakNcBa3RbE4nbQu76PDiJgoQgz8AJdhJuiU4TAw/go-cid"
+	ipld "gx/ipfs/QmRL22E4paat7ky7vx9MLpR97JHHbFPrg3ytFQw6qp1y1s/go-ipld-format"
 	blockstore "gx/ipfs/QmS2aqUZLJp8kF1ihE5rvDGE5LvmKDPnx32w9Z1BW9xLV5/go-ipfs-blockstore"
 	offline "gx/ipfs/QmYZwey1thDTynSrvd6qQkX24UpTka6TFhQ2v569UpoqxD/go-ipfs-exchange-offline"
 	ds "gx/ipfs/Qmf4xQhNomPNhrtZc67qSnfJSjxjXs9LWvknJtSXwimPrM/go-datastore"
@@ -406,3 +407,226 @@ func TestPinUpdate(t *testing.T) {
 	assertPinned(t, p, c2, "c2 should be pinned still")
 	assertPinned(t, p, c1, "c1 should be pinned now")
 }
+
+func TestPinInfo(t *testing.T) {
+	ctx := context.Background()
+
+	dstore := dssync.MutexWrap(ds.NewMapDatastore())
+	bstore := blockstore.NewBlockstore(dstore)
+	bserv := bs.New(bstore, offline.Exchange(bstore))
+
+	dserv := mdag.NewDAGService(bserv)
+	p := NewPinner(dstore, dserv, dserv)
+	n1, c1 := randNode()
+	n2, c2 := randNode()
+
+	dserv.Add(ctx, n1)
+	dserv.Add(ctx, n2)
+
+	if err := p.SetInfo(c1, Info{Name: "release"}); err != ErrNotPinned {
+		t.Fatalf("expected ErrNotPinned, got %v", err)
+	}
+
+	if err := p.Pin(ctx, n1, true); err != nil {
+		t.Fatal(err)
+	}
+
+	info := Info{Name: "release-1.0", Meta: map[string]string{"team": "web"}}
+	if err := p.SetInfo(c1, info); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	np, err := LoadPinner(dstore, dserv, dserv)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	loaded, ok := np.Info(c1)
+	if !ok || loaded.Name != "release-1.0" || loaded.Meta["team"] != "web" {
+		t.Fatalf("pin info not persisted: %+v", loaded)
+	}
+	if !loaded.Matches("release-", map[string]string{"team": "web"}) {
+		t.Error("expected info to match")
+	}
+	if loaded.Matches("release-", map[string]string{"team": "db"}) {
+		t.Error("expected info not to match other metadata")
+	}
+
+	if err := np.Update(ctx, c1, c2, true); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := np.Info(c1); ok {
+		t.Error("info of the old pin should be gone")
+	}
+	if moved, ok := np.Info(c2); !ok || moved.Name != "release-1.0" {
+		t.Errorf("info should follow the updated pin: %+v", moved)
+	}
+
+	if err := np.Unpin(ctx, c2, true); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := np.Info(c2); ok {
+		t.Error("info should be removed with the pin")
+	}
+}
+
+func TestMigrateInfos(t *testing.T) {
+	ctx := context.Background()
+
+	dstore := dssync.MutexWrap(ds.NewMapDatastore())
+	bstore := blockstore.NewBlockstore(dstore)
+	bserv := bs.New(bstore, offline.Exchange(bstore))
+
+	dserv := mdag.NewDAGService(bserv)
+	n1, c1 := randNode()
+	if err := dserv.Add(ctx, n1); err != nil {
+		t.Fatal(err)
+	}
+
+	// write a pin root without an info set, like older versions did
+	noop := func(cid.Cid) {}
+	root := &mdag.ProtoNode{}
+	direct, err := storeSet(ctx, dserv, nil, noop)
+	if err != nil {
+		t.Fatal(err)
+	}
+	recursive, err := storeSet(ctx, dserv, []cid.Cid{c1}, noop)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := root.AddNodeLink(linkDirect, direct); err != nil {
+		t.Fatal(err)
+	}
+	if err := root.AddNodeLink(linkRecursive, recursive); err != nil {
+		t.Fatal(err)
+	}
+	if err := dserv.AddMany(ctx, []ipld.Node{new(mdag.ProtoNode), root}); err != nil {
+		t.Fatal(err)
+	}
+	if err := dstore.Put(pinDatastoreKey, root.Cid().Bytes()); err != nil {
+		t.Fatal(err)
+	}
+
+	storedRoot := func() *mdag.ProtoNode {
+		rootKey, err := dstore.Get(pinDatastoreKey)
+		if err != nil {
+			t.Fatal(err)
+		}
+		rootCid, err := cid.Cast(rootKey)
+		if err != nil {
+			t.Fatal(err)
+		}
+		nd, err := dserv.Get(ctx, rootCid)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return nd.(*mdag.ProtoNode)
+	}
+
+	// loading the pins doesn't rewrite them, only the migration does
+	p, err := LoadPinner(dstore, dserv, dserv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertPinned(t, p, c1, "pin lost while loading")
+	if hasInfoSet(storedRoot()) {
+		t.Fatal("the pin state was rewritten when loaded")
+	}
+
+	if err := MigrateInfos(dstore, dserv, dserv); err != nil {
+		t.Fatal(err)
+	}
+	if !hasInfoSet(storedRoot()) {
+		t.Error("expected the pin state to be migrated")
+	}
+
+	p, err = LoadPinner(dstore, dserv, dserv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertPinned(t, p, c1, "pin lost during the migration")
+
+	// nothing to migrate without a pin state
+	empty := dssync.MutexWrap(ds.NewMapDatastore())
+	if err := MigrateInfos(empty, dserv, dserv); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// addRecorder records the nodes added to the DAG service
+type addRecorder struct {
+	ipld.DAGService
+	added *cid.Set
+}
+
+func (r addRecorder) Add(ctx context.Context, nd ipld.Node) error {
+	r.added.Add(nd.Cid())
+	return r.DAGService.Add(ctx, nd)
+}
+
+func (r addRecorder) AddMany(ctx context.Context, nds []ipld.Node) error {
+	for _, nd := range nds {
+		r.added.Add(nd.Cid())
+	}
+	return r.DAGService.AddMany(ctx, nds)
+}
+
+func TestFlushStoresChangedInfos(t *testing.T) {
+	ctx := context.Background()
+
+	dstore := dssync.MutexWrap(ds.NewMapDatastore())
+	bstore := blockstore.NewBlockstore(dstore)
+	bserv := bs.New(bstore, offline.Exchange(bstore))
+
+	dserv := mdag.NewDAGService(bserv)
+	internal := addRecorder{DAGService: dserv, added: cid.NewSet()}
+	p := NewPinner(dstore, dserv, internal)
+
+	n1, c1 := randNode()
+	n2, c2 := randNode()
+	for _, n := range []ipld.Node{n1, n2} {
+		if err := p.Pin(ctx, n, true); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := p.SetInfo(c1, Info{Name: "one"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	infoNode, ok := p.(*pinner).infoNodes[c1]
+	if !ok {
+		t.Fatal("expected the info node to be recorded")
+	}
+
+	internal.added = cid.NewSet()
+	p.(*pinner).internal = internal
+	if err := p.SetInfo(c2, Info{Name: "two"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	if internal.added.Has(infoNode) {
+		t.Error("the unchanged info node was stored again")
+	}
+	if !internal.added.Has(p.(*pinner).infoNodes[c2]) {
+		t.Error("the new info node wasn't stored")
+	}
+
+	np, err := LoadPinner(dstore, dserv, dserv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for c, name := range map[cid.Cid]string{c1: "one", c2: "two"} {
+		if info, ok := np.Info(c); !ok || info.Name != name {
+			t.Errorf("expected the info %q, got %+v", name, info)
+		}
+	}
+}
@@ -0,0 +1,294 @@
+// Package remote implements a client for remote pinning services speaking
+// the IPFS Pinning Service API, and tracks the pin requests made to them.
+package remote
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	cid "gx/ipfs/QmR8BauakNcBa3RbE4nbQu76PDiJgoQgz8AJdhJuiU4TAw/go-cid"
+	logging "gx/ipfs/QmcuXC5cxs79ro2cUuHs4HQ2bkDLJUYokwL8aivcX6HW3C/go-log"
+)
+
+var log = logging.Logger("pin/remote")
+
+// Status is the status of a pin request on a remote service
+type Status string
+
+const (
+	// Queued requests wait for the service to start pinning
+	Queued Status = "queued"
+	// Pinning requests are being pinned by the service
+	Pinning Status = "pinning"
+	// Pinned requests were pinned by the service
+	Pinned Status = "pinned"
+	// Failed requests couldn't be pinned by the service
+	Failed Status = "failed"
+)
+
+// ParseStatus parses a status name
+func ParseStatus(s string) (Status, error) {
+	switch st := Status(s); st {
+	case Queued, Pinning, Pinned, Failed:
+		return st, nil
+	default:
+		return "", fmt.Errorf("invalid pin status '%s', must be one of {queued, pinning, pinned, failed}", s)
+	}
+}
+
+// Final returns whether the status won't change anymore
+func (s Status) Final() bool {
+	return s == Pinned || s == Failed
+}
+
+// Pin is what is asked to be pinned
+type Pin struct {
+	Cid     cid.Cid
+	Name    string
+	Origins []string
+	Meta    map[string]string
+}
+
+// PinStatus is the state of a pin request on a remote service
+type PinStatus struct {
+	RequestID string
+	Status    Status
+	Created   time.Time
+	Pin       Pin
+
+	// Delegates are the multiaddrs of the service's nodes which will
+	// fetch the data
+	Delegates []string
+	Info      map[string]string
+}
+
+// ErrNotFound is returned when the service doesn't know the pin request
+var ErrNotFound = errors.New("pin request not found")
+
+// ServiceError is an error returned by the service
+type ServiceError struct {
+	Code    int
+	Reason  string
+	Details string
+}
+
+func (e *ServiceError) Error() string {
+	if e.Details != "" {
+		return fmt.Sprintf("remote pinning service error %d: %s: %s", e.Code, e.Reason, e.Details)
+	}
+	return fmt.Sprintf("remote pinning service error %d: %s", e.Code, e.Reason)
+}
+
+// the wire format of the API
+type pinJSON struct {
+	Cid     string            `json:"cid"`
+	Name    string            `json:"name,omitempty"`
+	Origins []string          `json:"origins,omitempty"`
+	Meta    map[string]string `json:"meta,omitempty"`
+}
+
+type pinStatusJSON struct {
+	RequestID string            `json:"requestid"`
+	Status    Status            `json:"status"`
+	Created   time.Time         `json:"created"`
+	Pin       pinJSON           `json:"pin"`
+	Delegates []string          `json:"delegates"`
+	Info      map[string]string `json:"info,omitempty"`
+}
+
+type pinResultsJSON struct {
+	Count   int             `json:"count"`
+	Results []pinStatusJSON `json:"results"`
+}
+
+type errorJSON struct {
+	Error struct {
+		Reason  string `json:"reason"`
+		Details string `json:"details"`
+	} `json:"error"`
+}
+
+func (ps *pinStatusJSON) decode() (*PinStatus, error) {
+	c, err := cid.Decode(ps.Pin.Cid)
+	if err != nil {
+		return nil, fmt.Errorf("service returned an invalid cid: %s", err)
+	}
+	if _, err := ParseStatus(string(ps.Status)); err != nil {
+		return nil, err
+	}
+
+	return &PinStatus{
+		RequestID: ps.RequestID,
+		Status:    ps.Status,
+		Created:   ps.Created,
+		Pin: Pin{
+			Cid:     c,
+			Name:    ps.Pin.Name,
+			Origins: ps.Pin.Origins,
+			Meta:    ps.Pin.Meta,
+		},
+		Delegates: ps.Delegates,
+		Info:      ps.Info,
+	}, nil
+}
+
+// Client talks to a remote pinning service
+type Client struct {
+	endpoint string
+	key      string
+	http     *http.Client
+}
+
+// NewClient creates a client for the service at the given endpoint,
+// authenticating with the given access key
+func NewClient(endpoint, key string) *Client {
+	return &Client{
+		endpoint: strings.TrimRight(endpoint, "/"),
+		key:      key,
+		http:     &http.Client{Timeout: time.Minute},
+	}
+}
+
+// Add asks the service to pin
+func (c *Client) Add(ctx context.Context, pin Pin) (*PinStatus, error) {
+	body, err := json.Marshal(&pinJSON{
+		Cid:     pin.Cid.String(),
+		Name:    pin.Name,
+		Origins: pin.Origins,
+		Meta:    pin.Meta,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var ps pinStatusJSON
+	if err := c.do(ctx, "POST", "/pins", nil, bytes.NewReader(body), &ps); err != nil {
+		return nil, err
+	}
+	return ps.decode()
+}
+
+// Get returns the current status of a pin request
+func (c *Client) Get(ctx context.Context, requestID string) (*PinStatus, error) {
+	var ps pinStatusJSON
+	if err := c.do(ctx, "GET", "/pins/"+url.PathEscape(requestID), nil, nil, &ps); err != nil {
+		return nil, err
+	}
+	return ps.decode()
+}
+
+// LsFilter selects the pin requests listed by Ls. Requests with any of the
+// statuses are listed, all of them when Status is empty.
+type LsFilter struct {
+	Cids   []cid.Cid
+	Name   string
+	Status []Status
+	Limit  int
+}
+
+// Ls lists the pin requests known to the service
+func (c *Client) Ls(ctx context.Context, filter LsFilter) ([]*PinStatus, error) {
+	q := url.Values{}
+	if len(filter.Cids) > 0 {
+		cids := make([]string, len(filter.Cids))
+		for i, c := range filter.Cids {
+			cids[i] = c.String()
+		}
+		q.Set("cid", strings.Join(cids, ","))
+	}
+	if filter.Name != "" {
+		q.Set("name", filter.Name)
+	}
+
+	status := filter.Status
+	if len(status) == 0 {
+		status = []Status{Queued, Pinning, Pinned, Failed}
+	}
+	sts := make([]string, len(status))
+	for i, s := range status {
+		sts[i] = string(s)
+	}
+	q.Set("status", strings.Join(sts, ","))
+
+	if filter.Limit > 0 {
+		q.Set("limit", strconv.Itoa(filter.Limit))
+	}
+
+	var res pinResultsJSON
+	if err := c.do(ctx, "GET", "/pins", q, nil, &res); err != nil {
+		return nil, err
+	}
+
+	out := make([]*PinStatus, 0, len(res.Results))
+	for i := range res.Results {
+		ps, err := res.Results[i].decode()
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, ps)
+	}
+	return out, nil
+}
+
+// Rm asks the service to remove a pin request
+func (c *Client) Rm(ctx context.Context, requestID string) error {
+	return c.do(ctx, "DELETE", "/pins/"+url.PathEscape(requestID), nil, nil, nil)
+}
+
+func (c *Client) do(ctx context.Context, method, path string, query url.Values, body io.Reader, out interface{}) error {
+	u := c.endpoint + path
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+
+	req, err := http.NewRequest(method, u, body)
+	if err != nil {
+		return err
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Authorization", "Bearer "+c.key)
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return ErrNotFound
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		data, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 1<<16))
+		var e errorJSON
+		if err := json.Unmarshal(data, &e); err != nil || e.Error.Reason == "" {
+			e.Error.Reason = http.StatusText(resp.StatusCode)
+		}
+		return &ServiceError{
+			Code:    resp.StatusCode,
+			Reason:  e.Error.Reason,
+			Details: e.Error.Details,
+		}
+	}
+
+	if out == nil {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("invalid response from the remote pinning service: %s", err)
+	}
+	return nil
+}
@@ -0,0 +1,80 @@
+package remote
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+
+	repo "github.com/ipfs/go-ipfs/repo"
+)
+
+// ConfigKey is the config section holding the remote pinning services, by
+// name
+const ConfigKey = "Pinning.RemoteServices"
+
+// Service is the config of a remote pinning service
+type Service struct {
+	API ServiceAPI
+}
+
+// ServiceAPI is where and how to reach a remote pinning service
+type ServiceAPI struct {
+	// Endpoint is the base URL of the Pinning Service API
+	Endpoint string
+
+	// Key is the access token sent to the service
+	Key string
+}
+
+var serviceNameRe = regexp.MustCompile(`^[a-zA-Z0-9_-]+$`)
+
+// CheckServiceName returns an error if the name can't be used for a service
+func CheckServiceName(name string) error {
+	if !serviceNameRe.MatchString(name) {
+		return fmt.Errorf("invalid service name '%s', only letters, digits, '-' and '_' are allowed", name)
+	}
+	return nil
+}
+
+// CheckEndpoint returns an error if the endpoint isn't an http(s) URL
+func CheckEndpoint(endpoint string) error {
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return fmt.Errorf("invalid service endpoint: %s", err)
+	}
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("invalid service endpoint '%s', must be an http or https URL", endpoint)
+	}
+	return nil
+}
+
+// Services returns the remote pinning services configured in the repo
+func Services(r repo.Repo) (map[string]Service, error) {
+	services := make(map[string]Service)
+	if _, err := repo.ReadConfigSection(r, ConfigKey, &services); err != nil {
+		return nil, fmt.Errorf("invalid %s config: %s", ConfigKey, err)
+	}
+	return services, nil
+}
+
+// SetServices replaces the remote pinning services configured in the repo
+func SetServices(r repo.Repo, services map[string]Service) error {
+	return repo.WriteConfigSection(r, ConfigKey, services)
+}
+
+// RepoClients returns a function creating clients for the services
+// configured in the repo. The config is read on every call, so that services
+// added while the node runs can be used.
+func RepoClients(r repo.Repo) ClientFunc {
+	return func(service string) (*Client, error) {
+		services, err := Services(r)
+		if err != nil {
+			return nil, err
+		}
+		s, ok := services[service]
+		if !ok {
+			return nil, fmt.Errorf("remote pinning service '%s' not found", service)
+		}
+		return NewClient(s.API.Endpoint, s.API.Key), nil
+	}
+}
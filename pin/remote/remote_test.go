@@ -0,0 +1,216 @@
+package remote
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	cid "gx/ipfs/QmR8BauakNcBa3RbE4nbQu76PDiJgoQgz8AJdhJuiU4TAw/go-cid"
+	ds "gx/ipfs/Qmf4xQhNomPNhrtZc67qSnfJSjxjXs9LWvknJtSXwimPrM/go-datastore"
+	dssync "gx/ipfs/Qmf4xQhNomPNhrtZc67qSnfJSjxjXs9LWvknJtSXwimPrM/go-datastore/sync"
+)
+
+const testKey = "secret"
+
+// fakeService is a stand-in pinning service. Each pin request is queued,
+// then pinned the next time its status is fetched.
+type fakeService struct {
+	lk   sync.Mutex
+	next int
+	pins map[string]*pinStatusJSON
+}
+
+func newFakeService() (*fakeService, *httptest.Server) {
+	s := &fakeService{pins: make(map[string]*pinStatusJSON)}
+	return s, httptest.NewServer(s)
+}
+
+func (s *fakeService) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get("Authorization") != "Bearer "+testKey {
+		w.WriteHeader(http.StatusUnauthorized)
+		fmt.Fprint(w, `{"error":{"reason":"UNAUTHORIZED"}}`)
+		return
+	}
+
+	s.lk.Lock()
+	defer s.lk.Unlock()
+
+	id := strings.TrimPrefix(r.URL.Path, "/pins/")
+	switch {
+	case r.Method == "POST" && r.URL.Path == "/pins":
+		var p pinJSON
+		if err := json.NewDecoder(r.Body).Decode(&p); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		s.next++
+		ps := &pinStatusJSON{
+			RequestID: fmt.Sprintf("req-%d", s.next),
+			Status:    Queued,
+			Created:   time.Now(),
+			Pin:       p,
+		}
+		s.pins[ps.RequestID] = ps
+		w.WriteHeader(http.StatusAccepted)
+		json.NewEncoder(w).Encode(ps)
+	case r.Method == "GET" && r.URL.Path == "/pins":
+		res := pinResultsJSON{Results: []pinStatusJSON{}}
+		for _, ps := range s.pins {
+			if strings.Contains(r.URL.Query().Get("status"), string(ps.Status)) {
+				res.Results = append(res.Results, *ps)
+			}
+		}
+		res.Count = len(res.Results)
+		json.NewEncoder(w).Encode(&res)
+	case r.Method == "GET":
+		ps, ok := s.pins[id]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		out := *ps
+		ps.Status = Pinned
+		json.NewEncoder(w).Encode(&out)
+	case r.Method == "DELETE":
+		if _, ok := s.pins[id]; !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		delete(s.pins, id)
+		w.WriteHeader(http.StatusAccepted)
+	default:
+		w.WriteHeader(http.StatusBadRequest)
+	}
+}
+
+func testCid(t *testing.T) cid.Cid {
+	c, err := cid.Decode("QmUNLLsPACCz1vLxQVkXqqLX5R1X345qqfHbsf67hvA3Nn")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+func TestClient(t *testing.T) {
+	ctx := context.Background()
+	_, ts := newFakeService()
+	defer ts.Close()
+
+	bad := NewClient(ts.URL, "wrong")
+	_, err := bad.Add(ctx, Pin{Cid: testCid(t)})
+	if serr, ok := err.(*ServiceError); !ok || serr.Code != http.StatusUnauthorized || serr.Reason != "UNAUTHORIZED" {
+		t.Fatalf("expected an unauthorized error, got %v", err)
+	}
+
+	c := NewClient(ts.URL+"/", testKey)
+	ps, err := c.Add(ctx, Pin{Cid: testCid(t), Name: "foo"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ps.Status != Queued || !ps.Pin.Cid.Equals(testCid(t)) || ps.Pin.Name != "foo" {
+		t.Fatalf("unexpected pin status: %+v", ps)
+	}
+
+	ls, err := c.Ls(ctx, LsFilter{Status: []Status{Queued}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ls) != 1 || ls[0].RequestID != ps.RequestID {
+		t.Fatalf("expected the queued request to be listed, got %v", ls)
+	}
+
+	// the fake service pins on the first fetch
+	if _, err := c.Get(ctx, ps.RequestID); err != nil {
+		t.Fatal(err)
+	}
+	got, err := c.Get(ctx, ps.RequestID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Status != Pinned {
+		t.Fatalf("expected pinned, got %s", got.Status)
+	}
+
+	if err := c.Rm(ctx, ps.RequestID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.Get(ctx, ps.RequestID); err != ErrNotFound {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+}
+
+func TestTracker(t *testing.T) {
+	ctx := context.Background()
+	_, ts := newFakeService()
+	defer ts.Close()
+
+	tr := NewTracker(dssync.MutexWrap(ds.NewMapDatastore()), func(service string) (*Client, error) {
+		if service != "fake" {
+			return nil, fmt.Errorf("unknown service %s", service)
+		}
+		return NewClient(ts.URL, testKey), nil
+	})
+
+	if _, err := tr.Add(ctx, "other", Pin{Cid: testCid(t)}); err == nil {
+		t.Fatal("expected adding to an unknown service to fail")
+	}
+
+	r1, err := tr.Add(ctx, "fake", Pin{Cid: testCid(t), Name: "one"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	r2, err := tr.Add(ctx, "fake", Pin{Cid: testCid(t), Name: "two"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	reqs, err := tr.Requests("fake")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(reqs) != 2 || reqs[0].Status != Queued {
+		t.Fatalf("expected two queued requests, got %v", reqs)
+	}
+
+	// the first poll sees the requests queued, the second pinned
+	for i := 0; i < 2; i++ {
+		if err := tr.Poll(ctx); err != nil {
+			t.Fatal(err)
+		}
+	}
+	r, err := tr.Get("fake", r1.RequestID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Status != Pinned || r.Name != "one" {
+		t.Fatalf("expected the request to be pinned, got %+v", r)
+	}
+
+	r, err = tr.Wait(ctx, "fake", r2.RequestID, time.Millisecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Status != Pinned {
+		t.Fatalf("expected the request to be pinned, got %s", r.Status)
+	}
+
+	if err := tr.Rm(ctx, "fake", r1.RequestID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tr.Get("fake", r1.RequestID); err != ErrNotFound {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+	reqs, err = tr.Requests("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(reqs) != 1 || reqs[0].RequestID != r2.RequestID {
+		t.Fatalf("expected only the second request to be left, got %v", reqs)
+	}
+}
@@ -0,0 +1,276 @@
+package remote
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"sort"
+	"sync"
+	"time"
+
+	cid "gx/ipfs/QmR8BauakNcBa3RbE4nbQu76PDiJgoQgz8AJdhJuiU4TAw/go-cid"
+	ds "gx/ipfs/Qmf4xQhNomPNhrtZc67qSnfJSjxjXs9LWvknJtSXwimPrM/go-datastore"
+	dsq "gx/ipfs/Qmf4xQhNomPNhrtZc67qSnfJSjxjXs9LWvknJtSXwimPrM/go-datastore/query"
+)
+
+// requestsKey is where the pin requests are tracked, under
+// /<service>/<request id>
+var requestsKey = ds.NewKey("/local/remotepins")
+
+// DefaultPollInterval is how often pending pin requests are polled by Run
+const DefaultPollInterval = time.Minute
+
+// Request is a pin request made to a remote service, as tracked locally
+type Request struct {
+	Service   string
+	RequestID string
+	Cid       cid.Cid
+	Name      string `json:",omitempty"`
+	Status    Status
+	Created   time.Time
+	Updated   time.Time
+
+	// Error is set when the last poll of the request failed
+	Error string `json:",omitempty"`
+}
+
+// ClientFunc returns the client of a service
+type ClientFunc func(service string) (*Client, error)
+
+// Tracker makes pin requests to remote services and keeps track of them in
+// a datastore, polling the pending ones for status changes
+type Tracker struct {
+	ds     ds.Datastore
+	client ClientFunc
+
+	// lk serializes the updates of the tracked requests
+	lk sync.Mutex
+}
+
+// NewTracker creates a tracker storing the requests in the datastore
+func NewTracker(d ds.Datastore, client ClientFunc) *Tracker {
+	return &Tracker{
+		ds:     d,
+		client: client,
+	}
+}
+
+func requestKey(service, requestID string) ds.Key {
+	return requestsKey.ChildString(service).ChildString(url.PathEscape(requestID))
+}
+
+// Add asks the service to pin and starts tracking the request
+func (t *Tracker) Add(ctx context.Context, service string, pin Pin) (*Request, error) {
+	c, err := t.client(service)
+	if err != nil {
+		return nil, err
+	}
+
+	ps, err := c.Add(ctx, pin)
+	if err != nil {
+		return nil, err
+	}
+
+	r := &Request{
+		Service:   service,
+		RequestID: ps.RequestID,
+		Cid:       ps.Pin.Cid,
+		Name:      ps.Pin.Name,
+		Status:    ps.Status,
+		Created:   ps.Created,
+		Updated:   time.Now(),
+	}
+
+	t.lk.Lock()
+	defer t.lk.Unlock()
+	if err := t.put(r); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Get returns a tracked request
+func (t *Tracker) Get(service, requestID string) (*Request, error) {
+	data, err := t.ds.Get(requestKey(service, requestID))
+	switch err {
+	case nil:
+	case ds.ErrNotFound:
+		return nil, ErrNotFound
+	default:
+		return nil, err
+	}
+
+	r := new(Request)
+	if err := json.Unmarshal(data, r); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Requests returns the tracked requests of a service, or of all services if
+// service is empty, oldest first
+func (t *Tracker) Requests(service string) ([]*Request, error) {
+	prefix := requestsKey
+	if service != "" {
+		prefix = prefix.ChildString(service)
+	}
+
+	res, err := t.ds.Query(dsq.Query{Prefix: prefix.String() + "/"})
+	if err != nil {
+		return nil, err
+	}
+	entries, err := res.Rest()
+	if err != nil {
+		return nil, err
+	}
+
+	out := make([]*Request, 0, len(entries))
+	for _, e := range entries {
+		r := new(Request)
+		if err := json.Unmarshal(e.Value, r); err != nil {
+			log.Warningf("ignoring invalid remote pin request %s: %s", e.Key, err)
+			continue
+		}
+		if service != "" && r.Service != service {
+			continue
+		}
+		out = append(out, r)
+	}
+
+	sort.Slice(out, func(i, j int) bool {
+		return out[i].Created.Before(out[j].Created)
+	})
+	return out, nil
+}
+
+// Rm asks the service to remove the pin request and stops tracking it
+func (t *Tracker) Rm(ctx context.Context, service, requestID string) error {
+	c, err := t.client(service)
+	if err != nil {
+		return err
+	}
+
+	if err := c.Rm(ctx, requestID); err != nil && err != ErrNotFound {
+		return err
+	}
+
+	t.lk.Lock()
+	defer t.lk.Unlock()
+	return t.ds.Delete(requestKey(service, requestID))
+}
+
+// Refresh fetches the current status of a tracked request from its service
+func (t *Tracker) Refresh(ctx context.Context, service, requestID string) (*Request, error) {
+	r, err := t.Get(service, requestID)
+	if err != nil {
+		return nil, err
+	}
+
+	ps, err := t.fetch(ctx, r)
+
+	t.lk.Lock()
+	defer t.lk.Unlock()
+
+	// the request may have been removed while we were fetching it
+	if has, herr := t.ds.Has(requestKey(service, requestID)); herr != nil || !has {
+		return nil, ErrNotFound
+	}
+
+	r.Updated = time.Now()
+	if err != nil {
+		r.Error = err.Error()
+	} else {
+		r.Status = ps.Status
+		r.Error = ""
+	}
+	if perr := t.put(r); perr != nil {
+		return nil, perr
+	}
+	return r, err
+}
+
+func (t *Tracker) fetch(ctx context.Context, r *Request) (*PinStatus, error) {
+	c, err := t.client(r.Service)
+	if err != nil {
+		return nil, err
+	}
+	return c.Get(ctx, r.RequestID)
+}
+
+// Wait polls a request until its status is final
+func (t *Tracker) Wait(ctx context.Context, service, requestID string, interval time.Duration) (*Request, error) {
+	for {
+		r, err := t.Refresh(ctx, service, requestID)
+		if err != nil {
+			return r, err
+		}
+		if r.Status.Final() {
+			return r, nil
+		}
+
+		select {
+		case <-time.After(interval):
+		case <-ctx.Done():
+			return r, ctx.Err()
+		}
+	}
+}
+
+// Poll refreshes the status of all pending requests
+func (t *Tracker) Poll(ctx context.Context) error {
+	reqs, err := t.Requests("")
+	if err != nil {
+		return err
+	}
+
+	failed := 0
+	pending := 0
+	for _, r := range reqs {
+		if r.Status.Final() {
+			continue
+		}
+		pending++
+
+		if _, err := t.Refresh(ctx, r.Service, r.RequestID); err != nil {
+			if err == ErrNotFound {
+				continue
+			}
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			log.Debugf("polling remote pin request %s on %s: %s", r.RequestID, r.Service, err)
+			failed++
+		}
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("failed to poll %d of %d pending remote pin requests", failed, pending)
+	}
+	return nil
+}
+
+// Run polls the pending requests every interval, until the context is done
+func (t *Tracker) Run(ctx context.Context, interval time.Duration) {
+	tick := time.NewTicker(interval)
+	defer tick.Stop()
+
+	for {
+		select {
+		case <-tick.C:
+			if err := t.Poll(ctx); err != nil {
+				log.Info(err)
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func (t *Tracker) put(r *Request) error {
+	data, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+	return t.ds.Put(requestKey(r.Service, r.RequestID), data)
+}
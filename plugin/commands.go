@@ -0,0 +1,18 @@
+package plugin
+
+import (
+	cmds "gx/ipfs/QmR77mMvvh8mJBBWQmBfQBu8oD38NUN4KE9SL2gDgAQNc6/go-ipfs-cmds"
+)
+
+// PluginCommands is an interface for plugins adding subcommands to ipfs. The
+// commands are served over the HTTP API like the built-in ones.
+type PluginCommands interface {
+	Plugin
+
+	// Commands returns the commands to add to the root command, by name
+	Commands() map[string]*cmds.Command
+
+	// ReadOnlyCommands returns the names of the commands also exposed by the
+	// read-only API of the gateway
+	ReadOnlyCommands() []string
+}
@@ -0,0 +1,45 @@
+package plugin
+
+import (
+	"net"
+	"net/http"
+
+	core "github.com/ipfs/go-ipfs/core"
+)
+
+// HTTPServer is one of the HTTP servers of the daemon
+type HTTPServer int
+
+const (
+	// HTTPGateway is the gateway server, listening on Addresses.Gateway
+	HTTPGateway HTTPServer = iota
+	// HTTPAPI is the API server, listening on Addresses.API
+	HTTPAPI
+)
+
+func (s HTTPServer) String() string {
+	switch s {
+	case HTTPGateway:
+		return "gateway"
+	case HTTPAPI:
+		return "api"
+	default:
+		return "unknown"
+	}
+}
+
+// ServeOption registers HTTP handlers on the mux of a server of the daemon.
+// It's a corehttp.ServeOption, which can't be referred to from here as
+// corehttp depends on the plugin loader.
+type ServeOption func(*core.IpfsNode, net.Listener, *http.ServeMux) (*http.ServeMux, error)
+
+// PluginHTTP is an interface for plugins adding HTTP handlers to the servers
+// of the daemon. The options are applied before the built-in ones, so that
+// they may mediate the requests to the built-in handlers by returning a new
+// mux.
+type PluginHTTP interface {
+	Plugin
+
+	// ServeOptions returns the options to apply to the given server
+	ServeOptions(server HTTPServer) []ServeOption
+}
@@ -3,8 +3,11 @@ package loader
 import (
 	"fmt"
 	"os"
+	"path/filepath"
 	"strings"
+	"sync"
 
+	core "github.com/ipfs/go-ipfs/core"
 	coreiface "github.com/ipfs/go-ipfs/core/coreapi/interface"
 	coredag "github.com/ipfs/go-ipfs/core/coredag"
 	plugin "github.com/ipfs/go-ipfs/plugin"
@@ -23,18 +26,33 @@ var loadPluginsFunc = func(string) ([]plugin.Plugin, error) {
 
 // PluginLoader keeps track of loaded plugins
 type PluginLoader struct {
-	plugins []plugin.Plugin
+	repoPath string
+
+	lk      sync.Mutex
+	plugins []*pluginEntry // sorted, see sortPlugins
+}
+
+// Preloaded returns the plugins built into the ipfs binary
+func Preloaded() []plugin.Plugin {
+	return append([]plugin.Plugin(nil), preloadPlugins...)
 }
 
-// NewPluginLoader creates new plugin loader
-func NewPluginLoader(pluginDir string) (*PluginLoader, error) {
-	plMap := make(map[string]plugin.Plugin)
+// NewPluginLoader creates new plugin loader, loading the dynamic plugins and
+// the config of the plugins from the repo at the given path. An empty path
+// loads the preloaded plugins only.
+func NewPluginLoader(repoPath string) (*PluginLoader, error) {
+	configs, err := readConfig(repoPath)
+	if err != nil {
+		return nil, err
+	}
+
+	plMap := make(map[string]*pluginEntry)
 	for _, v := range preloadPlugins {
-		plMap[v.Name()] = v
+		plMap[v.Name()] = &pluginEntry{Plugin: v, source: SourcePreloaded}
 	}
 
-	if pluginDir != "" {
-		newPls, err := loadDynamicPlugins(pluginDir)
+	if repoPath != "" {
+		newPls, err := loadDynamicPlugins(filepath.Join(repoPath, "plugins"))
 		if err != nil {
 			return nil, err
 		}
@@ -47,17 +65,22 @@ func NewPluginLoader(pluginDir string) (*PluginLoader, error) {
 						"while trying to load dynamically: %s",
 					ppl.Name(), ppl.Version(), pl.Version())
 			}
-			plMap[pl.Name()] = pl
+			plMap[pl.Name()] = &pluginEntry{Plugin: pl, source: SourceDynamic}
 		}
 	}
 
-	loader := &PluginLoader{plugins: make([]plugin.Plugin, 0, len(plMap))}
-
-	for _, v := range plMap {
-		loader.plugins = append(loader.plugins, v)
+	for name, e := range plMap {
+		e.config = configs[name]
+		if e.config.Disabled {
+			e.state = StateDisabled
+		}
 	}
 
-	return loader, nil
+	plugins, err := sortPlugins(plMap)
+	if err != nil {
+		return nil, err
+	}
+	return &PluginLoader{repoPath: repoPath, plugins: plugins}, nil
 }
 
 func loadDynamicPlugins(pluginDir string) ([]plugin.Plugin, error) {
@@ -72,66 +95,154 @@ func loadDynamicPlugins(pluginDir string) ([]plugin.Plugin, error) {
 	return loadPluginsFunc(pluginDir)
 }
 
-// Initialize initializes all loaded plugins
+// Plugins describes the loaded plugins, in the order they're initialized in
+func (loader *PluginLoader) Plugins() []Info {
+	loader.lk.Lock()
+	defer loader.lk.Unlock()
+
+	infos := make([]Info, 0, len(loader.plugins))
+	for _, e := range loader.plugins {
+		infos = append(infos, Info{
+			Name:    e.Name(),
+			Version: e.Version(),
+			Types:   e.types(),
+			State:   e.state,
+			Source:  e.source,
+		})
+	}
+	return infos
+}
+
+// inState returns the plugins in the given state, in order
+func (loader *PluginLoader) inState(state State) []*pluginEntry {
+	loader.lk.Lock()
+	defer loader.lk.Unlock()
+
+	var plugins []*pluginEntry
+	for _, e := range loader.plugins {
+		if e.state == state {
+			plugins = append(plugins, e)
+		}
+	}
+	return plugins
+}
+
+func (loader *PluginLoader) setState(e *pluginEntry, state State) {
+	loader.lk.Lock()
+	defer loader.lk.Unlock()
+	e.state = state
+}
+
+// Initialize initializes all loaded plugins, but the disabled ones
 func (loader *PluginLoader) Initialize() error {
-	for _, p := range loader.plugins {
-		err := p.Init()
+	for _, e := range loader.inState(StateLoaded) {
+		err := e.Init(&plugin.Environment{
+			Repo:   loader.repoPath,
+			Config: e.config.Config,
+		})
 		if err != nil {
-			return err
+			return fmt.Errorf("error initializing plugin %s: %s", e.Name(), err)
 		}
+		loader.setState(e, StateInitialized)
 	}
 
 	return nil
 }
 
-// Inject hooks all the plugins into the appropriate subsystems.
+// Running tells if the plugin of the given name is injected, or started
+func (loader *PluginLoader) Running(name string) bool {
+	loader.lk.Lock()
+	defer loader.lk.Unlock()
+
+	for _, e := range loader.plugins {
+		if e.Name() == name {
+			return e.state == StateInjected || e.state == StateStarted
+		}
+	}
+	return false
+}
+
+// Inject hooks all the initialized plugins into the appropriate subsystems.
 func (loader *PluginLoader) Inject() error {
-	for _, pl := range loader.plugins {
-		if pl, ok := pl.(plugin.PluginIPLD); ok {
-			err := injectIPLDPlugin(pl)
-			if err != nil {
-				return err
-			}
+	for _, e := range loader.inState(StateInitialized) {
+		if err := loader.inject(e); err != nil {
+			return err
 		}
-		if pl, ok := pl.(plugin.PluginTracer); ok {
-			err := injectTracerPlugin(pl)
-			if err != nil {
-				return err
-			}
+		loader.setState(e, StateInjected)
+	}
+	return nil
+}
+
+func (loader *PluginLoader) inject(e *pluginEntry) error {
+	if _, ok := e.Plugin.(plugin.PluginCommands); ok && e.source != SourcePreloaded {
+		// the commands are added to the roots when core/commands is
+		// initialized, before the dynamic plugins are loaded
+		return fmt.Errorf("plugin %s: only preloaded plugins can add commands", e.Name())
+	}
+
+	pl := e.Plugin
+	if pl, ok := pl.(plugin.PluginIPLD); ok {
+		err := injectIPLDPlugin(pl)
+		if err != nil {
+			return err
 		}
-		if pl, ok := pl.(plugin.PluginDatastore); ok {
-			err := injectDatastorePlugin(pl)
-			if err != nil {
-				return err
-			}
+	}
+	if pl, ok := pl.(plugin.PluginTracer); ok {
+		err := injectTracerPlugin(pl)
+		if err != nil {
+			return err
+		}
+	}
+	if pl, ok := pl.(plugin.PluginDatastore); ok {
+		err := injectDatastorePlugin(pl)
+		if err != nil {
+			return err
+		}
+	}
+	if pl, ok := pl.(plugin.PluginRouting); ok {
+		err := injectRoutingPlugin(pl)
+		if err != nil {
+			return err
 		}
 	}
 	return nil
 }
 
-// Start starts all long-running plugins.
+// Start starts all long-running plugins, after the plugins they depend on.
 func (loader *PluginLoader) Start(iface coreiface.CoreAPI) error {
-	for i, pl := range loader.plugins {
-		if pl, ok := pl.(plugin.PluginDaemon); ok {
+	for _, e := range loader.inState(StateInjected) {
+		if pl, ok := e.Plugin.(plugin.PluginDaemon); ok {
 			err := pl.Start(iface)
 			if err != nil {
-				closePlugins(loader.plugins[i:])
+				loader.Close()
 				return err
 			}
 		}
+		loader.setState(e, StateStarted)
 	}
 	return nil
 }
 
-// StopDaemon stops all long-running plugins.
-func (loader *PluginLoader) Close() error {
-	return closePlugins(loader.plugins)
+// ServeOptions returns the options the PluginHTTP apply to the given server
+// of the daemon
+func (loader *PluginLoader) ServeOptions(server plugin.HTTPServer) []plugin.ServeOption {
+	var opts []plugin.ServeOption
+	for _, e := range loader.inState(StateStarted) {
+		if pl, ok := e.Plugin.(plugin.PluginHTTP); ok {
+			opts = append(opts, pl.ServeOptions(server)...)
+		}
+	}
+	return opts
 }
 
-func closePlugins(plugins []plugin.Plugin) error {
+// Close stops all started plugins, before the plugins they depend on.
+func (loader *PluginLoader) Close() error {
+	started := loader.inState(StateStarted)
+
 	var errs []string
-	for _, pl := range plugins {
-		if pl, ok := pl.(plugin.PluginDaemon); ok {
+	for i := len(started) - 1; i >= 0; i-- {
+		e := started[i]
+		if pl, ok := e.Plugin.(plugin.PluginDaemon); ok {
 			err := pl.Close()
 			if err != nil {
 				errs = append(errs, fmt.Sprintf(
@@ -141,6 +252,7 @@ func closePlugins(plugins []plugin.Plugin) error {
 				))
 			}
 		}
+		loader.setState(e, StateClosed)
 	}
 	if errs != nil {
 		return fmt.Errorf(strings.Join(errs, "\n"))
@@ -152,6 +264,10 @@ func injectDatastorePlugin(pl plugin.PluginDatastore) error {
 	return fsrepo.AddDatastoreConfigHandler(pl.DatastoreTypeName(), pl.DatastoreConfigParser())
 }
 
+func injectRoutingPlugin(pl plugin.PluginRouting) error {
+	return core.AddRoutingOption(pl.RoutingTypeName(), pl.RoutingOption())
+}
+
 func injectIPLDPlugin(pl plugin.PluginIPLD) error {
 	err := pl.RegisterBlockDecoders(ipld.DefaultBlockDecoder)
 	if err != nil {
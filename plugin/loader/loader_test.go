@@ -0,0 +1,302 @@
+package loader
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"strings"
+	"testing"
+
+	core "github.com/ipfs/go-ipfs/core"
+	plugin "github.com/ipfs/go-ipfs/plugin"
+
+	u "gx/ipfs/QmNohiVssaPw3KVLZik59DBVGTSm2dGvYT9eoXt5DQ36Yz/go-ipfs-util"
+	peer "gx/ipfs/QmPJxxDsX2UbchSHobbYuvz7qnyJTFKvaKMzE2rZWJ4x5B/go-libp2p-peer"
+	pstore "gx/ipfs/QmQFFp4ntkd4C14sP3FaH9WJyBuetuGUVo6dShNHvnoEvC/go-libp2p-peerstore"
+	cmds "gx/ipfs/QmR77mMvvh8mJBBWQmBfQBu8oD38NUN4KE9SL2gDgAQNc6/go-ipfs-cmds"
+	cid "gx/ipfs/QmR8BauakNcBa3RbE4nbQu76PDiJgoQgz8AJdhJuiU4TAw/go-cid"
+	mockrouting "gx/ipfs/QmRJvdmKJoDcQEhhTt5NYXJPQFnJYPo1kfapxtjZLfDDqH/go-ipfs-routing/mock"
+	routing "gx/ipfs/QmRjT8Bkut84fHf9nxMQBxGsqLAkqzMdFaemDK7e61dBNZ/go-libp2p-routing"
+	libp2p "gx/ipfs/QmSgtf5vHyugoxcwMbyNy6bZ9qPDDTJSYEED2GkWjLwitZ/go-libp2p"
+	mocknet "gx/ipfs/QmSgtf5vHyugoxcwMbyNy6bZ9qPDDTJSYEED2GkWjLwitZ/go-libp2p/p2p/net/mock"
+	testutil "gx/ipfs/QmVnJMgafh5MBYiyqbvDtoCL8pcQvbEGD2k9o9GFpBWPzY/go-testutil"
+	record "gx/ipfs/QmexPd3srWxHC76gW2p5j5tQvwpPuCoW7b9vFhJ8BRPyh9/go-libp2p-record"
+	ds "gx/ipfs/Qmf4xQhNomPNhrtZc67qSnfJSjxjXs9LWvknJtSXwimPrM/go-datastore"
+	host "gx/ipfs/QmfRHxh8bt4jWLKRhNvR5fn7mFACrQBFLqV4wyoymEExKV/go-libp2p-host"
+)
+
+type testPlugin struct {
+	name string
+	deps []string
+
+	env   *plugin.Environment
+	inits *[]string // the names of the plugins initialized, in order
+}
+
+func (p *testPlugin) Name() string           { return p.name }
+func (p *testPlugin) Version() string        { return "0.0.1" }
+func (p *testPlugin) Dependencies() []string { return p.deps }
+
+func (p *testPlugin) Init(env *plugin.Environment) error {
+	p.env = env
+	if p.inits != nil {
+		*p.inits = append(*p.inits, p.name)
+	}
+	return nil
+}
+
+// newTestLoader creates a loader of the given plugins, as NewPluginLoader
+// does with the preloaded ones, and initializes them
+func newTestLoader(t *testing.T, configs map[string]Config, plugins ...plugin.Plugin) *PluginLoader {
+	loader, err := newLoader(configs, plugins...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := loader.Initialize(); err != nil {
+		t.Fatal(err)
+	}
+	return loader
+}
+
+func newLoader(configs map[string]Config, plugins ...plugin.Plugin) (*PluginLoader, error) {
+	plMap := make(map[string]*pluginEntry)
+	for _, pl := range plugins {
+		e := &pluginEntry{Plugin: pl, source: SourcePreloaded, config: configs[pl.Name()]}
+		if e.config.Disabled {
+			e.state = StateDisabled
+		}
+		plMap[pl.Name()] = e
+	}
+	sorted, err := sortPlugins(plMap)
+	if err != nil {
+		return nil, err
+	}
+	return &PluginLoader{plugins: sorted}, nil
+}
+
+func TestPluginOrder(t *testing.T) {
+	var inits []string
+	loader := newTestLoader(t, nil,
+		&testPlugin{name: "c", inits: &inits},
+		&testPlugin{name: "a", deps: []string{"d"}, inits: &inits},
+		&testPlugin{name: "b", inits: &inits},
+		&testPlugin{name: "d", deps: []string{"c"}, inits: &inits},
+	)
+
+	// by name, but after the dependencies
+	if strings.Join(inits, ",") != "b,c,d,a" {
+		t.Errorf("unexpected init order %v", inits)
+	}
+	for i, info := range loader.Plugins() {
+		if info.Name != inits[i] || info.State != StateInitialized {
+			t.Errorf("unexpected plugin %d: %+v", i, info)
+		}
+	}
+}
+
+func TestPluginDependencyErrors(t *testing.T) {
+	if _, err := newLoader(nil, &testPlugin{name: "a", deps: []string{"missing"}}); err == nil {
+		t.Error("expected a missing dependency to fail")
+	}
+	if _, err := newLoader(nil,
+		&testPlugin{name: "a", deps: []string{"b"}},
+		&testPlugin{name: "b", deps: []string{"a"}},
+	); err == nil {
+		t.Error("expected a dependency cycle to fail")
+	}
+	if _, err := newLoader(map[string]Config{"b": {Disabled: true}},
+		&testPlugin{name: "a", deps: []string{"b"}},
+		&testPlugin{name: "b"},
+	); err == nil {
+		t.Error("expected a dependency on a disabled plugin to fail")
+	}
+}
+
+func TestPluginConfig(t *testing.T) {
+	var inits []string
+	configured := &testPlugin{name: "configured", inits: &inits}
+	loader := newTestLoader(t,
+		map[string]Config{
+			"configured": {Config: json.RawMessage(`{"Key":"value"}`)},
+			"disabled":   {Disabled: true},
+		},
+		configured,
+		&testPlugin{name: "disabled", inits: &inits},
+	)
+
+	if len(inits) != 1 || inits[0] != "configured" {
+		t.Errorf("expected only the enabled plugin to be initialized, got %v", inits)
+	}
+	var cfg struct{ Key string }
+	if err := json.Unmarshal(configured.env.Config, &cfg); err != nil || cfg.Key != "value" {
+		t.Errorf("expected the config of the plugin to be passed to Init, got %s", configured.env.Config)
+	}
+
+	if err := loader.Inject(); err != nil {
+		t.Fatal(err)
+	}
+	for _, info := range loader.Plugins() {
+		expected := StateInjected
+		if info.Name == "disabled" {
+			expected = StateDisabled
+		}
+		if info.State != expected {
+			t.Errorf("expected plugin %s to be %s, got %s", info.Name, expected, info.State)
+		}
+	}
+}
+
+type testCommandsPlugin struct {
+	testPlugin
+	commands map[string]*cmds.Command
+	readOnly []string
+}
+
+func (p *testCommandsPlugin) Commands() map[string]*cmds.Command { return p.commands }
+func (p *testCommandsPlugin) ReadOnlyCommands() []string         { return p.readOnly }
+
+func TestInjectDynamicCommands(t *testing.T) {
+	loader := newTestLoader(t, nil, &testCommandsPlugin{
+		testPlugin: testPlugin{name: "hello"},
+		commands:   map[string]*cmds.Command{"hello": {}},
+	})
+	loader.plugins[0].source = SourceDynamic
+
+	if err := loader.Inject(); err == nil {
+		t.Fatal("expected a dynamic plugin adding commands to fail")
+	}
+	if loader.Running("hello") {
+		t.Error("expected the plugin not to run")
+	}
+}
+
+func TestRunning(t *testing.T) {
+	loader := newTestLoader(t, map[string]Config{"off": {Disabled: true}},
+		&testPlugin{name: "on"},
+		&testPlugin{name: "off"},
+	)
+	if loader.Running("on") {
+		t.Error("expected an initialized plugin not to run before it's injected")
+	}
+	if err := loader.Inject(); err != nil {
+		t.Fatal(err)
+	}
+	if !loader.Running("on") {
+		t.Error("expected the injected plugin to run")
+	}
+	if loader.Running("off") || loader.Running("missing") {
+		t.Error("expected disabled and missing plugins not to run")
+	}
+}
+
+type testHTTPPlugin struct {
+	testPlugin
+	opts map[plugin.HTTPServer][]plugin.ServeOption
+}
+
+func (p *testHTTPPlugin) ServeOptions(server plugin.HTTPServer) []plugin.ServeOption {
+	return p.opts[server]
+}
+
+func TestServeOptions(t *testing.T) {
+	var called []string
+	option := func(name string) plugin.ServeOption {
+		return func(_ *core.IpfsNode, _ net.Listener, mux *http.ServeMux) (*http.ServeMux, error) {
+			called = append(called, name)
+			return mux, nil
+		}
+	}
+
+	loader := newTestLoader(t, nil, &testHTTPPlugin{
+		testPlugin: testPlugin{name: "http"},
+		opts: map[plugin.HTTPServer][]plugin.ServeOption{
+			plugin.HTTPGateway: {option("gateway")},
+			plugin.HTTPAPI:     {option("api"), option("webhook")},
+		},
+	})
+	if err := loader.Inject(); err != nil {
+		t.Fatal(err)
+	}
+	if err := loader.Start(nil); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, opt := range loader.ServeOptions(plugin.HTTPAPI) {
+		if _, err := opt(nil, nil, http.NewServeMux()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(called) != 2 || called[0] != "api" || called[1] != "webhook" {
+		t.Errorf("expected the api options to be returned in order, got %v", called)
+	}
+}
+
+type testRoutingPlugin struct {
+	testPlugin
+	opt core.RoutingOption
+}
+
+func (p *testRoutingPlugin) RoutingTypeName() string           { return p.name }
+func (p *testRoutingPlugin) RoutingOption() core.RoutingOption { return p.opt }
+
+func TestInjectRouting(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	server := mockrouting.NewServer()
+	ident := testutil.RandIdentityOrFatal(t)
+	opt := func(ctx context.Context, _ host.Host, dstore ds.Batching, _ record.Validator) (routing.IpfsRouting, error) {
+		return server.ClientWithDatastore(ctx, ident, dstore), nil
+	}
+
+	loader := newTestLoader(t, nil, &testRoutingPlugin{
+		testPlugin: testPlugin{name: "mockrouting"},
+		opt:        opt,
+	})
+	if err := loader.Inject(); err != nil {
+		t.Fatal(err)
+	}
+
+	routingOpt, err := core.RoutingOptionByName("mockrouting")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	mn := mocknet.New(ctx)
+	n, err := core.NewNode(ctx, &core.BuildCfg{
+		Online:  true,
+		Routing: routingOpt,
+		Host: func(ctx context.Context, id peer.ID, ps pstore.Peerstore, _ ...libp2p.Option) (host.Host, error) {
+			return mn.AddPeerWithPeerstore(id, ps)
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer n.Close()
+
+	// the node provides through the mock router
+	c := cid.NewCidV1(cid.Raw, u.Hash([]byte("provided")))
+	if err := n.Routing.Provide(ctx, c, true); err != nil {
+		t.Fatal(err)
+	}
+	other := server.Client(testutil.RandIdentityOrFatal(t))
+	if provs := other.FindProvidersAsync(ctx, c, 1); len(collect(provs)) != 1 {
+		t.Error("expected the node to provide through the plugin router")
+	}
+
+	// the built-in names can't be taken
+	loader = newTestLoader(t, nil, &testRoutingPlugin{testPlugin: testPlugin{name: "dht"}, opt: opt})
+	if err := loader.Inject(); err == nil {
+		t.Error("expected registering a routing named dht to fail")
+	}
+}
+
+func collect(provs <-chan pstore.PeerInfo) []pstore.PeerInfo {
+	var out []pstore.PeerInfo
+	for p := range provs {
+		out = append(out, p)
+	}
+	return out
+}
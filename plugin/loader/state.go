@@ -0,0 +1,180 @@
+package loader
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	plugin "github.com/ipfs/go-ipfs/plugin"
+	fsrepo "github.com/ipfs/go-ipfs/repo/fsrepo"
+)
+
+// ConfigKey is the config section holding the config of the plugins, by
+// plugin name
+const ConfigKey = "Plugins"
+
+// Config is the config of a plugin
+type Config struct {
+	// Disabled keeps the plugin from being initialized and injected
+	Disabled bool `json:",omitempty"`
+
+	// Config is passed to the plugin on Init
+	Config json.RawMessage `json:",omitempty"`
+}
+
+// readConfig reads the config of the plugins from the repo at the given path
+func readConfig(repoPath string) (map[string]Config, error) {
+	configs := make(map[string]Config)
+	if repoPath == "" {
+		return configs, nil
+	}
+	if _, err := fsrepo.ConfigSectionAt(repoPath, ConfigKey, &configs); err != nil {
+		return nil, fmt.Errorf("invalid %s config: %s", ConfigKey, err)
+	}
+	return configs, nil
+}
+
+// Where the plugins are loaded from
+const (
+	SourcePreloaded = "preloaded"
+	SourceDynamic   = "dynamic"
+)
+
+// State is the state of a plugin in the loader
+type State int
+
+const (
+	// StateLoaded is the state of the plugins not initialized yet
+	StateLoaded State = iota
+	// StateDisabled is the state of the plugins disabled by their config
+	StateDisabled
+	StateInitialized
+	StateInjected
+	StateStarted
+	StateClosed
+)
+
+func (s State) String() string {
+	switch s {
+	case StateLoaded:
+		return "loaded"
+	case StateDisabled:
+		return "disabled"
+	case StateInitialized:
+		return "initialized"
+	case StateInjected:
+		return "injected"
+	case StateStarted:
+		return "started"
+	case StateClosed:
+		return "closed"
+	default:
+		return "unknown"
+	}
+}
+
+// Info describes a plugin of the loader
+type Info struct {
+	Name    string
+	Version string
+	Types   []string
+	State   State
+	Source  string
+}
+
+// pluginEntry is a plugin of the loader, with its config and state
+type pluginEntry struct {
+	plugin.Plugin
+
+	source string
+	config Config
+	state  State
+}
+
+func (e *pluginEntry) types() []string {
+	var types []string
+	if _, ok := e.Plugin.(plugin.PluginIPLD); ok {
+		types = append(types, "ipld")
+	}
+	if _, ok := e.Plugin.(plugin.PluginTracer); ok {
+		types = append(types, "tracer")
+	}
+	if _, ok := e.Plugin.(plugin.PluginDatastore); ok {
+		types = append(types, "datastore")
+	}
+	if _, ok := e.Plugin.(plugin.PluginRouting); ok {
+		types = append(types, "routing")
+	}
+	if _, ok := e.Plugin.(plugin.PluginCommands); ok {
+		types = append(types, "commands")
+	}
+	if _, ok := e.Plugin.(plugin.PluginHTTP); ok {
+		types = append(types, "http")
+	}
+	if _, ok := e.Plugin.(plugin.PluginDaemon); ok {
+		types = append(types, "daemon")
+	}
+	return types
+}
+
+func dependencies(pl plugin.Plugin) []string {
+	if pl, ok := pl.(plugin.PluginDependencies); ok {
+		return pl.Dependencies()
+	}
+	return nil
+}
+
+// sortPlugins orders the plugins so that they come after their dependencies,
+// by name otherwise. A plugin can't depend on a missing or disabled plugin.
+func sortPlugins(plugins map[string]*pluginEntry) ([]*pluginEntry, error) {
+	names := make([]string, 0, len(plugins))
+	for name, e := range plugins {
+		for _, dep := range dependencies(e.Plugin) {
+			d, ok := plugins[dep]
+			if !ok {
+				return nil, fmt.Errorf("plugin %s depends on %s, which isn't loaded", name, dep)
+			}
+			if d.state == StateDisabled && e.state != StateDisabled {
+				return nil, fmt.Errorf("plugin %s depends on %s, which is disabled", name, dep)
+			}
+		}
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	sorted := make([]*pluginEntry, 0, len(plugins))
+	done := make(map[string]bool, len(plugins))
+	for len(sorted) < len(plugins) {
+		progress := false
+		for _, name := range names {
+			if done[name] {
+				continue
+			}
+			ready := true
+			for _, dep := range dependencies(plugins[name].Plugin) {
+				if !done[dep] {
+					ready = false
+					break
+				}
+			}
+			if ready {
+				sorted = append(sorted, plugins[name])
+				done[name] = true
+				progress = true
+				// start over, so that the plugins come by name whenever
+				// possible
+				break
+			}
+		}
+		if !progress {
+			var cycle []string
+			for _, name := range names {
+				if !done[name] {
+					cycle = append(cycle, name)
+				}
+			}
+			return nil, fmt.Errorf("plugins %v depend on each other", cycle)
+		}
+	}
+	return sorted, nil
+}
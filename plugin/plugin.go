@@ -1,5 +1,19 @@
 package plugin
 
+import (
+	"encoding/json"
+)
+
+// Environment is the environment passed to the plugins on Init
+type Environment struct {
+	// Repo is the path of the repo, empty if it isn't accessible
+	Repo string
+
+	// Config is the config of the plugin, from the Plugins section of the
+	// repo config, nil if it isn't set
+	Config json.RawMessage
+}
+
 // Plugin is base interface for all kinds of go-ipfs plugins
 // It will be included in interfaces of different Plugins
 type Plugin interface {
@@ -8,5 +22,15 @@ type Plugin interface {
 	// Version returns current version of the plugin
 	Version() string
 	// Init is called once when the Plugin is being loaded
-	Init() error
+	Init(env *Environment) error
+}
+
+// PluginDependencies can be implemented by plugins depending on other
+// plugins. The plugins are initialized, injected and started after the ones
+// they depend on.
+type PluginDependencies interface {
+	Plugin
+
+	// Dependencies returns the names of the plugins the plugin depends on
+	Dependencies() []string
 }
@@ -0,0 +1,14 @@
+package plugin
+
+import (
+	core "github.com/ipfs/go-ipfs/core"
+)
+
+// PluginRouting is an interface that can be implemented to add routing
+// systems, selected by name with Routing.Type or 'ipfs daemon --routing'
+type PluginRouting interface {
+	Plugin
+
+	RoutingTypeName() string
+	RoutingOption() core.RoutingOption
+}
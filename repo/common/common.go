@@ -5,6 +5,22 @@ import (
 	"strings"
 )
 
+// KeyNotFoundError is returned when a key isn't set in a config map
+type KeyNotFoundError struct {
+	// Parent is the part of the key which was found
+	Parent string
+}
+
+func (e KeyNotFoundError) Error() string {
+	return fmt.Sprintf("%s key has no attributes", e.Parent)
+}
+
+// IsKeyNotFound returns whether the error means the key isn't set
+func IsKeyNotFound(err error) bool {
+	_, ok := err.(KeyNotFoundError)
+	return ok
+}
+
 func MapGetKV(v map[string]interface{}, key string) (interface{}, error) {
 	var ok bool
 	var mcursor map[string]interface{}
@@ -21,7 +37,7 @@ func MapGetKV(v map[string]interface{}, key string) (interface{}, error) {
 
 		cursor, ok = mcursor[part]
 		if !ok {
-			return nil, fmt.Errorf("%s key has no attributes", sofar)
+			return nil, KeyNotFoundError{Parent: sofar}
 		}
 	}
 	return cursor, nil
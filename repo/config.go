@@ -0,0 +1,55 @@
+package repo
+
+import (
+	"encoding/json"
+
+	common "github.com/ipfs/go-ipfs/repo/common"
+)
+
+// ReadConfigSection decodes the config section under the given key into v.
+// It is meant for the sections the config package doesn't know about, which
+// are only kept in the config file. It returns false when the section isn't
+// set.
+func ReadConfigSection(r Repo, key string, v interface{}) (bool, error) {
+	val, err := r.GetConfigKey(key)
+	if common.IsKeyNotFound(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return DecodeConfigSection(val, v)
+}
+
+// DecodeConfigSection decodes a value read from a generic config map into v.
+// It returns false when the value is null.
+func DecodeConfigSection(val interface{}, v interface{}) (bool, error) {
+	if val == nil {
+		return false, nil
+	}
+
+	// the value went through a generic json decoding, take it back
+	b, err := json.Marshal(val)
+	if err != nil {
+		return false, err
+	}
+	if err := json.Unmarshal(b, v); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// WriteConfigSection encodes v and stores it as the config section under the
+// given key
+func WriteConfigSection(r Repo, key string, v interface{}) error {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	var val interface{}
+	if err := json.Unmarshal(b, &val); err != nil {
+		return err
+	}
+	return r.SetConfigKey(key, val)
+}
@@ -36,7 +36,7 @@ const LockFile = "repo.lock"
 var log = logging.Logger("fsrepo")
 
 // version number that we are currently expecting to see
-var RepoVersion = 7
+var RepoVersion = 8
 
 var migrationInstructions = `See https://github.com/ipfs/fs-repo-migrations/blob/master/run.md
 Sorry for the inconvenience. In the future, these will run automatically.`
@@ -51,8 +51,18 @@ var (
 	ErrNoVersion     = errors.New("no version file found, please run 0-to-1 migration tool.\n" + migrationInstructions)
 	ErrOldRepo       = errors.New("ipfs repo found in old '~/.go-ipfs' location, please run migration tool.\n" + migrationInstructions)
 	ErrNeedMigration = errors.New("ipfs repo needs migration")
+
+	ErrKeystorePassphrase = errors.New("the keystore is encrypted, set " + EnvKeystorePassphrase + " to its passphrase")
 )
 
+// EnvKeystorePassphrase is the environment variable holding the passphrase of
+// an encrypted keystore
+const EnvKeystorePassphrase = "IPFS_KEYSTORE_PASSPHRASE"
+
+// KeystorePassphrase, if set, is called to ask for the passphrase of an
+// encrypted keystore when EnvKeystorePassphrase isn't set
+var KeystorePassphrase func() ([]byte, error)
+
 type NoRepoError struct {
 	Path string
 }
@@ -221,6 +231,35 @@ func ConfigAt(repoPath string) (*config.Config, error) {
 	return serialize.Load(configFilename)
 }
 
+// ConfigSectionAt reads the config section under the given key of the FSRepo
+// at the given path into v, even when another process is holding the lock.
+// It returns false if the repo has no config file or the key isn't set.
+func ConfigSectionAt(repoPath, key string, v interface{}) (bool, error) {
+	packageLock.Lock()
+	defer packageLock.Unlock()
+
+	configFilename, err := config.Filename(repoPath)
+	if err != nil {
+		return false, err
+	}
+	if _, err := os.Stat(configFilename); os.IsNotExist(err) {
+		return false, nil
+	}
+
+	var mapconf map[string]interface{}
+	if err := serialize.ReadConfigFile(configFilename, &mapconf); err != nil {
+		return false, err
+	}
+	val, err := common.MapGetKV(mapconf, key)
+	if common.IsKeyNotFound(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return repo.DecodeConfigSection(val, v)
+}
+
 // configIsInitialized returns true if the repo is initialized at
 // provided |path|.
 func configIsInitialized(path string) bool {
@@ -385,6 +424,38 @@ func (r *FSRepo) openConfig() error {
 
 func (r *FSRepo) openKeystore() error {
 	ksp := filepath.Join(r.path, "keystore")
+
+	encrypted, err := keystore.IsEncrypted(ksp)
+	if err != nil {
+		return err
+	}
+
+	converting, err := keystore.IsConverting(ksp)
+	if err != nil {
+		return err
+	}
+
+	if encrypted || converting {
+		passphrase, err := keystorePassphrase()
+		if err != nil {
+			return err
+		}
+
+		var ks *keystore.EncryptedKeystore
+		if converting {
+			// finish the conversion interrupted by SetKeystorePassphrase
+			ks, err = keystore.EncryptKeystore(ksp, passphrase)
+		} else {
+			ks, err = keystore.NewEncryptedKeystore(ksp, passphrase)
+		}
+		if err != nil {
+			return err
+		}
+
+		r.keystore = ks
+		return nil
+	}
+
 	ks, err := keystore.NewFSKeystore(ksp)
 	if err != nil {
 		return err
@@ -395,6 +466,47 @@ func (r *FSRepo) openKeystore() error {
 	return nil
 }
 
+func keystorePassphrase() ([]byte, error) {
+	if p := os.Getenv(EnvKeystorePassphrase); p != "" {
+		return []byte(p), nil
+	}
+
+	if KeystorePassphrase != nil {
+		p, err := KeystorePassphrase()
+		if err == nil && len(p) > 0 {
+			return p, nil
+		}
+		if err != nil {
+			log.Debugf("reading keystore passphrase: %s", err)
+		}
+	}
+
+	return nil, ErrKeystorePassphrase
+}
+
+// SetKeystorePassphrase sets the passphrase protecting the keystore. A
+// plaintext keystore is converted to the encrypted format.
+func (r *FSRepo) SetKeystorePassphrase(passphrase []byte) error {
+	packageLock.Lock()
+	defer packageLock.Unlock()
+
+	if r.closed {
+		return errors.New("repo is closed")
+	}
+
+	if ks, ok := r.keystore.(*keystore.EncryptedKeystore); ok {
+		return ks.ChangePassphrase(passphrase)
+	}
+
+	ks, err := keystore.EncryptKeystore(filepath.Join(r.path, "keystore"), passphrase)
+	if err != nil {
+		return err
+	}
+
+	r.keystore = ks
+	return nil
+}
+
 // openDatastore returns an error if the config file is not present.
 func (r *FSRepo) openDatastore() error {
 	if r.config.Datastore.Type != "" || r.config.Datastore.Path != "" {
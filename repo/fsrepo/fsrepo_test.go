@@ -7,6 +7,8 @@ import (
 	"path/filepath"
 	"testing"
 
+	"github.com/ipfs/go-ipfs/repo"
+	mfsr "github.com/ipfs/go-ipfs/repo/fsrepo/migrations"
 	"github.com/ipfs/go-ipfs/thirdparty/assert"
 
 	config "gx/ipfs/QmTbcMKv6GU3fxhnNcbzYChdox9Fdd7VpucM3PQ7UWjX3D/go-ipfs-config"
@@ -116,3 +118,69 @@ func TestOpenMoreThanOnceInSameProcess(t *testing.T) {
 	assert.Nil(r1.Close(), t)
 	assert.Nil(r2.Close(), t)
 }
+
+func TestMigrate(t *testing.T) {
+	path := testRepoPath("migrate", t)
+	defer Remove(path)
+	assert.Nil(Init(path, &config.Config{Datastore: config.DefaultDatastoreConfig()}), t)
+
+	defer func(m map[int]Migration) { builtinMigrations = m }(builtinMigrations)
+	builtinMigrations = map[int]Migration{}
+
+	key := datastore.NewKey("migrated")
+	RegisterMigration(RepoVersion, func(d repo.Datastore) error {
+		return d.Put(key, []byte("yes"))
+	})
+
+	rp := mfsr.RepoPath(path)
+	assert.Nil(rp.WriteVersion(RepoVersion-1), t)
+	_, err := Open(path)
+	assert.Err(err, t, "an outdated repo shouldn't open")
+
+	assert.Nil(Migrate(path), t, "the migration should succeed")
+	assert.Nil(rp.CheckVersion(RepoVersion), t, "the version should be bumped")
+
+	r, err := Open(path)
+	assert.Nil(err, t)
+	has, err := r.Datastore().Has(key)
+	assert.Nil(err, t)
+	assert.True(has, t, "the migration should have run")
+	assert.Nil(r.Close(), t)
+
+	assert.Nil(rp.WriteVersion(BuiltinMigrationsFrom-1), t)
+	if err := Migrate(path); err != ErrNeedMigration {
+		t.Fatalf("expected ErrNeedMigration, got %v", err)
+	}
+}
+
+func TestReadConfigSection(t *testing.T) {
+	t.Parallel()
+	path := testRepoPath("section", t)
+	defer Remove(path)
+	conf := &config.Config{
+		Identity:  config.Identity{PrivKey: "key"},
+		Datastore: config.DefaultDatastoreConfig(),
+	}
+	assert.Nil(Init(path, conf), t)
+
+	r, err := Open(path)
+	assert.Nil(err, t)
+	defer r.Close()
+
+	var v map[string]string
+	ok, err := repo.ReadConfigSection(r, "Section.Values", &v)
+	assert.Nil(err, t, "an unset section isn't an error")
+	assert.False(ok, t, "the section shouldn't be set")
+
+	assert.Nil(repo.WriteConfigSection(r, "Section.Values", map[string]string{"a": "b"}), t)
+	ok, err = repo.ReadConfigSection(r, "Section.Values", &v)
+	assert.Nil(err, t)
+	assert.True(ok && v["a"] == "b", t, "the section should be read back")
+
+	assert.Nil(r.SetConfigKey("Section", "not a map"), t)
+	_, err = repo.ReadConfigSection(r, "Section.Values", &v)
+	assert.Err(err, t, "a broken section should be reported")
+
+	_, err = ConfigSectionAt(path, "Section.Values", &v)
+	assert.Err(err, t, "a broken section should be reported")
+}
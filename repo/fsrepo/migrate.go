@@ -0,0 +1,92 @@
+package fsrepo
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/ipfs/go-ipfs/repo"
+	mfsr "github.com/ipfs/go-ipfs/repo/fsrepo/migrations"
+
+	lockfile "gx/ipfs/QmcWjZkQxyPMkgZRpda4hqWwaD6E1yqCvcxZfxbt98CEAK/go-fs-lock"
+)
+
+// Migration upgrades the datastore of a repo from the previous version to the
+// version it is registered for
+type Migration func(repo.Datastore) error
+
+// BuiltinMigrationsFrom is the oldest repo version the built-in migrations
+// can upgrade, older repos need the external fs-repo-migrations first
+var BuiltinMigrationsFrom = 7
+
+var builtinMigrations = map[int]Migration{}
+
+// RegisterMigration registers the migration to the given repo version
+func RegisterMigration(version int, m Migration) {
+	if _, ok := builtinMigrations[version]; ok {
+		panic(fmt.Sprintf("migration to repo version %d already registered", version))
+	}
+	builtinMigrations[version] = m
+}
+
+// Migrate runs the built-in migrations on the repo at the given path, up to
+// RepoVersion. It returns ErrNeedMigration if the repo is older than
+// BuiltinMigrationsFrom.
+func Migrate(repoPath string) error {
+	packageLock.Lock()
+	defer packageLock.Unlock()
+
+	r, err := newFSRepo(repoPath)
+	if err != nil {
+		return err
+	}
+
+	if err := checkInitialized(r.path); err != nil {
+		return err
+	}
+
+	r.lockfile, err = lockfile.Lock(r.path, LockFile)
+	if err != nil {
+		return err
+	}
+	defer r.lockfile.Close()
+
+	rp := mfsr.RepoPath(r.path)
+	ver, err := rp.Version()
+	if err != nil {
+		if os.IsNotExist(err) {
+			return ErrNoVersion
+		}
+		return err
+	}
+
+	if ver >= RepoVersion {
+		return nil
+	}
+	if ver < BuiltinMigrationsFrom {
+		return ErrNeedMigration
+	}
+
+	if err := r.openConfig(); err != nil {
+		return err
+	}
+	if err := r.openDatastore(); err != nil {
+		return err
+	}
+	defer r.ds.Close()
+
+	for ver < RepoVersion {
+		ver++
+		m, ok := builtinMigrations[ver]
+		if !ok {
+			return fmt.Errorf("no migration to repo version %d", ver)
+		}
+		log.Infof("migrating the repo to version %d", ver)
+		if err := m(r.ds); err != nil {
+			return fmt.Errorf("migrating the repo to version %d: %s", ver, err)
+		}
+		if err := rp.WriteVersion(ver); err != nil {
+			return err
+		}
+	}
+	return nil
+}
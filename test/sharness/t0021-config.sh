@@ -281,10 +281,37 @@ test_config_cmd() {
   '
 }
 
+# the sections the config struct doesn't know about must survive the commands
+# rewriting the whole config
+test_config_sections() {
+  test_expect_success "set the custom config sections" '
+    ipfs config --json PublicGateways "{\"dweb.link\": {\"UseSubdomains\": true}}" &&
+    ipfs config --json Namesys.Keys "{\"self\": {\"TTL\": \"5m\"}}" &&
+    ipfs config --json Namesys.PersistResolveCache true
+  '
+
+  test_expect_success "'ipfs bootstrap add' succeeds" '
+    ipfs bootstrap add /ip4/104.131.131.82/tcp/4001/ipfs/QmaCpDMGvV2BGHeYERUEnRQAwe3N8SzbUtfsmvsqQLuvuJ
+  '
+
+  test_expect_success "the custom config sections are kept" '
+    ipfs config PublicGateways >actual &&
+    grep "dweb.link" actual &&
+    grep "\"UseSubdomains\": true" actual &&
+    ipfs config Namesys.Keys.self.TTL >actual &&
+    echo 5m >expected &&
+    test_cmp expected actual &&
+    ipfs config Namesys.PersistResolveCache >actual &&
+    echo true >expected &&
+    test_cmp expected actual
+  '
+}
+
 test_init_ipfs
 
 # should work offline
 test_config_cmd
+test_config_sections
 
 # should work online
 test_launch_ipfs_daemon
@@ -268,6 +268,27 @@ test_dag_cmd() {
     test_cmp resolve_obj_exp resolve_obj &&
     test_cmp resolve_data_exp resolve_data
   '
+
+  test_expect_success "dag export works" '
+    ipfs dag export $IPLDHASH > export.car
+  '
+
+  test_expect_success "dag import prints the root" '
+    ipfs dag import export.car > import_out &&
+    echo $IPLDHASH > import_exp &&
+    test_cmp import_exp import_out
+  '
+
+  test_expect_success "dag import --pin-roots pins the root" '
+    ipfs dag import --pin-roots export.car &&
+    ipfs pin ls --type=recursive | grep $IPLDHASH &&
+    ipfs pin rm $IPLDHASH
+  '
+
+  test_expect_success "dag import rejects garbage" '
+    echo "not a car" > garbage.car &&
+    test_must_fail ipfs dag import garbage.car
+  '
 }
 
 # should work offline
@@ -127,6 +127,37 @@ test_expect_success "'ipfs refs local' no longer shows file" '
   test_must_fail grep "$PATCH_ROOT" actual8
 '
 
+test_expect_success "add pinned and unpinned files" '
+  KEEP=$(echo "keep me" | ipfs add -q) &&
+  DROP=$(echo "drop me" | ipfs add -q --pin=false)
+'
+
+test_expect_success "'ipfs repo gc --target' removes unpinned blocks" '
+  ipfs repo gc --target 1GB --max-duration 1m >actual_incr &&
+  grep "removed $DROP" actual_incr &&
+  test_must_fail grep "$KEEP" actual_incr
+'
+
+test_expect_success "pinned file survived the incremental gc" '
+  ipfs cat "$KEEP" >actual_keep &&
+  echo "keep me" >expected_keep &&
+  test_cmp expected_keep actual_keep &&
+  ipfs refs local >actual_refs &&
+  test_must_fail grep "$DROP" actual_refs
+'
+
+test_expect_success "'ipfs repo gc' rejects an invalid budget" '
+  test_must_fail ipfs repo gc --max-duration forever 2>err_dur &&
+  grep "invalid max duration" err_dur &&
+  test_must_fail ipfs repo gc --target lots 2>err_target &&
+  grep "invalid target" err_target
+'
+
+test_expect_success "clean up pinned file" '
+  ipfs pin rm "$KEEP" &&
+  ipfs repo gc >/dev/null
+'
+
 test_expect_success "adding multiblock random file succeeds" '
   random 1000000 >multiblock &&
   MBLOCKHASH=`ipfs add -q multiblock`
@@ -160,6 +160,64 @@ test_pin_progress() {
   '
 }
 
+test_pin_names() {
+  test_expect_success "add files to pin by name" '
+    REL1=$(echo "release 1" | ipfs add -q --pin=false) &&
+    REL2=$(echo "release 2" | ipfs add -q --pin=false) &&
+    OTHER=$(echo "other" | ipfs add -q --pin=false)
+  '
+
+  test_expect_success "'ipfs pin add --name --meta' succeeds" '
+    ipfs pin add --name release-1 --meta channel=stable,team=web $REL1 &&
+    ipfs pin add --name release-2 --meta channel=beta $REL2 &&
+    ipfs pin add -r=false $OTHER
+  '
+
+  test_expect_success "'ipfs pin ls' shows pin names" '
+    ipfs pin ls --type=recursive >ls_out &&
+    grep "$REL1 recursive release-1" ls_out &&
+    grep "$REL2 recursive release-2" ls_out
+  '
+
+  test_expect_success "'ipfs pin ls --name' filters by name prefix" '
+    ipfs pin ls -q --name release- | sort >actual_names &&
+    printf "%s\n" $REL1 $REL2 | sort >expected_names &&
+    test_cmp expected_names actual_names
+  '
+
+  test_expect_success "'ipfs pin ls --meta' filters by metadata" '
+    ipfs pin ls -q --meta channel=stable >actual_meta &&
+    echo $REL1 >expected_meta &&
+    test_cmp expected_meta actual_meta &&
+    ipfs pin ls -q --meta channel=stable,team=db >actual_none &&
+    test_must_be_empty actual_none
+  '
+
+  test_expect_success "'ipfs pin ls --enc=json' includes metadata" '
+    ipfs pin ls --enc=json --name release-1 >actual_json &&
+    grep "\"team\":\"web\"" actual_json
+  '
+
+  test_expect_success "'ipfs pin add --name' renames a pin and keeps its metadata" '
+    ipfs pin add --name release-1.0 $REL1 &&
+    ipfs pin ls -q --name release-1.0 --meta team=web >actual_renamed &&
+    echo $REL1 >expected_renamed &&
+    test_cmp expected_renamed actual_renamed
+  '
+
+  test_expect_success "'ipfs pin add --meta' rejects invalid metadata" '
+    test_must_fail ipfs pin add --meta novalue $OTHER 2>meta_err &&
+    grep "invalid pin metadata" meta_err
+  '
+
+  test_expect_success "names are removed with the pins" '
+    ipfs pin rm $REL1 $REL2 &&
+    ipfs pin rm $OTHER &&
+    ipfs pin ls -q --name release >actual_gone &&
+    test_must_be_empty actual_gone
+  '
+}
+
 test_init_ipfs
 
 test_pins
@@ -174,6 +232,8 @@ test_pin_dag --raw-leaves
 
 test_pin_progress
 
+test_pin_names
+
 test_launch_ipfs_daemon --offline
 
 test_pins
@@ -188,6 +248,8 @@ test_pin_dag --raw-leaves
 
 test_pin_progress
 
+test_pin_names
+
 test_kill_ipfs_daemon
 
 test_done
@@ -0,0 +1,100 @@
+#!/usr/bin/env bash
+#
+# MIT Licensed; see the LICENSE file in this repository.
+#
+
+test_description="Test remote pinning services configuration"
+
+. lib/test-lib.sh
+
+test_init_ipfs
+
+test_expect_success "no remote pinning services by default" '
+  ipfs pin remote service ls >actual &&
+  test_must_be_empty actual
+'
+
+test_expect_success "add a remote pinning service" '
+  echo secretkey | ipfs pin remote service add mysrv https://pinning.example.com/api/v1
+'
+
+test_expect_success "the key is read from a file" '
+  echo otherkey >keyfile &&
+  ipfs pin remote service add filesrv https://pinning.example.com/api/v1 keyfile &&
+  ipfs pin remote service rm filesrv
+'
+
+test_expect_success "an empty key is rejected" '
+  test_must_fail ipfs pin remote service add emptysrv https://pinning.example.com/api/v1 </dev/null 2>err &&
+  grep "must not be empty" err
+'
+
+test_expect_success "the service is stored in the config" '
+  ipfs config Pinning.RemoteServices.mysrv.API.Endpoint >actual &&
+  echo "https://pinning.example.com/api/v1" >expected &&
+  test_cmp expected actual
+'
+
+test_expect_success "service ls shows the endpoint but not the key" '
+  ipfs pin remote service ls >actual &&
+  grep "mysrv" actual &&
+  grep "https://pinning.example.com/api/v1" actual &&
+  test_must_fail grep secretkey actual
+'
+
+test_expect_success "config show hides the key" '
+  ipfs config show >actual &&
+  grep "https://pinning.example.com/api/v1" actual &&
+  test_must_fail grep secretkey actual
+'
+
+test_expect_success "reading the service config hides the key" '
+  ipfs config Pinning.RemoteServices.mysrv >actual &&
+  grep "https://pinning.example.com/api/v1" actual &&
+  test_must_fail grep secretkey actual &&
+  test_must_fail ipfs config Pinning.RemoteServices.mysrv.API.Key 2>err &&
+  grep "cannot show the access key" err
+'
+
+test_expect_success "adding the same service twice fails" '
+  echo key | test_must_fail ipfs pin remote service add mysrv https://other.example.com 2>err &&
+  grep "already exists" err
+'
+
+test_expect_success "invalid service names are rejected" '
+  echo key | test_must_fail ipfs pin remote service add "my srv" https://pinning.example.com 2>err &&
+  grep "invalid service name" err
+'
+
+test_expect_success "invalid endpoints are rejected" '
+  echo key | test_must_fail ipfs pin remote service add other ftp://pinning.example.com 2>err &&
+  grep "invalid service endpoint" err
+'
+
+test_expect_success "pinning requires a service" '
+  HASH=$(echo "remote" | ipfs add -q) &&
+  test_must_fail ipfs pin remote add $HASH 2>err &&
+  grep "remote pinning service must be given" err
+'
+
+test_expect_success "pinning to an unknown service fails" '
+  test_must_fail ipfs pin remote add --service=unknown $HASH 2>err &&
+  grep "not found" err
+'
+
+test_expect_success "no pin requests are tracked" '
+  ipfs pin remote ls >actual &&
+  test_must_be_empty actual
+'
+
+test_expect_success "remove the service" '
+  ipfs pin remote service rm mysrv &&
+  ipfs pin remote service ls >actual &&
+  test_must_be_empty actual
+'
+
+test_expect_success "removing an unknown service fails" '
+  test_must_fail ipfs pin remote service rm mysrv
+'
+
+test_done
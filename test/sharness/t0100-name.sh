@@ -158,6 +158,46 @@ test_expect_success "'ipfs name publish --help' succeeds" '
   ipfs name publish --help
 '
 
+# test offline record creation
+
+test_expect_success "'ipfs name create' succeeds" '
+  ipfs name create --key=keyname --sequence=5 --ttl=1m "/ipfs/$HASH_WELCOME_DOCS" >record.ipns
+'
+
+test_expect_success "'ipfs name inspect' succeeds" '
+  ipfs name inspect --verify="$NEWID" record.ipns >inspect_out
+'
+
+test_expect_success "inspect output looks good" '
+  grep "^Value: /ipfs/$HASH_WELCOME_DOCS$" inspect_out &&
+  grep "^Sequence: 5$" inspect_out &&
+  grep "^Validity Type: EOL$" inspect_out &&
+  grep "^TTL: 1m0s$" inspect_out &&
+  grep "^Public Key: $NEWID$" inspect_out &&
+  grep "^Signature: valid for $NEWID$" inspect_out
+'
+
+test_expect_success "'ipfs name inspect' reports a wrong name" '
+  ipfs name inspect --verify="$PEERID" <record.ipns >inspect_out &&
+  grep "^Signature: invalid for $PEERID" inspect_out
+'
+
+test_expect_success "'ipfs name put' of the wrong name fails" '
+  test_must_fail ipfs name put --allow-offline "$PEERID" record.ipns
+'
+
+test_expect_success "'ipfs name put' succeeds" '
+  ipfs name put --allow-offline "$NEWID" record.ipns >put_out &&
+  echo "Published to ${NEWID}: /ipfs/$HASH_WELCOME_DOCS" >expected_put &&
+  test_cmp expected_put put_out
+'
+
+test_expect_success "'ipfs name resolve' of the put record succeeds" '
+  ipfs name resolve "$NEWID" >output &&
+  printf "/ipfs/%s\n" "$HASH_WELCOME_DOCS" >expected_put_resolve &&
+  test_cmp expected_put_resolve output
+'
+
 # test offline resolve
 
 test_expect_success "'ipfs name resolve --offline' succeeds" '
@@ -194,6 +234,47 @@ test_expect_success "resolve output looks good" '
   test_cmp expected4 output
 '
 
+test_expect_success "'ipfs name republish status' lists the keys" '
+  ipfs name republish status >status_out &&
+  grep "^self  *$PEERID " status_out
+'
+
+test_expect_success "'ipfs name republish' of an unknown key fails" '
+  test_must_fail ipfs name republish --key=nosuchkey 2>republish_err &&
+  grep "no key by the given name was found" republish_err
+'
+
+test_expect_success "'ipfs name republish' of a key without record fails" '
+  ipfs key gen --type=rsa --size=2048 unpublished &&
+  test_must_fail ipfs name republish --key=unpublished 2>republish_err &&
+  grep "no record was published with the key" republish_err
+'
+
+test_expect_success "'ipfs name republish' reports the keys without record" '
+  ipfs name republish >republish_out &&
+  grep "Nothing to republish for unpublished" republish_out
+'
+
+test_expect_success "'ipfs name cache ls' succeeds" '
+  ipfs name resolve "$PEERID" &&
+  ipfs name cache ls >cache_out &&
+  grep "^$PEERID " cache_out
+'
+
+test_expect_success "'ipfs name cache rm' evicts the name" '
+  ipfs name cache rm "/ipns/$PEERID" >cache_rm_out &&
+  echo "removed $PEERID" >expected_cache_rm &&
+  test_cmp expected_cache_rm cache_rm_out &&
+  ipfs name cache ls >cache_out &&
+  test_must_fail grep "^$PEERID " cache_out
+'
+
+test_expect_success "'ipfs name cache clear' succeeds" '
+  ipfs name cache clear &&
+  ipfs name cache ls >cache_out &&
+  test_must_fail grep "/ipfs/" cache_out
+'
+
 test_expect_success "empty request to name publish doesn't panic and returns error" '
   curl "http://$API_ADDR/api/v0/name/publish" > curl_out || true &&
     grep "argument \"ipfs-path\" is required" curl_out
@@ -211,6 +292,16 @@ test_expect_success "'ipfs name publish' fails offline mode" '
   test_expect_code 1 ipfs name publish "/ipfs/$HASH_WELCOME_DOCS"
 '
 
+test_expect_success "'ipfs name republish' fails offline mode" '
+  test_must_fail ipfs name republish 2>republish_err &&
+  grep "only runs in online mode" republish_err
+'
+
+test_expect_success "'ipfs name cache ls' fails offline mode" '
+  test_must_fail ipfs name cache ls 2>cache_err &&
+  grep "only used in online mode" cache_err
+'
+
 test_kill_ipfs_daemon
 
 test_done
@@ -61,6 +61,48 @@ test_expect_success "GET IPFS non existent file returns code expected (404)" '
   test_curl_resp_http_code "http://127.0.0.1:$port/ipfs/$HASH2/pleaseDontAddMe" "HTTP/1.1 404 Not Found"
 '
 
+test_expect_success "GET IPFS path with Accept: application/vnd.ipld.raw returns the block" '
+  curl -sf -H "Accept: application/vnd.ipld.raw" -D raw_headers -o actual "http://127.0.0.1:$port/ipfs/$HASH2/test" &&
+  FILEHASH=$(ipfs resolve -r /ipfs/$HASH2/test | cut -d/ -f3) &&
+  ipfs block get $FILEHASH >expected &&
+  test_cmp expected actual &&
+  grep "Content-Type: application/vnd.ipld.raw" raw_headers
+'
+
+test_expect_success "GET IPFS path with ?format=raw returns the block" '
+  curl -sfo actual "http://127.0.0.1:$port/ipfs/$HASH2/test?format=raw" &&
+  test_cmp expected actual
+'
+
+test_expect_success "GET IPFS path with ?format=car returns the DAG" '
+  curl -sfo gateway.car "http://127.0.0.1:$port/ipfs/$HASH2?format=car" &&
+  ipfs dag export $HASH2 >expected.car &&
+  test_cmp expected.car gateway.car
+'
+
+test_expect_success "GET IPFS path with an unknown format fails" '
+  test_curl_resp_http_code "http://127.0.0.1:$port/ipfs/$HASH2?format=foo" "HTTP/1.1 400 Bad Request"
+'
+
+test_expect_success "GET dag-cbor object returns JSON" '
+  CBORHASH=$(echo "{\"a\": {\"b\": 1}}" | ipfs dag put) &&
+  curl -sfo actual "http://127.0.0.1:$port/ipfs/$CBORHASH/a" &&
+  printf "{\"b\":1}" >expected &&
+  test_cmp expected actual
+'
+
+test_expect_success "GET dag-cbor object with ?format=dag-cbor returns the block" '
+  curl -sfo actual "http://127.0.0.1:$port/ipfs/$CBORHASH?format=dag-cbor" &&
+  ipfs block get $CBORHASH >expected &&
+  test_cmp expected actual
+'
+
+test_expect_success "GET dag-cbor object from a browser returns html" '
+  curl -sf -H "Accept: text/html" -D html_headers -o actual "http://127.0.0.1:$port/ipfs/$CBORHASH" &&
+  grep "Content-Type: text/html" html_headers &&
+  grep "/ipfs/$CBORHASH/a/b" actual
+'
+
 test_expect_failure "GET IPNS path succeeds" '
   ipfs name publish --allow-offline "$HASH" &&
   PEERID=$(ipfs config Identity.PeerID) &&
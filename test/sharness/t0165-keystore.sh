@@ -74,8 +74,104 @@ test_key_cmd() {
     test_must_fail ipfs key rename -f fooed self 2>&1 | tee key_rename_out &&
     grep -q "Error: cannot overwrite key with name" key_rename_out
   '
+
+  test_expect_success "key export/import roundtrip" '
+    key_id=$(ipfs key gen exported --type=ed25519) &&
+    ipfs key export exported > exported.key &&
+    ipfs key rm exported &&
+    ipfs key import exported exported.key > import_out &&
+    echo $key_id > import_exp &&
+    test_cmp import_exp import_out
+  '
+
+  test_expect_success "key export/import as PEM" '
+    ipfs key export --format=pem-pkcs8-cleartext exported > exported.pem &&
+    grep -q "BEGIN PRIVATE KEY" exported.pem &&
+    ipfs key import --format=pem-pkcs8-cleartext exported-pem exported.pem > import_out &&
+    test_cmp import_exp import_out
+  '
+
+  test_expect_success "key export/import with a passphrase" '
+    IPFS_KEY_PASSPHRASE=secret ipfs key export --format=libp2p-protobuf-encrypted exported > exported.enc &&
+    test_must_fail env IPFS_KEY_PASSPHRASE=wrong ipfs key import --format=libp2p-protobuf-encrypted exported-enc exported.enc &&
+    IPFS_KEY_PASSPHRASE=secret ipfs key import --format=libp2p-protobuf-encrypted exported-enc exported.enc > import_out &&
+    test_cmp import_exp import_out
+  '
+
+  test_expect_success "key export needs a passphrase for the encrypted format" '
+    test_must_fail ipfs key export --format=libp2p-protobuf-encrypted exported </dev/null 2>&1 | tee export_err &&
+    grep -q "IPFS_KEY_PASSPHRASE" export_err
+  '
+
+  test_expect_success "key import refuses to overwrite" '
+    test_must_fail ipfs key import exported exported.key 2>&1 | tee key_import_out &&
+    grep -q "already exists" key_import_out
+  '
 }
 
 test_key_cmd
 
+test_keystore_encryption() {
+  test_expect_success "encrypt the keystore" '
+    ipfs key list | sort > list_before &&
+    IPFS_KEYSTORE_NEW_PASSPHRASE=secret ipfs key rotate-passphrase &&
+    test -f "$IPFS_PATH/keystore/.encrypted"
+  '
+
+  test_expect_success "keys are no longer stored in plaintext" '
+    ipfs_key_file="$IPFS_PATH/keystore/exported" &&
+    head -c 11 "$ipfs_key_file" > key_magic &&
+    printf "ipfs-key/1\n" > key_magic_exp &&
+    test_cmp key_magic_exp key_magic
+  '
+
+  test_expect_success "the encrypted keystore needs a passphrase" '
+    test_must_fail ipfs key list 2>&1 </dev/null | tee list_err &&
+    grep -q "IPFS_KEYSTORE_PASSPHRASE" list_err &&
+    test_must_fail env IPFS_KEYSTORE_PASSPHRASE=wrong ipfs key list
+  '
+
+  test_expect_success "the encrypted keystore lists the same keys" '
+    IPFS_KEYSTORE_PASSPHRASE=secret ipfs key list | sort > list_after &&
+    test_cmp list_before list_after
+  '
+
+  test_expect_success "change the keystore passphrase" '
+    IPFS_KEYSTORE_PASSPHRASE=secret IPFS_KEYSTORE_NEW_PASSPHRASE=other ipfs key rotate-passphrase &&
+    test_must_fail env IPFS_KEYSTORE_PASSPHRASE=secret ipfs key list &&
+    IPFS_KEYSTORE_PASSPHRASE=other ipfs key list | sort > list_after &&
+    test_cmp list_before list_after
+  '
+
+  export IPFS_KEYSTORE_PASSPHRASE=other
+  test_launch_ipfs_daemon
+
+  test_expect_success "rotate-passphrase can't run with the daemon running" '
+    test_must_fail env IPFS_KEYSTORE_NEW_PASSPHRASE=x ipfs key rotate-passphrase 2>&1 | tee rotate_err &&
+    grep -q "daemon is running" rotate_err
+  '
+
+  test_expect_success "key export and import can't run with the daemon running" '
+    test_must_fail ipfs key export self 2>&1 | tee export_err &&
+    grep -q "daemon is running" export_err &&
+    test_must_fail ipfs key import exported-again exported.key 2>&1 | tee import_err &&
+    grep -q "daemon is running" import_err
+  '
+
+  test_expect_success "key export is refused through the API" '
+    curl -s -X POST "http://$API_ADDR/api/v0/key/export?arg=self" > export_api &&
+    grep -q "through the API" export_api
+  '
+
+  test_expect_success "the daemon can use the encrypted keys" '
+    ipfs key list | sort > list_after &&
+    test_cmp list_before list_after
+  '
+
+  test_kill_ipfs_daemon
+  unset IPFS_KEYSTORE_PASSPHRASE
+}
+
+test_keystore_encryption
+
 test_done
@@ -121,6 +121,43 @@ test_expect_success 'stop peer 1' '
   iptb stop 1
 '
 
+# Test combined 'pinned+mfs' strategy
+init_strategy 'pinned+mfs'
+
+test_expect_success 'prepare test files' '
+  echo foo > f1 &&
+  echo bar > f2 &&
+  echo baz > f3
+'
+
+test_expect_success 'add test objects' '
+  HASH_FOO=$(ipfsi 0 add -q --local --pin=false f1) &&
+  HASH_BAR=$(ipfsi 0 add -q --local --pin=false f2) &&
+  HASH_BAZ=$(ipfsi 0 add -q --local f3) &&
+  ipfsi 0 files cp /ipfs/$HASH_BAR /bar
+'
+
+findprovs_empty '$HASH_FOO'
+findprovs_empty '$HASH_BAR'
+findprovs_empty '$HASH_BAZ'
+
+reprovide
+
+findprovs_empty '$HASH_FOO'
+findprovs_expect '$HASH_BAR' '$PEERID_0'
+findprovs_expect '$HASH_BAZ' '$PEERID_0'
+
+test_expect_success 'reprovider stats are reported' '
+  ipfsi 0 bitswap reprovide --stat > stat_out &&
+  grep -q "running: false" stat_out &&
+  grep -q "failures in last run: 0" stat_out &&
+  ! grep -q "last run: never" stat_out
+'
+
+test_expect_success 'stop peer 1' '
+  iptb stop 1
+'
+
 # Test reprovider working with ticking disabled
 test_expect_success 'init iptb' '
   iptb testbed create -type localipfs -force -count $NUM_NODES -init
@@ -207,6 +207,14 @@ test_expect_success "'ipfs p2p stream ls' output looks good" '
   test_cmp expected actual
 '
 
+test_expect_success "'ipfs p2p stream ls -v' shows the peer and traffic of the streams" '
+  ipfsi 0 p2p stream ls -v > actual &&
+  head -1 actual | tr -s " " > header &&
+  echo "ID Protocol Origin Target Peer In Out Duration" > expected_header &&
+  test_cmp expected_header header &&
+  tail -1 actual | tr -s " " | grep "^3 .* $PEERID_1 0 B 0 B"
+'
+
 test_expect_success "'ipfs p2p stream close' closes stream" '
   ipfsi 0 p2p stream close 3 &&
   ipfsi 0 p2p stream ls > actual &&
@@ -371,6 +379,110 @@ test_expect_success 'C->S Close local listener' '
 
 check_test_ports
 
+test_expect_success 'start p2p listener allowing another peer' '
+  ipfsi 0 p2p listen --allow-peer=$(iptb attr get 2 id) /x/p2p-acl /ip4/127.0.0.1/tcp/10101
+'
+
+test_expect_success "'ipfs p2p ls -v' shows the allowed peers" '
+  printf "Protocol Listen Address Target Address Allowed Peers In Out Max Rate\n/x/p2p-acl /ipfs/$PEERID_0 /ip4/127.0.0.1/tcp/10101 $(iptb attr get 2 id) 0 B 0 B none\n" > expected &&
+  ipfsi 0 p2p ls -v | tr -s " " > actual &&
+  test_cmp expected actual
+'
+
+test_expect_success 'streams from other peers are rejected' '
+  ma-pipe-unidir --listen --pidFile=listener.pid recv /ip4/127.0.0.1/tcp/10101 > server.out &
+  test_wait_for_file 30 100ms listener.pid &&
+  ipfsi 1 p2p forward /x/p2p-acl /ip4/127.0.0.1/tcp/10102 /ipfs/$PEERID_0 &&
+  (ma-pipe-unidir send /ip4/127.0.0.1/tcp/10102 < test0.bin || true) &&
+  ipfsi 0 p2p stream ls > actual &&
+  test_must_be_empty actual &&
+  kill -0 $(cat listener.pid) &&
+  test_must_be_empty server.out
+'
+
+test_expect_success 'close the allow-listed listeners' '
+  kill $(cat listener.pid) &&
+  ipfsi 0 p2p close -a &&
+  ipfsi 1 p2p close -a
+'
+
+test_expect_success 'allow-peer can be repeated' '
+  ipfsi 0 p2p listen --allow-peer=$PEERID_1 --allow-peer=$(iptb attr get 2 id) /x/p2p-acl /ip4/127.0.0.1/tcp/10101 &&
+  ipfsi 0 p2p ls -v | grep "/x/p2p-acl" > actual &&
+  grep "$PEERID_1" actual &&
+  grep "$(iptb attr get 2 id)" actual &&
+  ipfsi 0 p2p close -a
+'
+
+test_expect_success 'allow-peer accepts comma-separated peer IDs' '
+  ipfsi 0 p2p listen --allow-peer=$PEERID_1,$(iptb attr get 2 id) /x/p2p-acl /ip4/127.0.0.1/tcp/10101 &&
+  ipfsi 0 p2p ls -v | grep "/x/p2p-acl" > actual &&
+  grep "$PEERID_1" actual &&
+  grep "$(iptb attr get 2 id)" actual &&
+  ipfsi 0 p2p close -a
+'
+
+test_expect_success 'listening with an unknown peer group fails' '
+  test_must_fail ipfsi 0 p2p listen --allow-group=missing /x/p2p-acl /ip4/127.0.0.1/tcp/10101 2> group_err &&
+  grep "peer group \"missing\" not found" group_err
+'
+
+test_expect_success 'start p2p listener allowing a peer group' '
+  ipfsi 0 config --json P2P.PeerGroups "{\"friends\": [\"$PEERID_1\"]}" &&
+  ipfsi 0 p2p listen --allow-group=friends /x/p2p-acl /ip4/127.0.0.1/tcp/10101 &&
+  ipfsi 0 p2p ls -v | tr -s " " | grep "/x/p2p-acl .* $PEERID_1 0 B 0 B none\$" &&
+  ipfsi 0 p2p close -a
+'
+
+check_test_ports
+
+test_expect_success 'persist a p2p listener and forward' '
+  ipfsi 0 p2p listen --persist --allow-peer=$PEERID_1 --max-rate=1MB /x/p2p-persist /ip4/127.0.0.1/tcp/10101 &&
+  ipfsi 0 config P2P.Forwards | grep "\"MaxRate\": 1000000" &&
+  ipfsi 1 p2p forward --persist /x/p2p-persist /ip4/127.0.0.1/tcp/10102 /ipfs/$PEERID_0 &&
+  ipfsi 0 config P2P.Forwards | grep "/x/p2p-persist" &&
+  ipfsi 1 config P2P.Forwards | grep "/ip4/127.0.0.1/tcp/10102"
+'
+
+test_expect_success 'restart the nodes' '
+  iptb stop 0 && iptb stop 1 &&
+  iptb start -wait 0 && iptb start -wait 1 &&
+  iptb connect 0 1
+'
+
+test_expect_success 'the persisted listeners are started again' '
+  echo "/x/p2p-persist /ipfs/$PEERID_0 /ip4/127.0.0.1/tcp/10101" > expected &&
+  ipfsi 0 p2p ls > actual &&
+  test_cmp expected actual &&
+  echo "/x/p2p-persist /ip4/127.0.0.1/tcp/10102 /ipfs/$PEERID_0" > expected &&
+  ipfsi 1 p2p ls > actual &&
+  test_cmp expected actual &&
+  ipfsi 0 p2p ls -v | tr -s " " | grep "/x/p2p-persist .* $PEERID_1 .* 1.0 MB/s\$"
+'
+
+spawn_sending_server
+
+test_server_to_client
+
+test_expect_success "'ipfs p2p ls -v' shows the traffic of the listeners" '
+  ipfsi 0 p2p ls -v | tr -s " " | grep "/x/p2p-persist .* 0 B 7 B 1.0 MB/s\$" &&
+  ipfsi 1 p2p ls -v | tr -s " " | grep "/x/p2p-persist .* 7 B 0 B none\$"
+'
+
+test_expect_success "'ipfs p2p close --persist' removes the persisted listeners" '
+  ipfsi 0 p2p close --persist -p /x/p2p-persist &&
+  ipfsi 1 p2p close --persist -a &&
+  ipfsi 0 p2p ls > actual &&
+  test_must_be_empty actual &&
+  echo "[]" > expected &&
+  ipfsi 0 config P2P.Forwards > actual &&
+  test_cmp expected actual &&
+  ipfsi 1 config P2P.Forwards > actual &&
+  test_cmp expected actual
+'
+
+check_test_ports
+
 test_expect_success 'stop iptb' '
   iptb stop
 '
@@ -0,0 +1,60 @@
+#!/usr/bin/env bash
+#
+# MIT Licensed; see the LICENSE file in this repository.
+#
+
+test_description="Test plugin lifecycle and config"
+
+. lib/test-lib.sh
+
+test_init_ipfs
+
+test_expect_success "'ipfs plugin ls' succeeds" '
+  ipfs plugin ls > plugins_out
+'
+
+test_expect_success "'ipfs plugin ls' lists the preloaded plugins" '
+  grep "^ds-flatfs .* datastore *injected *preloaded$" plugins_out &&
+  grep "^ipld-git .* ipld *injected *preloaded$" plugins_out
+'
+
+test_expect_success "'ipfs plugin ls -v' prints the headers" '
+  ipfs plugin ls -v | head -1 > header_out &&
+  grep "^Name *Version *Type *State *Source$" header_out
+'
+
+test_expect_success "disable the git plugin" '
+  ipfs config --json Plugins.ipld-git.Disabled true
+'
+
+test_expect_success "'ipfs plugin ls' shows the git plugin disabled" '
+  ipfs plugin ls > plugins_out &&
+  grep "^ipld-git .* disabled *preloaded$" plugins_out &&
+  grep "^ds-flatfs .* injected *preloaded$" plugins_out
+'
+
+test_expect_success "git objects can't be added with the plugin disabled" '
+  echo "blob 0" > git_object &&
+  test_must_fail ipfs dag put --format=git --input-enc=raw git_object
+'
+
+test_expect_success "enable the git plugin" '
+  ipfs config --json Plugins.ipld-git.Disabled false &&
+  ipfs plugin ls > plugins_out &&
+  grep "^ipld-git .* injected *preloaded$" plugins_out
+'
+
+test_expect_success "an invalid plugin config is reported" '
+  ipfs config --json Plugins "[]" &&
+  test_must_fail ipfs plugin ls 2> plugins_err &&
+  grep "invalid Plugins config" plugins_err
+'
+
+test_expect_success "the plugin config can be fixed with ipfs config" '
+  ipfs config --json Plugins "{}" 2> config_err &&
+  grep "falling back to the preloaded plugins" config_err &&
+  ipfs plugin ls 2> plugins_err &&
+  test_must_be_empty plugins_err
+'
+
+test_done
@@ -33,6 +33,22 @@ test_expect_success "metrics work" '
   test_fsh cat pro_data
 '
 
+test_expect_success "node metrics are exported" '
+  curl "$API_ADDR/debug/metrics/prometheus" > pro_data &&
+  grep "^ipfs_repo_size_bytes " < pro_data &&
+  grep "^ipfs_pin_pins{type=\"recursive\"} " < pro_data &&
+  grep "^ipfs_gc_runs_total 0" < pro_data &&
+  grep "^ipfs_bitswap_wantlist_keys " < pro_data ||
+  test_fsh cat pro_data
+'
+
+test_expect_success "gc runs are counted" '
+  ipfs repo gc &&
+  curl "$API_ADDR/debug/metrics/prometheus" > pro_data &&
+  grep "^ipfs_gc_runs_total 1" < pro_data ||
+  test_fsh cat pro_data
+'
+
 test_expect_success "pin add api looks right - #3753" '
   HASH=$(echo "foo" | ipfs add -q) &&
   curl "http://$API_ADDR/api/v0/pin/add/$HASH" > pinadd_out &&
@@ -0,0 +1,65 @@
+// Package passphrase reads passphrases from the terminal
+package passphrase
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// ErrNoTerminal is returned when stdin isn't a terminal
+var ErrNoTerminal = errors.New("stdin is not a terminal")
+
+// Read prints the prompt to stderr and reads a line from the terminal,
+// trying to turn off echoing while the passphrase is typed.
+func Read(prompt string) ([]byte, error) {
+	fi, err := os.Stdin.Stat()
+	if err != nil {
+		return nil, err
+	}
+	if fi.Mode()&os.ModeCharDevice == 0 {
+		return nil, ErrNoTerminal
+	}
+
+	fmt.Fprint(os.Stderr, prompt)
+	if stty("-echo") == nil {
+		defer func() {
+			stty("echo")
+			fmt.Fprintln(os.Stderr)
+		}()
+	}
+
+	line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && line == "" {
+		return nil, err
+	}
+
+	return []byte(strings.TrimRight(line, "\r\n")), nil
+}
+
+// ReadNew reads a new passphrase, asking for it twice to rule out typos
+func ReadNew(prompt string) ([]byte, error) {
+	p, err := Read(prompt)
+	if err != nil {
+		return nil, err
+	}
+
+	confirm, err := Read("Repeat the passphrase: ")
+	if err != nil {
+		return nil, err
+	}
+
+	if string(p) != string(confirm) {
+		return nil, errors.New("passphrases don't match")
+	}
+	return p, nil
+}
+
+func stty(arg string) error {
+	cmd := exec.Command("stty", arg)
+	cmd.Stdin = os.Stdin
+	return cmd.Run()
+}